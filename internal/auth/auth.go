@@ -0,0 +1,275 @@
+// Package auth provides optional authentication and role-based access control
+// for the dashboard HTTP API. Credentials can be static API tokens, HTTP basic
+// auth users, or JWTs verified against a local JWKS file.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Authentication modes accepted by New.
+const (
+	ModeNone  = "none"
+	ModeToken = "token"
+	ModeBasic = "basic"
+	ModeJWT   = "jwt"
+)
+
+var (
+	// ErrNoCredentials is returned when a request carries no credentials for
+	// the configured scheme.
+	ErrNoCredentials = errors.New("no credentials provided")
+	// ErrInvalidCredentials is returned when credentials are present but do
+	// not verify.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Role is an access level. Higher roles include every permission of the lower
+// ones: an admin can publish, and a publisher can view.
+type Role int
+
+const (
+	// RoleNone grants nothing; it is the zero value.
+	RoleNone Role = iota
+	// RoleViewer may call read-only routes.
+	RoleViewer
+	// RolePublisher may additionally publish and replay messages.
+	RolePublisher
+	// RoleAdmin may additionally create and modify resources.
+	RoleAdmin
+)
+
+// String returns the role's configuration name.
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RolePublisher:
+		return "publisher"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole parses a role name (case-insensitive).
+func ParseRole(s string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "viewer":
+		return RoleViewer, nil
+	case "publisher":
+		return RolePublisher, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleNone, fmt.Errorf("unknown role %q (want viewer, publisher or admin)", s)
+	}
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Name string
+	Role Role
+}
+
+// Authenticator verifies the credentials on a request.
+type Authenticator interface {
+	// Authenticate returns the caller, ErrNoCredentials if the request has no
+	// credentials for this scheme, or ErrInvalidCredentials (possibly wrapped).
+	Authenticate(r *http.Request) (*Principal, error)
+	// Challenge returns the WWW-Authenticate header value sent with 401s.
+	Challenge() string
+}
+
+// Options configures New. Tokens and Users use the same comma-separated
+// formats as the DASHBOARD_AUTH_* environment variables:
+//
+//	Tokens: "token:role,token2:role"
+//	Users:  "user:password:role,user2:password:role"
+//
+// A missing role defaults to viewer.
+type Options struct {
+	Mode      string
+	Tokens    string
+	Users     string
+	JWKSFile  string
+	Issuer    string
+	Audience  string
+	RoleClaim string
+}
+
+// New builds the Authenticator for opts.Mode. It returns nil for ModeNone (or
+// an empty mode), meaning the API is open.
+func New(opts Options) (Authenticator, error) {
+	switch strings.ToLower(opts.Mode) {
+	case "", ModeNone:
+		return nil, nil
+	case ModeToken:
+		return NewTokenAuthenticator(opts.Tokens)
+	case ModeBasic:
+		return NewBasicAuthenticator(opts.Users)
+	case ModeJWT:
+		return NewJWTAuthenticator(opts.JWKSFile, opts.Issuer, opts.Audience, opts.RoleClaim)
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q (want none, token, basic or jwt)", opts.Mode)
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller attached by Middleware, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// TokenAuthenticator accepts static bearer tokens (Authorization: Bearer <t>)
+// or the X-API-Token header.
+type TokenAuthenticator struct {
+	tokens []tokenEntry
+}
+
+type tokenEntry struct {
+	token string
+	role  Role
+}
+
+// NewTokenAuthenticator parses a "token:role,..." list.
+func NewTokenAuthenticator(spec string) (*TokenAuthenticator, error) {
+	a := &TokenAuthenticator{}
+	for _, entry := range splitList(spec) {
+		token, roleStr, _ := strings.Cut(entry, ":")
+		role, err := parseRoleOrDefault(roleStr)
+		if err != nil {
+			return nil, fmt.Errorf("auth token entry: %w", err)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("auth token entry has an empty token")
+		}
+		a.tokens = append(a.tokens, tokenEntry{token: token, role: role})
+	}
+	if len(a.tokens) == 0 {
+		return nil, fmt.Errorf("token auth requires at least one token")
+	}
+	return a, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		token = r.Header.Get("X-API-Token")
+	}
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	// Compare against every token so timing does not reveal which matched.
+	var match *tokenEntry
+	for i := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.tokens[i].token)) == 1 {
+			match = &a.tokens[i]
+		}
+	}
+	if match == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: "token", Role: match.role}, nil
+}
+
+// Challenge implements Authenticator.
+func (a *TokenAuthenticator) Challenge() string {
+	return `Bearer realm="pubsub-emulator"`
+}
+
+// BasicAuthenticator accepts HTTP basic auth users.
+type BasicAuthenticator struct {
+	users map[string]basicUser
+}
+
+type basicUser struct {
+	password string
+	role     Role
+}
+
+// NewBasicAuthenticator parses a "user:password:role,..." list.
+func NewBasicAuthenticator(spec string) (*BasicAuthenticator, error) {
+	a := &BasicAuthenticator{users: make(map[string]basicUser)}
+	for _, entry := range splitList(spec) {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("auth user entry must be user:password[:role]")
+		}
+		var roleStr string
+		if len(parts) == 3 {
+			roleStr = parts[2]
+		}
+		role, err := parseRoleOrDefault(roleStr)
+		if err != nil {
+			return nil, fmt.Errorf("auth user %s: %w", parts[0], err)
+		}
+		a.users[parts[0]] = basicUser{password: parts[1], role: role}
+	}
+	if len(a.users) == 0 {
+		return nil, fmt.Errorf("basic auth requires at least one user")
+	}
+	return a, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *BasicAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	user, found := a.users[username]
+	if !found {
+		return nil, ErrInvalidCredentials
+	}
+	if subtle.ConstantTimeCompare([]byte(password), []byte(user.password)) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: username, Role: user.role}, nil
+}
+
+// Challenge implements Authenticator.
+func (a *BasicAuthenticator) Challenge() string {
+	return `Basic realm="pubsub-emulator", charset="UTF-8"`
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func parseRoleOrDefault(s string) (Role, error) {
+	if strings.TrimSpace(s) == "" {
+		return RoleViewer, nil
+	}
+	return ParseRole(s)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for part := range strings.SplitSeq(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Role
+		wantErr bool
+	}{
+		{"viewer", RoleViewer, false},
+		{"Publisher", RolePublisher, false},
+		{" admin ", RoleAdmin, false},
+		{"root", RoleNone, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRole(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRole(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseRole(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNew_Modes(t *testing.T) {
+	a, err := New(Options{Mode: "none"})
+	if err != nil || a != nil {
+		t.Errorf("Expected nil authenticator for mode none, got %v, %v", a, err)
+	}
+	if _, err := New(Options{Mode: "token", Tokens: "abc:admin"}); err != nil {
+		t.Errorf("Expected token mode to build, got %v", err)
+	}
+	if _, err := New(Options{Mode: "basic", Users: "alice:secret:viewer"}); err != nil {
+		t.Errorf("Expected basic mode to build, got %v", err)
+	}
+	if _, err := New(Options{Mode: "magic"}); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+	if _, err := New(Options{Mode: "jwt"}); err == nil {
+		t.Error("Expected error for jwt mode without JWKS file")
+	}
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	a, err := NewTokenAuthenticator("view-token:viewer, pub-token:publisher, plain")
+	if err != nil {
+		t.Fatalf("Failed to build authenticator: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		want    Role
+		wantErr error
+	}{
+		{"bearer", "Authorization", "Bearer pub-token", RolePublisher, nil},
+		{"api token header", "X-API-Token", "view-token", RoleViewer, nil},
+		{"default role", "Authorization", "Bearer plain", RoleViewer, nil},
+		{"wrong token", "Authorization", "Bearer nope", RoleNone, ErrInvalidCredentials},
+		{"no credentials", "", "", RoleNone, ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			p, err := a.Authenticate(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && p.Role != tt.want {
+				t.Errorf("Expected role %v, got %v", tt.want, p.Role)
+			}
+		})
+	}
+}
+
+func TestNewTokenAuthenticator_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "tok:superuser", ":admin"} {
+		if _, err := NewTokenAuthenticator(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	a, err := NewBasicAuthenticator("alice:s3cret:admin,bob:pw")
+	if err != nil {
+		t.Fatalf("Failed to build authenticator: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.SetBasicAuth("alice", "s3cret")
+	p, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Expected alice to authenticate, got %v", err)
+	}
+	if p.Name != "alice" || p.Role != RoleAdmin {
+		t.Errorf("Unexpected principal %+v", p)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.SetBasicAuth("bob", "pw")
+	if p, err := a.Authenticate(req); err != nil || p.Role != RoleViewer {
+		t.Errorf("Expected bob to be a viewer, got %+v, %v", p, err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.SetBasicAuth("alice", "wrong")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials, got %v", err)
+	}
+}
+
+func TestNewBasicAuthenticator_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "alice", "alice::admin", "alice:pw:owner"} {
+		if _, err := NewBasicAuthenticator(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultRoleClaim is the JWT claim consulted for the caller's role.
+const defaultRoleClaim = "role"
+
+// clockSkew tolerates small clock differences when checking exp/nbf.
+const clockSkew = 30 * time.Second
+
+// JWTAuthenticator verifies RS256/384/512 and ES256/384/512 bearer tokens
+// against keys loaded from a local JWKS file, as issued by an OIDC provider.
+type JWTAuthenticator struct {
+	keys      map[string]crypto.PublicKey
+	issuer    string
+	audience  string
+	roleClaim string
+	now       func() time.Time
+}
+
+// NewJWTAuthenticator loads the JWKS at jwksFile. issuer and audience are
+// checked when non-empty. roleClaim names the claim holding the role (a string
+// or a list of strings, the highest of which wins); it defaults to "role".
+func NewJWTAuthenticator(jwksFile, issuer, audience, roleClaim string) (*JWTAuthenticator, error) {
+	if jwksFile == "" {
+		return nil, fmt.Errorf("jwt auth requires a JWKS file")
+	}
+	raw, err := os.ReadFile(jwksFile) // #nosec G304 -- path comes from operator configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	keys, err := ParseJWKS(raw)
+	if err != nil {
+		return nil, err
+	}
+	if roleClaim == "" {
+		roleClaim = defaultRoleClaim
+	}
+	return &JWTAuthenticator{
+		keys:      keys,
+		issuer:    issuer,
+		audience:  audience,
+		roleClaim: roleClaim,
+		now:       time.Now,
+	}, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+	claims, err := a.verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	role := roleFromClaim(claims[a.roleClaim])
+	if role == RoleNone {
+		return nil, fmt.Errorf("%w: token has no recognised %q claim", ErrInvalidCredentials, a.roleClaim)
+	}
+	name, _ := claims["sub"].(string)
+	return &Principal{Name: name, Role: role}, nil
+}
+
+// Challenge implements Authenticator.
+func (a *JWTAuthenticator) Challenge() string {
+	return `Bearer realm="pubsub-emulator"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify checks the signature and registered claims of a compact JWS and
+// returns its claims.
+func (a *JWTAuthenticator) verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %w", err)
+	}
+
+	key, err := a.lookupKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+
+	now := a.now()
+	if exp, ok := numericClaim(claims, "exp"); ok && now.After(exp.Add(clockSkew)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(clockSkew).Before(nbf) {
+		return nil, fmt.Errorf("token not yet valid")
+	}
+	if a.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.issuer {
+			return nil, fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+	if a.audience != "" && !audienceMatches(claims["aud"], a.audience) {
+		return nil, fmt.Errorf("token not issued for audience %q", a.audience)
+	}
+	return claims, nil
+}
+
+// lookupKey picks the key by kid; tokens without a kid are accepted only when
+// the JWKS holds exactly one key.
+func (a *JWTAuthenticator) lookupKey(kid string) (crypto.PublicKey, error) {
+	if kid != "" {
+		if key, ok := a.keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("token has no key id")
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %s does not match RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, sig); err != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("algorithm %s does not match EC key", alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return fmt.Errorf("invalid signature")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type")
+	}
+	return nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses a JSON Web Key Set, returning its RSA and EC signing keys
+// indexed by kid. Keys marked for encryption ("use": "enc") are skipped.
+func ParseJWKS(raw []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for i, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %d (%s): %w", i, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func numericClaim(claims map[string]any, name string) (time.Time, bool) {
+	v, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(v), 0), true
+}
+
+func audienceMatches(aud any, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []any:
+		return slices.ContainsFunc(v, func(a any) bool { s, _ := a.(string); return s == want })
+	}
+	return false
+}
+
+// roleFromClaim accepts a single role name or a list, returning the highest
+// recognised role.
+func roleFromClaim(v any) Role {
+	var names []string
+	switch c := v.(type) {
+	case string:
+		names = strings.Fields(c)
+	case []any:
+		for _, item := range c {
+			if s, ok := item.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+
+	best := RoleNone
+	for _, name := range names {
+		if role, err := ParseRole(name); err == nil && role > best {
+			best = role
+		}
+	}
+	return best
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	return signed + "." + b64(sig)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signed + "." + b64(sig)
+}
+
+func writeJWKS(t *testing.T, rsaKey *rsa.PublicKey, ecKey *ecdsa.PublicKey) string {
+	t.Helper()
+	ecX := make([]byte, 32)
+	ecY := make([]byte, 32)
+	ecKey.X.FillBytes(ecX)
+	ecKey.Y.FillBytes(ecY)
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecX), "y": b64(ecY)},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}}
+	raw, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	a, err := NewJWTAuthenticator(writeJWKS(t, &rsaKey.PublicKey, &ecKey.PublicKey), "https://issuer.test", "pubsub-dashboard", "roles")
+	if err != nil {
+		t.Fatalf("Failed to build authenticator: %v", err)
+	}
+
+	now := time.Now()
+	valid := func(extra map[string]any) map[string]any {
+		claims := map[string]any{
+			"sub":   "ci-bot",
+			"iss":   "https://issuer.test",
+			"aud":   []string{"other", "pubsub-dashboard"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"roles": []string{"viewer", "publisher"},
+		}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantRole Role
+		wantErr  bool
+	}{
+		{"rsa valid", signRS256(t, rsaKey, "rsa-1", valid(nil)), RolePublisher, false},
+		{"ec valid", signES256(t, ecKey, "ec-1", valid(map[string]any{"roles": "admin"})), RoleAdmin, false},
+		{"expired", signRS256(t, rsaKey, "rsa-1", valid(map[string]any{"exp": now.Add(-time.Hour).Unix()})), RoleNone, true},
+		{"not yet valid", signRS256(t, rsaKey, "rsa-1", valid(map[string]any{"nbf": now.Add(time.Hour).Unix()})), RoleNone, true},
+		{"wrong issuer", signRS256(t, rsaKey, "rsa-1", valid(map[string]any{"iss": "evil"})), RoleNone, true},
+		{"wrong audience", signRS256(t, rsaKey, "rsa-1", valid(map[string]any{"aud": "other"})), RoleNone, true},
+		{"no role", signRS256(t, rsaKey, "rsa-1", valid(map[string]any{"roles": []string{"guest"}})), RoleNone, true},
+		{"wrong key", signRS256(t, otherKey, "rsa-1", valid(nil)), RoleNone, true},
+		{"unknown kid", signRS256(t, rsaKey, "rsa-9", valid(nil)), RoleNone, true},
+		{"alg key mismatch", signRS256(t, rsaKey, "ec-1", valid(nil)), RoleNone, true},
+		{"malformed", "not-a-jwt", RoleNone, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			p, err := a.Authenticate(req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Expected ErrInvalidCredentials, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected token to verify, got %v", err)
+			}
+			if p.Role != tt.wantRole || p.Name != "ci-bot" {
+				t.Errorf("Unexpected principal %+v", p)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials, got %v", err)
+	}
+}
+
+func TestParseJWKS_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":    `{`,
+		"no keys":     `{"keys": []}`,
+		"bad kty":     `{"keys": [{"kty": "oct", "k": "AAAA"}]}`,
+		"bad curve":   `{"keys": [{"kty": "EC", "crv": "P-192", "x": "AQ", "y": "AQ"}]}`,
+		"bad modulus": `{"keys": [{"kty": "RSA", "n": "", "e": "AQAB"}]}`,
+	}
+	for name, raw := range tests {
+		if _, err := ParseJWKS([]byte(raw)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
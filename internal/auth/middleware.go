@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+// publicPaths are served without credentials: the HTML shell and its static
+// assets (which hold no data) and the health probes used by container
+// healthchecks.
+var publicPaths = []string{"/", "/static/", "/api/health", "/livez", "/readyz"}
+
+// publisherPaths are the state-changing routes a publisher may call; any other
+// non-read request requires admin. A trailing slash matches the routes below
+// it.
+var publisherPaths = []string{"/api/publish", "/api/replay", "/api/messages/import", "/api/replays", "/api/replays/"}
+
+// viewerPaths are POST routes that only compute a result from the request
+// body, so a viewer may call them.
+var viewerPaths = []string{"/api/messages/diff"}
+
+// RequiredRole returns the minimum role needed for r, or RoleNone if the route
+// is public.
+func RequiredRole(r *http.Request) Role {
+	if isPublic(r.URL.Path) {
+		return RoleNone
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleViewer
+	}
+	for _, p := range viewerPaths {
+		if r.URL.Path == p && r.Method == http.MethodPost {
+			return RoleViewer
+		}
+	}
+	for _, p := range publisherPaths {
+		if matchPath(r.URL.Path, p) {
+			return RolePublisher
+		}
+	}
+	return RoleAdmin
+}
+
+func isPublic(path string) bool {
+	for _, p := range publicPaths {
+		if matchPath(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath reports whether path is pattern or, when pattern ends in a
+// slash (other than "/" itself), lies below it.
+func matchPath(path, pattern string) bool {
+	return path == pattern || (strings.HasSuffix(pattern, "/") && pattern != "/" && strings.HasPrefix(path, pattern))
+}
+
+// Middleware enforces authentication and role checks. A nil Authenticator
+// disables it. Public routes are served without credentials; everything else
+// needs a principal whose role satisfies RequiredRole.
+func Middleware(a Authenticator, log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if a == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			required := RequiredRole(r)
+			if required == RoleNone {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := a.Authenticate(r)
+			if err != nil {
+				if !errors.Is(err, ErrNoCredentials) {
+					log.With("path", r.URL.Path, "remote_addr", r.RemoteAddr, "error", err.Error()).
+						Warn("Rejected dashboard credentials")
+				}
+				w.Header().Set("WWW-Authenticate", a.Challenge())
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if principal.Role < required {
+				log.With("path", r.URL.Path, "principal", principal.Name, "role", principal.Role.String(), "required", required.String()).
+					Warn("Dashboard request forbidden")
+				http.Error(w, "Forbidden: requires "+required.String()+" role", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   Role
+	}{
+		{http.MethodGet, "/", RoleNone},
+		{http.MethodGet, "/static/js/dashboard.js", RoleNone},
+		{http.MethodGet, "/api/health", RoleNone},
+		{http.MethodGet, "/livez", RoleNone},
+		{http.MethodGet, "/readyz", RoleNone},
+		{http.MethodGet, "/api/stats", RoleViewer},
+		{http.MethodGet, "/api/messages/abc", RoleViewer},
+		{http.MethodPost, "/api/publish", RolePublisher},
+		{http.MethodPost, "/api/replay", RolePublisher},
+		{http.MethodPost, "/api/messages/import", RolePublisher},
+		{http.MethodPost, "/api/replays", RolePublisher},
+		{http.MethodPost, "/api/replays/1/pause", RolePublisher},
+		{http.MethodDelete, "/api/replays/1", RolePublisher},
+		{http.MethodGet, "/api/replays/1/events", RoleViewer},
+		{http.MethodPost, "/api/messages/diff", RoleViewer},
+		{http.MethodDelete, "/api/messages/diff", RoleAdmin},
+		{http.MethodPost, "/api/topics", RoleAdmin},
+		{http.MethodPost, "/api/subscriptions", RoleAdmin},
+		{http.MethodDelete, "/api/anything", RoleAdmin},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := RequiredRole(req); got != tt.want {
+			t.Errorf("%s %s: expected %v, got %v", tt.method, tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a, err := NewTokenAuthenticator("v:viewer,p:publisher,a:admin")
+	if err != nil {
+		t.Fatalf("Failed to build authenticator: %v", err)
+	}
+
+	var seen *Principal
+	handler := Middleware(a, logger.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"public health", http.MethodGet, "/api/health", "", http.StatusOK},
+		{"missing token", http.MethodGet, "/api/stats", "", http.StatusUnauthorized},
+		{"bad token", http.MethodGet, "/api/stats", "nope", http.StatusUnauthorized},
+		{"viewer reads", http.MethodGet, "/api/stats", "v", http.StatusOK},
+		{"viewer cannot publish", http.MethodPost, "/api/publish", "v", http.StatusForbidden},
+		{"publisher publishes", http.MethodPost, "/api/publish", "p", http.StatusOK},
+		{"publisher cannot create", http.MethodPost, "/api/topics", "p", http.StatusForbidden},
+		{"admin creates", http.MethodPost, "/api/topics", "a", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate challenge on 401")
+			}
+			if rec.Code == http.StatusOK && tt.token != "" && seen == nil {
+				t.Error("Expected principal in request context")
+			}
+		})
+	}
+}
+
+func TestMiddleware_NilAuthenticatorPassesThrough(t *testing.T) {
+	called := false
+	handler := Middleware(nil, logger.New())(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/topics", nil))
+	if !called {
+		t.Error("Expected request to reach the handler when auth is disabled")
+	}
+}
@@ -0,0 +1,229 @@
+package chaos
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// defaultAckDeadline is assumed when a subscription's ack deadline
+	// cannot be looked up.
+	defaultAckDeadline = 10 * time.Second
+	// maxExpiredAcks bounds how many expired ack IDs are remembered so late
+	// acks for them can be ignored.
+	maxExpiredAcks = 10_000
+	// leaseSweepInterval is how often leases past their deadline are
+	// reported outside test mode.
+	leaseSweepInterval = time.Second
+)
+
+// Observer is told what clients of the proxy do with the messages it
+// delivers. Message IDs are the emulator's and subscriptions are full
+// resource names.
+type Observer interface {
+	// Nacked reports a nack, including an ack a rule turned into one.
+	Nacked(messageID, subscription string)
+	// Expired reports an ack deadline passing without an ack or nack.
+	Expired(messageID, subscription string)
+}
+
+// SetObserver reports the nacks and expired ack deadlines of messages
+// delivered through the proxy to o. Call it before Serve.
+func (p *Proxy) SetObserver(o Observer) {
+	p.observer = o
+	if p.leases == nil {
+		p.leases = newLeases(time.Now, false)
+	}
+}
+
+// lease is a delivered message awaiting its ack.
+type lease struct {
+	ackID        string
+	subscription string
+	// messageID is the emulator's ID for the message.
+	messageID string
+	deadline  time.Time
+}
+
+// leases tracks delivered messages, by ack ID, until they are acked, nacked
+// or their ack deadline passes.
+type leases struct {
+	now func() time.Time
+	// remember keeps the ack IDs of expired leases so late acks for them
+	// can be ignored, for test mode where the proxy expires them itself.
+	remember bool
+
+	mu        sync.Mutex
+	active    map[string]lease
+	expired   map[string]bool
+	deadlines map[string]time.Duration
+}
+
+func newLeases(now func() time.Time, remember bool) *leases {
+	return &leases{
+		now:       now,
+		remember:  remember,
+		active:    make(map[string]lease),
+		expired:   make(map[string]bool),
+		deadlines: make(map[string]time.Duration),
+	}
+}
+
+// track leases delivered messages for ackDeadline.
+func (l *leases) track(sub string, ackDeadline time.Duration, msgs []*pubsubpb.ReceivedMessage) {
+	deadline := l.now().Add(ackDeadline)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, m := range msgs {
+		if msg := m.GetMessage(); msg != nil {
+			l.active[m.AckId] = lease{ackID: m.AckId, subscription: sub, messageID: msg.MessageId, deadline: deadline}
+		}
+	}
+}
+
+// live drops ack IDs whose deadline has passed.
+func (l *leases) live(ackIDs []string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	live := ackIDs[:0:0]
+	for _, id := range ackIDs {
+		if l.expired[id] {
+			delete(l.expired, id)
+			continue
+		}
+		live = append(live, id)
+	}
+	return live
+}
+
+// settle ends the leases of acked or nacked messages and returns them.
+func (l *leases) settle(ackIDs []string) []lease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var ended []lease
+	for _, id := range ackIDs {
+		if ls, ok := l.active[id]; ok {
+			ended = append(ended, ls)
+			delete(l.active, id)
+		}
+	}
+	return ended
+}
+
+// extend moves a lease's deadline to seconds from now. Zero is a nack,
+// which ends the lease; the nacked leases are returned.
+func (l *leases) extend(ackIDs []string, seconds []int32) []lease {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var nacked []lease
+	for i, id := range ackIDs {
+		ls, ok := l.active[id]
+		if !ok || i >= len(seconds) {
+			continue
+		}
+		if seconds[i] <= 0 {
+			nacked = append(nacked, ls)
+			delete(l.active, id)
+			continue
+		}
+		ls.deadline = now.Add(time.Duration(seconds[i]) * time.Second)
+		l.active[id] = ls
+	}
+	return nacked
+}
+
+// takeExpired removes and returns the leases whose deadline has passed.
+func (l *leases) takeExpired() []lease {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var expired []lease
+	for id, ls := range l.active {
+		if now.Before(ls.deadline) {
+			continue
+		}
+		expired = append(expired, ls)
+		delete(l.active, id)
+		if l.remember && len(l.expired) < maxExpiredAcks {
+			l.expired[id] = true
+		}
+	}
+	return expired
+}
+
+// reset forgets every lease and expired ack ID. Subscription ack deadlines
+// are kept.
+func (l *leases) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clear(l.active)
+	clear(l.expired)
+}
+
+// nacked reports leases ended by a nack to the observer.
+func (p *Proxy) nacked(ended []lease) {
+	if p.observer == nil {
+		return
+	}
+	for _, ls := range ended {
+		p.observer.Nacked(ls.messageID, ls.subscription)
+	}
+}
+
+// expired reports leases past their deadline to the observer.
+func (p *Proxy) expired(ended []lease) {
+	if p.observer == nil {
+		return
+	}
+	for _, ls := range ended {
+		p.observer.Expired(ls.messageID, ls.subscription)
+	}
+}
+
+// sweepLeases reports expired leases every leaseSweepInterval until ctx is
+// cancelled. The emulator redelivers the messages on its own.
+func (p *Proxy) sweepLeases(ctx context.Context) {
+	ticker := time.NewTicker(leaseSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.expired(p.leases.takeExpired())
+		}
+	}
+}
+
+// ackDeadline returns the ack deadline configured on sub, looked up once.
+func (p *Proxy) ackDeadline(ctx context.Context, sub string) time.Duration {
+	l := p.leases
+	l.mu.Lock()
+	d, ok := l.deadlines[sub]
+	l.mu.Unlock()
+	if ok {
+		return d
+	}
+
+	d = defaultAckDeadline
+	req, err := proto.Marshal(&pubsubpb.GetSubscriptionRequest{Subscription: sub})
+	if err == nil {
+		var resp frame
+		if err := p.backend.Invoke(ctx, pubsubpb.Subscriber_GetSubscription_FullMethodName, &frame{req}, &resp, grpc.ForceCodec(rawCodec{})); err == nil {
+			var s pubsubpb.Subscription
+			if proto.Unmarshal(resp.payload, &s) == nil && s.AckDeadlineSeconds > 0 {
+				d = time.Duration(s.AckDeadlineSeconds) * time.Second
+			}
+		}
+	}
+	l.mu.Lock()
+	l.deadlines[sub] = d
+	l.mu.Unlock()
+	return d
+}
@@ -0,0 +1,136 @@
+package chaos
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+)
+
+// recordingObserver records the events a proxy reports as
+// "kind:messageID@subscription".
+type recordingObserver struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (o *recordingObserver) record(kind, messageID, subscription string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.got = append(o.got, kind+":"+messageID+"@"+subscription)
+}
+
+func (o *recordingObserver) Nacked(messageID, subscription string) {
+	o.record("nacked", messageID, subscription)
+}
+
+func (o *recordingObserver) Expired(messageID, subscription string) {
+	o.record("expired", messageID, subscription)
+}
+
+// events returns the "messageID@subscription" of each event of kind.
+func (o *recordingObserver) events(kind string) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var out []string
+	for _, e := range o.got {
+		if rest, ok := strings.CutPrefix(e, kind+":"); ok {
+			out = append(out, rest)
+		}
+	}
+	return out
+}
+
+func TestLeases(t *testing.T) {
+	c := clock.NewManual(testStart)
+	l := newLeases(c.Now, false)
+	l.track("sub", 10*time.Second, []*pubsubpb.ReceivedMessage{
+		{AckId: "a1", Message: &pubsubpb.PubsubMessage{MessageId: "m1"}},
+		{AckId: "a2", Message: &pubsubpb.PubsubMessage{MessageId: "m2"}},
+		{AckId: "a3", Message: &pubsubpb.PubsubMessage{MessageId: "m3"}},
+	})
+
+	if nacked := l.extend([]string{"a1", "a2"}, []int32{60, 0}); len(nacked) != 1 || nacked[0].messageID != "m2" {
+		t.Errorf("Expected a zero deadline to nack m2, got %+v", nacked)
+	}
+	c.Advance(30 * time.Second)
+	expired := l.takeExpired()
+	if len(expired) != 1 || expired[0].messageID != "m3" {
+		t.Errorf("Expected m3 expired, got %+v", expired)
+	}
+	if acked := l.settle([]string{"a1", "a3"}); len(acked) != 1 || acked[0].messageID != "m1" {
+		t.Errorf("Expected only m1 still leased, got %+v", acked)
+	}
+	// Outside test mode late acks are passed on to the emulator.
+	if live := l.live([]string{"a3"}); !slices.Equal(live, []string{"a3"}) {
+		t.Errorf("Expected the late ack kept, got %v", live)
+	}
+	// A reset forgets the leases of the previous run.
+	l.track("sub", 10*time.Second, []*pubsubpb.ReceivedMessage{
+		{AckId: "a4", Message: &pubsubpb.PubsubMessage{MessageId: "m4"}},
+	})
+	l.reset()
+	c.Advance(time.Hour)
+	if expired := l.takeExpired(); len(expired) != 0 {
+		t.Errorf("Expected no leases after a reset, got %+v", expired)
+	}
+}
+
+func TestProxy_ObserverReportsNacks(t *testing.T) {
+	observed := &recordingObserver{}
+	pt := setupProxyTest(t, func(p *Proxy) { p.SetObserver(observed) })
+	first, _ := pt.publish(t, "one")
+	second, _ := pt.publish(t, "two")
+	msgs := pt.pull(t)
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(msgs))
+	}
+	byID := map[string]*pubsubpb.ReceivedMessage{}
+	for _, m := range msgs {
+		byID[m.Message.MessageId] = m
+	}
+
+	_, err := pt.sub.ModifyAckDeadline(context.Background(), &pubsubpb.ModifyAckDeadlineRequest{
+		Subscription: testSub,
+		AckIds:       []string{byID[first].AckId},
+	})
+	if err != nil {
+		t.Fatalf("Failed to nack: %v", err)
+	}
+	pt.addRule(t, Rule{Action: ActionRedeliver, Subscription: testSub})
+	pt.ack(t, byID[second])
+
+	want := []string{first + "@" + testSub, second + "@" + testSub}
+	if got := observed.events("nacked"); !slices.Equal(got, want) {
+		t.Errorf("Expected nacks %v, got %v", want, got)
+	}
+}
+
+func TestProxy_ObserverReportsExpiries(t *testing.T) {
+	observed := &recordingObserver{}
+	c := clock.NewManual(time.Now())
+	var proxy *Proxy
+	pt := setupProxyTest(t, func(p *Proxy) {
+		p.SetObserver(observed)
+		p.leases.now = c.Now
+		proxy = p
+	})
+	id, _ := pt.publish(t, "hello")
+	acked, _ := pt.publish(t, "acked")
+	for _, m := range pt.pull(t) {
+		if m.Message.MessageId == acked {
+			pt.ack(t, m)
+		}
+	}
+
+	c.Advance(11 * time.Second)
+	proxy.expired(proxy.leases.takeExpired())
+	if got := observed.events("expired"); !slices.Equal(got, []string{id + "@" + testSub}) {
+		t.Errorf("Expected only %s to expire, got %v", id, got)
+	}
+}
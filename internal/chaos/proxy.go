@@ -0,0 +1,529 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Proxy is a gRPC server that forwards every call to the emulator, applying
+// the engine's rules to publishes, pulls and acks on the way. Calls the
+// rules do not cover are relayed unchanged.
+type Proxy struct {
+	engine  *Engine
+	backend *grpc.ClientConn
+	log     *logger.Logger
+	server  *grpc.Server
+	// ctx is cancelled by Stop to end the proxy's background work.
+	ctx  context.Context
+	stop context.CancelFunc
+	// test is set in test mode; see SetTestMode.
+	test *testMode
+	// leases tracks delivered messages in test mode or with an observer.
+	leases   *leases
+	observer Observer
+}
+
+// NewProxy creates a Proxy relaying to backend, typically a connection to
+// the emulator.
+func NewProxy(engine *Engine, backend *grpc.ClientConn, log *logger.Logger) *Proxy {
+	p := &Proxy{engine: engine, backend: backend, log: log}
+	p.ctx, p.stop = context.WithCancel(context.Background())
+	p.server = grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(p.handle),
+	)
+	return p
+}
+
+// Serve accepts connections on ln until Stop is called.
+func (p *Proxy) Serve(ln net.Listener) error {
+	if p.leases != nil && p.test == nil {
+		go p.sweepLeases(p.ctx)
+	}
+	return p.server.Serve(ln)
+}
+
+// Stop closes the listener and cancels in-flight calls.
+func (p *Proxy) Stop() {
+	p.stop()
+	p.server.Stop()
+}
+
+// handle relays one call, intercepting the methods rules apply to.
+func (p *Proxy) handle(_ any, ss grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(ss)
+	switch method {
+	case pubsubpb.Publisher_Publish_FullMethodName:
+		return p.unary(ss, method, p.publish)
+	case pubsubpb.Subscriber_Pull_FullMethodName:
+		return p.unary(ss, method, p.pull)
+	case pubsubpb.Subscriber_Acknowledge_FullMethodName:
+		return p.unary(ss, method, p.acknowledge)
+	case pubsubpb.Subscriber_ModifyAckDeadline_FullMethodName:
+		if p.leases != nil {
+			return p.unary(ss, method, p.modifyAckDeadline)
+		}
+		return p.stream(ss, method, nil)
+	case pubsubpb.Subscriber_StreamingPull_FullMethodName:
+		return p.stream(ss, method, &streamingPull{proxy: p})
+	default:
+		return p.stream(ss, method, nil)
+	}
+}
+
+// forwardFunc invokes a unary method on the backend with a marshalled
+// request and returns the marshalled response.
+type forwardFunc func(ctx context.Context, method string, req []byte) ([]byte, error)
+
+// unary reads the single request of a unary call and passes it to intercept,
+// which decides whether and how to forward it.
+func (p *Proxy) unary(ss grpc.ServerStream, method string, intercept func(ctx context.Context, req []byte, forward forwardFunc) ([]byte, error)) error {
+	var req frame
+	if err := ss.RecvMsg(&req); err != nil {
+		return err
+	}
+	forward := func(ctx context.Context, method string, req []byte) ([]byte, error) {
+		var header, trailer metadata.MD
+		var resp frame
+		err := p.backend.Invoke(ctx, method, &frame{req}, &resp,
+			grpc.ForceCodec(rawCodec{}), grpc.Header(&header), grpc.Trailer(&trailer))
+		_ = ss.SetHeader(header)
+		ss.SetTrailer(trailer)
+		return resp.payload, err
+	}
+	resp, err := intercept(outgoing(ss.Context()), req.payload, forward)
+	if err != nil {
+		return err
+	}
+	return ss.SendMsg(&frame{resp})
+}
+
+func (p *Proxy) publish(ctx context.Context, req []byte, forward forwardFunc) ([]byte, error) {
+	var in pubsubpb.PublishRequest
+	if err := proto.Unmarshal(req, &in); err != nil {
+		return forward(ctx, pubsubpb.Publisher_Publish_FullMethodName, req)
+	}
+	if err := p.delay(ctx, in.GetTopic()); err != nil {
+		return nil, err
+	}
+	if rule, ok := p.engine.Fire(ActionPublishError, in.GetTopic()); ok {
+		p.log.Debug("Chaos rule %s failed publish to %s with %s", rule.ID, in.GetTopic(), rule.Code)
+		return nil, status.Errorf(rule.StatusCode(), "chaos: injected %s by rule %s", rule.Code, rule.ID)
+	}
+	resp, err := forward(ctx, pubsubpb.Publisher_Publish_FullMethodName, req)
+	if err != nil || p.test == nil {
+		return resp, err
+	}
+	return p.test.rewritePublish(resp)
+}
+
+func (p *Proxy) pull(ctx context.Context, req []byte, forward forwardFunc) ([]byte, error) {
+	var in pubsubpb.PullRequest
+	if err := proto.Unmarshal(req, &in); err != nil {
+		return forward(ctx, pubsubpb.Subscriber_Pull_FullMethodName, req)
+	}
+	if err := p.delay(ctx, in.GetSubscription()); err != nil {
+		return nil, err
+	}
+	resp, err := forward(ctx, pubsubpb.Subscriber_Pull_FullMethodName, req)
+	if err != nil {
+		return nil, err
+	}
+	var out pubsubpb.PullResponse
+	if err := proto.Unmarshal(resp, &out); err != nil {
+		return resp, nil
+	}
+	var changed bool
+	if out.ReceivedMessages, changed = p.deliver(ctx, in.GetSubscription(), 0, out.ReceivedMessages); !changed {
+		return resp, nil
+	}
+	return proto.Marshal(&out)
+}
+
+func (p *Proxy) acknowledge(ctx context.Context, req []byte, forward forwardFunc) ([]byte, error) {
+	var in pubsubpb.AcknowledgeRequest
+	if err := proto.Unmarshal(req, &in); err != nil {
+		return forward(ctx, pubsubpb.Subscriber_Acknowledge_FullMethodName, req)
+	}
+	sub := in.GetSubscription()
+	if err := p.delay(ctx, sub); err != nil {
+		return nil, err
+	}
+	ackIDs := in.GetAckIds()
+	if p.test != nil {
+		ackIDs = p.leases.live(ackIDs)
+	}
+	keep, nack := p.splitAcks(sub, ackIDs)
+	if len(nack) > 0 {
+		modack, err := proto.Marshal(&pubsubpb.ModifyAckDeadlineRequest{Subscription: sub, AckIds: nack})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := forward(ctx, pubsubpb.Subscriber_ModifyAckDeadline_FullMethodName, modack); err != nil {
+			return nil, err
+		}
+	}
+	if len(keep) == len(in.GetAckIds()) {
+		return forward(ctx, pubsubpb.Subscriber_Acknowledge_FullMethodName, req)
+	}
+	if len(keep) == 0 {
+		// Every ack was dropped or nacked: answer as the emulator would
+		// (an empty message) without acking anything.
+		return nil, nil
+	}
+	in.AckIds = keep
+	req, err := proto.Marshal(&in)
+	if err != nil {
+		return nil, err
+	}
+	return forward(ctx, pubsubpb.Subscriber_Acknowledge_FullMethodName, req)
+}
+
+// modifyAckDeadline moves leases along with the emulator's deadlines and
+// reports nacks. In test mode messages whose deadline has already passed are
+// ignored.
+func (p *Proxy) modifyAckDeadline(ctx context.Context, req []byte, forward forwardFunc) ([]byte, error) {
+	var in pubsubpb.ModifyAckDeadlineRequest
+	if err := proto.Unmarshal(req, &in); err != nil {
+		return forward(ctx, pubsubpb.Subscriber_ModifyAckDeadline_FullMethodName, req)
+	}
+	live := in.GetAckIds()
+	if p.test != nil {
+		live = p.leases.live(live)
+	}
+	if len(live) == 0 {
+		return nil, nil
+	}
+	if len(live) != len(in.GetAckIds()) {
+		in.AckIds = live
+		var err error
+		if req, err = proto.Marshal(&in); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := forward(ctx, pubsubpb.Subscriber_ModifyAckDeadline_FullMethodName, req)
+	if err != nil {
+		return nil, err
+	}
+	seconds := make([]int32, len(live))
+	for i := range seconds {
+		seconds[i] = in.GetAckDeadlineSeconds()
+	}
+	p.nacked(p.leases.extend(live, seconds))
+	return resp, nil
+}
+
+// delay sleeps for the first latency rule on resource that fires.
+func (p *Proxy) delay(ctx context.Context, resource string) error {
+	rule, ok := p.engine.Fire(ActionLatency, resource)
+	if !ok {
+		return nil
+	}
+	timer := time.NewTimer(rule.Latency())
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// splitAcks decides the fate of each ack for sub: kept, dropped (neither
+// returned) or turned into a nack.
+func (p *Proxy) splitAcks(sub string, ackIDs []string) (keep, nack []string) {
+	for _, id := range ackIDs {
+		if _, ok := p.engine.Fire(ActionDropAck, sub); ok {
+			continue
+		}
+		if _, ok := p.engine.Fire(ActionRedeliver, sub); ok {
+			nack = append(nack, id)
+			continue
+		}
+		keep = append(keep, id)
+	}
+	if p.leases != nil {
+		p.leases.settle(keep)
+		p.nacked(p.leases.settle(nack))
+	}
+	return keep, nack
+}
+
+// deliver prepares a batch pulled from sub for the client and reports
+// whether it changed. When leases are tracked the messages are leased for
+// streamDeadline, or the subscription's ack deadline when that is zero. In
+// test mode they are given deterministic IDs. Then the subscription's rules
+// are applied.
+func (p *Proxy) deliver(ctx context.Context, sub string, streamDeadline time.Duration, msgs []*pubsubpb.ReceivedMessage) ([]*pubsubpb.ReceivedMessage, bool) {
+	var changed bool
+	if p.leases != nil && len(msgs) > 0 {
+		deadline := streamDeadline
+		if deadline <= 0 {
+			deadline = p.ackDeadline(ctx, sub)
+		}
+		p.leases.track(sub, deadline, msgs)
+	}
+	if p.test != nil && len(msgs) > 0 {
+		p.test.rewrite(msgs)
+		changed = true
+	}
+	msgs, mangled := p.mangle(sub, msgs)
+	return msgs, changed || mangled
+}
+
+// mangle applies duplicate and reorder rules for sub to a delivered batch
+// and reports whether it changed.
+func (p *Proxy) mangle(sub string, msgs []*pubsubpb.ReceivedMessage) ([]*pubsubpb.ReceivedMessage, bool) {
+	if len(msgs) == 0 {
+		return msgs, false
+	}
+	var changed bool
+	if _, ok := p.engine.Fire(ActionReorder, sub); ok {
+		changed = reverseOrderingKeys(msgs)
+	}
+	out := make([]*pubsubpb.ReceivedMessage, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, m)
+		if _, ok := p.engine.Fire(ActionDuplicate, sub); ok {
+			out = append(out, m)
+			changed = true
+		}
+	}
+	return out, changed
+}
+
+// reverseOrderingKeys reverses, in place, the relative order of messages
+// that share an ordering key, leaving the slots each key occupies unchanged.
+// It reports whether any key had more than one message.
+func reverseOrderingKeys(msgs []*pubsubpb.ReceivedMessage) bool {
+	slots := make(map[string][]int)
+	for i, m := range msgs {
+		if key := m.GetMessage().GetOrderingKey(); key != "" {
+			slots[key] = append(slots[key], i)
+		}
+	}
+	var changed bool
+	for _, idx := range slots {
+		for i, j := 0, len(idx)-1; i < j; i, j = i+1, j-1 {
+			msgs[idx[i]], msgs[idx[j]] = msgs[idx[j]], msgs[idx[i]]
+			changed = true
+		}
+	}
+	return changed
+}
+
+// streamHooks rewrite the messages of a relayed stream.
+type streamHooks interface {
+	request(ctx context.Context, msg []byte) ([]byte, error)
+	response(ctx context.Context, msg []byte) ([]byte, error)
+}
+
+// streamingPull applies subscription rules to a StreamingPull stream. The
+// subscription is named in the first request only.
+type streamingPull struct {
+	proxy *Proxy
+
+	mu  sync.Mutex
+	sub string
+	// deadline is the stream's ack deadline, zero for the subscription's.
+	deadline time.Duration
+}
+
+func (s *streamingPull) subscription() (string, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sub, s.deadline
+}
+
+func (s *streamingPull) request(_ context.Context, msg []byte) ([]byte, error) {
+	var in pubsubpb.StreamingPullRequest
+	if err := proto.Unmarshal(msg, &in); err != nil {
+		return msg, nil
+	}
+	s.mu.Lock()
+	if in.GetSubscription() != "" {
+		s.sub = in.GetSubscription()
+		s.deadline = time.Duration(in.GetStreamAckDeadlineSeconds()) * time.Second
+	}
+	sub := s.sub
+	s.mu.Unlock()
+
+	ackIDs := in.GetAckIds()
+	if l := s.proxy.leases; l != nil {
+		if s.proxy.test != nil {
+			ackIDs = l.live(ackIDs)
+		}
+		s.proxy.nacked(l.extend(in.GetModifyDeadlineAckIds(), in.GetModifyDeadlineSeconds()))
+	}
+	keep, nack := s.proxy.splitAcks(sub, ackIDs)
+	if !rewriteStreamingAcks(&in, keep, nack) {
+		return msg, nil
+	}
+	return proto.Marshal(&in)
+}
+
+// rewriteStreamingAcks replaces the request's acks with keep and adds the
+// nacks as zero-second deadline modifications. It reports whether the
+// request changed.
+func rewriteStreamingAcks(in *pubsubpb.StreamingPullRequest, keep, nack []string) bool {
+	if len(keep) == len(in.GetAckIds()) {
+		return false
+	}
+	in.AckIds = keep
+	for _, id := range nack {
+		in.ModifyDeadlineAckIds = append(in.ModifyDeadlineAckIds, id)
+		in.ModifyDeadlineSeconds = append(in.ModifyDeadlineSeconds, 0)
+	}
+	return true
+}
+
+func (s *streamingPull) response(ctx context.Context, msg []byte) ([]byte, error) {
+	sub, deadline := s.subscription()
+	if err := s.proxy.delay(ctx, sub); err != nil {
+		return nil, err
+	}
+	var out pubsubpb.StreamingPullResponse
+	if err := proto.Unmarshal(msg, &out); err != nil {
+		return msg, nil
+	}
+	var changed bool
+	if out.ReceivedMessages, changed = s.proxy.deliver(ctx, sub, deadline, out.ReceivedMessages); !changed {
+		return msg, nil
+	}
+	return proto.Marshal(&out)
+}
+
+// stream relays a call message by message in both directions, passing each
+// through hooks when set.
+func (p *Proxy) stream(ss grpc.ServerStream, method string, hooks streamHooks) error {
+	ctx, cancel := context.WithCancel(outgoing(ss.Context()))
+	defer cancel()
+
+	desc := &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}
+	cs, err := p.backend.NewStream(ctx, desc, method, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return err
+	}
+
+	upErr := make(chan error, 1)
+	go func() { upErr <- relayRequests(ctx, ss, cs, hooks) }()
+	downErr := make(chan error, 1)
+	go func() { downErr <- relayResponses(ctx, cs, ss, hooks) }()
+
+	for {
+		select {
+		case err := <-upErr:
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+			// The client finished sending; keep relaying responses.
+			_ = cs.CloseSend()
+			upErr = nil
+		case err := <-downErr:
+			ss.SetTrailer(cs.Trailer())
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// relayRequests copies client messages to the backend until the client
+// closes its side (io.EOF) or an error occurs.
+func relayRequests(ctx context.Context, ss grpc.ServerStream, cs grpc.ClientStream, hooks streamHooks) error {
+	for {
+		var msg frame
+		if err := ss.RecvMsg(&msg); err != nil {
+			return err
+		}
+		if hooks != nil {
+			payload, err := hooks.request(ctx, msg.payload)
+			if err != nil {
+				return err
+			}
+			msg.payload = payload
+		}
+		if err := cs.SendMsg(&msg); err != nil {
+			return err
+		}
+	}
+}
+
+// relayResponses copies the backend's header and messages to the client
+// until the backend ends the call (io.EOF) or an error occurs.
+func relayResponses(ctx context.Context, cs grpc.ClientStream, ss grpc.ServerStream, hooks streamHooks) error {
+	if header, err := cs.Header(); err == nil {
+		if err := ss.SendHeader(header); err != nil {
+			return err
+		}
+	}
+	for {
+		var msg frame
+		if err := cs.RecvMsg(&msg); err != nil {
+			return err
+		}
+		if hooks != nil {
+			payload, err := hooks.response(ctx, msg.payload)
+			if err != nil {
+				return err
+			}
+			msg.payload = payload
+		}
+		if err := ss.SendMsg(&msg); err != nil {
+			return err
+		}
+	}
+}
+
+// outgoing carries the client's metadata over to the backend call.
+func outgoing(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	md = md.Copy()
+	delete(md, ":authority")
+	delete(md, "content-type")
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// frame is an undecoded gRPC message.
+type frame struct {
+	payload []byte
+}
+
+// rawCodec passes message bytes through unchanged so the proxy can relay
+// any method without its descriptors.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	f, ok := v.(*frame)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "chaos proxy: unexpected message type %T", v)
+	}
+	return f.payload, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	f, ok := v.(*frame)
+	if !ok {
+		return status.Errorf(codes.Internal, "chaos proxy: unexpected message type %T", v)
+	}
+	f.payload = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
@@ -0,0 +1,300 @@
+package chaos
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// proxyTest is a chaos proxy in front of a fake emulator holding testTopic
+// and testSub.
+type proxyTest struct {
+	engine *Engine
+	srv    *pstest.Server
+	conn   *grpc.ClientConn
+	pub    pubsubpb.PublisherClient
+	sub    pubsubpb.SubscriberClient
+}
+
+// setupProxyTest starts the proxy, passing it to each configure function
+// before it serves.
+func setupProxyTest(t *testing.T, configure ...func(*Proxy)) *proxyTest {
+	t.Helper()
+	srv := pstest.NewServer()
+	backend, err := grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial fake emulator: %v", err)
+	}
+
+	engine := NewEngine()
+	proxy := NewProxy(engine, backend, logger.New())
+	for _, fn := range configure {
+		fn(proxy)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go func() { _ = proxy.Serve(ln) }()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial proxy: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		proxy.Stop()
+		_ = backend.Close()
+		_ = srv.Close()
+	})
+
+	pt := &proxyTest{
+		engine: engine,
+		srv:    srv,
+		conn:   conn,
+		pub:    pubsubpb.NewPublisherClient(conn),
+		sub:    pubsubpb.NewSubscriberClient(conn),
+	}
+	ctx := context.Background()
+	if _, err := pt.pub.CreateTopic(ctx, &pubsubpb.Topic{Name: testTopic}); err != nil {
+		t.Fatalf("Failed to create topic through proxy: %v", err)
+	}
+	if _, err := pt.sub.CreateSubscription(ctx, &pubsubpb.Subscription{Name: testSub, Topic: testTopic, AckDeadlineSeconds: 10}); err != nil {
+		t.Fatalf("Failed to create subscription through proxy: %v", err)
+	}
+	return pt
+}
+
+func (pt *proxyTest) publish(t *testing.T, data string) (string, error) {
+	t.Helper()
+	resp, err := pt.pub.Publish(context.Background(), &pubsubpb.PublishRequest{
+		Topic:    testTopic,
+		Messages: []*pubsubpb.PubsubMessage{{Data: []byte(data)}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.MessageIds[0], nil
+}
+
+func (pt *proxyTest) pull(t *testing.T) []*pubsubpb.ReceivedMessage {
+	t.Helper()
+	resp, err := pt.sub.Pull(context.Background(), &pubsubpb.PullRequest{Subscription: testSub, MaxMessages: 10})
+	if err != nil {
+		t.Fatalf("Failed to pull: %v", err)
+	}
+	return resp.ReceivedMessages
+}
+
+func (pt *proxyTest) ack(t *testing.T, msgs ...*pubsubpb.ReceivedMessage) {
+	t.Helper()
+	req := &pubsubpb.AcknowledgeRequest{Subscription: testSub}
+	for _, m := range msgs {
+		req.AckIds = append(req.AckIds, m.AckId)
+	}
+	if _, err := pt.sub.Acknowledge(context.Background(), req); err != nil {
+		t.Fatalf("Failed to ack: %v", err)
+	}
+}
+
+func (pt *proxyTest) addRule(t *testing.T, rule Rule) Rule {
+	t.Helper()
+	rule, err := pt.engine.Add(rule)
+	if err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	return rule
+}
+
+func TestProxy_Passthrough(t *testing.T) {
+	pt := setupProxyTest(t)
+
+	id, err := pt.publish(t, "hello")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	msgs := pt.pull(t)
+	if len(msgs) != 1 || string(msgs[0].Message.Data) != "hello" {
+		t.Fatalf("Expected the published message, got %v", msgs)
+	}
+	pt.ack(t, msgs...)
+	if acks := pt.srv.Message(id).Acks; acks != 1 {
+		t.Errorf("Expected 1 ack, got %d", acks)
+	}
+}
+
+func TestProxy_PublishError(t *testing.T) {
+	pt := setupProxyTest(t)
+	pt.addRule(t, Rule{Action: ActionPublishError, Topic: testTopic, Code: "RESOURCE_EXHAUSTED"})
+
+	_, err := pt.publish(t, "hello")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected RESOURCE_EXHAUSTED, got %v", err)
+	}
+	if n := len(pt.srv.Messages()); n != 0 {
+		t.Errorf("Expected the publish not to reach the emulator, got %d messages", n)
+	}
+	if hits := pt.engine.Rules()[0].Hits; hits != 1 {
+		t.Errorf("Expected 1 hit, got %d", hits)
+	}
+}
+
+func TestProxy_Latency(t *testing.T) {
+	pt := setupProxyTest(t)
+	pt.addRule(t, Rule{Action: ActionLatency, Topic: testTopic, LatencyMs: 150})
+
+	start := time.Now()
+	if _, err := pt.publish(t, "slow"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected publish to take at least 150ms, took %v", elapsed)
+	}
+}
+
+func TestProxy_DropAck(t *testing.T) {
+	pt := setupProxyTest(t)
+	pt.addRule(t, Rule{Action: ActionDropAck, Subscription: testSub})
+
+	id, _ := pt.publish(t, "hello")
+	pt.ack(t, pt.pull(t)...)
+
+	msg := pt.srv.Message(id)
+	if msg.Acks != 0 || len(msg.Modacks) != 0 {
+		t.Errorf("Expected the ack to be dropped, got %d acks and %d modacks", msg.Acks, len(msg.Modacks))
+	}
+}
+
+func TestProxy_Redeliver(t *testing.T) {
+	pt := setupProxyTest(t)
+	pt.addRule(t, Rule{Action: ActionRedeliver, Subscription: testSub})
+
+	id, _ := pt.publish(t, "hello")
+	pt.ack(t, pt.pull(t)...)
+
+	msg := pt.srv.Message(id)
+	if msg.Acks != 0 {
+		t.Errorf("Expected no acks, got %d", msg.Acks)
+	}
+	if len(msg.Modacks) != 1 || msg.Modacks[0].AckDeadline != 0 {
+		t.Errorf("Expected the ack to become a nack, got %+v", msg.Modacks)
+	}
+	if msgs := pt.pull(t); len(msgs) != 1 {
+		t.Errorf("Expected the message to be redelivered, got %d messages", len(msgs))
+	}
+}
+
+func TestProxy_DuplicateAndReorder(t *testing.T) {
+	pt := setupProxyTest(t)
+	for _, data := range []string{"1", "2", "3"} {
+		pt.srv.PublishOrdered(testTopic, []byte(data), nil, "key")
+	}
+	pt.addRule(t, Rule{Action: ActionReorder, Subscription: testSub})
+	pt.addRule(t, Rule{Action: ActionDuplicate, Subscription: testSub})
+
+	// The fake server does not keep order on this subscription, so check
+	// that each message is duplicated in place and that reorder fired;
+	// TestReverseOrderingKeys covers the reversal itself.
+	var got []string
+	for _, m := range pt.pull(t) {
+		got = append(got, string(m.Message.Data))
+	}
+	if len(got) != 6 {
+		t.Fatalf("Expected 6 deliveries, got %v", got)
+	}
+	for i := 0; i < len(got); i += 2 {
+		if got[i] != got[i+1] {
+			t.Errorf("Expected each message to be delivered twice in a row, got %v", got)
+		}
+	}
+	if rules := pt.engine.Rules(); rules[0].Hits != 1 {
+		t.Errorf("Expected the reorder rule to fire once, got %d hits", rules[0].Hits)
+	}
+}
+
+func TestReverseOrderingKeys(t *testing.T) {
+	msg := func(data, key string) *pubsubpb.ReceivedMessage {
+		return &pubsubpb.ReceivedMessage{Message: &pubsubpb.PubsubMessage{Data: []byte(data), OrderingKey: key}}
+	}
+	msgs := []*pubsubpb.ReceivedMessage{msg("a1", "a"), msg("x", ""), msg("b1", "b"), msg("a2", "a"), msg("a3", "a"), msg("b2", "b")}
+	if !reverseOrderingKeys(msgs) {
+		t.Error("Expected the batch to change")
+	}
+	var got []string
+	for _, m := range msgs {
+		got = append(got, string(m.Message.Data))
+	}
+	if want := []string{"a3", "x", "b2", "a2", "a1", "b1"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if reverseOrderingKeys([]*pubsubpb.ReceivedMessage{msg("a1", "a"), msg("x", "")}) {
+		t.Error("Expected a batch without repeated keys to be unchanged")
+	}
+}
+
+func TestProxy_StreamingPull(t *testing.T) {
+	pt := setupProxyTest(t)
+	pt.addRule(t, Rule{Action: ActionDropAck, Subscription: testSub})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := pubsub.NewClient(ctx, "test-project", option.WithGRPCConn(pt.conn))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	id, _ := pt.publish(t, "streamed")
+
+	received := make(chan string, 1)
+	recvCtx, stop := context.WithCancel(ctx)
+	go func() {
+		_ = client.Subscriber(testSub).Receive(recvCtx, func(_ context.Context, m *pubsub.Message) {
+			m.Ack()
+			select {
+			case received <- string(m.Data):
+			default:
+			}
+		})
+	}()
+	select {
+	case data := <-received:
+		if data != "streamed" {
+			t.Errorf("Expected 'streamed', got %q", data)
+		}
+	case <-ctx.Done():
+		t.Fatal("Expected a message over the streaming pull")
+	}
+	// Receive flushes pending acks before returning.
+	stop()
+	time.Sleep(200 * time.Millisecond)
+	if acks := pt.srv.Message(id).Acks; acks != 0 {
+		t.Errorf("Expected the streamed ack to be dropped, got %d acks", acks)
+	}
+}
+
+func TestRewriteStreamingAcks(t *testing.T) {
+	in := &pubsubpb.StreamingPullRequest{AckIds: []string{"a", "b", "c"}}
+	if rewriteStreamingAcks(in, []string{"a", "b", "c"}, nil) {
+		t.Error("Expected no change when every ack is kept")
+	}
+	if !rewriteStreamingAcks(in, []string{"a"}, []string{"c"}) {
+		t.Fatal("Expected the request to change")
+	}
+	if !slices.Equal(in.AckIds, []string{"a"}) {
+		t.Errorf("Expected acks [a], got %v", in.AckIds)
+	}
+	if !slices.Equal(in.ModifyDeadlineAckIds, []string{"c"}) || !slices.Equal(in.ModifyDeadlineSeconds, []int32{0}) {
+		t.Errorf("Expected a zero-second modack for c, got %v %v", in.ModifyDeadlineAckIds, in.ModifyDeadlineSeconds)
+	}
+}
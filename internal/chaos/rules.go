@@ -0,0 +1,223 @@
+// Package chaos injects faults into Pub/Sub traffic. Rules registered on an
+// Engine target a topic or subscription; a Proxy placed in front of the
+// emulator applies them to the gRPC requests clients make. In test mode the
+// Proxy also gives clients deterministic message IDs and runs their ack
+// deadlines on a manual clock.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+	"google.golang.org/grpc/codes"
+)
+
+// Action is the kind of fault a rule injects.
+type Action string
+
+// Supported actions. PublishError applies to topics; Latency to topics
+// (publishes) or subscriptions (pulls and acks); the rest to subscriptions.
+const (
+	// ActionPublishError fails publishes with the rule's gRPC code.
+	ActionPublishError Action = "publish_error"
+	// ActionLatency delays requests by the rule's latency.
+	ActionLatency Action = "latency"
+	// ActionDropAck swallows acks so messages are redelivered once their
+	// ack deadline expires.
+	ActionDropAck Action = "drop_ack"
+	// ActionRedeliver turns acks into nacks so messages are redelivered
+	// immediately.
+	ActionRedeliver Action = "redeliver"
+	// ActionDuplicate delivers messages twice.
+	ActionDuplicate Action = "duplicate"
+	// ActionReorder reverses the order of messages sharing an ordering key
+	// within each delivered batch.
+	ActionReorder Action = "reorder"
+)
+
+// Actions lists the supported actions.
+var Actions = []Action{ActionPublishError, ActionLatency, ActionDropAck, ActionRedeliver, ActionDuplicate, ActionReorder}
+
+// defaultCode is the gRPC code injected by publish_error rules without one.
+const defaultCode = "UNAVAILABLE"
+
+// Rule is one fault injection rule. Topic and Subscription are full resource
+// names; exactly one is set.
+type Rule struct {
+	ID           string `json:"id"`
+	Action       Action `json:"action"`
+	Topic        string `json:"topic,omitempty"`
+	Subscription string `json:"subscription,omitempty"`
+	// Code is the gRPC status code name injected by publish_error rules.
+	Code string `json:"code,omitempty"`
+	// LatencyMs is the delay added by latency rules.
+	LatencyMs int64 `json:"latency_ms,omitempty"`
+	// Probability is the chance, in (0, 1], that the rule fires for a
+	// matching request or message.
+	Probability float64 `json:"probability"`
+	// ExpiresAt removes the rule once passed; nil rules never expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	// Hits counts how many times the rule fired.
+	Hits int64 `json:"hits"`
+}
+
+// Resource returns the topic or subscription the rule targets.
+func (r *Rule) Resource() string {
+	if r.Topic != "" {
+		return r.Topic
+	}
+	return r.Subscription
+}
+
+// Latency returns the delay added by a latency rule.
+func (r *Rule) Latency() time.Duration {
+	return time.Duration(r.LatencyMs) * time.Millisecond
+}
+
+// StatusCode returns the gRPC code injected by a publish_error rule.
+func (r *Rule) StatusCode() codes.Code {
+	c, _ := parseCode(r.Code)
+	return c
+}
+
+func (r *Rule) expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// validate checks the rule and fills in defaults.
+func (r *Rule) validate() error {
+	if !slices.Contains(Actions, r.Action) {
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	if (r.Topic == "") == (r.Subscription == "") {
+		return errors.New("exactly one of topic or subscription is required")
+	}
+	switch r.Action {
+	case ActionPublishError:
+		if r.Topic == "" {
+			return fmt.Errorf("%s rules target a topic", r.Action)
+		}
+		if r.Code == "" {
+			r.Code = defaultCode
+		}
+		if _, err := parseCode(r.Code); err != nil {
+			return err
+		}
+		r.Code = strings.ToUpper(r.Code)
+	case ActionLatency:
+		if r.LatencyMs <= 0 {
+			return fmt.Errorf("%s rules need a positive latency_ms", r.Action)
+		}
+	default:
+		if r.Subscription == "" {
+			return fmt.Errorf("%s rules target a subscription", r.Action)
+		}
+	}
+	if r.Probability == 0 {
+		r.Probability = 1
+	}
+	if r.Probability < 0 || r.Probability > 1 {
+		return fmt.Errorf("probability must be between 0 and 1, got %v", r.Probability)
+	}
+	return nil
+}
+
+// parseCode parses a gRPC code name such as "UNAVAILABLE", in any case.
+func parseCode(name string) (codes.Code, error) {
+	var c codes.Code
+	if err := c.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(name)))); err != nil || c == codes.OK {
+		return codes.Unknown, fmt.Errorf("invalid gRPC code %q", name)
+	}
+	return c, nil
+}
+
+// Engine holds the active rules. It is safe for concurrent use.
+type Engine struct {
+	mu     sync.Mutex
+	rules  []*Rule
+	nextID int
+	now    func() time.Time
+	rand   func() float64
+}
+
+// NewEngine creates an Engine with no rules.
+func NewEngine() *Engine {
+	return &Engine{now: time.Now, rand: rand.Float64}
+}
+
+// SetClock makes rule creation and expiry times follow c, such as a manual
+// clock in test mode.
+func (e *Engine) SetClock(c clock.Clock) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now = c.Now
+}
+
+// Add validates rule, assigns its ID and creation time and activates it.
+func (e *Engine) Add(rule Rule) (Rule, error) {
+	if err := rule.validate(); err != nil {
+		return Rule{}, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	rule.ID = strconv.Itoa(e.nextID)
+	rule.CreatedAt = e.now()
+	rule.Hits = 0
+	e.rules = append(e.rules, &rule)
+	return rule, nil
+}
+
+// Remove deletes the rule with id and reports whether it existed.
+func (e *Engine) Remove(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := len(e.rules)
+	e.rules = slices.DeleteFunc(e.rules, func(r *Rule) bool { return r.ID == id })
+	return len(e.rules) != n
+}
+
+// Rules returns the active rules in the order they were added.
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pruneLocked()
+	rules := make([]Rule, len(e.rules))
+	for i, r := range e.rules {
+		rules[i] = *r
+	}
+	return rules
+}
+
+// Fire returns the first active rule for action on resource that fires,
+// rolling each matching rule's probability and counting the hit.
+func (e *Engine) Fire(action Action, resource string) (Rule, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pruneLocked()
+	for _, r := range e.rules {
+		if r.Action != action || r.Resource() != resource {
+			continue
+		}
+		if r.Probability < 1 && e.rand() >= r.Probability {
+			continue
+		}
+		r.Hits++
+		return *r, true
+	}
+	return Rule{}, false
+}
+
+// pruneLocked drops expired rules.
+func (e *Engine) pruneLocked() {
+	now := e.now()
+	e.rules = slices.DeleteFunc(e.rules, func(r *Rule) bool { return r.expired(now) })
+}
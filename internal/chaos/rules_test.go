@@ -0,0 +1,123 @@
+package chaos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	testTopic = "projects/test-project/topics/orders"
+	testSub   = "projects/test-project/subscriptions/orders-sub"
+)
+
+func TestEngine_AddValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{"publish error", Rule{Action: ActionPublishError, Topic: testTopic}, false},
+		{"publish error with code", Rule{Action: ActionPublishError, Topic: testTopic, Code: "resource_exhausted"}, false},
+		{"publish error on subscription", Rule{Action: ActionPublishError, Subscription: testSub}, true},
+		{"publish error with OK code", Rule{Action: ActionPublishError, Topic: testTopic, Code: "OK"}, true},
+		{"publish error with bad code", Rule{Action: ActionPublishError, Topic: testTopic, Code: "BROKEN"}, true},
+		{"latency on topic", Rule{Action: ActionLatency, Topic: testTopic, LatencyMs: 100}, false},
+		{"latency on subscription", Rule{Action: ActionLatency, Subscription: testSub, LatencyMs: 100}, false},
+		{"latency without delay", Rule{Action: ActionLatency, Topic: testTopic}, true},
+		{"drop ack", Rule{Action: ActionDropAck, Subscription: testSub}, false},
+		{"duplicate on topic", Rule{Action: ActionDuplicate, Topic: testTopic}, true},
+		{"no target", Rule{Action: ActionRedeliver}, true},
+		{"two targets", Rule{Action: ActionLatency, Topic: testTopic, Subscription: testSub, LatencyMs: 1}, true},
+		{"unknown action", Rule{Action: "explode", Subscription: testSub}, true},
+		{"probability too high", Rule{Action: ActionReorder, Subscription: testSub, Probability: 1.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEngine().Add(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEngine_AddDefaults(t *testing.T) {
+	e := NewEngine()
+	rule, err := e.Add(Rule{Action: ActionPublishError, Topic: testTopic, Code: "resource_exhausted"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rule.ID != "1" {
+		t.Errorf("Expected ID 1, got %s", rule.ID)
+	}
+	if rule.Probability != 1 {
+		t.Errorf("Expected default probability 1, got %v", rule.Probability)
+	}
+	if rule.Code != "RESOURCE_EXHAUSTED" || rule.StatusCode() != codes.ResourceExhausted {
+		t.Errorf("Expected code RESOURCE_EXHAUSTED, got %s", rule.Code)
+	}
+
+	rule, _ = e.Add(Rule{Action: ActionPublishError, Topic: testTopic})
+	if rule.ID != "2" || rule.Code != "UNAVAILABLE" {
+		t.Errorf("Expected rule 2 with code UNAVAILABLE, got %s with %s", rule.ID, rule.Code)
+	}
+}
+
+func TestEngine_Fire(t *testing.T) {
+	e := NewEngine()
+	roll := 0.0
+	e.rand = func() float64 { return roll }
+	rule, _ := e.Add(Rule{Action: ActionDuplicate, Subscription: testSub, Probability: 0.5})
+
+	if _, ok := e.Fire(ActionDuplicate, "projects/test-project/subscriptions/other"); ok {
+		t.Error("Expected rule not to fire for another subscription")
+	}
+	if _, ok := e.Fire(ActionDropAck, testSub); ok {
+		t.Error("Expected rule not to fire for another action")
+	}
+	if _, ok := e.Fire(ActionDuplicate, testSub); !ok {
+		t.Error("Expected rule to fire when the roll is below the probability")
+	}
+	roll = 0.7
+	if _, ok := e.Fire(ActionDuplicate, testSub); ok {
+		t.Error("Expected rule not to fire when the roll is above the probability")
+	}
+
+	if hits := e.Rules()[0].Hits; hits != 1 {
+		t.Errorf("Expected 1 hit, got %d", hits)
+	}
+	if !e.Remove(rule.ID) {
+		t.Error("Expected Remove to find the rule")
+	}
+	if e.Remove(rule.ID) {
+		t.Error("Expected second Remove to report a missing rule")
+	}
+	if len(e.Rules()) != 0 {
+		t.Errorf("Expected no rules, got %d", len(e.Rules()))
+	}
+}
+
+func TestEngine_Expiry(t *testing.T) {
+	e := NewEngine()
+	manual := clock.NewManual(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	e.SetClock(manual)
+	expires := manual.Now().Add(time.Minute)
+	if _, err := e.Add(Rule{Action: ActionDropAck, Subscription: testSub, ExpiresAt: &expires}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, ok := e.Fire(ActionDropAck, testSub); !ok {
+		t.Error("Expected rule to fire before it expires")
+	}
+	manual.Set(expires)
+	if _, ok := e.Fire(ActionDropAck, testSub); ok {
+		t.Error("Expected expired rule not to fire")
+	}
+	if len(e.Rules()) != 0 {
+		t.Errorf("Expected expired rule to be removed, got %d rules", len(e.Rules()))
+	}
+}
@@ -0,0 +1,86 @@
+package chaos
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// expiryTimeout bounds the nacks sent when leases expire.
+const expiryTimeout = 5 * time.Second
+
+// testMode is the proxy state kept in test mode, where message IDs and
+// publish times are rewritten and ack deadlines run on a manual clock.
+type testMode struct {
+	clock *clock.Manual
+	ids   *clock.IDs
+}
+
+// SetTestMode rewrites message IDs and publish times with ids and enforces
+// ack deadlines against manual: when the clock passes a delivered message's
+// deadline the message is nacked so it is redelivered, and a late ack for it
+// is ignored. Expiries are reported to the observer (see SetObserver).
+// Resetting ids forgets the outstanding leases, whose messages belong to the
+// previous run. Call it before Serve.
+func (p *Proxy) SetTestMode(manual *clock.Manual, ids *clock.IDs) {
+	p.test = &testMode{clock: manual, ids: ids}
+	p.leases = newLeases(manual.Now, true)
+	manual.OnChange(func(time.Time) { p.expireLeases() })
+	ids.OnReset(p.leases.reset)
+}
+
+// rewritePublish replaces the emulator's message IDs in a publish response.
+func (t *testMode) rewritePublish(resp []byte) ([]byte, error) {
+	var out pubsubpb.PublishResponse
+	if err := proto.Unmarshal(resp, &out); err != nil {
+		return resp, nil
+	}
+	for i, id := range out.MessageIds {
+		out.MessageIds[i] = t.ids.Assign(id).ID
+	}
+	return proto.Marshal(&out)
+}
+
+// rewrite replaces the IDs and publish times of delivered messages.
+func (t *testMode) rewrite(msgs []*pubsubpb.ReceivedMessage) {
+	for _, m := range msgs {
+		msg := m.GetMessage()
+		if msg == nil {
+			continue
+		}
+		a := t.ids.Assign(msg.MessageId)
+		msg.MessageId = a.ID
+		msg.PublishTime = timestamppb.New(a.PublishTime)
+	}
+}
+
+// expireLeases nacks every message whose ack deadline has passed on the
+// manual clock so the emulator redelivers it.
+func (p *Proxy) expireLeases() {
+	expired := p.leases.takeExpired()
+	bySub := make(map[string][]string)
+	for _, ls := range expired {
+		bySub[ls.subscription] = append(bySub[ls.subscription], ls.ackID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), expiryTimeout)
+	defer cancel()
+	for sub, ackIDs := range bySub {
+		req, err := proto.Marshal(&pubsubpb.ModifyAckDeadlineRequest{Subscription: sub, AckIds: ackIDs})
+		if err != nil {
+			continue
+		}
+		var resp frame
+		if err := p.backend.Invoke(ctx, pubsubpb.Subscriber_ModifyAckDeadline_FullMethodName, &frame{req}, &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+			p.log.Warn("Failed to expire %d messages on %s: %v", len(ackIDs), sub, err)
+			continue
+		}
+		p.log.Debug("Expired %d messages on %s", len(ackIDs), sub)
+	}
+	p.expired(expired)
+}
@@ -0,0 +1,138 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+)
+
+var testStart = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testModeProxy is a proxy in test mode recording the expiries it reports.
+type testModeProxy struct {
+	*proxyTest
+	clock    *clock.Manual
+	observed *recordingObserver
+}
+
+func setupTestModeProxy(t *testing.T) *testModeProxy {
+	t.Helper()
+	tp := &testModeProxy{clock: clock.NewManual(testStart), observed: &recordingObserver{}}
+	ids := clock.NewIDs(7, tp.clock)
+	tp.proxyTest = setupProxyTest(t, func(p *Proxy) {
+		p.SetTestMode(tp.clock, ids)
+		p.SetObserver(tp.observed)
+	})
+	return tp
+}
+
+func (tp *testModeProxy) expiries() []string {
+	return tp.observed.events("expired")
+}
+
+func TestTestMode_RewritesIDsAndPublishTimes(t *testing.T) {
+	tp := setupTestModeProxy(t)
+
+	first, err := tp.publish(t, "one")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tp.clock.Advance(time.Minute)
+	second, _ := tp.publish(t, "two")
+	if first != "7-1" || second != "7-2" {
+		t.Fatalf("Expected IDs 7-1 and 7-2, got %s and %s", first, second)
+	}
+
+	msgs := tp.pull(t)
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(msgs))
+	}
+	want := map[string]time.Time{"7-1": testStart, "7-2": testStart.Add(time.Minute)}
+	for _, m := range msgs {
+		at, ok := want[m.Message.MessageId]
+		if !ok {
+			t.Errorf("Unexpected message ID %q", m.Message.MessageId)
+			continue
+		}
+		if got := m.Message.PublishTime.AsTime(); !got.Equal(at) {
+			t.Errorf("Expected %s published at %v, got %v", m.Message.MessageId, at, got)
+		}
+	}
+}
+
+func TestTestMode_AckDeadlineFollowsClock(t *testing.T) {
+	tp := setupTestModeProxy(t)
+	if _, err := tp.publish(t, "hello"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	backendID := tp.srv.Messages()[0].ID
+
+	msgs := tp.pull(t)
+	if len(msgs) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(msgs))
+	}
+
+	// Still within the subscription's 10s deadline: nothing expires.
+	tp.clock.Advance(9 * time.Second)
+	if got := len(tp.srv.Message(backendID).Modacks); got != 0 {
+		t.Fatalf("Expected no modacks before the deadline, got %d", got)
+	}
+
+	tp.clock.Advance(2 * time.Second)
+	modacks := tp.srv.Message(backendID).Modacks
+	if len(modacks) != 1 || modacks[0].AckDeadline != 0 {
+		t.Fatalf("Expected the expired message to be nacked, got %+v", modacks)
+	}
+
+	// The late ack is ignored and the message is redelivered with its ID.
+	tp.ack(t, msgs...)
+	if acks := tp.srv.Message(backendID).Acks; acks != 0 {
+		t.Errorf("Expected late ack to be ignored, got %d acks", acks)
+	}
+	again := tp.pull(t)
+	if len(again) != 1 || again[0].Message.MessageId != "7-1" {
+		t.Fatalf("Expected 7-1 to be redelivered, got %v", again)
+	}
+	tp.ack(t, again...)
+	if acks := tp.srv.Message(backendID).Acks; acks != 1 {
+		t.Errorf("Expected 1 ack, got %d", acks)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(tp.expiries()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := tp.expiries(); len(got) != 1 || got[0] != backendID+"@"+testSub {
+		t.Errorf("Expected expiry of %s on %s, got %v", backendID, testSub, got)
+	}
+}
+
+func TestTestMode_ModifyAckDeadlineExtendsLease(t *testing.T) {
+	tp := setupTestModeProxy(t)
+	if _, err := tp.publish(t, "hello"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	backendID := tp.srv.Messages()[0].ID
+	msgs := tp.pull(t)
+
+	_, err := tp.sub.ModifyAckDeadline(context.Background(), &pubsubpb.ModifyAckDeadlineRequest{
+		Subscription:       testSub,
+		AckIds:             []string{msgs[0].AckId},
+		AckDeadlineSeconds: 60,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tp.clock.Advance(30 * time.Second)
+	tp.ack(t, msgs...)
+	if acks := tp.srv.Message(backendID).Acks; acks != 1 {
+		t.Errorf("Expected the extended message to be acked, got %d acks", acks)
+	}
+	if got := tp.expiries(); len(got) != 0 {
+		t.Errorf("Expected no expiries, got %v", got)
+	}
+}
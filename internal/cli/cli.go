@@ -0,0 +1,197 @@
+// Package cli implements the pubsub-emulator subcommands used to script a
+// running emulator: managing topics and subscriptions, publishing, pulling,
+// tailing, generating load and publishing fixture files over gRPC, and
+// exporting or importing state through the dashboard REST API.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/api/option"
+)
+
+const (
+	// defaultEmulatorHost is used when neither --emulator nor
+	// PUBSUB_EMULATOR_HOST is set.
+	defaultEmulatorHost = "localhost:8085"
+	// defaultDashboardURL is used when neither --dashboard nor DASHBOARD_URL
+	// is set.
+	defaultDashboardURL = "http://localhost:8080"
+)
+
+// errUsage marks errors caused by bad arguments; Run prints the command's
+// usage and exits with status 2 for them.
+var errUsage = errors.New("usage error")
+
+// App runs CLI commands. main sets Stdout and Stderr (Stdin defaults to
+// os.Stdin); tests also inject ClientOptions and HTTPClient.
+type App struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  io.Reader
+	// ClientOptions are appended to the gRPC client options, e.g.
+	// option.WithGRPCConn to target an in-process server.
+	ClientOptions []option.ClientOption
+	// HTTPClient is used for dashboard requests; nil builds one from
+	// --dashboard.
+	HTTPClient *http.Client
+}
+
+// command is one subcommand; run receives the arguments after its name.
+type command struct {
+	name    string
+	summary string
+	run     func(a *App, ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"topics", "Create, list or delete topics (gRPC)", (*App).runTopics},
+	{"subs", "Create, list, delete, pull from or ack subscriptions (gRPC)", (*App).runSubs},
+	{"publish", "Publish messages to a topic (gRPC)", (*App).runPublish},
+	{"tail", "Stream messages arriving on a topic (gRPC)", (*App).runTail},
+	{"loadgen", "Publish synthetic load to a topic and report statistics (gRPC)", (*App).runLoadGen},
+	{"fixtures", "Publish the messages of an NDJSON or CSV fixture file (gRPC)", (*App).runFixtures},
+	{"export", "Download the emulator state (dashboard API)", (*App).runExport},
+	{"import", "Restore an exported state archive (dashboard API)", (*App).runImport},
+	{"healthcheck", "Exit non-zero unless the dashboard reports ready (container healthchecks)", (*App).runHealthcheck},
+}
+
+// IsCommand reports whether name is a CLI subcommand (or a help request),
+// so main can tell it apart from serving.
+func IsCommand(name string) bool {
+	switch name {
+	case "help", "-h", "-help", "--help":
+		return true
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the command in args (without the program name) and returns
+// the process exit status.
+func (a *App) Run(ctx context.Context, args []string) int {
+	if a.Stdin == nil {
+		a.Stdin = os.Stdin
+	}
+	if len(args) == 0 || !IsCommand(args[0]) {
+		a.usage()
+		return 2
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(a, ctx, args[1:])
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			_, _ = fmt.Fprintf(a.Stderr, "%s %s: %v\n", programName, c.name, err)
+			return 2
+		default:
+			_, _ = fmt.Fprintf(a.Stderr, "%s %s: %v\n", programName, c.name, err)
+			return 1
+		}
+	}
+	a.usage()
+	return 0
+}
+
+const programName = "pubsub-emulator"
+
+func (a *App) usage() {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s [command] [flags]\n\n", programName)
+	fmt.Fprintf(&b, "Without a command (or with \"serve\") the emulator dashboard and receivers start.\n\n")
+	fmt.Fprintf(&b, "Commands:\n")
+	fmt.Fprintf(&b, "  %-11s %s\n", "serve", "Run the emulator helper and dashboard (default)")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "  %-11s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(&b, "\nRun '%s <command> -h' for the command's flags.\n", programName)
+	_, _ = io.WriteString(a.Stderr, b.String())
+}
+
+// globals are the connection and output flags shared by every command.
+type globals struct {
+	emulator  string
+	dashboard string
+	project   string
+	token     string
+	output    string
+}
+
+// newFlagSet returns a flag set for a command with the shared flags
+// registered, defaulting from the environment.
+func (a *App) newFlagSet(name, usage string) (*flag.FlagSet, *globals) {
+	g := &globals{}
+	fs := flag.NewFlagSet(programName+" "+name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	fs.StringVar(&g.emulator, "emulator", envOr("PUBSUB_EMULATOR_HOST", defaultEmulatorHost), "emulator gRPC `host:port`")
+	fs.StringVar(&g.dashboard, "dashboard", envOr("DASHBOARD_URL", defaultDashboardURL), "dashboard `URL` (http://, https:// or unix://path)")
+	fs.StringVar(&g.project, "project", defaultProject(), "project `ID` (defaults to the first PUBSUB_PROJECT entry)")
+	fs.StringVar(&g.token, "token", os.Getenv("DASHBOARD_TOKEN"), "bearer `token` for the dashboard API")
+	fs.StringVar(&g.output, "output", "table", "output `format`: table or json")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(a.Stderr, "Usage: %s %s %s\n\nFlags:\n", programName, name, usage)
+		fs.PrintDefaults()
+	}
+	return fs, g
+}
+
+// parse parses args, allowing flags before and after positional arguments,
+// validates the shared flags and returns the positional arguments.
+func parse(fs *flag.FlagSet, g *globals, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if g.output != "table" && g.output != "json" {
+		return nil, fmt.Errorf("%w: --output must be table or json, got %q", errUsage, g.output)
+	}
+	return positional, nil
+}
+
+// usageErrorf reports a usage error after printing the command's usage.
+func usageErrorf(fs *flag.FlagSet, format string, args ...any) error {
+	fs.Usage()
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+// defaultProject returns the first entry of PUBSUB_PROJECT, the server's
+// default project.
+func defaultProject() string {
+	project, _, _ := strings.Cut(os.Getenv("PUBSUB_PROJECT"), ",")
+	return strings.TrimSpace(project)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
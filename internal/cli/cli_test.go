@@ -0,0 +1,379 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/dashboard"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// setupCLITest returns an App wired to an in-process Pub/Sub server and a
+// run helper that executes a command, returning its exit status and output.
+// Each command closes its client, so every run gets a fresh connection.
+func setupCLITest(t *testing.T) (*App, *pstest.Server, func(args ...string) (int, string, string)) {
+	t.Helper()
+	t.Setenv("PUBSUB_PROJECT", "test-project")
+
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	app := &App{}
+	run := func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		app.Stdout, app.Stderr = &stdout, &stderr
+		app.ClientOptions = dialTestServer(t, srv)
+		code := app.Run(context.Background(), args)
+		return code, stdout.String(), stderr.String()
+	}
+	return app, srv, run
+}
+
+func dialTestServer(t *testing.T, srv *pstest.Server) []option.ClientOption {
+	conn, err := grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Errorf("Failed to dial test server: %v", err)
+	}
+	return []option.ClientOption{option.WithGRPCConn(conn)}
+}
+
+func TestRun_Usage(t *testing.T) {
+	_, _, run := setupCLITest(t)
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"help"}, 0},
+		{[]string{"bogus"}, 2},
+		{[]string{"topics"}, 2},
+		{[]string{"topics", "create"}, 2},
+		{[]string{"topics", "list", "--output", "yaml"}, 2},
+		{[]string{"subs", "create", "only-sub"}, 2},
+		{[]string{"publish", "--topic", "t"}, 2},
+		{[]string{"tail"}, 2},
+		{[]string{"fixtures"}, 2},
+		{[]string{"fixtures", "f.csv", "--timing", "fast"}, 2},
+		{[]string{"topics", "list", "-h"}, 0},
+	}
+	for _, tt := range tests {
+		if code, _, _ := run(tt.args...); code != tt.want {
+			t.Errorf("%v: expected exit %d, got %d", tt.args, tt.want, code)
+		}
+	}
+}
+
+func TestRun_TopicsAndSubs(t *testing.T) {
+	_, _, run := setupCLITest(t)
+
+	if code, _, stderr := run("topics", "create", "orders", "payments"); code != 0 {
+		t.Fatalf("topics create failed: %s", stderr)
+	}
+	// Flags may follow positional arguments.
+	if code, _, stderr := run("subs", "create", "orders-sub", "--topic", "orders", "--ack-deadline", "30"); code != 0 {
+		t.Fatalf("subs create failed: %s", stderr)
+	}
+
+	code, stdout, _ := run("topics", "list", "--output", "json")
+	var topics []resourceJSON
+	if code != 0 || json.Unmarshal([]byte(stdout), &topics) != nil || len(topics) != 2 {
+		t.Fatalf("Expected 2 topics as JSON, got %q", stdout)
+	}
+
+	code, stdout, _ = run("subs", "list")
+	if code != 0 || !strings.Contains(stdout, "ACK_DEADLINE") ||
+		!strings.Contains(stdout, "projects/test-project/topics/orders") || !strings.Contains(stdout, "30") {
+		t.Errorf("Expected subscription table, got %q", stdout)
+	}
+
+	if code, _, stderr := run("subs", "delete", "orders-sub"); code != 0 {
+		t.Errorf("subs delete failed: %s", stderr)
+	}
+	if code, _, stderr := run("topics", "delete", "payments"); code != 0 {
+		t.Errorf("topics delete failed: %s", stderr)
+	}
+	if code, _, _ := run("topics", "delete", "payments"); code != 1 {
+		t.Errorf("Expected exit 1 deleting a missing topic, got %d", code)
+	}
+}
+
+func TestRun_PublishPullAndAck(t *testing.T) {
+	app, _, run := setupCLITest(t)
+	run("topics", "create", "orders")
+	run("subs", "create", "orders-sub", "--topic", "orders")
+
+	app.Stdin = strings.NewReader("{\"id\":1}\n\n{\"id\":2}\n")
+	code, stdout, stderr := run("publish", "--topic", "orders", "--file", "-", "--lines", "--attr", "source=cli", "--output", "json")
+	if code != 0 {
+		t.Fatalf("publish failed: %s", stderr)
+	}
+	var published []map[string]string
+	if err := json.Unmarshal([]byte(stdout), &published); err != nil || len(published) != 2 {
+		t.Fatalf("Expected 2 message IDs, got %q", stdout)
+	}
+
+	code, stdout, stderr = run("subs", "pull", "orders-sub", "--max", "5", "--output", "json")
+	if code != 0 {
+		t.Fatalf("subs pull failed: %s", stderr)
+	}
+	var pulled []messageJSON
+	if err := json.Unmarshal([]byte(stdout), &pulled); err != nil || len(pulled) != 2 {
+		t.Fatalf("Expected 2 pulled messages, got %q", stdout)
+	}
+	if pulled[0].Attributes["source"] != "cli" || pulled[0].AckID == "" {
+		t.Errorf("Expected attributes and an ack ID, got %+v", pulled[0])
+	}
+
+	if code, _, stderr := run("subs", "ack", "orders-sub", pulled[0].AckID, pulled[1].AckID); code != 0 {
+		t.Errorf("subs ack failed: %s", stderr)
+	}
+}
+
+func TestRun_Fixtures(t *testing.T) {
+	app, _, run := setupCLITest(t)
+	run("topics", "create", "orders")
+	run("topics", "create", "audit")
+	run("subs", "create", "orders-sub", "--topic", "orders")
+
+	path := filepath.Join(t.TempDir(), "orders.csv")
+	csv := "topic,publish_time,body,attr_type,tenant\n" +
+		"orders,2024-05-01T12:00:00Z,one,OrderCreated,acme\n" +
+		"audit,2024-05-01T12:00:00.02Z,two,,\n" +
+		"orders,2024-05-01T12:00:00.04Z,three,OrderPaid,\n"
+	if err := os.WriteFile(path, []byte(csv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := run("fixtures", path, "--data-column", "body", "--attr-column", "tenant", "--timing", "original", "--speed", "2")
+	if code != 0 {
+		t.Fatalf("fixtures failed: %s", stderr)
+	}
+	if !strings.Contains(stdout, "orders  2") || !strings.Contains(stdout, "audit   1") {
+		t.Errorf("Expected per-topic counts, got %q", stdout)
+	}
+
+	code, stdout, stderr = run("subs", "pull", "orders-sub", "--max", "5", "--output", "json")
+	if code != 0 {
+		t.Fatalf("subs pull failed: %s", stderr)
+	}
+	var pulled []messageJSON
+	if err := json.Unmarshal([]byte(stdout), &pulled); err != nil || len(pulled) != 2 {
+		t.Fatalf("Expected 2 pulled messages, got %q", stdout)
+	}
+	if pulled[0].Attributes["type"] != "OrderCreated" || pulled[0].Attributes["tenant"] != "acme" {
+		t.Errorf("Expected mapped attributes, got %+v", pulled[0])
+	}
+
+	// Lines that fail are reported and fail the command.
+	app.Stdin = strings.NewReader("{\"topic\":\"orders\",\"data\":\"x\"}\n{\"topic\":\"missing\",\"data\":\"y\"}\n")
+	code, stdout, stderr = run("fixtures", "-", "--output", "json")
+	if code != 1 || !strings.Contains(stderr, "1 of 2 messages failed") {
+		t.Errorf("Expected exit 1 for a failed line, got %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"line": 2`) {
+		t.Errorf("Expected the failed line in the report, got %s", stdout)
+	}
+}
+
+func TestRun_Tail(t *testing.T) {
+	_, srv, run := setupCLITest(t)
+	exec := func(out io.Writer, args ...string) {
+		app := &App{ClientOptions: dialTestServer(t, srv), Stdout: out, Stderr: io.Discard}
+		app.Run(context.Background(), args)
+	}
+	exec(io.Discard, "topics", "create", "orders")
+
+	// Publish once the temporary subscription exists.
+	go func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			var out bytes.Buffer
+			exec(&out, "subs", "list")
+			if strings.Contains(out.String(), "tail-") {
+				exec(io.Discard, "publish", "--topic", "orders", "--data", "hello")
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	code, stdout, stderr := run("tail", "--topic", "orders", "--count", "1", "--output", "json")
+	if code != 0 {
+		t.Fatalf("tail failed: %s", stderr)
+	}
+	var msg messageJSON
+	if err := json.Unmarshal([]byte(stdout), &msg); err != nil || msg.Data != "hello" {
+		t.Errorf("Expected one tailed message, got %q", stdout)
+	}
+
+	// The temporary subscription is removed on exit.
+	if _, stdout, _ := run("subs", "list"); strings.Contains(stdout, "tail-") {
+		t.Errorf("Expected temporary subscription to be deleted, got %q", stdout)
+	}
+}
+
+func TestRun_ExportImport(t *testing.T) {
+	app, _, run := setupCLITest(t)
+
+	var gotAuth, gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotQuery = r.Header.Get("Authorization"), r.URL.RawQuery
+		switch r.URL.Path {
+		case "/api/export":
+			_, _ = io.WriteString(w, `{"version":1}`)
+		case "/api/import":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"version":1}` {
+				http.Error(w, "unexpected body", http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(dashboard.ImportResult{Topics: 2, Skipped: []string{"topic a already exists"}})
+		default:
+			http.Error(w, "Unknown project", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	app.HTTPClient = srv.Client()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	code, _, stderr := run("export", "--dashboard", srv.URL, "--token", "secret", "--file", path)
+	if code != 0 {
+		t.Fatalf("export failed: %s", stderr)
+	}
+	if gotAuth != "Bearer secret" || !strings.Contains(gotQuery, "project=test-project") {
+		t.Errorf("Expected token and project on the request, got %q and %q", gotAuth, gotQuery)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"version":1}` {
+		t.Errorf("Expected archive written to file, got %q", data)
+	}
+
+	code, stdout, stderr := run("import", path, "--dashboard", srv.URL, "--skip-messages")
+	if code != 0 {
+		t.Fatalf("import failed: %s", stderr)
+	}
+	if !strings.Contains(gotQuery, "skip_messages=true") || !strings.Contains(stdout, "topics") || !strings.Contains(stderr, "skipped: topic a") {
+		t.Errorf("Unexpected import output %q / %q (query %q)", stdout, stderr, gotQuery)
+	}
+
+	// Server errors surface the response message and a failing exit status.
+	t.Setenv("PUBSUB_PROJECT", "")
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unknown project", http.StatusNotFound)
+	})
+	code, _, stderr = run("export", "--dashboard", srv.URL)
+	if code != 1 || !strings.Contains(stderr, "Unknown project") {
+		t.Errorf("Expected exit 1 with the server's message, got %d: %s", code, stderr)
+	}
+}
+
+func TestRun_LoadGen(t *testing.T) {
+	_, srv, run := setupCLITest(t)
+	run("topics", "create", "orders")
+
+	code, stdout, stderr := run("loadgen", "--topic", "orders", "--count", "25", "--concurrency", "4",
+		"--template", `{"n":{{seq}}}`, "--attr", "region={{pick:eu|us}}", "--output", "json")
+	if code != 0 {
+		t.Fatalf("loadgen failed: %s", stderr)
+	}
+	var status struct {
+		State     string `json:"state"`
+		Published int64  `json:"published"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &status); err != nil {
+		t.Fatalf("Failed to decode %q: %v", stdout, err)
+	}
+	if status.State != "completed" || status.Published != 25 {
+		t.Errorf("Expected 25 messages published, got %+v", status)
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 25 {
+		t.Fatalf("Expected 25 messages on the server, got %d", len(msgs))
+	}
+	if r := msgs[0].Attributes["region"]; r != "eu" && r != "us" {
+		t.Errorf("Expected a templated region attribute, got %q", r)
+	}
+
+	if code, _, _ := run("loadgen", "--topic", "orders", "--template", "{{nope}}"); code != 2 {
+		t.Errorf("Expected exit status 2 for a bad template, got %d", code)
+	}
+	if code, _, stderr := run("loadgen", "--topic", "missing", "--count", "1"); code != 1 || !strings.Contains(stderr, "failed") {
+		t.Errorf("Expected a missing topic to fail the job, got %d: %s", code, stderr)
+	}
+}
+
+func TestRun_Healthcheck(t *testing.T) {
+	ready := true
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			http.NotFound(w, r)
+			return
+		}
+		if !ready {
+			http.Error(w, "startup in progress", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	})
+	plain := httptest.NewServer(handler)
+	t.Cleanup(plain.Close)
+	secure := httptest.NewTLSServer(handler)
+	t.Cleanup(secure.Close)
+
+	run := func(addr string, selfSigned bool) (int, string) {
+		t.Setenv("DASHBOARD_ADDR", addr)
+		t.Setenv("DASHBOARD_TLS_SELF_SIGNED", strconv.FormatBool(selfSigned))
+		var stderr bytes.Buffer
+		app := &App{Stdout: io.Discard, Stderr: &stderr}
+		return app.Run(context.Background(), []string{"healthcheck"}), stderr.String()
+	}
+
+	if code, stderr := run(plain.Listener.Addr().String(), false); code != 0 {
+		t.Errorf("Expected a plain HTTP probe to pass, got %d: %s", code, stderr)
+	}
+	if code, stderr := run(secure.Listener.Addr().String(), true); code != 0 {
+		t.Errorf("Expected an HTTPS probe to pass, got %d: %s", code, stderr)
+	}
+	ready = false
+	if code, stderr := run(plain.Listener.Addr().String(), false); code != 1 || !strings.Contains(stderr, "startup in progress") {
+		t.Errorf("Expected exit 1 with the failing check, got %d: %s", code, stderr)
+	}
+	t.Setenv("DASHBOARD_PORT", "")
+	if code, _ := run("", false); code != 1 {
+		t.Errorf("Expected exit 1 without a dashboard listener, got %d", code)
+	}
+}
+
+func TestHealthcheckTarget(t *testing.T) {
+	tests := []struct {
+		addr, port, cert string
+		target, socket   string
+	}{
+		{"", "8080", "", "http://localhost:8080", ""},
+		{"0.0.0.0:9000", "", "", "http://localhost:9000", ""},
+		{"[::]:9000", "", "cert.pem", "https://localhost:9000", ""},
+		{"127.0.0.1:9000", "", "", "http://127.0.0.1:9000", ""},
+		{"unix:///run/dash.sock", "", "", "http://localhost", "/run/dash.sock"},
+	}
+	for _, tt := range tests {
+		t.Setenv("DASHBOARD_ADDR", tt.addr)
+		t.Setenv("DASHBOARD_PORT", tt.port)
+		t.Setenv("DASHBOARD_TLS_CERT", tt.cert)
+		target, socket, _, err := healthcheckTarget()
+		if err != nil || target != tt.target || socket != tt.socket {
+			t.Errorf("%q/%q: expected %s %q, got %s %q (%v)", tt.addr, tt.port, tt.target, tt.socket, target, socket, err)
+		}
+	}
+}
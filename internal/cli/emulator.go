@@ -0,0 +1,500 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gcppubsub "cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/pubsub"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultAckDeadlineSeconds matches the Pub/Sub default for new
+	// subscriptions.
+	defaultAckDeadlineSeconds = 10
+	// defaultPullTimeout bounds how long subs pull waits for messages.
+	defaultPullTimeout = 5 * time.Second
+)
+
+// messageJSON is the JSON form of a pulled or tailed message.
+type messageJSON struct {
+	AckID       string            `json:"ack_id,omitempty"`
+	MessageID   string            `json:"message_id"`
+	PublishTime time.Time         `json:"publish_time"`
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"ordering_key,omitempty"`
+}
+
+// resourceJSON is the JSON form of a created or deleted topic or subscription.
+type resourceJSON struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// subscriptionJSON is the JSON form of a listed subscription.
+type subscriptionJSON struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Topic              string `json:"topic"`
+	AckDeadlineSeconds int32  `json:"ack_deadline_seconds"`
+}
+
+// connect opens a gRPC client to the emulator named by --emulator.
+func (a *App) connect(ctx context.Context, g *globals) (*gcppubsub.Client, error) {
+	opts, err := a.clientOptions(g)
+	if err != nil {
+		return nil, err
+	}
+	client, err := gcppubsub.NewClient(ctx, g.project, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to emulator at %s: %w", g.emulator, err)
+	}
+	return client, nil
+}
+
+// clientOptions returns the options for a gRPC client to the emulator named
+// by --emulator.
+func (a *App) clientOptions(g *globals) ([]option.ClientOption, error) {
+	if g.project == "" {
+		return nil, fmt.Errorf("%w: --project or PUBSUB_PROJECT is required", errUsage)
+	}
+	opts := []option.ClientOption{
+		option.WithEndpoint(g.emulator),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		option.WithTelemetryDisabled(),
+	}
+	return append(opts, a.ClientOptions...), nil
+}
+
+// subcommand splits "<group> <action> ..." arguments, checking the action.
+func subcommand(group string, args []string, actions ...string) (string, []string, error) {
+	if len(args) == 0 || !slices.Contains(actions, args[0]) {
+		return "", nil, fmt.Errorf("%w: %s needs one of %s", errUsage, group, strings.Join(actions, ", "))
+	}
+	return args[0], args[1:], nil
+}
+
+func (a *App) runTopics(ctx context.Context, args []string) error {
+	action, args, err := subcommand("topics", args, "create", "list", "delete")
+	if err != nil {
+		return err
+	}
+	fs, g := a.newFlagSet("topics "+action, map[string]string{
+		"create": "<topic-id>... [flags]",
+		"list":   "[flags]",
+		"delete": "<topic-id>... [flags]",
+	}[action])
+	ids, err := parse(fs, g, args)
+	if err != nil {
+		return err
+	}
+	if action == "list" && len(ids) > 0 {
+		return usageErrorf(fs, "list takes no arguments")
+	}
+	if action != "list" && len(ids) == 0 {
+		return usageErrorf(fs, "at least one topic ID is required")
+	}
+
+	client, err := a.connect(ctx, g)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	out := []resourceJSON{}
+	t := &table{header: []string{"ID", "NAME"}}
+	add := func(name string) {
+		_, id, _ := pubsub.ParseName(name)
+		out = append(out, resourceJSON{ID: id, Name: name})
+		t.add(id, name)
+	}
+
+	switch action {
+	case "list":
+		it := client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{Project: "projects/" + g.project})
+		for {
+			topic, err := it.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to list topics: %w", err)
+			}
+			add(topic.Name)
+		}
+	case "create":
+		for _, id := range ids {
+			topic, err := client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: pubsub.TopicName(g.project, id)})
+			if err != nil {
+				return fmt.Errorf("failed to create topic %s: %w", id, err)
+			}
+			add(topic.Name)
+		}
+	case "delete":
+		for _, id := range ids {
+			name := pubsub.TopicName(g.project, id)
+			if err := client.TopicAdminClient.DeleteTopic(ctx, &pubsubpb.DeleteTopicRequest{Topic: name}); err != nil {
+				return fmt.Errorf("failed to delete topic %s: %w", id, err)
+			}
+			add(name)
+		}
+	}
+	return a.print(g, out, t)
+}
+
+func (a *App) runSubs(ctx context.Context, args []string) error {
+	action, args, err := subcommand("subs", args, "create", "list", "delete", "pull", "ack")
+	if err != nil {
+		return err
+	}
+	fs, g := a.newFlagSet("subs "+action, map[string]string{
+		"create": "<subscription-id> --topic <topic-id> [flags]",
+		"list":   "[flags]",
+		"delete": "<subscription-id>... [flags]",
+		"pull":   "<subscription-id> [flags]",
+		"ack":    "<subscription-id> <ack-id>... [flags]",
+	}[action])
+	var (
+		topic       string
+		ackDeadline int
+		maxMessages int
+		ack         bool
+		timeout     time.Duration
+	)
+	switch action {
+	case "create":
+		fs.StringVar(&topic, "topic", "", "topic `ID` or full name to subscribe to (required)")
+		fs.IntVar(&ackDeadline, "ack-deadline", defaultAckDeadlineSeconds, "ack deadline in `seconds`")
+	case "pull":
+		fs.IntVar(&maxMessages, "max", 10, "maximum `number` of messages to pull")
+		fs.BoolVar(&ack, "ack", false, "acknowledge the pulled messages")
+		fs.DurationVar(&timeout, "timeout", defaultPullTimeout, "how long to wait for messages")
+	}
+	pos, err := parse(fs, g, args)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "list":
+		if len(pos) > 0 {
+			return usageErrorf(fs, "list takes no arguments")
+		}
+	case "create":
+		if len(pos) != 1 || topic == "" {
+			return usageErrorf(fs, "one subscription ID and --topic are required")
+		}
+	case "delete":
+		if len(pos) == 0 {
+			return usageErrorf(fs, "at least one subscription ID is required")
+		}
+	case "pull":
+		if len(pos) != 1 || maxMessages <= 0 {
+			return usageErrorf(fs, "one subscription ID and a positive --max are required")
+		}
+	case "ack":
+		if len(pos) < 2 {
+			return usageErrorf(fs, "a subscription ID and at least one ack ID are required")
+		}
+	}
+
+	client, err := a.connect(ctx, g)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	admin := client.SubscriptionAdminClient
+
+	switch action {
+	case "list":
+		out := []subscriptionJSON{}
+		t := &table{header: []string{"ID", "TOPIC", "ACK_DEADLINE"}}
+		it := admin.ListSubscriptions(ctx, &pubsubpb.ListSubscriptionsRequest{Project: "projects/" + g.project})
+		for {
+			sub, err := it.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to list subscriptions: %w", err)
+			}
+			_, id, _ := pubsub.ParseName(sub.Name)
+			out = append(out, subscriptionJSON{ID: id, Name: sub.Name, Topic: sub.Topic, AckDeadlineSeconds: sub.AckDeadlineSeconds})
+			t.add(id, sub.Topic, strconv.Itoa(int(sub.AckDeadlineSeconds)))
+		}
+		return a.print(g, out, t)
+
+	case "create":
+		sub, err := admin.CreateSubscription(ctx, &pubsubpb.Subscription{
+			Name:               pubsub.SubscriptionName(g.project, pos[0]),
+			Topic:              pubsub.TopicName(g.project, topic),
+			AckDeadlineSeconds: int32(ackDeadline),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create subscription %s: %w", pos[0], err)
+		}
+		_, id, _ := pubsub.ParseName(sub.Name)
+		out := subscriptionJSON{ID: id, Name: sub.Name, Topic: sub.Topic, AckDeadlineSeconds: sub.AckDeadlineSeconds}
+		t := &table{header: []string{"ID", "TOPIC", "ACK_DEADLINE"}}
+		t.add(id, sub.Topic, strconv.Itoa(int(sub.AckDeadlineSeconds)))
+		return a.print(g, out, t)
+
+	case "delete":
+		out := []resourceJSON{}
+		t := &table{header: []string{"ID", "NAME"}}
+		for _, id := range pos {
+			name := pubsub.SubscriptionName(g.project, id)
+			if err := admin.DeleteSubscription(ctx, &pubsubpb.DeleteSubscriptionRequest{Subscription: name}); err != nil {
+				return fmt.Errorf("failed to delete subscription %s: %w", id, err)
+			}
+			_, short, _ := pubsub.ParseName(name)
+			out = append(out, resourceJSON{ID: short, Name: name})
+			t.add(short, name)
+		}
+		return a.print(g, out, t)
+
+	case "pull":
+		name := pubsub.SubscriptionName(g.project, pos[0])
+		pullCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		resp, err := admin.Pull(pullCtx, &pubsubpb.PullRequest{Subscription: name, MaxMessages: int32(maxMessages)})
+		// The emulator holds a pull open until messages arrive, so running
+		// out of time just means there was nothing to pull.
+		if err != nil && status.Code(err) != codes.DeadlineExceeded && !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("failed to pull from %s: %w", pos[0], err)
+		}
+		out := []messageJSON{}
+		t := &table{header: []string{"ACK_ID", "MESSAGE_ID", "PUBLISHED", "DATA", "ATTRIBUTES"}}
+		var ackIDs []string
+		for _, rm := range resp.GetReceivedMessages() {
+			m := rm.GetMessage()
+			msg := messageJSON{
+				AckID:       rm.GetAckId(),
+				MessageID:   m.GetMessageId(),
+				PublishTime: m.GetPublishTime().AsTime(),
+				Data:        string(m.GetData()),
+				Attributes:  m.GetAttributes(),
+				OrderingKey: m.GetOrderingKey(),
+			}
+			out = append(out, msg)
+			t.add(msg.AckID, msg.MessageID, msg.PublishTime.Format(time.RFC3339), msg.Data, formatAttributes(msg.Attributes))
+			ackIDs = append(ackIDs, msg.AckID)
+		}
+		if ack && len(ackIDs) > 0 {
+			if err := admin.Acknowledge(ctx, &pubsubpb.AcknowledgeRequest{Subscription: name, AckIds: ackIDs}); err != nil {
+				return fmt.Errorf("failed to ack pulled messages: %w", err)
+			}
+		}
+		return a.print(g, out, t)
+
+	case "ack":
+		name := pubsub.SubscriptionName(g.project, pos[0])
+		if err := admin.Acknowledge(ctx, &pubsubpb.AcknowledgeRequest{Subscription: name, AckIds: pos[1:]}); err != nil {
+			return fmt.Errorf("failed to ack on %s: %w", pos[0], err)
+		}
+		t := &table{header: []string{"ACKED"}}
+		t.add(strconv.Itoa(len(pos) - 1))
+		return a.print(g, map[string]int{"acked": len(pos) - 1}, t)
+	}
+	return nil
+}
+
+func (a *App) runPublish(ctx context.Context, args []string) error {
+	fs, g := a.newFlagSet("publish", "--topic <topic-id> (--data <text> | --file <path>) [flags]")
+	var (
+		topic       string
+		data        string
+		file        string
+		lines       bool
+		orderingKey string
+	)
+	attrs := attrFlag{}
+	fs.StringVar(&topic, "topic", "", "topic `ID` or full name (required)")
+	fs.StringVar(&data, "data", "", "message `text`")
+	fs.StringVar(&file, "file", "", "read the message from `path` (\"-\" for stdin)")
+	fs.BoolVar(&lines, "lines", false, "publish each non-empty line of the input as its own message")
+	fs.StringVar(&orderingKey, "ordering-key", "", "ordering `key` for the messages")
+	fs.Var(attrs, "attr", "message attribute as `key=value` (repeatable)")
+	pos, err := parse(fs, g, args)
+	if err != nil {
+		return err
+	}
+	if topic == "" || len(pos) > 0 {
+		return usageErrorf(fs, "--topic is required and no arguments are accepted")
+	}
+	if (data == "") == (file == "") {
+		return usageErrorf(fs, "exactly one of --data or --file is required")
+	}
+
+	payload := []byte(data)
+	if file != "" {
+		if payload, err = a.readInput(file); err != nil {
+			return err
+		}
+	}
+	bodies := [][]byte{payload}
+	if lines {
+		bodies = splitLines(payload)
+		if len(bodies) == 0 {
+			return fmt.Errorf("no messages in input")
+		}
+	}
+
+	client, err := a.connect(ctx, g)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	publisher := client.Publisher(pubsub.TopicName(g.project, topic))
+	publisher.EnableMessageOrdering = orderingKey != ""
+	defer publisher.Stop()
+
+	results := make([]*gcppubsub.PublishResult, len(bodies))
+	for i, body := range bodies {
+		results[i] = publisher.Publish(ctx, &gcppubsub.Message{Data: body, Attributes: attrs, OrderingKey: orderingKey})
+	}
+
+	out := []map[string]string{}
+	t := &table{header: []string{"MESSAGE_ID"}}
+	for _, res := range results {
+		id, err := res.Get(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", topic, err)
+		}
+		out = append(out, map[string]string{"message_id": id})
+		t.add(id)
+	}
+	return a.print(g, out, t)
+}
+
+func (a *App) runTail(ctx context.Context, args []string) error {
+	fs, g := a.newFlagSet("tail", "--topic <topic-id> [flags]")
+	var (
+		topic        string
+		subscription string
+		count        int
+	)
+	fs.StringVar(&topic, "topic", "", "topic `ID` or full name to watch")
+	fs.StringVar(&subscription, "subscription", "", "read from an existing subscription `ID` instead of a temporary one")
+	fs.IntVar(&count, "count", 0, "exit after this `number` of messages (0 runs until interrupted)")
+	pos, err := parse(fs, g, args)
+	if err != nil {
+		return err
+	}
+	if (topic == "") == (subscription == "") || len(pos) > 0 {
+		return usageErrorf(fs, "exactly one of --topic or --subscription is required")
+	}
+
+	client, err := a.connect(ctx, g)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	// Tailing a topic uses a throwaway subscription so it sees new messages
+	// without taking them from anyone else.
+	subName := pubsub.SubscriptionName(g.project, subscription)
+	if subscription == "" {
+		subName = pubsub.SubscriptionName(g.project, fmt.Sprintf("tail-%d", time.Now().UnixNano()))
+		if _, err := client.SubscriptionAdminClient.CreateSubscription(ctx, &pubsubpb.Subscription{
+			Name:               subName,
+			Topic:              pubsub.TopicName(g.project, topic),
+			AckDeadlineSeconds: defaultAckDeadlineSeconds,
+		}); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+		}
+		defer func() {
+			cleanup, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = client.SubscriptionAdminClient.DeleteSubscription(cleanup, &pubsubpb.DeleteSubscriptionRequest{Subscription: subName})
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	seen := 0
+	if g.output == "table" {
+		_, _ = fmt.Fprintln(a.Stdout, "PUBLISHED\tMESSAGE_ID\tDATA\tATTRIBUTES")
+	}
+	err = client.Subscriber(subName).Receive(ctx, func(_ context.Context, m *gcppubsub.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		if count > 0 && seen >= count {
+			m.Nack()
+			return
+		}
+		m.Ack()
+		seen++
+		a.writeTailLine(g, m)
+		if count > 0 && seen >= count {
+			cancel()
+		}
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("failed to receive from %s: %w", subName, err)
+	}
+	return nil
+}
+
+// writeTailLine streams one message: a tab-separated line for tables (rows
+// cannot be aligned while streaming) or one JSON object per line.
+func (a *App) writeTailLine(g *globals, m *gcppubsub.Message) {
+	msg := messageJSON{
+		MessageID:   m.ID,
+		PublishTime: m.PublishTime,
+		Data:        string(m.Data),
+		Attributes:  m.Attributes,
+		OrderingKey: m.OrderingKey,
+	}
+	if g.output == "json" {
+		_ = json.NewEncoder(a.Stdout).Encode(msg)
+		return
+	}
+	_, _ = fmt.Fprintf(a.Stdout, "%s\t%s\t%s\t%s\n",
+		msg.PublishTime.Format(time.RFC3339), msg.MessageID, msg.Data, formatAttributes(msg.Attributes))
+}
+
+// readInput reads path, or stdin for "-".
+func (a *App) readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(a.Stdin)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// splitLines returns the non-empty lines of data.
+func splitLines(data []byte) [][]byte {
+	var out [][]byte
+	for line := range bytes.Lines(data) {
+		if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/fixtures"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/pubsub"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+func (a *App) runFixtures(ctx context.Context, args []string) error {
+	fs, g := a.newFlagSet("fixtures", "<file|-> [--topic <topic-id>] [--timing none|original] [flags]")
+	var (
+		topic, format, timing string
+		speed                 float64
+		mapping               fixtures.Mapping
+		attrColumns           listFlag
+		interval              time.Duration
+	)
+	fs.StringVar(&topic, "topic", "", "publish every message to this topic `ID` instead of the file's topic column")
+	fs.StringVar(&format, "format", "", "file `format`: ndjson or csv (detected from the file when omitted)")
+	fs.StringVar(&timing, "timing", "none", "`timing`: none publishes back to back, original keeps the gaps between publish times")
+	fs.Float64Var(&speed, "speed", 1, "with --timing original, replay this many `times` faster (0.5 is half speed)")
+	fs.StringVar(&mapping.Data, "data-column", fixtures.DefaultDataColumn, "`column` holding the message data")
+	fs.StringVar(&mapping.Topic, "topic-column", fixtures.DefaultTopicColumn, "`column` holding the topic")
+	fs.StringVar(&mapping.Time, "time-column", fixtures.DefaultTimeColumn, "`column` holding the original publish time")
+	fs.Var(&attrColumns, "attr-column", "copy this `column` into an attribute of the same name (repeatable); attr_<key> columns always are")
+	fs.DurationVar(&interval, "interval", time.Second, "how often to report progress on stderr (0 disables)")
+	pos, err := parse(fs, g, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf(fs, "one fixture file is required")
+	}
+	mapping.Attributes = attrColumns
+
+	opts := fixtures.Options{Topic: topic, Speed: speed}
+	if opts.Timing, err = fixtures.ParseTiming(timing); err != nil {
+		return usageErrorf(fs, "%v", err)
+	}
+	if speed <= 0 {
+		return usageErrorf(fs, "--speed must be positive")
+	}
+
+	data, err := a.readInput(pos[0])
+	if err != nil {
+		return err
+	}
+	f := fixtures.DetectFormat(pos[0], data)
+	if format != "" {
+		if f, err = fixtures.ParseFormat(format); err != nil {
+			return usageErrorf(fs, "%v", err)
+		}
+	}
+	records, invalid, err := fixtures.Read(bytes.NewReader(data), f, mapping)
+	if err != nil {
+		return fmt.Errorf("invalid %s file %s: %w", f, pos[0], err)
+	}
+	if len(records) == 0 && len(invalid) == 0 {
+		return fmt.Errorf("no messages in %s", pos[0])
+	}
+
+	clientOpts, err := a.clientOptions(g)
+	if err != nil {
+		return err
+	}
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(a.Stderr, nil))}
+	client, err := pubsub.NewClient(ctx, g.project, log, clientOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to emulator at %s: %w", g.emulator, err)
+	}
+	defer func() { _ = client.Close() }()
+
+	publisher := pubsub.NewPublisher(client, log)
+	topics := map[string]*pubsub.TopicPublisher{}
+	defer func() {
+		for _, t := range topics {
+			t.Stop()
+		}
+	}()
+	publish := func(ctx context.Context, topic string, rec *fixtures.Record) (string, error) {
+		name := pubsub.TopicName(g.project, topic)
+		t, ok := topics[name]
+		if !ok {
+			t = publisher.Topic(name)
+			topics[name] = t
+		}
+		return t.Publish(ctx, rec.Message())
+	}
+
+	if interval > 0 {
+		last := time.Now()
+		opts.Progress = func(r fixtures.Report) {
+			if time.Since(last) < interval {
+				return
+			}
+			last = time.Now()
+			_, _ = fmt.Fprintf(a.Stderr, "%6.1fs  %d/%d  published %d  failed %d\n",
+				r.ElapsedSeconds, r.Published+r.Failed, r.Total, r.Published, r.Failed)
+		}
+	}
+
+	report, err := fixtures.Publish(ctx, records, invalid, publish, opts)
+	if err != nil {
+		return fmt.Errorf("stopped after publishing %d of %d messages: %w", report.Published, report.Total, err)
+	}
+
+	t := &table{header: []string{"TOPIC", "PUBLISHED"}}
+	for _, name := range slices.Sorted(maps.Keys(report.Topics)) {
+		t.add(name, strconv.Itoa(report.Topics[name]))
+	}
+	if err := a.print(g, report, t); err != nil {
+		return err
+	}
+	if g.output == "table" {
+		for _, e := range report.Errors {
+			_, _ = fmt.Fprintf(a.Stderr, "error: line %d: %s\n", e.Line, e.Error)
+		}
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d messages failed", report.Failed, report.Total)
+	}
+	return nil
+}
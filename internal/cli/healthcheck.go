@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHealthcheckTimeout bounds a healthcheck probe.
+const defaultHealthcheckTimeout = 5 * time.Second
+
+// runHealthcheck probes the dashboard's /readyz the way the server listens:
+// on DASHBOARD_ADDR or DASHBOARD_PORT, over HTTPS when TLS is configured.
+// It is meant for container healthchecks, so it only reports failures.
+func (a *App) runHealthcheck(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet(programName+" healthcheck", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	var certFile, keyFile string
+	var timeout time.Duration
+	fs.StringVar(&certFile, "cert", os.Getenv("DASHBOARD_HEALTHCHECK_CERT"), "client certificate `file` to present when DASHBOARD_TLS_CLIENT_CA is set")
+	fs.StringVar(&keyFile, "key", os.Getenv("DASHBOARD_HEALTHCHECK_KEY"), "client key `file` for --cert")
+	fs.DurationVar(&timeout, "timeout", defaultHealthcheckTimeout, "give up after `duration`")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(a.Stderr, "Usage: %s healthcheck [flags]\n\nFlags:\n", programName)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf(fs, "healthcheck takes no arguments")
+	}
+	if (certFile == "") != (keyFile == "") {
+		return usageErrorf(fs, "--cert and --key must be set together")
+	}
+
+	target, socket, secure, err := healthcheckTarget()
+	if err != nil {
+		return err
+	}
+	transport := &http.Transport{}
+	if socket != "" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		}
+	}
+	if secure {
+		// The probe runs next to the server and only checks that it is
+		// ready, so the (often self-signed) certificate is not verified.
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // #nosec G402 -- local readiness probe
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	client := &http.Client{Transport: transport, Timeout: timeout}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target+"/readyz", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach dashboard: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return fmt.Errorf("not ready: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// healthcheckTarget derives the dashboard's base URL from the server's
+// listener settings. For a Unix socket it returns the socket path to dial and
+// a placeholder host.
+func healthcheckTarget() (target, socket string, secure bool, err error) {
+	selfSigned, _ := strconv.ParseBool(os.Getenv("DASHBOARD_TLS_SELF_SIGNED"))
+	secure = selfSigned || os.Getenv("DASHBOARD_TLS_CERT") != ""
+	scheme := "http://"
+	if secure {
+		scheme = "https://"
+	}
+
+	addr := os.Getenv("DASHBOARD_ADDR")
+	if path, ok := strings.CutPrefix(addr, unixScheme); ok {
+		return scheme + "localhost", path, secure, nil
+	}
+	if addr == "" {
+		port := os.Getenv("DASHBOARD_PORT")
+		if port == "" {
+			return "", "", false, fmt.Errorf("the dashboard is disabled: set DASHBOARD_PORT or DASHBOARD_ADDR")
+		}
+		addr = ":" + port
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", false, fmt.Errorf("invalid DASHBOARD_ADDR %q: %w", addr, err)
+	}
+	// A wildcard bind is probed over loopback.
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "localhost"
+	}
+	return scheme + net.JoinHostPort(host, port), "", secure, nil
+}
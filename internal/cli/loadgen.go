@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/loadgen"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/pubsub"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+func (a *App) runLoadGen(ctx context.Context, args []string) error {
+	fs, g := a.newFlagSet("loadgen", "--topic <topic-id> [flags]")
+	var (
+		topic       string
+		rate        float64
+		concurrency int
+		duration    time.Duration
+		count       int64
+		template    string
+		seed        uint64
+		interval    time.Duration
+	)
+	attrs := attrFlag{}
+	fs.StringVar(&topic, "topic", "", "topic `ID` or full name (required)")
+	fs.Float64Var(&rate, "rate", 0, "target messages per `second` (0 publishes as fast as --concurrency allows)")
+	fs.IntVar(&concurrency, "concurrency", loadgen.DefaultConcurrency, "`number` of publishes in flight at once")
+	fs.DurationVar(&duration, "duration", 0, "how long to run (default 10s unless --count is set)")
+	fs.Int64Var(&count, "count", 0, "stop after this `number` of messages")
+	fs.StringVar(&template, "template", loadgen.DefaultTemplate, "message `template` with placeholders such as {{seq}}, {{uuid}} or {{int:1:100}}")
+	fs.Var(attrs, "attr", "attribute as `key=template` (repeatable)")
+	fs.Uint64Var(&seed, "seed", 0, "`seed` for repeatable random placeholders (0 picks one)")
+	fs.DurationVar(&interval, "interval", time.Second, "how often to report progress on stderr (0 disables)")
+	pos, err := parse(fs, g, args)
+	if err != nil {
+		return err
+	}
+	if topic == "" || len(pos) > 0 {
+		return usageErrorf(fs, "--topic is required and no arguments are accepted")
+	}
+
+	opts, err := a.clientOptions(g)
+	if err != nil {
+		return err
+	}
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(a.Stderr, nil))}
+	client, err := pubsub.NewClient(ctx, g.project, log, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to emulator at %s: %w", g.emulator, err)
+	}
+	defer func() { _ = client.Close() }()
+
+	name := pubsub.TopicName(g.project, topic)
+	job, err := loadgen.Start(ctx, loadgen.Spec{
+		Topic:       name,
+		Rate:        rate,
+		Concurrency: concurrency,
+		Duration:    duration,
+		Count:       count,
+		Template:    template,
+		Attributes:  attrs,
+		Seed:        seed,
+	}, pubsub.NewPublisher(client, log).Topic(name))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for done := false; !done; {
+		select {
+		case <-tick:
+			s := job.Status()
+			_, _ = fmt.Fprintf(a.Stderr, "%6.1fs  published %d (%.1f/s)  errors %d  p50 %.2fms  p99 %.2fms\n",
+				s.ElapsedSeconds, s.Published, s.Throughput, s.Errors, s.Latency.P50, s.Latency.P99)
+		case <-job.Done():
+			done = true
+		}
+	}
+
+	s := job.Status()
+	t := &table{header: []string{"STATE", "PUBLISHED", "ERRORS", "ELAPSED", "THROUGHPUT", "P50_MS", "P95_MS", "P99_MS", "MAX_MS"}}
+	t.add(string(s.State), strconv.FormatInt(s.Published, 10), strconv.FormatInt(s.Errors, 10),
+		(time.Duration(s.ElapsedSeconds * float64(time.Second))).Round(time.Millisecond).String(),
+		fmt.Sprintf("%.1f/s", s.Throughput), ms(s.Latency.P50), ms(s.Latency.P95), ms(s.Latency.P99), ms(s.Latency.Max))
+	if err := a.print(g, s, t); err != nil {
+		return err
+	}
+	if s.State == loadgen.StateFailed {
+		return fmt.Errorf("load job failed: %s", s.Error)
+	}
+	return nil
+}
+
+func ms(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
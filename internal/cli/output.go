@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// table is tabular output with one header row.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// write renders the table with aligned columns.
+func (t *table) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		_, _ = fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// print writes v as indented JSON with --output json, otherwise t.
+func (a *App) print(g *globals, v any, t *table) error {
+	if g.output == "json" {
+		enc := json.NewEncoder(a.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	return t.write(a.Stdout)
+}
+
+// formatAttributes renders attributes as sorted k=v pairs for tables.
+func formatAttributes(attrs map[string]string) string {
+	pairs := make([]string, 0, len(attrs))
+	for _, k := range slices.Sorted(maps.Keys(attrs)) {
+		pairs = append(pairs, k+"="+attrs[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// attrFlag collects repeated --attr key=value flags.
+type attrFlag map[string]string
+
+func (f attrFlag) String() string { return formatAttributes(f) }
+
+func (f attrFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[k] = v
+	return nil
+}
+
+// listFlag collects a repeated string flag.
+type listFlag []string
+
+func (f *listFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *listFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/dashboard"
+)
+
+const (
+	unixScheme = "unix://"
+	// maxErrorBodyBytes caps how much of an error response is echoed.
+	maxErrorBodyBytes = 4 << 10
+)
+
+// dashboardRequest calls the dashboard API, scoping it to --project and
+// authenticating with --token. Non-2xx responses become errors carrying the
+// server's message.
+func (a *App) dashboardRequest(ctx context.Context, g *globals, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	client, base := a.HTTPClient, strings.TrimRight(g.dashboard, "/")
+	if client == nil {
+		client = &http.Client{}
+		// A dashboard bound to a Unix socket (DASHBOARD_ADDR=unix://...)
+		// is reached by dialling the socket; the host is a placeholder.
+		if socket, ok := strings.CutPrefix(g.dashboard, unixScheme); ok {
+			client.Transport = &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+				},
+			}
+			base = "http://localhost"
+		}
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+	if g.project != "" {
+		query.Set("project", g.project)
+	}
+	target := base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach dashboard at %s: %w", g.dashboard, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer func() { _ = resp.Body.Close() }()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (a *App) runExport(ctx context.Context, args []string) error {
+	fs, g := a.newFlagSet("export", "[--format json|tar.gz] [--file <path>] [flags]")
+	var format, file string
+	fs.StringVar(&format, "format", "json", "archive `format`: json or tar.gz")
+	fs.StringVar(&file, "file", "-", "write the archive to `path` (\"-\" for stdout)")
+	pos, err := parse(fs, g, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) > 0 {
+		return usageErrorf(fs, "export takes no arguments")
+	}
+
+	resp, err := a.dashboardRequest(ctx, g, http.MethodGet, "/api/export", url.Values{"format": {format}}, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if file == "-" {
+		_, err = io.Copy(a.Stdout, resp.Body)
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return f.Close()
+}
+
+func (a *App) runImport(ctx context.Context, args []string) error {
+	fs, g := a.newFlagSet("import", "<archive|-> [--skip-messages] [flags]")
+	var skipMessages bool
+	fs.BoolVar(&skipMessages, "skip-messages", false, "restore resources but not the message history")
+	pos, err := parse(fs, g, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf(fs, "one archive path is required")
+	}
+
+	data, err := a.readInput(pos[0])
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	if skipMessages {
+		query.Set("skip_messages", "true")
+	}
+	resp, err := a.dashboardRequest(ctx, g, http.MethodPost, "/api/import", query, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var res dashboard.ImportResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("invalid import response: %w", err)
+	}
+
+	t := &table{header: []string{"RESOURCE", "IMPORTED"}}
+	for _, row := range []struct {
+		name  string
+		count int
+	}{
+		{"schemas", res.Schemas},
+		{"topics", res.Topics},
+		{"subscriptions", res.Subscriptions},
+		{"snapshots", res.Snapshots},
+		{"messages", res.Messages},
+	} {
+		t.add(row.name, strconv.Itoa(row.count))
+	}
+	if err := a.print(g, res, t); err != nil {
+		return err
+	}
+	if g.output == "table" {
+		for _, s := range res.Skipped {
+			_, _ = fmt.Fprintf(a.Stderr, "skipped: %s\n", s)
+		}
+		for _, e := range res.Errors {
+			_, _ = fmt.Fprintf(a.Stderr, "error: %s\n", e)
+		}
+	}
+	if len(res.Errors) > 0 {
+		return fmt.Errorf("%d resources failed to import", len(res.Errors))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package clock provides the time and message ID sources used for what the
+// process records, so test runs can make both deterministic: a Manual clock
+// only moves when told to, and IDs hands out sequential message IDs.
+package clock
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock tells the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock.
+type System struct{}
+
+// Now returns the current time.
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Manual is a clock that stands still until it is set or advanced.
+// Functions registered with OnChange run after every change, so timers kept
+// against the clock (such as ack deadlines) can be re-evaluated.
+type Manual struct {
+	start time.Time
+
+	mu        sync.Mutex
+	now       time.Time
+	listeners []func(time.Time)
+}
+
+// NewManual creates a Manual clock reading start.
+func NewManual(start time.Time) *Manual {
+	return &Manual{start: start, now: start}
+}
+
+// Now returns the clock's current reading.
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Set moves the clock to t, which may be in the past.
+func (m *Manual) Set(t time.Time) {
+	m.mu.Lock()
+	m.now = t
+	listeners := slices.Clone(m.listeners)
+	m.mu.Unlock()
+	for _, fn := range listeners {
+		fn(t)
+	}
+}
+
+// Advance moves the clock forward by d and returns the new reading.
+func (m *Manual) Advance(d time.Duration) time.Time {
+	m.mu.Lock()
+	t := m.now.Add(d)
+	m.mu.Unlock()
+	m.Set(t)
+	return t
+}
+
+// Reset moves the clock back to its start time.
+func (m *Manual) Reset() {
+	m.Set(m.start)
+}
+
+// OnChange registers fn to run, with the new reading, after every change.
+func (m *Manual) OnChange(fn func(time.Time)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
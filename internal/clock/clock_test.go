@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestManual(t *testing.T) {
+	m := NewManual(start)
+	var seen []time.Time
+	m.OnChange(func(now time.Time) { seen = append(seen, now) })
+
+	if !m.Now().Equal(start) {
+		t.Fatalf("Expected %v, got %v", start, m.Now())
+	}
+	if got := m.Advance(90 * time.Second); !got.Equal(start.Add(90 * time.Second)) {
+		t.Errorf("Expected advance to return %v, got %v", start.Add(90*time.Second), got)
+	}
+	later := start.Add(time.Hour)
+	m.Set(later)
+	if !m.Now().Equal(later) {
+		t.Errorf("Expected %v, got %v", later, m.Now())
+	}
+	m.Reset()
+	if !m.Now().Equal(start) {
+		t.Errorf("Expected reset to %v, got %v", start, m.Now())
+	}
+	if len(seen) != 3 || !seen[1].Equal(later) {
+		t.Errorf("Expected 3 change notifications, got %v", seen)
+	}
+}
+
+func TestIDs(t *testing.T) {
+	m := NewManual(start)
+	ids := NewIDs(42, m)
+
+	if ids.Next() != "42-1" {
+		t.Errorf("Expected next ID 42-1, got %s", ids.Next())
+	}
+	a := ids.Assign("backend-a")
+	m.Advance(time.Second)
+	b := ids.Assign("backend-b")
+	if a.ID != "42-1" || b.ID != "42-2" {
+		t.Fatalf("Expected 42-1 and 42-2, got %s and %s", a.ID, b.ID)
+	}
+	if !a.PublishTime.Equal(start) || !b.PublishTime.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected publish times from the clock, got %v and %v", a.PublishTime, b.PublishTime)
+	}
+	if again := ids.Assign("backend-a"); again != a {
+		t.Errorf("Expected the same assignment for a known ID, got %+v", again)
+	}
+
+	resets := 0
+	ids.OnReset(func() { resets++ })
+	ids.Reset()
+	if got := ids.Assign("backend-b").ID; got != "42-1" {
+		t.Errorf("Expected numbering to restart after reset, got %s", got)
+	}
+	if resets != 1 {
+		t.Errorf("Expected 1 reset notification, got %d", resets)
+	}
+}
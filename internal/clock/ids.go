@@ -0,0 +1,94 @@
+package clock
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxAssigned bounds how many emulator IDs IDs remembers; the oldest are
+// forgotten first.
+const maxAssigned = 100_000
+
+// Assigned is the identity given to a message the emulator knows by another
+// ID.
+type Assigned struct {
+	ID          string
+	PublishTime time.Time
+}
+
+// IDs gives messages deterministic, sequential IDs of the form
+// "<seed>-<n>", numbered in the order the process first sees them, and
+// stamps each with the clock's time at that moment. The same emulator ID
+// always maps to the same assignment. Functions registered with OnReset run
+// after every Reset, so state keyed by the reused IDs can be dropped.
+type IDs struct {
+	seed  int64
+	clock Clock
+
+	mu        sync.Mutex
+	next      int64
+	assigned  map[string]Assigned
+	order     []string
+	listeners []func()
+}
+
+// NewIDs creates an ID source for seed, stamping publish times from clock.
+func NewIDs(seed int64, clock Clock) *IDs {
+	return &IDs{seed: seed, clock: clock, assigned: make(map[string]Assigned)}
+}
+
+// Seed returns the seed IDs are prefixed with.
+func (s *IDs) Seed() int64 {
+	return s.seed
+}
+
+// Assign returns the identity of the message the emulator calls backendID,
+// assigning the next ID on first sight.
+func (s *IDs) Assign(backendID string) Assigned {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.assigned[backendID]; ok {
+		return a
+	}
+	s.next++
+	a := Assigned{
+		ID:          strconv.FormatInt(s.seed, 10) + "-" + strconv.FormatInt(s.next, 10),
+		PublishTime: s.clock.Now(),
+	}
+	s.assigned[backendID] = a
+	s.order = append(s.order, backendID)
+	if len(s.order) > maxAssigned {
+		delete(s.assigned, s.order[0])
+		s.order = s.order[1:]
+	}
+	return a
+}
+
+// Next returns the ID the next new message will get.
+func (s *IDs) Next() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strconv.FormatInt(s.seed, 10) + "-" + strconv.FormatInt(s.next+1, 10)
+}
+
+// Reset forgets every assignment and restarts numbering at 1.
+func (s *IDs) Reset() {
+	s.mu.Lock()
+	s.next = 0
+	s.assigned = make(map[string]Assigned)
+	s.order = nil
+	listeners := slices.Clone(s.listeners)
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// OnReset registers fn to run after every Reset.
+func (s *IDs) OnReset(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
@@ -2,28 +2,108 @@ package config
 
 import (
 	"fmt"
+	"io/fs"
+	"net"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/retention"
 )
 
 // Config holds all application configuration
 type Config struct {
+	// ProjectID is the default project: bare topic and subscription IDs
+	// belong to it. ProjectIDs lists every project the emulator serves,
+	// starting with ProjectID.
 	ProjectID        string
+	ProjectIDs       []string
 	TopicIDs         []string
 	SubscriptionIDs  []string
 	MessageToPublish string
 	DashboardPort    string
 	PubSubPort       string
+
+	// DashboardAddr is a host:port or unix:///path.sock; it overrides
+	// DashboardPort. DashboardSocketMode applies to socket files.
+	DashboardAddr       string
+	DashboardSocketMode fs.FileMode
+
+	// Dashboard authentication (see the auth package for formats)
+	AuthMode        string
+	AuthTokens      string
+	AuthUsers       string
+	AuthJWKSFile    string
+	AuthJWTIssuer   string
+	AuthJWTAudience string
+	AuthRoleClaim   string
+
+	// Dashboard CORS policy and CSRF protection. Empty methods/headers fall
+	// back to the dashboard defaults.
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+	CSRFMode             string
+
+	// Dashboard TLS. A client CA enables mTLS.
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+	TLSSelfSigned   bool
+
+	// Hot reload. ConfigFile holds KEY=VALUE overrides that are re-read on
+	// SIGHUP and, when ConfigPollInterval > 0, whenever the file changes.
+	ConfigFile          string
+	ConfigPollInterval  time.Duration
+	ReloadDeleteRemoved bool
+
+	// Emulator supervision. With SuperviseEmulator set, EmulatorCommand is
+	// launched and restarted on crash; startup waits up to
+	// EmulatorStartupTimeout for it to listen on PubSubPort.
+	SuperviseEmulator      bool
+	EmulatorCommand        []string
+	EmulatorStartupTimeout time.Duration
+
+	// ChaosPort, when set, serves a fault-injecting gRPC proxy in front of
+	// the emulator; rules are managed through /api/chaos.
+	ChaosPort string
+
+	// Test mode. With TestMode set, recorded times come from a manual clock
+	// starting at TestStartTime and message IDs are assigned sequentially
+	// from TestSeed; both are controlled through /api/clock.
+	TestMode      bool
+	TestSeed      int64
+	TestStartTime time.Time
+
+	// TemplatesDir holds publish templates (*.json) loaded at startup.
+	TemplatesDir string
+
+	// History bounds the dashboard's message history. Full payloads of
+	// truncated messages are kept in a directory under HistorySpillDir
+	// (the system temporary directory if empty).
+	History         retention.Policy
+	HistorySpillDir string
 }
 
-// LoadFromEnv loads configuration from environment variables
+// LoadFromEnv loads configuration from environment variables. When
+// PUBSUB_CONFIG_FILE is set, values in that file take precedence over the
+// process environment, so editing it and reloading changes the topology.
 func LoadFromEnv() (*Config, error) {
-	projectID := os.Getenv("PUBSUB_PROJECT")
-	topicsStr := os.Getenv("PUBSUB_TOPIC")
-	subsStr := os.Getenv("PUBSUB_SUBSCRIPTION")
+	configFile := os.Getenv("PUBSUB_CONFIG_FILE")
+	e, err := readEnvFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := parseCommaSeparated(e.get("PUBSUB_PROJECT"))
+	topicsStr := e.get("PUBSUB_TOPIC")
+	subsStr := e.get("PUBSUB_SUBSCRIPTION")
 
-	if projectID == "" || topicsStr == "" || subsStr == "" {
+	if len(projects) == 0 || topicsStr == "" || subsStr == "" {
 		return nil, fmt.Errorf("required environment variables PUBSUB_PROJECT, PUBSUB_TOPIC, or PUBSUB_SUBSCRIPTION are not set")
 	}
 
@@ -34,13 +114,128 @@ func LoadFromEnv() (*Config, error) {
 		return nil, fmt.Errorf("number of topics (%d) and subscriptions (%d) must match", len(topics), len(subs))
 	}
 
+	corsCredentials, err := e.parseBool("DASHBOARD_CORS_CREDENTIALS", false)
+	if err != nil {
+		return nil, err
+	}
+	corsMaxAge, err := e.parseSeconds("DASHBOARD_CORS_MAX_AGE", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsSelfSigned, err := e.parseBool("DASHBOARD_TLS_SELF_SIGNED", false)
+	if err != nil {
+		return nil, err
+	}
+	socketMode, err := e.parseFileMode("DASHBOARD_SOCKET_MODE", 0o660)
+	if err != nil {
+		return nil, err
+	}
+	pollInterval, err := e.parseSeconds("PUBSUB_CONFIG_POLL_INTERVAL", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	reloadDelete, err := e.parseBool("PUBSUB_RELOAD_DELETE", false)
+	if err != nil {
+		return nil, err
+	}
+	supervise, err := e.parseBool("PUBSUB_SUPERVISE", false)
+	if err != nil {
+		return nil, err
+	}
+	startupTimeout, err := e.parseSeconds("PUBSUB_EMULATOR_STARTUP_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	testMode, err := e.parseBool("PUBSUB_TEST_MODE", false)
+	if err != nil {
+		return nil, err
+	}
+	testSeed, err := e.parseInt("PUBSUB_TEST_SEED", 1)
+	if err != nil {
+		return nil, err
+	}
+	testStart, err := e.parseTime("PUBSUB_TEST_START_TIME", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return nil, err
+	}
+	historyMax, err := e.parseInt("PUBSUB_HISTORY_MAX_MESSAGES", 1000)
+	if err != nil {
+		return nil, err
+	}
+	historyMemory, err := e.parseSize("PUBSUB_HISTORY_MEMORY", 256<<20)
+	if err != nil {
+		return nil, err
+	}
+	historyPayload, err := e.parseSize("PUBSUB_HISTORY_MAX_PAYLOAD", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	historyTopics, err := retention.ParseTopics(e.get("PUBSUB_HISTORY_RETENTION"))
+	if err != nil {
+		return nil, fmt.Errorf("PUBSUB_HISTORY_RETENTION: %w", err)
+	}
+	pubsubPort := e.getOrDefault("PUBSUB_PORT", "8085")
+	emulatorCommand := strings.Fields(e.get("PUBSUB_EMULATOR_CMD"))
+	if len(emulatorCommand) == 0 {
+		emulatorCommand = []string{"gcloud", "beta", "emulators", "pubsub", "start",
+			"--host-port=0.0.0.0:" + pubsubPort, "--project=" + projects[0]}
+	}
+
 	cfg := &Config{
-		ProjectID:        projectID,
+		ProjectID:        projects[0],
+		ProjectIDs:       referencedProjects(projects, topics, subs),
 		TopicIDs:         topics,
 		SubscriptionIDs:  subs,
 		MessageToPublish: "Hello, Pub/Sub emulator!",
-		DashboardPort:    getEnvOrDefault("DASHBOARD_PORT", ""),
-		PubSubPort:       getEnvOrDefault("PUBSUB_PORT", "8085"),
+		DashboardPort:    e.getOrDefault("DASHBOARD_PORT", ""),
+		PubSubPort:       pubsubPort,
+		DashboardAddr:    e.get("DASHBOARD_ADDR"),
+		AuthMode:         e.getOrDefault("DASHBOARD_AUTH_MODE", "none"),
+		AuthTokens:       e.get("DASHBOARD_AUTH_TOKENS"),
+		AuthUsers:        e.get("DASHBOARD_AUTH_USERS"),
+		AuthJWKSFile:     e.get("DASHBOARD_AUTH_JWKS_FILE"),
+		AuthJWTIssuer:    e.get("DASHBOARD_AUTH_JWT_ISSUER"),
+		AuthJWTAudience:  e.get("DASHBOARD_AUTH_JWT_AUDIENCE"),
+		AuthRoleClaim:    e.get("DASHBOARD_AUTH_ROLE_CLAIM"),
+
+		CORSAllowedOrigins:   parseCommaSeparated(e.getOrDefault("DASHBOARD_CORS_ORIGINS", "*")),
+		CORSAllowedMethods:   parseCommaSeparated(e.get("DASHBOARD_CORS_METHODS")),
+		CORSAllowedHeaders:   parseCommaSeparated(e.get("DASHBOARD_CORS_HEADERS")),
+		CORSAllowCredentials: corsCredentials,
+		CORSMaxAge:           corsMaxAge,
+		CSRFMode:             e.getOrDefault("DASHBOARD_CSRF", "origin"),
+
+		TLSCertFile:     e.get("DASHBOARD_TLS_CERT"),
+		TLSKeyFile:      e.get("DASHBOARD_TLS_KEY"),
+		TLSClientCAFile: e.get("DASHBOARD_TLS_CLIENT_CA"),
+		TLSSelfSigned:   tlsSelfSigned,
+
+		DashboardSocketMode: socketMode,
+
+		ConfigFile:          configFile,
+		ConfigPollInterval:  pollInterval,
+		ReloadDeleteRemoved: reloadDelete,
+
+		SuperviseEmulator:      supervise,
+		EmulatorCommand:        emulatorCommand,
+		EmulatorStartupTimeout: startupTimeout,
+
+		ChaosPort: e.get("PUBSUB_CHAOS_PORT"),
+
+		TestMode:      testMode,
+		TestSeed:      testSeed,
+		TestStartTime: testStart,
+
+		TemplatesDir: e.get("PUBSUB_TEMPLATES_DIR"),
+
+		History: retention.Policy{
+			MaxMessages:     int(historyMax),
+			MemoryBytes:     historyMemory,
+			MaxPayloadBytes: int(historyPayload),
+			Topics:          historyTopics,
+		},
+		HistorySpillDir: e.get("PUBSUB_HISTORY_SPILL_DIR"),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -64,15 +259,135 @@ func (c *Config) Validate() error {
 	if len(c.TopicIDs) != len(c.SubscriptionIDs) {
 		return fmt.Errorf("number of topics and subscriptions must match")
 	}
+	for _, topic := range c.TopicIDs {
+		if err := validateResourceRef("PUBSUB_TOPIC", "topics", topic); err != nil {
+			return err
+		}
+	}
+	for _, sub := range c.SubscriptionIDs {
+		if err := validateResourceRef("PUBSUB_SUBSCRIPTION", "subscriptions", sub); err != nil {
+			return err
+		}
+	}
 	if err := validatePort("PUBSUB_PORT", c.PubSubPort); err != nil {
 		return err
 	}
 	if err := validatePort("DASHBOARD_PORT", c.DashboardPort); err != nil {
 		return err
 	}
+	if err := validatePort("PUBSUB_CHAOS_PORT", c.ChaosPort); err != nil {
+		return err
+	}
+	if c.ChaosPort != "" && c.ChaosPort == c.PubSubPort {
+		return fmt.Errorf("PUBSUB_CHAOS_PORT must differ from PUBSUB_PORT")
+	}
+	if err := validateListenAddr("DASHBOARD_ADDR", c.DashboardAddr); err != nil {
+		return err
+	}
+	if err := c.validateAuth(); err != nil {
+		return err
+	}
+	switch c.CSRFMode {
+	case "", "off", "origin", "token":
+	default:
+		return fmt.Errorf("DASHBOARD_CSRF must be one of off, origin or token, got %q", c.CSRFMode)
+	}
+	if c.CORSMaxAge < 0 {
+		return fmt.Errorf("DASHBOARD_CORS_MAX_AGE cannot be negative")
+	}
+	if c.CORSAllowCredentials && slices.Contains(c.CORSAllowedOrigins, "*") {
+		return fmt.Errorf("DASHBOARD_CORS_CREDENTIALS requires DASHBOARD_CORS_ORIGINS to list origins instead of *")
+	}
+	if err := c.validateTLS(); err != nil {
+		return err
+	}
+	if c.SuperviseEmulator && len(c.EmulatorCommand) == 0 {
+		return fmt.Errorf("PUBSUB_EMULATOR_CMD cannot be empty when PUBSUB_SUPERVISE=true")
+	}
+	if c.History.MaxMessages < 0 {
+		return fmt.Errorf("PUBSUB_HISTORY_MAX_MESSAGES cannot be negative")
+	}
+	return nil
+}
+
+// validateTLS checks that TLS settings are complete and not contradictory.
+func (c *Config) validateTLS() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("DASHBOARD_TLS_CERT and DASHBOARD_TLS_KEY must be set together")
+	}
+	if c.TLSCertFile != "" && c.TLSSelfSigned {
+		return fmt.Errorf("DASHBOARD_TLS_SELF_SIGNED cannot be combined with DASHBOARD_TLS_CERT")
+	}
+	if c.TLSClientCAFile != "" && !c.IsTLSEnabled() {
+		return fmt.Errorf("DASHBOARD_TLS_CLIENT_CA requires DASHBOARD_TLS_CERT or DASHBOARD_TLS_SELF_SIGNED")
+	}
+	return nil
+}
+
+// IsTLSEnabled returns true if the dashboard serves HTTPS
+func (c *Config) IsTLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSSelfSigned
+}
+
+// validateAuth checks that the selected auth mode has the settings it needs.
+// Credential formats are parsed (and reported) when the authenticator is built.
+func (c *Config) validateAuth() error {
+	switch c.AuthMode {
+	case "", "none":
+	case "token":
+		if c.AuthTokens == "" {
+			return fmt.Errorf("DASHBOARD_AUTH_TOKENS is required when DASHBOARD_AUTH_MODE=token")
+		}
+	case "basic":
+		if c.AuthUsers == "" {
+			return fmt.Errorf("DASHBOARD_AUTH_USERS is required when DASHBOARD_AUTH_MODE=basic")
+		}
+	case "jwt":
+		if c.AuthJWKSFile == "" {
+			return fmt.Errorf("DASHBOARD_AUTH_JWKS_FILE is required when DASHBOARD_AUTH_MODE=jwt")
+		}
+	default:
+		return fmt.Errorf("DASHBOARD_AUTH_MODE must be one of none, token, basic or jwt, got %q", c.AuthMode)
+	}
+	return nil
+}
+
+// validateResourceRef accepts a bare ID or a full projects/<p>/<collection>/<id>
+// name, which places the resource in another project.
+func validateResourceRef(name, collection, value string) error {
+	if !strings.Contains(value, "/") {
+		return nil
+	}
+	parts := strings.Split(value, "/")
+	if len(parts) != 4 || parts[0] != "projects" || parts[1] == "" || parts[2] != collection || parts[3] == "" {
+		return fmt.Errorf("%s entries must be an ID or projects/<project>/%s/<id>, got %q", name, collection, value)
+	}
 	return nil
 }
 
+// referencedProjects returns the configured projects followed by any other
+// project named by a fully qualified topic or subscription, without
+// duplicates.
+func referencedProjects(projects []string, names ...[]string) []string {
+	out := make([]string, 0, len(projects))
+	add := func(project string) {
+		if project != "" && !slices.Contains(out, project) {
+			out = append(out, project)
+		}
+	}
+	for _, project := range projects {
+		add(project)
+	}
+	for _, list := range names {
+		for _, name := range list {
+			if parts := strings.Split(name, "/"); len(parts) == 4 && parts[0] == "projects" {
+				add(parts[1])
+			}
+		}
+	}
+	return out
+}
+
 // validatePort accepts an empty value (default/disabled) but rejects any
 // non-empty value that is not a valid TCP port.
 func validatePort(name, value string) error {
@@ -86,9 +401,32 @@ func validatePort(name, value string) error {
 	return nil
 }
 
+// validateListenAddr accepts an empty value, unix:///path or a host:port
+// whose port is numeric (0 picks an ephemeral port).
+func validateListenAddr(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	if path, ok := strings.CutPrefix(value, "unix://"); ok {
+		if path == "" {
+			return fmt.Errorf("%s unix socket needs a path, e.g. unix:///tmp/dashboard.sock", name)
+		}
+		return nil
+	}
+	_, portStr, err := net.SplitHostPort(value)
+	if err != nil {
+		return fmt.Errorf("%s must be host:port or unix:///path, got %q", name, value)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return fmt.Errorf("%s must have a valid port number (0-65535), got %q", name, value)
+	}
+	return nil
+}
+
 // IsDashboardEnabled returns true if dashboard should be started
 func (c *Config) IsDashboardEnabled() bool {
-	return c.DashboardPort != ""
+	return c.DashboardPort != "" || c.DashboardAddr != ""
 }
 
 // parseCommaSeparated splits a comma-separated string and trims whitespace
@@ -107,9 +445,88 @@ func parseCommaSeparated(s string) []string {
 	return result
 }
 
-// getEnvOrDefault returns environment variable value or default
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+// parseBool reads a boolean setting, returning def if unset
+func (e env) parseBool(key string, def bool) (bool, error) {
+	value := e.get(key)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, got %q", key, value)
+	}
+	return b, nil
+}
+
+// parseSeconds reads a whole number of seconds, returning def if unset
+func (e env) parseSeconds(key string, def time.Duration) (time.Duration, error) {
+	value := e.get(key)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number of seconds, got %q", key, value)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// parseInt reads an integer, returning def if unset
+func (e env) parseInt(key string, def int64) (int64, error) {
+	value := e.get(key)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", key, value)
+	}
+	return n, nil
+}
+
+// parseSize reads a byte size such as 64MB (see retention.ParseSize),
+// returning def if unset
+func (e env) parseSize(key string, def int64) (int64, error) {
+	value := e.get(key)
+	if value == "" {
+		return def, nil
+	}
+	n, err := retention.ParseSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a size such as 64MB, got %q", key, value)
+	}
+	return n, nil
+}
+
+// parseTime reads an RFC 3339 timestamp, returning def if unset
+func (e env) parseTime(key string, def time.Time) (time.Time, error) {
+	value := e.get(key)
+	if value == "" {
+		return def, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time such as 2000-01-01T00:00:00Z, got %q", key, value)
+	}
+	return t, nil
+}
+
+// parseFileMode reads octal permission bits (e.g. 660), returning def if unset
+func (e env) parseFileMode(key string, def fs.FileMode) (fs.FileMode, error) {
+	value := e.get(key)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || n > 0o777 {
+		return 0, fmt.Errorf("%s must be octal permissions such as 660, got %q", key, value)
+	}
+	return fs.FileMode(n), nil
+}
+
+// getOrDefault returns the setting's value or default
+func (e env) getOrDefault(key, defaultValue string) string {
+	if value := e.get(key); value != "" {
 		return value
 	}
 	return defaultValue
@@ -2,7 +2,11 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadFromEnv_Success(t *testing.T) {
@@ -202,6 +206,308 @@ func TestIsDashboardEnabled_False(t *testing.T) {
 	}
 }
 
+func TestValidate_AuthModes(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			ProjectID:       "test-project",
+			TopicIDs:        []string{"topic1"},
+			SubscriptionIDs: []string{"sub1"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr bool
+	}{
+		{"default none", func(*Config) {}, false},
+		{"token with tokens", func(c *Config) { c.AuthMode = "token"; c.AuthTokens = "t:admin" }, false},
+		{"token without tokens", func(c *Config) { c.AuthMode = "token" }, true},
+		{"basic without users", func(c *Config) { c.AuthMode = "basic" }, true},
+		{"jwt without jwks", func(c *Config) { c.AuthMode = "jwt" }, true},
+		{"jwt with jwks", func(c *Config) { c.AuthMode = "jwt"; c.AuthJWKSFile = "/etc/jwks.json" }, false},
+		{"unknown mode", func(c *Config) { c.AuthMode = "ldap" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_CORSAndCSRF(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+	_ = os.Setenv("PUBSUB_TOPIC", "topic1")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "sub1")
+	_ = os.Setenv("DASHBOARD_CORS_ORIGINS", "http://a.local, http://b.local")
+	_ = os.Setenv("DASHBOARD_CORS_CREDENTIALS", "true")
+	_ = os.Setenv("DASHBOARD_CORS_MAX_AGE", "120")
+	_ = os.Setenv("DASHBOARD_CSRF", "token")
+	defer cleanupEnv()
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(cfg.CORSAllowedOrigins) != 2 || cfg.CORSAllowedOrigins[1] != "http://b.local" {
+		t.Errorf("Unexpected origins %v", cfg.CORSAllowedOrigins)
+	}
+	if !cfg.CORSAllowCredentials {
+		t.Error("Expected credentials to be enabled")
+	}
+	if cfg.CORSMaxAge != 2*time.Minute {
+		t.Errorf("Expected max age 2m, got %v", cfg.CORSMaxAge)
+	}
+	if cfg.CSRFMode != "token" {
+		t.Errorf("Expected CSRF mode 'token', got '%s'", cfg.CSRFMode)
+	}
+}
+
+func TestLoadFromEnv_CORSDefaults(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+	_ = os.Setenv("PUBSUB_TOPIC", "topic1")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "sub1")
+	defer cleanupEnv()
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(cfg.CORSAllowedOrigins) != 1 || cfg.CORSAllowedOrigins[0] != "*" {
+		t.Errorf("Expected wildcard origin by default, got %v", cfg.CORSAllowedOrigins)
+	}
+	if cfg.CSRFMode != "origin" {
+		t.Errorf("Expected CSRF mode 'origin' by default, got '%s'", cfg.CSRFMode)
+	}
+}
+
+func TestLoadFromEnv_InvalidCORSAndCSRF(t *testing.T) {
+	tests := map[string]string{
+		"DASHBOARD_CORS_CREDENTIALS": "maybe",
+		"DASHBOARD_CORS_MAX_AGE":     "-5",
+		"DASHBOARD_CSRF":             "strict",
+	}
+	for key, value := range tests {
+		t.Run(key, func(t *testing.T) {
+			_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+			_ = os.Setenv("PUBSUB_TOPIC", "topic1")
+			_ = os.Setenv("PUBSUB_SUBSCRIPTION", "sub1")
+			_ = os.Setenv(key, value)
+			defer cleanupEnv()
+
+			if _, err := LoadFromEnv(); err == nil {
+				t.Errorf("Expected error for %s=%s", key, value)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_CORSCredentialsNeedOrigins(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+	_ = os.Setenv("PUBSUB_TOPIC", "topic1")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "sub1")
+	_ = os.Setenv("DASHBOARD_CORS_CREDENTIALS", "true")
+	defer cleanupEnv()
+
+	if _, err := LoadFromEnv(); err == nil || !strings.Contains(err.Error(), "DASHBOARD_CORS_ORIGINS") {
+		t.Errorf("Expected credentials with the wildcard origin to be rejected, got %v", err)
+	}
+}
+
+func TestValidate_TLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr bool
+	}{
+		{"disabled", func(*Config) {}, false},
+		{"cert and key", func(c *Config) { c.TLSCertFile = "c.pem"; c.TLSKeyFile = "k.pem" }, false},
+		{"cert without key", func(c *Config) { c.TLSCertFile = "c.pem" }, true},
+		{"self-signed with cert", func(c *Config) { c.TLSCertFile = "c.pem"; c.TLSKeyFile = "k.pem"; c.TLSSelfSigned = true }, true},
+		{"client CA without TLS", func(c *Config) { c.TLSClientCAFile = "ca.pem" }, true},
+		{"self-signed with client CA", func(c *Config) { c.TLSSelfSigned = true; c.TLSClientCAFile = "ca.pem" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ProjectID:       "test-project",
+				TopicIDs:        []string{"topic1"},
+				SubscriptionIDs: []string{"sub1"},
+			}
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.IsTLSEnabled() != (cfg.TLSCertFile != "" || cfg.TLSSelfSigned) {
+				t.Error("IsTLSEnabled disagrees with configuration")
+			}
+		})
+	}
+}
+
+func TestValidate_DashboardAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{"", false},
+		{"127.0.0.1:8080", false},
+		{":8080", false},
+		{"[::1]:0", false},
+		{"unix:///tmp/dashboard.sock", false},
+		{"unix://", true},
+		{"localhost", true},
+		{"localhost:http", true},
+		{"localhost:70000", true},
+	}
+	for _, tt := range tests {
+		cfg := &Config{
+			ProjectID:       "test-project",
+			TopicIDs:        []string{"topic1"},
+			SubscriptionIDs: []string{"sub1"},
+			DashboardAddr:   tt.addr,
+		}
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadFromEnv_DashboardAddr(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+	_ = os.Setenv("PUBSUB_TOPIC", "topic1")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "sub1")
+	_ = os.Setenv("DASHBOARD_ADDR", "unix:///tmp/dashboard.sock")
+	_ = os.Setenv("DASHBOARD_SOCKET_MODE", "600")
+	defer cleanupEnv()
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.DashboardAddr != "unix:///tmp/dashboard.sock" {
+		t.Errorf("Expected DashboardAddr to be loaded, got '%s'", cfg.DashboardAddr)
+	}
+	if cfg.DashboardSocketMode != 0o600 {
+		t.Errorf("Expected socket mode 0600, got %o", cfg.DashboardSocketMode)
+	}
+	if !cfg.IsDashboardEnabled() {
+		t.Error("Expected DASHBOARD_ADDR to enable the dashboard")
+	}
+
+	_ = os.Setenv("DASHBOARD_SOCKET_MODE", "rw-rw----")
+	if _, err := LoadFromEnv(); err == nil {
+		t.Error("Expected error for non-octal DASHBOARD_SOCKET_MODE")
+	}
+}
+
+func TestLoadFromEnv_MultipleProjects(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "app, billing")
+	_ = os.Setenv("PUBSUB_TOPIC", "orders,projects/shared/topics/events")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "orders-sub,projects/billing/subscriptions/events-sub")
+	defer cleanupEnv()
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.ProjectID != "app" {
+		t.Errorf("Expected default project 'app', got '%s'", cfg.ProjectID)
+	}
+	if want := []string{"app", "billing", "shared"}; !slices.Equal(cfg.ProjectIDs, want) {
+		t.Errorf("Expected projects %v, got %v", want, cfg.ProjectIDs)
+	}
+}
+
+func TestValidate_ResourceRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		topic   string
+		sub     string
+		wantErr bool
+	}{
+		{"bare IDs", "orders", "orders-sub", false},
+		{"qualified names", "projects/other/topics/orders", "projects/other/subscriptions/orders-sub", false},
+		{"wrong collection", "projects/other/subscriptions/orders", "orders-sub", true},
+		{"missing project", "projects//topics/orders", "orders-sub", true},
+		{"too many segments", "orders", "projects/other/subscriptions/a/b", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ProjectID: "app", TopicIDs: []string{tt.topic}, SubscriptionIDs: []string{tt.sub}}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_ConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pubsub.env")
+	content := `# topology managed by hot reload
+PUBSUB_TOPIC=orders,payments
+export PUBSUB_SUBSCRIPTION="orders-sub,payments-sub"
+PUBSUB_RELOAD_DELETE='true'
+
+PUBSUB_CONFIG_POLL_INTERVAL=5
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+	_ = os.Setenv("PUBSUB_TOPIC", "from-env")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "from-env-sub")
+	_ = os.Setenv("PUBSUB_CONFIG_FILE", path)
+	defer cleanupEnv()
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.ProjectID != "test-project" {
+		t.Errorf("Expected project from environment, got '%s'", cfg.ProjectID)
+	}
+	if len(cfg.TopicIDs) != 2 || cfg.TopicIDs[0] != "orders" {
+		t.Errorf("Expected topics from file to override environment, got %v", cfg.TopicIDs)
+	}
+	if len(cfg.SubscriptionIDs) != 2 || cfg.SubscriptionIDs[1] != "payments-sub" {
+		t.Errorf("Expected quoted subscriptions from file, got %v", cfg.SubscriptionIDs)
+	}
+	if !cfg.ReloadDeleteRemoved {
+		t.Error("Expected ReloadDeleteRemoved to be true")
+	}
+	if cfg.ConfigFile != path || cfg.ConfigPollInterval != 5*time.Second {
+		t.Errorf("Expected config file %s polled every 5s, got %s every %s", path, cfg.ConfigFile, cfg.ConfigPollInterval)
+	}
+}
+
+func TestLoadFromEnv_ConfigFileErrors(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+	_ = os.Setenv("PUBSUB_TOPIC", "topic1")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "sub1")
+	defer cleanupEnv()
+
+	_ = os.Setenv("PUBSUB_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.env"))
+	if _, err := LoadFromEnv(); err == nil {
+		t.Error("Expected error for missing config file")
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.env")
+	_ = os.WriteFile(path, []byte("PUBSUB_TOPIC=ok\nnot a setting\n"), 0o600)
+	_ = os.Setenv("PUBSUB_CONFIG_FILE", path)
+	if _, err := LoadFromEnv(); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("Expected error naming line 2, got %v", err)
+	}
+}
+
 func TestParseCommaSeparated_MultipleValues(t *testing.T) {
 	result := parseCommaSeparated("topic1,topic2,topic3")
 	expected := []string{"topic1", "topic2", "topic3"}
@@ -252,18 +558,18 @@ func TestParseCommaSeparated_SingleValue(t *testing.T) {
 	}
 }
 
-func TestGetEnvOrDefault_WithValue(t *testing.T) {
+func TestEnvGetOrDefault_WithValue(t *testing.T) {
 	_ = os.Setenv("TEST_KEY", "test-value")
 	defer func() { _ = os.Unsetenv("TEST_KEY") }()
 
-	result := getEnvOrDefault("TEST_KEY", "default")
+	result := env(nil).getOrDefault("TEST_KEY", "default")
 	if result != "test-value" {
 		t.Errorf("Expected 'test-value', got '%s'", result)
 	}
 }
 
-func TestGetEnvOrDefault_WithoutValue(t *testing.T) {
-	result := getEnvOrDefault("NONEXISTENT_KEY", "default")
+func TestEnvGetOrDefault_WithoutValue(t *testing.T) {
+	result := env(nil).getOrDefault("NONEXISTENT_KEY", "default")
 	if result != "default" {
 		t.Errorf("Expected 'default', got '%s'", result)
 	}
@@ -276,4 +582,186 @@ func cleanupEnv() {
 	_ = os.Unsetenv("PUBSUB_SUBSCRIPTION")
 	_ = os.Unsetenv("DASHBOARD_PORT")
 	_ = os.Unsetenv("PUBSUB_PORT")
+	_ = os.Unsetenv("DASHBOARD_CORS_ORIGINS")
+	_ = os.Unsetenv("DASHBOARD_CORS_CREDENTIALS")
+	_ = os.Unsetenv("DASHBOARD_CORS_MAX_AGE")
+	_ = os.Unsetenv("DASHBOARD_CSRF")
+	_ = os.Unsetenv("DASHBOARD_TLS_CERT")
+	_ = os.Unsetenv("DASHBOARD_TLS_KEY")
+	_ = os.Unsetenv("DASHBOARD_TLS_CLIENT_CA")
+	_ = os.Unsetenv("DASHBOARD_TLS_SELF_SIGNED")
+	_ = os.Unsetenv("DASHBOARD_ADDR")
+	_ = os.Unsetenv("DASHBOARD_SOCKET_MODE")
+	_ = os.Unsetenv("PUBSUB_CONFIG_FILE")
+	_ = os.Unsetenv("PUBSUB_SUPERVISE")
+	_ = os.Unsetenv("PUBSUB_EMULATOR_CMD")
+	_ = os.Unsetenv("PUBSUB_EMULATOR_STARTUP_TIMEOUT")
+	_ = os.Unsetenv("PUBSUB_CHAOS_PORT")
+	_ = os.Unsetenv("PUBSUB_TEST_MODE")
+	_ = os.Unsetenv("PUBSUB_TEST_SEED")
+	_ = os.Unsetenv("PUBSUB_TEST_START_TIME")
+	_ = os.Unsetenv("PUBSUB_TEMPLATES_DIR")
+	_ = os.Unsetenv("PUBSUB_HISTORY_MAX_MESSAGES")
+	_ = os.Unsetenv("PUBSUB_HISTORY_MEMORY")
+	_ = os.Unsetenv("PUBSUB_HISTORY_MAX_PAYLOAD")
+	_ = os.Unsetenv("PUBSUB_HISTORY_RETENTION")
+	_ = os.Unsetenv("PUBSUB_HISTORY_SPILL_DIR")
+}
+
+func TestLoadFromEnv_Supervision(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "app,billing")
+	_ = os.Setenv("PUBSUB_TOPIC", "orders")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "orders-sub")
+	_ = os.Setenv("PUBSUB_PORT", "9085")
+	defer cleanupEnv()
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.SuperviseEmulator {
+		t.Error("Expected supervision to be off by default")
+	}
+	want := []string{"gcloud", "beta", "emulators", "pubsub", "start", "--host-port=0.0.0.0:9085", "--project=app"}
+	if !slices.Equal(cfg.EmulatorCommand, want) {
+		t.Errorf("Expected default command %v, got %v", want, cfg.EmulatorCommand)
+	}
+	if cfg.EmulatorStartupTimeout != time.Minute {
+		t.Errorf("Expected startup timeout 1m, got %v", cfg.EmulatorStartupTimeout)
+	}
+
+	_ = os.Setenv("PUBSUB_SUPERVISE", "true")
+	_ = os.Setenv("PUBSUB_EMULATOR_CMD", "java -jar  cloud-pubsub-emulator.jar --port=9085")
+	_ = os.Setenv("PUBSUB_EMULATOR_STARTUP_TIMEOUT", "90")
+	cfg, err = LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !cfg.SuperviseEmulator {
+		t.Error("Expected supervision to be on")
+	}
+	want = []string{"java", "-jar", "cloud-pubsub-emulator.jar", "--port=9085"}
+	if !slices.Equal(cfg.EmulatorCommand, want) {
+		t.Errorf("Expected command %v, got %v", want, cfg.EmulatorCommand)
+	}
+	if cfg.EmulatorStartupTimeout != 90*time.Second {
+		t.Errorf("Expected startup timeout 90s, got %v", cfg.EmulatorStartupTimeout)
+	}
+}
+
+func TestValidate_ChaosPort(t *testing.T) {
+	tests := []struct {
+		port    string
+		wantErr bool
+	}{
+		{"", false},
+		{"8086", false},
+		{"8085", true},
+		{"chaos", true},
+	}
+	for _, tt := range tests {
+		cfg := &Config{
+			ProjectID:       "test-project",
+			TopicIDs:        []string{"topic1"},
+			SubscriptionIDs: []string{"sub1"},
+			PubSubPort:      "8085",
+			ChaosPort:       tt.port,
+		}
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.port, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadFromEnv_TestMode(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+	_ = os.Setenv("PUBSUB_TOPIC", "orders")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "orders-sub")
+	defer cleanupEnv()
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.TestMode {
+		t.Error("Expected test mode to be off by default")
+	}
+	if cfg.TestSeed != 1 {
+		t.Errorf("Expected default seed 1, got %d", cfg.TestSeed)
+	}
+	if want := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC); !cfg.TestStartTime.Equal(want) {
+		t.Errorf("Expected default start time %v, got %v", want, cfg.TestStartTime)
+	}
+
+	_ = os.Setenv("PUBSUB_TEST_MODE", "true")
+	_ = os.Setenv("PUBSUB_TEST_SEED", "42")
+	_ = os.Setenv("PUBSUB_TEST_START_TIME", "2024-06-01T12:00:00Z")
+	cfg, err = LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !cfg.TestMode || cfg.TestSeed != 42 {
+		t.Errorf("Expected test mode with seed 42, got %v and %d", cfg.TestMode, cfg.TestSeed)
+	}
+	if want := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC); !cfg.TestStartTime.Equal(want) {
+		t.Errorf("Expected start time %v, got %v", want, cfg.TestStartTime)
+	}
+
+	for key, value := range map[string]string{
+		"PUBSUB_TEST_SEED":       "abc",
+		"PUBSUB_TEST_START_TIME": "yesterday",
+	} {
+		_ = os.Setenv(key, value)
+		if _, err := LoadFromEnv(); err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected error naming %s, got %v", key, err)
+		}
+		_ = os.Unsetenv(key)
+	}
+}
+
+func TestLoadFromEnv_History(t *testing.T) {
+	_ = os.Setenv("PUBSUB_PROJECT", "test-project")
+	_ = os.Setenv("PUBSUB_TOPIC", "orders")
+	_ = os.Setenv("PUBSUB_SUBSCRIPTION", "orders-sub")
+	defer cleanupEnv()
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if h := cfg.History; h.MaxMessages != 1000 || h.MemoryBytes != 256<<20 || h.MaxPayloadBytes != 1<<20 || len(h.Topics) != 0 {
+		t.Errorf("Unexpected default history policy: %+v", h)
+	}
+
+	_ = os.Setenv("PUBSUB_HISTORY_MAX_MESSAGES", "5000")
+	_ = os.Setenv("PUBSUB_HISTORY_MEMORY", "1GB")
+	_ = os.Setenv("PUBSUB_HISTORY_MAX_PAYLOAD", "0")
+	_ = os.Setenv("PUBSUB_HISTORY_RETENTION", "orders:count=100,age=1h;*:bytes=16MB")
+	_ = os.Setenv("PUBSUB_HISTORY_SPILL_DIR", "/var/tmp")
+	cfg, err = LoadFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	h := cfg.History
+	if h.MaxMessages != 5000 || h.MemoryBytes != 1<<30 || h.MaxPayloadBytes != 0 || cfg.HistorySpillDir != "/var/tmp" {
+		t.Errorf("Unexpected history policy: %+v in %s", h, cfg.HistorySpillDir)
+	}
+	if h.Topics["orders"].MaxCount != 100 || h.Topics["orders"].MaxAge != time.Hour || h.Topics["*"].MaxBytes != 16<<20 {
+		t.Errorf("Unexpected topic limits: %+v", h.Topics)
+	}
+
+	for key, value := range map[string]string{
+		"PUBSUB_HISTORY_MAX_MESSAGES": "-1",
+		"PUBSUB_HISTORY_MEMORY":       "lots",
+		"PUBSUB_HISTORY_MAX_PAYLOAD":  "1TB",
+		"PUBSUB_HISTORY_RETENTION":    "orders:count=many",
+	} {
+		old := os.Getenv(key)
+		_ = os.Setenv(key, value)
+		if _, err := LoadFromEnv(); err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected error naming %s, got %v", key, err)
+		}
+		_ = os.Setenv(key, old)
+	}
 }
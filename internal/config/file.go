@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// env resolves configuration keys. Values read from the config file shadow
+// the process environment.
+type env map[string]string
+
+// get returns the file value for key if present, else the environment's.
+func (e env) get(key string) string {
+	if value, ok := e[key]; ok {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// readEnvFile parses a config file of KEY=VALUE lines, as used by Docker's
+// --env-file: blank lines and # comments are skipped, an optional "export "
+// prefix is allowed and values may be single- or double-quoted. An empty
+// path yields an empty env.
+func readEnvFile(path string) (env, error) {
+	if path == "" {
+		return env{}, nil
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from operator configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	e := env{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			if value[0] == '"' {
+				if unquoted, err := strconv.Unquote(value); err == nil {
+					value = unquoted
+				} else {
+					value = value[1 : len(value)-1]
+				}
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		}
+		e[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return e, nil
+}
@@ -0,0 +1,150 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxTagsPerMessage bounds the tags on one message.
+	maxTagsPerMessage = 16
+	// maxTagLength bounds one tag.
+	maxTagLength = 64
+	// maxNoteLength bounds a message's note, in bytes.
+	maxNoteLength = 4096
+)
+
+// tagPattern matches a valid tag: it starts with a letter or digit and
+// contains only letters, digits, or . _ : / -, so tags fit a CSV cell and a
+// query parameter without quoting.
+var tagPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._:/\-]*$`)
+
+// errTooManyPins is returned when pinning would take more than
+// maxPinnedMessages.
+var errTooManyPins = errors.New("too many pinned messages")
+
+// maxPinnedMessages is how many messages may be pinned: half the history,
+// so new traffic always has room.
+func (d *Dashboard) maxPinnedMessages() int {
+	return d.maxMessages / 2
+}
+
+// normalizeTags trims, de-duplicates and sorts tags, returning an error if
+// one is invalid or there are too many.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if len(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: tags have up to %d letters, digits, or . _ : / - and start with a letter or digit", tag, maxTagLength)
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > maxTagsPerMessage {
+		return nil, fmt.Errorf("too many tags (max %d)", maxTagsPerMessage)
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// validate normalizes the request's tags and note, returning an error if
+// they are invalid.
+func (req *AnnotateRequest) validate() error {
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return err
+		}
+		req.Tags = &tags
+	}
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		if len(note) > maxNoteLength {
+			return fmt.Errorf("note too long (max %d bytes)", maxNoteLength)
+		}
+		req.Note = &note
+	}
+	return nil
+}
+
+// annotateMessage applies a validated request to the message with id in
+// project and returns a copy of it, or nil if there is none. Pinning fails
+// with errTooManyPins once maxPinnedMessages are pinned.
+func (d *Dashboard) annotateMessage(project, id string, req AnnotateRequest) (*MessageInfo, error) {
+	d.messagesMutex.Lock()
+	defer d.messagesMutex.Unlock()
+
+	_, info := d.history.find(id)
+	if info == nil || d.projectOf(info) != project {
+		return nil, nil
+	}
+	if req.Pinned != nil && *req.Pinned && !info.Pinned && d.history.pinned >= d.maxPinnedMessages() {
+		return nil, errTooManyPins
+	}
+
+	info = d.updateMessageLocked(id, func(info *MessageInfo) {
+		if req.Pinned != nil {
+			info.Pinned = *req.Pinned
+		}
+		// Tags is replaced, never changed in place, so copies handed out
+		// earlier keep their tags.
+		if req.Tags != nil {
+			info.Tags = *req.Tags
+		}
+		if req.Note != nil {
+			info.Note = *req.Note
+		}
+	})
+	msgCopy := *info
+	return &msgCopy, nil
+}
+
+// handleAnnotateMessage pins or unpins a message and sets its tags and
+// note, as PATCH /api/messages/{id}. It responds with the updated message.
+func (d *Dashboard) handleAnnotateMessage(w http.ResponseWriter, r *http.Request) {
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	var req AnnotateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	msg, err := d.annotateMessage(project, id, req)
+	if errors.Is(err, errTooManyPins) {
+		http.Error(w, fmt.Sprintf("At most %d messages can be pinned; unpin one first", d.maxPinnedMessages()), http.StatusConflict)
+		return
+	}
+	if msg == nil {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	d.log.With("message_id", id, "pinned", msg.Pinned, "tags", len(msg.Tags)).Info("Message annotated")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		d.log.Error("Failed to encode message response: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2"
+)
+
+func annotate(t *testing.T, mux *http.ServeMux, id, body string) (*httptest.ResponseRecorder, MessageInfo) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, "/api/messages/"+id, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var msg MessageInfo
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&msg); err != nil {
+			t.Fatalf("Invalid message: %v", err)
+		}
+	}
+	return w, msg
+}
+
+func TestHandleAnnotateMessage(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+
+	dash.AddMessage(&pubsub.Message{ID: "m1", Data: []byte("order")}, "orders")
+
+	w, msg := annotate(t, mux, "m1", `{"pinned":true,"tags":["orders"," golden","orders"],"note":" first order "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !msg.Pinned || !slices.Equal(msg.Tags, []string{"golden", "orders"}) || msg.Note != "first order" {
+		t.Errorf("Expected normalized annotations, got %+v", msg)
+	}
+
+	// Fields left out are unchanged; empty values clear them.
+	if _, msg = annotate(t, mux, "m1", `{"note":""}`); !msg.Pinned || len(msg.Tags) != 2 || msg.Note != "" {
+		t.Errorf("Expected only the note cleared, got %+v", msg)
+	}
+	if _, msg = annotate(t, mux, "m1", `{"pinned":false,"tags":[]}`); msg.Pinned || msg.Tags != nil {
+		t.Errorf("Expected the message unpinned and untagged, got %+v", msg)
+	}
+	if got := dash.GetMessageByID("m1"); got.Pinned || got.Tags != nil {
+		t.Errorf("Expected the history updated, got %+v", got)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		body string
+		want int
+	}{
+		{"bad tag", "m1", `{"tags":["has space"]}`, http.StatusBadRequest},
+		{"long tag", "m1", fmt.Sprintf(`{"tags":[%q]}`, strings.Repeat("t", maxTagLength+1)), http.StatusBadRequest},
+		{"long note", "m1", fmt.Sprintf(`{"note":%q}`, strings.Repeat("n", maxNoteLength+1)), http.StatusBadRequest},
+		{"bad body", "m1", `{"pinned":"yes"}`, http.StatusBadRequest},
+		{"unknown message", "missing", `{"pinned":true}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w, _ := annotate(t, mux, tt.id, tt.body); w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAppendMessage_KeepsPinned(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	dash.maxMessages = 4
+
+	for i := range 4 {
+		dash.AddMessage(&pubsub.Message{ID: fmt.Sprintf("m%d", i)}, "orders")
+	}
+	for _, id := range []string{"m0", "m2"} {
+		if w, _ := annotate(t, mux, id, `{"pinned":true}`); w.Code != http.StatusOK {
+			t.Fatalf("Expected %s pinned, got %d", id, w.Code)
+		}
+	}
+	if w, _ := annotate(t, mux, "m1", `{"pinned":true}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 pinning past half the history, got %d", w.Code)
+	}
+
+	for i := 4; i < 7; i++ {
+		dash.AddMessage(&pubsub.Message{ID: fmt.Sprintf("m%d", i)}, "orders")
+	}
+	var ids []string
+	for _, m := range dash.GetMessages() {
+		ids = append(ids, m.ID)
+	}
+	if got := strings.Join(ids, ","); got != "m0,m2,m5,m6" {
+		t.Errorf("Expected the pinned messages kept in order, got %s", got)
+	}
+}
+
+func TestHandleSearchMessages_Annotations(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+
+	dash.AddMessage(&pubsub.Message{ID: "m1", Data: []byte("a")}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "m2", Data: []byte("b")}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "m3", Data: []byte("c")}, "orders")
+	annotate(t, mux, "m1", `{"pinned":true,"tags":["golden","orders"]}`)
+	annotate(t, mux, "m2", `{"tags":["golden"],"note":"Refund edge case"}`)
+
+	for target, want := range map[string]string{
+		"/api/messages/search?tag=golden":            "m1,m2",
+		"/api/messages/search?tag=golden&tag=orders": "m1",
+		"/api/messages/search?pinned=true":           "m1",
+		"/api/messages/search?q=refund":              "m2",
+		"/api/messages/search?q=gold":                "m1,m2",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var msgs []MessageInfo
+		if err := json.NewDecoder(w.Body).Decode(&msgs); err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+		var ids []string
+		for _, m := range msgs {
+			ids = append(ids, m.ID)
+		}
+		if got := strings.Join(ids, ","); got != want {
+			t.Errorf("%s: expected %s, got %s", target, want, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/messages/search?pinned=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a bad pinned filter, got %d", w.Code)
+	}
+}
+
+func TestHandleImportMessages_Annotations(t *testing.T) {
+	dash, mux := setupFixtureTest(t, "orders")
+
+	body := `{"topic":"orders","data":"a","pinned":true,"tags":["golden"],"note":"keep"}
+{"topic":"orders","data":"b","tags":["bad tag"]}
+`
+	w, report := importFixture(t, mux, "/api/messages/import", "", []byte(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if report.Published != 1 || report.Failed != 1 || !strings.Contains(report.Errors[0].Error, "invalid tag") {
+		t.Errorf("Expected the invalid tag reported, got %+v", report)
+	}
+	msgs := dash.GetMessages()
+	if len(msgs) != 1 || !msgs[0].Pinned || !slices.Equal(msgs[0].Tags, []string{"golden"}) || msgs[0].Note != "keep" {
+		t.Errorf("Expected the annotations restored, got %+v", msgs)
+	}
+}
@@ -0,0 +1,140 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/chaos"
+)
+
+// SetChaos enables the /api/chaos routes, which manage the fault injection
+// rules applied by the chaos proxy. Without it they return 404.
+func (d *Dashboard) SetChaos(engine *chaos.Engine) {
+	d.chaos = engine
+}
+
+// projectRules returns the chaos rules targeting resources in project.
+func (d *Dashboard) projectRules(project string) []chaos.Rule {
+	prefix := "projects/" + project + "/"
+	rules := []chaos.Rule{}
+	for _, rule := range d.chaos.Rules() {
+		if strings.HasPrefix(rule.Resource(), prefix) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// handleChaos lists (GET), adds (POST) or clears (DELETE) the fault
+// injection rules of the request's project
+func (d *Dashboard) handleChaos(w http.ResponseWriter, r *http.Request) {
+	if d.chaos == nil {
+		http.Error(w, "Fault injection is disabled (set PUBSUB_CHAOS_PORT)", http.StatusNotFound)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		d.writeChaosResponse(w, http.StatusOK, d.projectRules(project))
+	case http.MethodPost:
+		d.addChaosRule(w, r, project)
+	case http.MethodDelete:
+		rules := d.projectRules(project)
+		for _, rule := range rules {
+			d.chaos.Remove(rule.ID)
+		}
+		d.log.With("project", project, "rules_removed", len(rules)).Info("Chaos rules cleared")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (d *Dashboard) addChaosRule(w http.ResponseWriter, r *http.Request, project string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var req ChaosRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule := chaos.Rule{
+		Action:      chaos.Action(req.Action),
+		Code:        req.Code,
+		LatencyMs:   req.LatencyMs,
+		Probability: req.Probability,
+	}
+	if req.TopicID != "" {
+		if !validateResourceID(w, "Topic ID", req.TopicID) {
+			return
+		}
+		rule.Topic = topicName(project, req.TopicID)
+	}
+	if req.SubscriptionID != "" {
+		if !validateResourceID(w, "Subscription ID", req.SubscriptionID) {
+			return
+		}
+		rule.Subscription = subscriptionName(project, req.SubscriptionID)
+	}
+	if req.TTLSeconds < 0 {
+		http.Error(w, "ttl_seconds cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if req.TTLSeconds > 0 {
+		expires := d.now().Add(time.Duration(req.TTLSeconds) * time.Second)
+		rule.ExpiresAt = &expires
+	}
+
+	rule, err := d.chaos.Add(rule)
+	if err != nil {
+		http.Error(w, "Invalid chaos rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d.log.With("rule_id", rule.ID, "action", string(rule.Action), "resource", rule.Resource(), "probability", rule.Probability).
+		Info("Chaos rule added")
+	d.writeChaosResponse(w, http.StatusCreated, rule)
+}
+
+// handleChaosRule removes one fault injection rule (DELETE)
+func (d *Dashboard) handleChaosRule(w http.ResponseWriter, r *http.Request) {
+	if d.chaos == nil {
+		http.Error(w, "Fault injection is disabled (set PUBSUB_CHAOS_PORT)", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	for _, rule := range d.projectRules(project) {
+		if rule.ID == id && d.chaos.Remove(id) {
+			d.log.With("rule_id", id).Info("Chaos rule removed")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	http.Error(w, "Chaos rule not found", http.StatusNotFound)
+}
+
+func (d *Dashboard) writeChaosResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		d.log.Error("Failed to encode chaos response: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/chaos"
+)
+
+func TestHandleChaos_Disabled(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	for _, target := range []string{"/api/chaos", "/api/chaos/1"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404 without an engine, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestHandleChaos(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.SetProjects([]string{"billing"})
+	engine := chaos.NewEngine()
+	dash.SetChaos(engine)
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	do := func(method, target string, body any) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/chaos", ChaosRuleRequest{Action: "publish_error", TopicID: "orders", Code: "unavailable", Probability: 0.5, TTLSeconds: 60})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var rule chaos.Rule
+	if err := json.NewDecoder(w.Body).Decode(&rule); err != nil {
+		t.Fatalf("Failed to decode rule: %v", err)
+	}
+	if rule.Topic != "projects/test-project/topics/orders" || rule.Code != "UNAVAILABLE" || rule.Probability != 0.5 || rule.ExpiresAt == nil {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+
+	if w := do(http.MethodPost, "/api/chaos?project=billing", ChaosRuleRequest{Action: "drop_ack", SubscriptionID: "invoices-sub"}); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	invalid := []ChaosRuleRequest{
+		{Action: "explode", TopicID: "orders"},
+		{Action: "drop_ack", TopicID: "orders"},
+		{Action: "latency", SubscriptionID: "orders-sub"},
+		{Action: "drop_ack", SubscriptionID: "1bad"},
+		{Action: "drop_ack", SubscriptionID: "orders-sub", TTLSeconds: -1},
+	}
+	for _, req := range invalid {
+		if w := do(http.MethodPost, "/api/chaos", req); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %+v, got %d", req, w.Code)
+		}
+	}
+
+	var rules []chaos.Rule
+	w = do(http.MethodGet, "/api/chaos", nil)
+	if err := json.NewDecoder(w.Body).Decode(&rules); err != nil {
+		t.Fatalf("Failed to decode rules: %v", err)
+	}
+	if len(rules) != 1 || rules[0].ID != rule.ID {
+		t.Errorf("Expected only the default project's rule, got %+v", rules)
+	}
+
+	// Rules are deleted within their own project only.
+	if w := do(http.MethodDelete, "/api/chaos/"+rule.ID+"?project=billing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting another project's rule, got %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/chaos/"+rule.ID, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/chaos?project=billing", nil); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if n := len(engine.Rules()); n != 0 {
+		t.Errorf("Expected no rules left, got %d", n)
+	}
+}
@@ -0,0 +1,125 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+)
+
+// SetTestMode makes the dashboard reproducible: times come from manual and
+// message IDs from ids, and both can be controlled through /api/clock.
+// Resetting ids clears the message history, whose IDs would be reused.
+func (d *Dashboard) SetTestMode(manual *clock.Manual, ids *clock.IDs) {
+	d.clock = manual
+	d.manualClock = manual
+	d.ids = ids
+	ids.OnReset(d.resetHistory)
+}
+
+// resetHistory drops every recorded message, with its spilled payload, the
+// history's usage and eviction counts and the IDs expectations have seen.
+func (d *Dashboard) resetHistory() {
+	d.messagesMutex.Lock()
+	defer d.messagesMutex.Unlock()
+
+	d.history.each(func(_ int, m *MessageInfo) bool {
+		d.discardSpill(m)
+		return true
+	})
+	d.history = newMessageRing(d.maxMessages)
+	d.historyBytes = 0
+	d.usage = make(map[string]*topicUsage)
+	d.evictions = make(map[string]int64)
+	d.observed = observedIDs{}
+}
+
+// now returns the dashboard's current time.
+func (d *Dashboard) now() time.Time {
+	return d.clock.Now()
+}
+
+// messageID returns the ID the dashboard reports for the message the
+// emulator calls backendID; the two differ only in test mode.
+func (d *Dashboard) messageID(backendID string) string {
+	if d.ids == nil {
+		return backendID
+	}
+	return d.ids.Assign(backendID).ID
+}
+
+// clockState describes the clock for /api/clock.
+func (d *Dashboard) clockState() ClockState {
+	state := ClockState{Mode: "system", Now: d.now()}
+	if d.manualClock != nil {
+		state.Mode = "manual"
+	}
+	if d.ids != nil {
+		state.Seed = d.ids.Seed()
+		state.NextMessageID = d.ids.Next()
+	}
+	return state
+}
+
+// handleClock reports (GET) or changes (POST) the clock. Changing it needs
+// test mode.
+func (d *Dashboard) handleClock(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !d.updateClock(w, r) {
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d.clockState()); err != nil {
+		d.log.Error("Failed to encode clock response: %v", err)
+	}
+}
+
+func (d *Dashboard) updateClock(w http.ResponseWriter, r *http.Request) bool {
+	if d.manualClock == nil {
+		http.Error(w, "The clock can only be changed in test mode (set PUBSUB_TEST_MODE=true)", http.StatusConflict)
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	var req ClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+
+	var advance time.Duration
+	if req.Advance != "" {
+		var err error
+		if advance, err = time.ParseDuration(req.Advance); err != nil || advance < 0 {
+			http.Error(w, "advance must be a non-negative duration such as 90s or 1h", http.StatusBadRequest)
+			return false
+		}
+	}
+	if req.Set != nil && req.Reset {
+		http.Error(w, "set and reset cannot be combined", http.StatusBadRequest)
+		return false
+	}
+
+	// Reset first so a request can reset and then advance.
+	if req.Reset {
+		d.manualClock.Reset()
+		d.ids.Reset()
+	}
+	if req.Set != nil {
+		d.manualClock.Set(*req.Set)
+	}
+	if advance > 0 {
+		d.manualClock.Advance(advance)
+	}
+
+	d.log.With("now", d.now().Format(time.RFC3339Nano), "reset", req.Reset).Info("Clock changed")
+	return true
+}
@@ -0,0 +1,148 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+)
+
+func TestHandleClock_SystemMode(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/clock", nil))
+	var state ClockState
+	if err := json.NewDecoder(w.Body).Decode(&state); err != nil {
+		t.Fatalf("Failed to decode clock: %v", err)
+	}
+	if state.Mode != "system" || time.Since(state.Now) > time.Minute {
+		t.Errorf("Expected the system clock, got %+v", state)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/clock", bytes.NewReader([]byte(`{"advance":"1m"}`))))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 outside test mode, got %d", w.Code)
+	}
+}
+
+func TestHandleClock_TestMode(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	manual := clock.NewManual(start)
+	dash.SetTestMode(manual, clock.NewIDs(7, manual))
+
+	_, _ = dash.client.TopicAdminClient.CreateTopic(context.Background(), &pubsubpb.Topic{
+		Name: "projects/test-project/topics/orders",
+	})
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	post := func(target string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	publish := func() string {
+		t.Helper()
+		w := post("/api/publish", PublishRequest{TopicID: "orders", Data: "hello"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var result map[string]string
+		_ = json.NewDecoder(w.Body).Decode(&result)
+		return result["messageId"]
+	}
+	clockState := func(w *httptest.ResponseRecorder) ClockState {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var state ClockState
+		if err := json.NewDecoder(w.Body).Decode(&state); err != nil {
+			t.Fatalf("Failed to decode clock: %v", err)
+		}
+		return state
+	}
+
+	if id := publish(); id != "7-1" {
+		t.Fatalf("Expected message ID 7-1, got %s", id)
+	}
+	state := clockState(post("/api/clock", ClockRequest{Advance: "90s"}))
+	if state.Mode != "manual" || !state.Now.Equal(start.Add(90*time.Second)) || state.NextMessageID != "7-2" {
+		t.Errorf("Unexpected clock after advance: %+v", state)
+	}
+	if id := publish(); id != "7-2" {
+		t.Fatalf("Expected message ID 7-2, got %s", id)
+	}
+	msg := dash.GetMessageByID("7-2")
+	if msg == nil || !msg.PublishTime.Equal(start.Add(90*time.Second)) {
+		t.Fatalf("Expected 7-2 published at the manual time, got %+v", msg)
+	}
+
+	later := start.Add(24 * time.Hour)
+	if state := clockState(post("/api/clock", ClockRequest{Set: &later})); !state.Now.Equal(later) {
+		t.Errorf("Expected clock set to %v, got %v", later, state.Now)
+	}
+
+	state = clockState(post("/api/clock", ClockRequest{Reset: true, Advance: "1s"}))
+	if !state.Now.Equal(start.Add(time.Second)) || state.NextMessageID != "7-1" {
+		t.Errorf("Unexpected clock after reset: %+v", state)
+	}
+
+	invalid := []ClockRequest{
+		{Advance: "-1s"},
+		{Advance: "soon"},
+		{Set: &later, Reset: true},
+	}
+	for _, req := range invalid {
+		if w := post("/api/clock", req); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %+v, got %d", req, w.Code)
+		}
+	}
+}
+
+func TestClockReset_StartsNewHistory(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	manual := clock.NewManual(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	dash.SetTestMode(manual, clock.NewIDs(7, manual))
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+
+	dash.AddMessage(&pubsub.Message{ID: "b1", Data: []byte("old")}, "orders")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/clock", strings.NewReader(`{"reset":true}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	dash.RecordDelivery(&pubsub.Message{ID: "b2", Data: []byte("new")}, "orders", "orders-sub")
+
+	msgs := dash.GetMessages()
+	if len(msgs) != 1 {
+		t.Fatalf("Expected only the new run's message, got %+v", msgs)
+	}
+	if m := msgs[0]; m.ID != "7-1" || m.Data != "new" || len(m.Lifecycle.Events) != 2 {
+		t.Errorf("Expected b2 recorded as 7-1 with its own lifecycle, got %+v", m)
+	}
+	if stats := dash.historyStats("test-project"); stats.Bytes != messageBytes(&msgs[0]) {
+		t.Errorf("Expected the usage of the old run dropped, got %d bytes", stats.Bytes)
+	}
+}
@@ -0,0 +1,155 @@
+package dashboard
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+// CSRF protection modes
+const (
+	// CSRFOff disables CSRF checks.
+	CSRFOff = "off"
+	// CSRFOrigin rejects state-changing requests whose Origin (or Referer)
+	// is neither the dashboard itself nor an explicitly trusted origin.
+	CSRFOrigin = "origin"
+	// CSRFToken additionally requires a double-submit token: the value of the
+	// csrf_token cookie echoed in the X-CSRF-Token header.
+	CSRFToken = "token"
+)
+
+const (
+	csrfCookieName = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// CSRFConfig controls CSRF protection of state-changing dashboard routes
+type CSRFConfig struct {
+	Mode string
+	// TrustedOrigins may issue state-changing requests cross-origin. A "*"
+	// entry is ignored: wildcard CORS must never imply cross-site writes.
+	TrustedOrigins []string
+}
+
+// CSRFMiddleware protects POST/PUT/PATCH/DELETE requests according to cfg.
+//
+// Requests that authenticate with a bearer or X-API-Token header are exempt
+// from the token check: browsers never attach those automatically, so they
+// cannot be forged cross-site. Requests with neither Origin nor Referer (curl,
+// the CLI, service clients) pass the origin check for the same reason.
+func CSRFMiddleware(cfg CSRFConfig, log *logger.Logger) func(http.Handler) http.Handler {
+	trusted := CORSConfig{AllowedOrigins: cfg.TrustedOrigins}
+
+	return func(next http.Handler) http.Handler {
+		if cfg.Mode == CSRFOff || cfg.Mode == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Mode == CSRFToken {
+				ensureCSRFCookie(w, r)
+			}
+
+			if isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !sameOriginOrTrusted(r, trusted) {
+				log.With("path", r.URL.Path, "origin", r.Header.Get("Origin"), "remote_addr", r.RemoteAddr).
+					Warn("Rejected cross-origin state-changing request")
+				http.Error(w, "Forbidden: cross-origin request rejected", http.StatusForbidden)
+				return
+			}
+
+			if cfg.Mode == CSRFToken && !hasTokenCredentials(r) && !validCSRFToken(r) {
+				log.With("path", r.URL.Path, "remote_addr", r.RemoteAddr).
+					Warn("Rejected request with missing or invalid CSRF token")
+				http.Error(w, "Forbidden: missing or invalid CSRF token", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// sameOriginOrTrusted checks the request's Origin (falling back to Referer)
+// against the Host it was sent to and the trusted origins.
+func sameOriginOrTrusted(r *http.Request, trusted CORSConfig) bool {
+	// Fetch metadata is the most direct signal when the browser sends it.
+	if r.Header.Get("Sec-Fetch-Site") == "same-origin" {
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		if ref := r.Header.Get("Referer"); ref != "" {
+			if u, err := url.Parse(ref); err == nil && u.Host != "" {
+				origin = u.Scheme + "://" + u.Host
+			}
+		} else if origin == "" {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return trusted.allowsOrigin(origin)
+}
+
+// hasTokenCredentials reports whether the request authenticates with a header
+// that browsers never send on their own.
+func hasTokenCredentials(r *http.Request) bool {
+	if r.Header.Get("X-API-Token") != "" {
+		return true
+	}
+	scheme, _, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	return strings.EqualFold(scheme, "Bearer")
+}
+
+func validCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(csrfHeaderName)
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) == 1
+}
+
+// ensureCSRFCookie issues a random token cookie if the browser has none. It is
+// readable by the dashboard's JavaScript (not HttpOnly) so it can be echoed in
+// the header, and SameSite=Strict so other sites never see it.
+func ensureCSRFCookie(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(csrfCookieName); err == nil && c.Value != "" {
+		return
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    hex.EncodeToString(buf),
+		Path:     "/",
+		SameSite: http.SameSiteStrictMode,
+		Secure:   r.TLS != nil,
+	})
+}
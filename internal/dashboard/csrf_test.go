@@ -0,0 +1,102 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+func csrfTestHandler(cfg CSRFConfig) http.Handler {
+	return CSRFMiddleware(cfg, logger.New())(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestCSRFMiddleware_OriginMode(t *testing.T) {
+	handler := csrfTestHandler(CSRFConfig{Mode: CSRFOrigin, TrustedOrigins: []string{"*", "http://trusted.local"}})
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		{"safe method cross-origin", http.MethodGet, map[string]string{"Origin": "http://evil.example"}, http.StatusOK},
+		{"no origin (cli)", http.MethodPost, nil, http.StatusOK},
+		{"same origin", http.MethodPost, map[string]string{"Origin": "http://example.com"}, http.StatusOK},
+		{"trusted origin", http.MethodPost, map[string]string{"Origin": "http://trusted.local"}, http.StatusOK},
+		{"cross origin", http.MethodPost, map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+		{"cross origin referer", http.MethodPost, map[string]string{"Referer": "http://evil.example/page"}, http.StatusForbidden},
+		{"null origin", http.MethodPost, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"fetch metadata same-origin", http.MethodPost, map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://proxy.local"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/api/publish", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCSRFMiddleware_TokenMode(t *testing.T) {
+	handler := csrfTestHandler(CSRFConfig{Mode: CSRFToken})
+
+	// A safe request issues the cookie.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookieName || cookies[0].Value == "" {
+		t.Fatalf("expected csrf cookie to be issued, got %v", cookies)
+	}
+	token := cookies[0].Value
+
+	post := func(setup func(*http.Request)) int {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/api/publish", nil)
+		setup(req)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if got := post(func(*http.Request) {}); got != http.StatusForbidden {
+		t.Errorf("expected missing token to be rejected, got %d", got)
+	}
+	if got := post(func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: token})
+		r.Header.Set(csrfHeaderName, "wrong")
+	}); got != http.StatusForbidden {
+		t.Errorf("expected mismatched token to be rejected, got %d", got)
+	}
+	if got := post(func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: token})
+		r.Header.Set(csrfHeaderName, token)
+	}); got != http.StatusOK {
+		t.Errorf("expected matching token to pass, got %d", got)
+	}
+	if got := post(func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer api-token")
+	}); got != http.StatusOK {
+		t.Errorf("expected bearer-authenticated request to be exempt, got %d", got)
+	}
+}
+
+func TestCSRFMiddleware_Off(t *testing.T) {
+	handler := csrfTestHandler(CSRFConfig{Mode: CSRFOff})
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/api/publish", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected CSRF checks to be disabled, got %d", rec.Code)
+	}
+}
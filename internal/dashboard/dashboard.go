@@ -5,9 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"cloud.google.com/go/pubsub/v2"
+	vkit "cloud.google.com/go/pubsub/v2/apiv1"
 	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/chaos"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/expect"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/health"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/loadgen"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/replay"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/retention"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/templates"
 	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
 	"google.golang.org/api/iterator"
 )
@@ -19,11 +29,33 @@ const defaultMaxMessages = 1000
 // Dashboard manages the dashboard state and operations
 type Dashboard struct {
 	client        *pubsub.Client
+	schemas       *vkit.SchemaClient
 	projectID     string
-	messages      []MessageInfo
+	projects      []string
+	projectsMutex sync.RWMutex
+	history       *messageRing
 	messagesMutex sync.RWMutex
 	maxMessages   int
-	log           *logger.Logger
+	retention     retention.Policy
+	spill         *retention.Spill
+	// spillKeys numbers the spill files of truncated payloads.
+	spillKeys atomic.Uint64
+	// historyBytes and usage track the history's estimated size, overall
+	// and by project/topic; evictions counts evicted messages by reason.
+	historyBytes int64
+	usage        map[string]*topicUsage
+	evictions    map[string]int64
+	readiness    *health.Checker
+	chaos        *chaos.Engine
+	loadgen      *loadgen.Manager
+	replays      *replay.Manager
+	templates    *templates.Store
+	expectations *expect.Registry
+	observed     observedIDs
+	clock        clock.Clock
+	manualClock  *clock.Manual
+	ids          *clock.IDs
+	log          *logger.Logger
 }
 
 // New creates a new Dashboard instance
@@ -31,45 +63,154 @@ func New(client *pubsub.Client, projectID string, log *logger.Logger) *Dashboard
 	return &Dashboard{
 		client:      client,
 		projectID:   projectID,
-		messages:    make([]MessageInfo, 0),
+		history:     newMessageRing(defaultMaxMessages),
 		maxMessages: defaultMaxMessages,
+		usage:       make(map[string]*topicUsage),
+		evictions:   make(map[string]int64),
+		clock:       clock.System{},
 		log:         log,
 	}
 }
 
+// SetSchemaClient enables schema operations (such as including schemas in
+// state exports). Without it schemas are skipped.
+func (d *Dashboard) SetSchemaClient(schemas *vkit.SchemaClient) {
+	d.schemas = schemas
+}
+
+// SetReadiness sets the checks behind /readyz. Without it /readyz reports
+// ready whenever the server is up.
+func (d *Dashboard) SetReadiness(checker *health.Checker) {
+	d.readiness = checker
+}
+
 // AddMessage adds a message to the dashboard
 func (d *Dashboard) AddMessage(msg *pubsub.Message, topic string) {
+	// Expectations see the whole payload, before any truncation.
+	info := d.newMessageInfo(msg, topic)
+	stored := info
+	d.truncate(&stored)
+
 	d.messagesMutex.Lock()
 	defer d.messagesMutex.Unlock()
 
-	msgInfo := MessageInfo{
-		ID:          msg.ID,
+	d.appendMessage(stored)
+	d.observe(&info)
+}
+
+// recordReplay records a message published by replaying originalID. A
+// receiver may have recorded the message already, in which case it is only
+// linked to the original.
+func (d *Dashboard) recordReplay(msg *pubsub.Message, topic, originalID string) {
+	info := d.newMessageInfo(msg, topic)
+	info.ReplayOf = originalID
+	stored := info
+	d.truncate(&stored)
+
+	d.messagesMutex.Lock()
+	defer d.messagesMutex.Unlock()
+
+	if d.updateMessageLocked(info.ID, func(info *MessageInfo) {
+		info.ReplayOf = originalID
+	}) != nil {
+		d.discardSpill(&stored)
+		return
+	}
+	d.appendMessage(stored)
+	d.observe(&info)
+}
+
+// newMessageInfo converts a Pub/Sub message into its dashboard record, with
+// the lifecycle seeded by the publish event. topic may be a bare ID in the
+// default project or a full topic name in any project.
+func (d *Dashboard) newMessageInfo(msg *pubsub.Message, topic string) MessageInfo {
+	project, topicID := d.resolveTopic(topic)
+	id, publishTime := msg.ID, msg.PublishTime
+	if d.ids != nil {
+		a := d.ids.Assign(msg.ID)
+		id, publishTime = a.ID, a.PublishTime
+	}
+	return MessageInfo{
+		ID:          id,
 		Data:        string(msg.Data),
 		Attributes:  msg.Attributes,
-		PublishTime: msg.PublishTime,
-		Topic:       topic,
-		Received:    msg.PublishTime,
+		PublishTime: publishTime,
+		Topic:       topicID,
+		Project:     project,
+		Received:    publishTime,
+		Lifecycle: MessageLifecycle{
+			PublishedAt:   publishTime,
+			Subscriptions: []string{},
+			Events: []LifecycleEvent{{
+				Type: EventPublished,
+				Time: publishTime,
+			}},
+		},
 	}
+}
 
-	d.messages = append(d.messages, msgInfo)
+// appendMessage stores msgInfo and applies the retention limits (see
+// enforceLocked). The new message is always kept, as the newest. A large
+// payload must already have been spilled with truncate. Callers must hold
+// messagesMutex for writing.
+func (d *Dashboard) appendMessage(msgInfo MessageInfo) {
+	if !msgInfo.Truncated {
+		msgInfo.Size = len(msgInfo.Data)
+	}
+	stored := new(MessageInfo)
+	*stored = msgInfo
+	d.history.push(stored)
+	d.accountLocked(stored, 1)
+	d.enforceLocked(stored)
+}
 
-	// Keep only the last maxMessages
-	if len(d.messages) > d.maxMessages {
-		d.messages = d.messages[len(d.messages)-d.maxMessages:]
+// updateMessageLocked applies change to a copy of the newest message with
+// id and stores the copy in its place, so snapshots taken earlier are left
+// as they were. It returns the stored copy, or nil if there is no such
+// message. Slices in the copy share their arrays with the original: change
+// may append to them but must not modify their elements. Callers must hold
+// messagesMutex for writing.
+func (d *Dashboard) updateMessageLocked(id string, change func(*MessageInfo)) *MessageInfo {
+	slot, info := d.history.find(id)
+	if info == nil {
+		return nil
 	}
+	updated := new(MessageInfo)
+	*updated = *info
+	change(updated)
+	d.history.set(slot, updated)
+	return updated
 }
 
-// GetStats retrieves dashboard statistics
+// snapshot returns the messages in the history, oldest first. The lock is
+// held only to copy the pointers: stored messages are never changed in
+// place, so they can be read after it is released but must not be
+// modified.
+func (d *Dashboard) snapshot() []*MessageInfo {
+	d.messagesMutex.RLock()
+	defer d.messagesMutex.RUnlock()
+
+	return d.history.appendTo(make([]*MessageInfo, 0, d.history.len()))
+}
+
+// GetStats retrieves dashboard statistics for the default project
 func (d *Dashboard) GetStats(ctx context.Context) (*DashboardStats, error) {
+	return d.GetProjectStats(ctx, d.projectID)
+}
+
+// GetProjectStats retrieves dashboard statistics for one project
+func (d *Dashboard) GetProjectStats(ctx context.Context, project string) (*DashboardStats, error) {
 	stats := &DashboardStats{
 		Topics:           make([]TopicInfo, 0),
 		Subscriptions:    make([]SubscriptionInfo, 0),
 		TopicList:        make([]string, 0),
 		SubscriptionList: make([]string, 0),
+		Project:          project,
+		Projects:         d.Projects(),
 	}
 
 	it := d.client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{
-		Project: fmt.Sprintf("projects/%s", d.projectID),
+		Project: fmt.Sprintf("projects/%s", project),
 	})
 
 	for {
@@ -91,7 +232,7 @@ func (d *Dashboard) GetStats(ctx context.Context) (*DashboardStats, error) {
 
 	// List subscriptions
 	subIt := d.client.SubscriptionAdminClient.ListSubscriptions(ctx, &pubsubpb.ListSubscriptionsRequest{
-		Project: fmt.Sprintf("projects/%s", d.projectID),
+		Project: fmt.Sprintf("projects/%s", project),
 	})
 
 	for {
@@ -114,49 +255,60 @@ func (d *Dashboard) GetStats(ctx context.Context) (*DashboardStats, error) {
 	}
 
 	// Get recent messages
-	d.messagesMutex.RLock()
-	stats.MessageCount = len(d.messages)
-	stats.TotalMessages = len(d.messages)
+	messages := d.GetProjectMessages(project)
+	stats.MessageCount = len(messages)
+	stats.TotalMessages = len(messages)
 	stats.TopicCount = len(stats.Topics)
 	stats.SubCount = len(stats.Subscriptions)
 
 	// Get last message time
-	if len(d.messages) > 0 {
-		lastMsg := d.messages[len(d.messages)-1]
+	if len(messages) > 0 {
+		lastMsg := messages[len(messages)-1]
 		stats.LastMessageTime = &lastMsg.Received
 	}
 
 	// Return last 20 messages
-	start := max(0, len(d.messages)-20)
-	stats.RecentMessages = d.messages[start:]
-	d.messagesMutex.RUnlock()
+	start := max(0, len(messages)-20)
+	stats.RecentMessages = messages[start:]
+	stats.History = d.historyStats(project)
 
 	return stats, nil
 }
 
 // GetMessages returns all messages
 func (d *Dashboard) GetMessages() []MessageInfo {
-	d.messagesMutex.RLock()
-	defer d.messagesMutex.RUnlock()
+	snapshot := d.snapshot()
+	messages := make([]MessageInfo, len(snapshot))
+	for i, m := range snapshot {
+		messages[i] = *m
+	}
+	return messages
+}
 
-	messages := make([]MessageInfo, len(d.messages))
-	copy(messages, d.messages)
+// GetProjectMessages returns the messages published to topics in project
+func (d *Dashboard) GetProjectMessages(project string) []MessageInfo {
+	snapshot := d.snapshot()
+	messages := make([]MessageInfo, 0, len(snapshot))
+	for _, m := range snapshot {
+		if d.projectOf(m) == project {
+			messages = append(messages, *m)
+		}
+	}
 	return messages
 }
 
-// GetMessageByID finds a message by its ID
+// GetMessageByID finds the newest message with an ID
 func (d *Dashboard) GetMessageByID(id string) *MessageInfo {
 	d.messagesMutex.RLock()
-	defer d.messagesMutex.RUnlock()
+	_, info := d.history.find(id)
+	d.messagesMutex.RUnlock()
 
-	for i := range d.messages {
-		if d.messages[i].ID == id {
-			// Create a copy to avoid race condition
-			msgCopy := d.messages[i]
-			return &msgCopy
-		}
+	if info == nil {
+		return nil
 	}
-	return nil
+	// Return a copy so callers may change it
+	msgCopy := *info
+	return &msgCopy
 }
 
 // extractID extracts the ID from a full resource name
@@ -31,8 +31,8 @@ func TestNew(t *testing.T) {
 		t.Errorf("Expected maxMessages 1000, got %d", dash.maxMessages)
 	}
 
-	if len(dash.messages) != 0 {
-		t.Errorf("Expected empty messages slice, got %d messages", len(dash.messages))
+	if dash.history.len() != 0 {
+		t.Errorf("Expected empty message history, got %d messages", dash.history.len())
 	}
 }
 
@@ -51,11 +51,12 @@ func TestAddMessage(t *testing.T) {
 
 	dash.AddMessage(msg, "test-topic")
 
-	if len(dash.messages) != 1 {
-		t.Fatalf("Expected 1 message, got %d", len(dash.messages))
+	messages := dash.GetMessages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
 	}
 
-	storedMsg := dash.messages[0]
+	storedMsg := messages[0]
 	if storedMsg.ID != "msg-123" {
 		t.Errorf("Expected ID 'msg-123', got '%s'", storedMsg.ID)
 	}
@@ -88,8 +89,8 @@ func TestAddMessage_MaxMessagesLimit(t *testing.T) {
 		dash.AddMessage(msg, "test-topic")
 	}
 
-	if len(dash.messages) != 10 {
-		t.Errorf("Expected messages to be capped at 10, got %d", len(dash.messages))
+	if dash.history.len() != 10 {
+		t.Errorf("Expected messages to be capped at 10, got %d", dash.history.len())
 	}
 }
 
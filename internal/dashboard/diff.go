@@ -0,0 +1,140 @@
+package dashboard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/diff"
+)
+
+// maxDiffMemoryBytes is how much of an uploaded history is held in memory
+// while parsing; the rest is buffered on disk.
+const maxDiffMemoryBytes = 32 << 20
+
+// handleMessageDiff compares messages. GET ?a=ID&b=ID compares two recorded
+// messages; a may be left out when b is a replay, to compare it with the
+// message it replays. POST compares two exported histories uploaded as the
+// multipart files a and b, grouped by topic; ?key=<attribute> pairs their
+// messages by that attribute instead of by position.
+func (d *Dashboard) handleMessageDiff(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		d.diffMessages(w, r)
+	case http.MethodPost:
+		d.diffHistories(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (d *Dashboard) diffMessages(w http.ResponseWriter, r *http.Request) {
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	idA, idB := q.Get("a"), q.Get("b")
+	if idB == "" {
+		http.Error(w, "Message ID b required", http.StatusBadRequest)
+		return
+	}
+	b := d.GetMessageByID(idB)
+	if b == nil || d.projectOf(b) != project {
+		http.Error(w, "Message not found: "+idB, http.StatusNotFound)
+		return
+	}
+	if idA == "" {
+		if b.ReplayOf == "" {
+			http.Error(w, "Message ID a required unless b is a replay", http.StatusBadRequest)
+			return
+		}
+		idA = b.ReplayOf
+	}
+	a := d.GetMessageByID(idA)
+	if a == nil || d.projectOf(a) != project {
+		http.Error(w, "Message not found: "+idA, http.StatusNotFound)
+		return
+	}
+
+	d.writeDiffResponse(w, diff.Messages(diffMessage(d.expandPayload(a)), diffMessage(d.expandPayload(b))))
+}
+
+func (d *Dashboard) diffHistories(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	if err := r.ParseMultipartForm(maxDiffMemoryBytes); err != nil {
+		http.Error(w, "Expected a multipart form with files a and b", http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+	var histories [2][]diff.Message
+	for i, field := range []string{"a", "b"} {
+		f, _, err := r.FormFile(field)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("File %s required", field), http.StatusBadRequest)
+			return
+		}
+		msgs, err := readMessageHistory(f)
+		_ = f.Close()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid history %s: %v", field, err), http.StatusBadRequest)
+			return
+		}
+		histories[i] = make([]diff.Message, len(msgs))
+		for j := range msgs {
+			histories[i][j] = diffMessage(&msgs[j])
+		}
+	}
+
+	key := r.FormValue("key")
+	result := diff.Histories(histories[0], histories[1], key)
+	d.log.With("messages_a", len(histories[0]), "messages_b", len(histories[1]), "key", key, "identical", result.Identical).
+		Info("Message histories compared")
+	d.writeDiffResponse(w, result)
+}
+
+// readMessageHistory reads the messages of a state export (JSON or tar.gz,
+// as written by /api/export) or a JSON array of messages (as returned by
+// /api/messages).
+func readMessageHistory(r io.Reader) ([]MessageInfo, error) {
+	br := bufio.NewReader(r)
+	for {
+		c, err := br.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("empty history")
+		}
+		if !bytes.ContainsAny(c, " \t\r\n") {
+			if c[0] == '[' {
+				var msgs []MessageInfo
+				if err := json.NewDecoder(br).Decode(&msgs); err != nil {
+					return nil, fmt.Errorf("invalid message list: %w", err)
+				}
+				return msgs, nil
+			}
+			break
+		}
+		_, _ = br.Discard(1)
+	}
+
+	archive, err := readStateArchive(br)
+	if err != nil {
+		return nil, err
+	}
+	return archive.Messages, nil
+}
+
+func diffMessage(m *MessageInfo) diff.Message {
+	return diff.Message{ID: m.ID, Topic: m.Topic, Data: m.Data, Attributes: m.Attributes}
+}
+
+func (d *Dashboard) writeDiffResponse(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		d.log.Error("Failed to encode diff response: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/diff"
+)
+
+func TestHandleMessageDiff(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.SetProjects([]string{"billing"})
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	dash.AddMessage(&pubsub.Message{ID: "m1", Data: []byte(`{"total":10,"items":["a"]}`), Attributes: map[string]string{"type": "OrderCreated"}}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "m2", Data: []byte(`{"items":["a","b"],"total":12}`), Attributes: map[string]string{"type": "OrderCreated", "v": "2"}}, "orders")
+
+	w := get("/api/messages/diff?a=m1&b=m2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got diff.MessageDiff
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Identical || got.Data.Format != diff.FormatJSON || len(got.Data.Changes) != 2 || len(got.Attributes) != 1 {
+		t.Errorf("Unexpected diff: %+v", got)
+	}
+	if c := got.Data.Changes[0]; c.Path != "items.1" || c.Kind != diff.Added {
+		t.Errorf("Expected items.1 added first, got %+v", c)
+	}
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/api/messages/diff?a=m1", http.StatusBadRequest},
+		{"/api/messages/diff?b=m2", http.StatusBadRequest},
+		{"/api/messages/diff?a=m1&b=missing", http.StatusNotFound},
+		{"/api/messages/diff?a=m1&b=m2&project=billing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := get(tt.target); w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.target, tt.want, w.Code)
+		}
+	}
+}
+
+func TestHandleMessageDiff_Replay(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	_, _ = dash.client.TopicAdminClient.CreateTopic(context.Background(), &pubsubpb.Topic{
+		Name: "projects/test-project/topics/orders",
+	})
+	dash.AddMessage(&pubsub.Message{ID: "original", Data: []byte(`{"id":1}`), PublishTime: time.Now()}, "orders")
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/replay?id=original", nil))
+	var replay map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&replay); err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+
+	if msg := dash.GetMessageByID(replay["messageId"]); msg == nil || msg.ReplayOf != "original" {
+		t.Fatalf("Expected the replay linked to its original, got %+v", msg)
+	}
+
+	// Without a, the replay is compared with the message it replays.
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/messages/diff?b="+replay["messageId"], nil))
+	var got diff.MessageDiff
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.A != "original" || got.B != replay["messageId"] || !got.Identical {
+		t.Errorf("Expected the replay identical to the original, got %+v", got)
+	}
+}
+
+func TestHandleMessageDiff_Histories(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+
+	before, _ := json.Marshal(StateArchive{Version: stateFormatVersion, Messages: []MessageInfo{
+		{ID: "a1", Topic: "orders", Data: `{"n":1}`, Attributes: map[string]string{"order": "1"}},
+		{ID: "a2", Topic: "orders", Data: `{"n":2}`, Attributes: map[string]string{"order": "2"}},
+	}})
+	after, _ := json.Marshal([]MessageInfo{
+		{ID: "b1", Topic: "orders", Data: `{"n":2}`, Attributes: map[string]string{"order": "2"}},
+		{ID: "b2", Topic: "orders", Data: `{"n":10}`, Attributes: map[string]string{"order": "1"}},
+		{ID: "b3", Topic: "audit", Data: "x"},
+	})
+
+	post := func(target string, files map[string][]byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for name, content := range files {
+			fw, _ := mw.CreateFormFile(name, name+".json")
+			_, _ = fw.Write(content)
+		}
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, target, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/api/messages/diff?key=order", map[string][]byte{"a": before, "b": after})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got diff.HistoryDiff
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Identical || got.Key != "order" || len(got.Topics) != 2 {
+		t.Fatalf("Unexpected history diff: %+v", got)
+	}
+	orders := got.Topics[1]
+	if orders.Unchanged != 1 || len(orders.Changed) != 1 || orders.Changed[0].A != "a1" || orders.Changed[0].B != "b2" {
+		t.Errorf("Expected a1 and b2 paired by key, got %+v", orders)
+	}
+
+	if w := post("/api/messages/diff", map[string][]byte{"a": before}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without b, got %d", w.Code)
+	}
+	if w := post("/api/messages/diff", map[string][]byte{"a": before, "b": []byte("{}")}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid history, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/messages/diff", bytes.NewReader(before)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a multipart form, got %d", w.Code)
+	}
+}
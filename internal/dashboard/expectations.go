@@ -0,0 +1,202 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/expect"
+)
+
+const (
+	// maxExpectationWait bounds how long GET /api/expectations/{id}?wait=
+	// holds the request open.
+	maxExpectationWait = time.Minute
+	// maxObservedIDs bounds how many observed message IDs are remembered.
+	maxObservedIDs = 100_000
+)
+
+// observedIDs remembers the most recent maxObservedIDs observed message IDs,
+// so a message evicted from the history and then delivered again is not
+// counted twice. A test-mode ID reset clears it, as IDs are then reused.
+// Guarded by messagesMutex.
+type observedIDs struct {
+	seen  map[string]struct{}
+	order []string
+	next  int
+}
+
+// add records id and reports whether it was new.
+func (o *observedIDs) add(id string) bool {
+	if _, ok := o.seen[id]; ok {
+		return false
+	}
+	if o.seen == nil {
+		o.seen = make(map[string]struct{})
+	}
+	if len(o.order) < maxObservedIDs {
+		o.order = append(o.order, id)
+	} else {
+		delete(o.seen, o.order[o.next])
+		o.order[o.next] = id
+		o.next = (o.next + 1) % maxObservedIDs
+	}
+	o.seen[id] = struct{}{}
+	return true
+}
+
+// SetExpectations enables the /api/expectations routes and checks every
+// message the dashboard records from then on against the registered
+// expectations. Without it the routes return 404.
+func (d *Dashboard) SetExpectations(r *expect.Registry) {
+	d.expectations = r
+}
+
+// observe checks a newly recorded message against the expectations. It sees
+// every message, including those later dropped from the capped history, once.
+func (d *Dashboard) observe(msg *MessageInfo) {
+	if d.expectations == nil || !d.observed.add(msg.ID) {
+		return
+	}
+	d.expectations.Observe(expect.Message{
+		ID:         msg.ID,
+		Project:    d.projectOf(msg),
+		Topic:      msg.Topic,
+		Data:       msg.Data,
+		Attributes: msg.Attributes,
+	})
+}
+
+// handleExpectations lists (GET), registers (POST) or cancels (DELETE) the
+// expectations of the request's project
+func (d *Dashboard) handleExpectations(w http.ResponseWriter, r *http.Request) {
+	if d.expectations == nil {
+		http.Error(w, "Expectations are disabled", http.StatusNotFound)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		d.writeExpectationResponse(w, http.StatusOK, d.expectations.List(project))
+	case http.MethodPost:
+		d.addExpectation(w, r, project)
+	case http.MethodDelete:
+		list := d.expectations.List(project)
+		for _, e := range list {
+			d.expectations.Remove(e.ID)
+		}
+		d.log.With("project", project, "expectations_removed", len(list)).Info("Expectations cleared")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (d *Dashboard) addExpectation(w http.ResponseWriter, r *http.Request, project string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var req ExpectationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TopicID != "" && !validateResourceID(w, "Topic ID", req.TopicID) {
+		return
+	}
+
+	spec := expect.Spec{
+		Project:    project,
+		Topic:      req.TopicID,
+		Attributes: req.Attributes,
+		Fields:     req.Fields,
+		Count:      req.Count,
+	}
+	if req.Timeout != "" {
+		var err error
+		if spec.Timeout, err = time.ParseDuration(req.Timeout); err != nil {
+			http.Error(w, "timeout must be a duration such as 5s", http.StatusBadRequest)
+			return
+		}
+	}
+
+	e, err := d.expectations.Add(spec)
+	switch {
+	case errors.Is(err, expect.ErrTooMany):
+		http.Error(w, "Too many pending expectations", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Invalid expectation: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d.log.With("expectation_id", e.ID, "project", project, "topic_id", e.Topic, "count", e.Count, "timeout", e.Timeout).
+		Debug("Expectation registered")
+	d.writeExpectationResponse(w, http.StatusCreated, e)
+}
+
+// handleExpectation reports on (GET) or cancels (DELETE) one expectation.
+// GET with ?wait=<duration> long-polls until the expectation is satisfied
+// or fails, or the wait passes.
+func (d *Dashboard) handleExpectation(w http.ResponseWriter, r *http.Request) {
+	if d.expectations == nil {
+		http.Error(w, "Expectations are disabled", http.StatusNotFound)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	e, ok := d.expectations.Get(id)
+	if !ok || e.Project != project {
+		http.Error(w, "Expectation not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		wait := r.URL.Query().Get("wait")
+		if wait == "" {
+			break
+		}
+		timeout, err := time.ParseDuration(wait)
+		if err != nil || timeout < 0 {
+			http.Error(w, "wait must be a duration such as 10s", http.StatusBadRequest)
+			return
+		}
+		timeout = min(timeout, maxExpectationWait)
+		// Outlast the server's write timeout while waiting.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 5*time.Second))
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		if e, ok = d.expectations.Wait(ctx, id); !ok {
+			http.Error(w, "Expectation not found", http.StatusNotFound)
+			return
+		}
+	case http.MethodDelete:
+		d.expectations.Remove(id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.writeExpectationResponse(w, http.StatusOK, e)
+}
+
+func (d *Dashboard) writeExpectationResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		d.log.Error("Failed to encode expectation response: %v", err)
+	}
+}
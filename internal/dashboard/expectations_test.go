@@ -0,0 +1,188 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/expect"
+)
+
+func TestHandleExpectations_Disabled(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	for _, target := range []string{"/api/expectations", "/api/expectations/1"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404 without a registry, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestHandleExpectations(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.SetProjects([]string{"billing"})
+	dash.SetExpectations(expect.NewRegistry())
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	do := func(method, target string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewReader([]byte(body))))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) expect.Status {
+		t.Helper()
+		var s expect.Status
+		if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+			t.Fatalf("Failed to decode expectation: %v", err)
+		}
+		return s
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"topic_id":"<script>"}`,
+		`{"timeout":"soon"}`,
+		`{"attributes":[{"key":"type","op":"like","value":"x"}]}`,
+	} {
+		if w := do(http.MethodPost, "/api/expectations", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	w := do(http.MethodPost, "/api/expectations",
+		`{"topic_id":"orders","attributes":{"type":"OrderCreated"},"fields":[{"key":"total","op":"gte","value":10}],"timeout":"30s"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	created := decode(w)
+	if created.State != expect.StatePending || created.Project != "test-project" {
+		t.Errorf("Unexpected expectation: %+v", created)
+	}
+
+	// A message recorded after registration satisfies it; the wait returns
+	// as soon as it does.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		dash.AddMessage(&pubsub.Message{ID: "m1", Data: []byte(`{"total":5}`), Attributes: map[string]string{"type": "OrderCreated"}}, "orders")
+		dash.AddMessage(&pubsub.Message{ID: "m2", Data: []byte(`{"total":12}`), Attributes: map[string]string{"type": "OrderCreated"}}, "orders")
+	}()
+	start := time.Now()
+	w = do(http.MethodGet, "/api/expectations/"+created.ID+"?wait=10s", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	got := decode(w)
+	if got.State != expect.StateSatisfied || len(got.Matched) != 1 || got.Matched[0] != "m2" {
+		t.Errorf("Expected the expectation satisfied by m2, got %+v", got)
+	}
+	if len(got.NearMisses) != 1 || got.NearMisses[0].MessageID != "m1" {
+		t.Errorf("Expected m1 reported as a near miss, got %+v", got.NearMisses)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Expected the wait to end once the expectation was satisfied")
+	}
+
+	// Expectations are scoped to the request's project.
+	if w := do(http.MethodGet, "/api/expectations/"+created.ID+"?project=billing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 from another project, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/expectations/"+created.ID+"?wait=forever", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid wait, got %d", w.Code)
+	}
+
+	// A pending expectation is reported as-is once the wait passes.
+	w = do(http.MethodPost, "/api/expectations", `{"topic_id":"payments"}`)
+	pending := decode(w)
+	if got := decode(do(http.MethodGet, "/api/expectations/"+pending.ID+"?wait=10ms", "")); got.State != expect.StatePending {
+		t.Errorf("Expected the expectation still pending, got %s", got.State)
+	}
+
+	var list []expect.Status
+	if err := json.NewDecoder(do(http.MethodGet, "/api/expectations", "").Body).Decode(&list); err != nil || len(list) != 2 {
+		t.Errorf("Expected 2 expectations, got %+v (%v)", list, err)
+	}
+	if w := do(http.MethodDelete, "/api/expectations/"+pending.ID, ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/expectations", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/expectations", "").Body).Decode(&list); err != nil || len(list) != 0 {
+		t.Errorf("Expected no expectations after clearing, got %+v (%v)", list, err)
+	}
+}
+
+func TestExpectations_BeyondHistory(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.maxMessages = 2
+	registry := expect.NewRegistry()
+	dash.SetExpectations(registry)
+
+	e, err := registry.Add(expect.Spec{Project: "test-project", Topic: "orders", Count: 5, Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 5 {
+		dash.RecordDelivery(&pubsub.Message{ID: string(rune('a' + i)), Data: []byte("x")}, "orders", "orders-sub")
+	}
+	if got, _ := registry.Get(e.ID); got.State != expect.StateSatisfied {
+		t.Errorf("Expected messages dropped from the history to count, got %+v", got)
+	}
+}
+
+func TestExpectations_RedeliveryAfterEviction(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.maxMessages = 1
+	registry := expect.NewRegistry()
+	dash.SetExpectations(registry)
+
+	e, err := registry.Add(expect.Spec{Project: "test-project", Topic: "orders", Count: 3, Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dash.RecordDelivery(&pubsub.Message{ID: "a"}, "orders", "orders-sub")
+	dash.RecordDelivery(&pubsub.Message{ID: "b"}, "orders", "orders-sub")
+	// a was evicted by b; its redelivery is recorded again but not counted.
+	dash.RecordDelivery(&pubsub.Message{ID: "a"}, "orders", "orders-sub")
+	dash.RecordDelivery(&pubsub.Message{ID: "a"}, "orders", "orders-sub")
+	if got, _ := registry.Get(e.ID); got.State != expect.StatePending || !slices.Equal(got.Matched, []string{"a", "b"}) {
+		t.Errorf("Expected 2 distinct messages counted, got %+v", got)
+	}
+}
+
+func TestExpectations_AfterIDReset(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	manual := clock.NewManual(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	ids := clock.NewIDs(1, manual)
+	dash.SetTestMode(manual, ids)
+	registry := expect.NewRegistry()
+	dash.SetExpectations(registry)
+
+	dash.RecordDelivery(&pubsub.Message{ID: "b1"}, "orders", "orders-sub")
+	ids.Reset()
+	e, err := registry.Add(expect.Spec{Project: "test-project", Topic: "orders", Count: 1, Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b2 reuses the ID 1-1 in the new run.
+	dash.RecordDelivery(&pubsub.Message{ID: "b2"}, "orders", "orders-sub")
+	if got, _ := registry.Get(e.ID); got.State != expect.StateSatisfied || !slices.Equal(got.Matched, []string{"1-1"}) {
+		t.Errorf("Expected the new run's 1-1 matched, got %+v", got)
+	}
+}
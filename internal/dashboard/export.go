@@ -0,0 +1,213 @@
+package dashboard
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// attributeColumnPrefix names the CSV and Parquet column holding each
+// attribute: attribute "type" goes in column "attr_type".
+const attributeColumnPrefix = "attr_"
+
+// parquetRowGroupSize is how many rows a Parquet export buffers before
+// writing them out as a row group.
+const parquetRowGroupSize = 500
+
+// exportFormats maps each ?format= of /api/messages/export to its content
+// type and file extension.
+var exportFormats = map[string]struct{ contentType, ext string }{
+	"ndjson":  {"application/x-ndjson", "ndjson"},
+	"csv":     {"text/csv; charset=utf-8", "csv"},
+	"parquet": {"application/vnd.apache.parquet", "parquet"},
+}
+
+// messageColumns are the CSV and Parquet columns before the attributes.
+var messageColumns = []string{
+	"id", "project", "topic", "publish_time", "received",
+	"delivery_attempts", "dead_lettered", "replay_of",
+	"pinned", "tags", "note", "data",
+}
+
+// tagSeparator joins a message's tags in its CSV tags cell; tags cannot
+// contain it.
+const tagSeparator = ","
+
+// handleExportMessages downloads the messages that match the search filters
+// (q, topic, tag and pinned) as NDJSON (the default), CSV or Parquet. NDJSON
+// has one message per line as /api/messages returns them; CSV and Parquet
+// have one row per message and an attr_<key> column per attribute key.
+func (d *Dashboard) handleExportMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	f, ok := exportFormats[format]
+	if !ok {
+		http.Error(w, "format must be ndjson, csv or parquet", http.StatusBadRequest)
+		return
+	}
+
+	filter, ok := d.parseMessageFilter(w, r)
+	if !ok {
+		return
+	}
+	// The snapshot's entries are never changed in place, so they are
+	// written as they are instead of being copied up front.
+	messages := slices.DeleteFunc(d.snapshot(), func(m *MessageInfo) bool {
+		return !d.matchesFilter(m, filter)
+	})
+	payload := func(m *MessageInfo) *MessageInfo {
+		expanded := *d.expandPayload(m)
+		expanded.Project = filter.project
+		return &expanded
+	}
+
+	// A large export can outlast the server's write timeout; the request
+	// context still ends it if the client goes away.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("messages-%s-%s.%s", filter.project, d.now().UTC().Format("20060102-150405"), f.ext)
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// Truncated payloads are read back one message at a time as they are
+	// written, so a large history is never whole in memory.
+	var err error
+	switch format {
+	case "ndjson":
+		err = writeNDJSON(w, messages, payload)
+	case "csv":
+		err = writeCSV(w, messages, payload)
+	case "parquet":
+		err = writeParquet(w, messages, payload)
+	}
+	if err != nil {
+		// Headers are sent; the client sees a truncated file.
+		d.log.With("format", format, "error", err.Error()).Error("Failed to export messages")
+		return
+	}
+	d.log.With("format", format, "topic_filter", filter.topic, "messages", len(messages)).Info("Messages exported")
+}
+
+// payloadFunc returns a message with its whole payload.
+type payloadFunc func(*MessageInfo) *MessageInfo
+
+func writeNDJSON(w io.Writer, messages []*MessageInfo, payload payloadFunc) error {
+	enc := json.NewEncoder(w)
+	for _, m := range messages {
+		if err := enc.Encode(payload(m)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attributeKeys returns every attribute key used by messages, sorted.
+func attributeKeys(messages []*MessageInfo) []string {
+	keys := make(map[string]struct{})
+	for _, m := range messages {
+		for k := range m.Attributes {
+			keys[k] = struct{}{}
+		}
+	}
+	return slices.Sorted(maps.Keys(keys))
+}
+
+func writeCSV(w io.Writer, messages []*MessageInfo, payload payloadFunc) error {
+	keys := attributeKeys(messages)
+	cw := csv.NewWriter(w)
+
+	header := slices.Clone(messageColumns)
+	for _, k := range keys {
+		header = append(header, attributeColumnPrefix+k)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(header))
+	for _, msg := range messages {
+		m := payload(msg)
+		record = append(record[:0],
+			m.ID, m.Project, m.Topic,
+			m.PublishTime.UTC().Format(time.RFC3339Nano), m.Received.UTC().Format(time.RFC3339Nano),
+			strconv.Itoa(m.Lifecycle.DeliveryAttempts), strconv.FormatBool(m.Lifecycle.DeadLettered),
+			m.ReplayOf, strconv.FormatBool(m.Pinned), strings.Join(m.Tags, tagSeparator), m.Note,
+			m.Data,
+		)
+		for _, k := range keys {
+			record = append(record, m.Attributes[k])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeParquet(w io.Writer, messages []*MessageInfo, payload payloadFunc) error {
+	keys := attributeKeys(messages)
+	group := parquet.Group{
+		"id":                parquet.String(),
+		"project":           parquet.String(),
+		"topic":             parquet.String(),
+		"publish_time":      parquet.Timestamp(parquet.Microsecond),
+		"received":          parquet.Timestamp(parquet.Microsecond),
+		"delivery_attempts": parquet.Int(64),
+		"dead_lettered":     parquet.Leaf(parquet.BooleanType),
+		"replay_of":         parquet.Optional(parquet.String()),
+		"pinned":            parquet.Leaf(parquet.BooleanType),
+		"tags":              parquet.Repeated(parquet.String()),
+		"note":              parquet.Optional(parquet.String()),
+		"data":              parquet.String(),
+	}
+	for _, k := range keys {
+		group[attributeColumnPrefix+k] = parquet.Optional(parquet.String())
+	}
+	pw := parquet.NewWriter(w, parquet.NewSchema("message", group), parquet.MaxRowsPerRowGroup(parquetRowGroupSize))
+
+	for _, msg := range messages {
+		m := payload(msg)
+		row := map[string]any{
+			"id":                m.ID,
+			"project":           m.Project,
+			"topic":             m.Topic,
+			"publish_time":      m.PublishTime,
+			"received":          m.Received,
+			"delivery_attempts": int64(m.Lifecycle.DeliveryAttempts),
+			"dead_lettered":     m.Lifecycle.DeadLettered,
+			"pinned":            m.Pinned,
+			"tags":              m.Tags,
+			"data":              m.Data,
+		}
+		if m.ReplayOf != "" {
+			row["replay_of"] = m.ReplayOf
+		}
+		if m.Note != "" {
+			row["note"] = m.Note
+		}
+		for k, v := range m.Attributes {
+			row[attributeColumnPrefix+k] = v
+		}
+		if err := pw.Write(row); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
@@ -0,0 +1,185 @@
+package dashboard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/parquet-go/parquet-go"
+)
+
+func setupExportTest(t *testing.T) *http.ServeMux {
+	t.Helper()
+	dash, cleanup := setupHandlerTest(t)
+	t.Cleanup(cleanup)
+
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	dash.AddMessage(&pubsub.Message{ID: "m1", Data: []byte(`{"id":1}`), Attributes: map[string]string{"type": "OrderCreated", "region": "eu"}, PublishTime: published}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "m2", Data: []byte("line one\nline \"two\""), Attributes: map[string]string{"type": "Note"}, PublishTime: published}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "m3", Data: []byte(`{"id":3}`), PublishTime: published}, "audit")
+	pinned, tags, note := true, []string{"golden", "orders"}, "first order"
+	if _, err := dash.annotateMessage("test-project", "m1", AnnotateRequest{Pinned: &pinned, Tags: &tags, Note: &note}); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	return mux
+}
+
+func getExport(t *testing.T, mux *http.ServeMux, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: expected status 200, got %d: %s", target, w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestHandleExportMessages_NDJSON(t *testing.T) {
+	mux := setupExportTest(t)
+
+	w := getExport(t, mux, "/api/messages/export")
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON by default, got %s", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "messages-test-project-") || !strings.HasSuffix(cd, `.ndjson"`) {
+		t.Errorf("Unexpected Content-Disposition: %s", cd)
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var msg MessageInfo
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Expected one JSON message per line, got %q: %v", scanner.Text(), err)
+		}
+		if msg.Project != "test-project" {
+			t.Errorf("Expected the project set on every message, got %q", msg.Project)
+		}
+		ids = append(ids, msg.ID)
+	}
+	if strings.Join(ids, ",") != "m1,m2,m3" {
+		t.Errorf("Expected all messages oldest first, got %v", ids)
+	}
+
+	// The search filters apply.
+	w = getExport(t, mux, "/api/messages/export?topic=orders&q=LINE")
+	if lines := strings.Count(w.Body.String(), "\n"); lines != 1 || !strings.Contains(w.Body.String(), `"id":"m2"`) {
+		t.Errorf("Expected only m2, got %s", w.Body.String())
+	}
+}
+
+func TestHandleExportMessages_CSV(t *testing.T) {
+	mux := setupExportTest(t)
+
+	w := getExport(t, mux, "/api/messages/export?format=csv&topic=orders")
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d", len(records))
+	}
+	wantHeader := "id,project,topic,publish_time,received,delivery_attempts,dead_lettered,replay_of,pinned,tags,note,data,attr_region,attr_type"
+	if got := strings.Join(records[0], ","); got != wantHeader {
+		t.Errorf("Unexpected header:\n got %s\nwant %s", got, wantHeader)
+	}
+	if row := records[1]; row[0] != "m1" || row[3] != "2024-05-01T12:00:00Z" || row[12] != "eu" || row[13] != "OrderCreated" ||
+		row[8] != "true" || row[9] != "golden,orders" || row[10] != "first order" {
+		t.Errorf("Unexpected row for m1: %v", row)
+	}
+	if row := records[2]; row[11] != "line one\nline \"two\"" || row[12] != "" || row[13] != "Note" {
+		t.Errorf("Expected m2's payload quoted and a missing attribute empty, got %v", row)
+	}
+}
+
+func TestHandleExportMessages_Parquet(t *testing.T) {
+	mux := setupExportTest(t)
+
+	w := getExport(t, mux, "/api/messages/export?format=parquet")
+	data := w.Body.Bytes()
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Failed to open Parquet file: %v", err)
+	}
+	if f.NumRows() != 3 {
+		t.Errorf("Expected 3 rows, got %d", f.NumRows())
+	}
+
+	r := parquet.NewReader(f)
+	defer func() { _ = r.Close() }()
+	row := map[string]any{}
+	if err := r.Read(&row); err != nil {
+		t.Fatal(err)
+	}
+	if row["id"] != "m1" || row["attr_type"] != "OrderCreated" || row["attr_region"] != "eu" || row["topic"] != "orders" {
+		t.Errorf("Unexpected first row: %v", row)
+	}
+	if tags, _ := row["tags"].([]any); row["pinned"] != true || len(tags) != 2 || tags[0] != "golden" || row["note"] != "first order" {
+		t.Errorf("Expected the annotations in the first row, got %v", row)
+	}
+	row = map[string]any{}
+	if err := r.Read(&row); err != nil {
+		t.Fatal(err)
+	}
+	if row["id"] != "m2" || row["attr_region"] != nil {
+		t.Errorf("Expected a missing attribute to be null, got %v", row)
+	}
+}
+
+func TestHandleExportMessages_Invalid(t *testing.T) {
+	mux := setupExportTest(t)
+
+	for target, want := range map[string]int{
+		"/api/messages/export?format=xml":                                      http.StatusBadRequest,
+		"/api/messages/export?q=" + strings.Repeat("x", maxSearchTermLength+1): http.StatusBadRequest,
+		"/api/messages/export?project=unknown":                                 http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Errorf("%.60s: expected status %d, got %d", target, want, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/messages/export", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
+
+func TestExports_OutlastWriteTimeout(t *testing.T) {
+	mux := setupExportTest(t)
+	// The handler starts after the write deadline has passed, as a slow
+	// export of a large history would reach it.
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		mux.ServeHTTP(w, r)
+	}))
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	for _, target := range []string{"/api/messages/export", "/api/export"} {
+		resp, err := srv.Client().Get(srv.URL + target)
+		if err != nil {
+			t.Errorf("%s: expected the export to be sent, got %v", target, err)
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte("m1")) {
+			t.Errorf("%s: expected the whole export, got %d %q (%v)", target, resp.StatusCode, body, err)
+		}
+	}
+}
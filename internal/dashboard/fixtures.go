@@ -0,0 +1,271 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/fixtures"
+)
+
+// handleImportMessages publishes the messages of an NDJSON or CSV fixture,
+// in the shape /api/messages/export writes, to the topics it names in the
+// request's project. The file is the request body or the multipart file
+// "file". Query parameters:
+//
+//   - format: ndjson or csv; detected from the file name, content type or
+//     content when omitted
+//   - topic: publish every message to this topic instead
+//   - timing: none (the default) publishes back to back; original keeps the
+//     gaps between the messages' publish times, divided by speed
+//   - data_column, topic_column, time_column: rename the mapped columns
+//   - attr_column: copy a further column into an attribute (repeatable)
+//
+// The response reports how many messages were published and which lines
+// failed.
+func (d *Dashboard) handleImportMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	opts := fixtures.Options{Topic: q.Get("topic")}
+	if opts.Topic != "" && !validateResourceID(w, "Topic ID", opts.Topic) {
+		return
+	}
+	var err error
+	if opts.Timing, err = fixtures.ParseTiming(q.Get("timing")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Speed, ok = parseSpeed(w, q.Get("speed")); !ok {
+		return
+	}
+
+	records, invalid, _, ok := readFixture(w, r)
+	if !ok {
+		return
+	}
+
+	d.localizeTopics(project, records)
+
+	// Publishing a large fixture, or keeping its original timing, can
+	// outlast the server's write timeout; the request context still ends
+	// it if the client goes away.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	publish, stop := d.fixturePublisher(project, false)
+	defer stop()
+
+	report, err := fixtures.Publish(r.Context(), records, invalid, publish, opts)
+	if err != nil {
+		d.log.With("published", report.Published, "error", err.Error()).Warn("Message import interrupted")
+		return
+	}
+	d.log.With("timing", opts.Timing, "total", report.Total,
+		"published", report.Published, "failed", report.Failed).Info("Messages imported")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		d.log.Error("Failed to encode import report: %v", err)
+	}
+}
+
+// parseSpeed parses a speed parameter, which defaults to 1, writing a 400
+// response and returning false if it is not a positive number.
+func parseSpeed(w http.ResponseWriter, s string) (float64, bool) {
+	if s == "" {
+		return 1, true
+	}
+	speed, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(speed > 0) || math.IsInf(speed, 1) {
+		http.Error(w, "speed must be a positive number", http.StatusBadRequest)
+		return 0, false
+	}
+	return speed, true
+}
+
+// readFixture reads the fixture uploaded with r, parsed as the format,
+// data_column, topic_column, time_column and attr_column parameters say. It
+// also returns the file name, if any. An unreadable or empty file gets a
+// 400 response and ok false.
+func readFixture(w http.ResponseWriter, r *http.Request) (records []fixtures.Record, invalid []fixtures.LineError, name string, ok bool) {
+	q := r.URL.Query()
+	mapping := fixtures.Mapping{
+		Data:       q.Get("data_column"),
+		Topic:      q.Get("topic_column"),
+		Time:       q.Get("time_column"),
+		Attributes: q["attr_column"],
+	}
+	var format fixtures.Format
+	if f := q.Get("format"); f != "" {
+		var err error
+		if format, err = fixtures.ParseFormat(f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, nil, "", false
+		}
+	}
+
+	body, name, err := fixtureBody(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, "", false
+	}
+	defer func() { _ = body.Close() }()
+
+	br := bufio.NewReader(body)
+	if format == "" {
+		head, _ := br.Peek(512)
+		format = fixtures.DetectFormat(name, head)
+	}
+	records, invalid, err = fixtures.Read(br, format, mapping)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			return nil, nil, "", false
+		}
+		http.Error(w, fmt.Sprintf("Invalid %s file: %v", format, err), http.StatusBadRequest)
+		return nil, nil, "", false
+	}
+	if len(records) == 0 && len(invalid) == 0 {
+		http.Error(w, "No messages in file", http.StatusBadRequest)
+		return nil, nil, "", false
+	}
+	return records, invalid, name, true
+}
+
+// fixtureBody returns the uploaded fixture and its file name (or, for a
+// plain body, its content type) for format detection.
+func fixtureBody(w http.ResponseWriter, r *http.Request) (io.ReadCloser, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, r.Header.Get("Content-Type"), nil
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", errors.New("invalid multipart form")
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, "", errors.New("file required")
+		}
+		if part.FormName() == "file" {
+			return part, part.FileName(), nil
+		}
+		_ = part.Close()
+	}
+}
+
+// localizeTopics rewrites the full names of topics in project to topic IDs,
+// so reports name them as the export does.
+func (d *Dashboard) localizeTopics(project string, records []fixtures.Record) {
+	for i := range records {
+		if p, id := d.resolveTopic(records[i].Topic); p == project {
+			records[i].Topic = id
+		}
+	}
+}
+
+// fixturePublisher returns a PublishFunc that publishes records to their
+// topics in project and records the messages in the history, with their
+// annotations, and a func that stops its publishers. With replay set, a
+// record with an ID is recorded as a replay of that message.
+func (d *Dashboard) fixturePublisher(project string, replay bool) (fixtures.PublishFunc, func()) {
+	publishers := map[string]*pubsub.Publisher{}
+	publish := func(ctx context.Context, topic string, rec *fixtures.Record) (string, error) {
+		name, err := d.fixtureTopic(project, topic)
+		if err != nil {
+			return "", err
+		}
+		annotations, err := recordAnnotations(rec)
+		if err != nil {
+			return "", err
+		}
+		p, ok := publishers[name]
+		if !ok {
+			p = d.client.Publisher(name)
+			publishers[name] = p
+		}
+		msg := rec.Message()
+		id, err := p.Publish(ctx, msg).Get(ctx)
+		if err != nil {
+			return "", err
+		}
+		msg.ID = id
+		msg.PublishTime = d.now()
+		if replay && rec.ID != "" {
+			d.recordReplay(msg, name, rec.ID)
+		} else {
+			d.AddMessage(msg, name)
+		}
+		if annotations != nil {
+			d.restoreAnnotations(project, d.messageID(id), *annotations)
+		}
+		return id, nil
+	}
+	stop := func() {
+		for _, p := range publishers {
+			p.Stop()
+		}
+	}
+	return publish, stop
+}
+
+// fixtureTopic returns the full name of a fixture record's topic, which is
+// a topic ID in project or a full topic name in project.
+func (d *Dashboard) fixtureTopic(project, topic string) (string, error) {
+	id := topic
+	if strings.HasPrefix(topic, "projects/") {
+		var p string
+		if p, id = d.resolveTopic(topic); p != project {
+			return "", fmt.Errorf("topic %s is not in project %s", topic, project)
+		}
+	}
+	if len(id) > maxResourceIDLength || !validResourceID(id) {
+		return "", fmt.Errorf("invalid topic ID %q", topic)
+	}
+	return topicName(project, id), nil
+}
+
+// recordAnnotations returns the validated annotations of rec, or nil if it
+// has none.
+func recordAnnotations(rec *fixtures.Record) (*AnnotateRequest, error) {
+	if !rec.Pinned && len(rec.Tags) == 0 && rec.Note == "" {
+		return nil, nil
+	}
+	req := AnnotateRequest{Tags: &rec.Tags, Note: &rec.Note}
+	if rec.Pinned {
+		req.Pinned = &rec.Pinned
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// restoreAnnotations applies a fixture's annotations to the message it
+// published. The message stays unpinned if too many are pinned already.
+func (d *Dashboard) restoreAnnotations(project, id string, req AnnotateRequest) {
+	if _, err := d.annotateMessage(project, id, req); errors.Is(err, errTooManyPins) {
+		d.log.With("message_id", id).Warn("Too many pinned messages; imported message left unpinned")
+		req.Pinned = nil
+		_, _ = d.annotateMessage(project, id, req)
+	}
+}
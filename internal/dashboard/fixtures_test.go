@@ -0,0 +1,131 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/fixtures"
+)
+
+func setupFixtureTest(t *testing.T, topics ...string) (*Dashboard, *http.ServeMux) {
+	t.Helper()
+	dash, cleanup := setupHandlerTest(t)
+	t.Cleanup(cleanup)
+	for _, topic := range topics {
+		if _, err := dash.client.TopicAdminClient.CreateTopic(context.Background(), &pubsubpb.Topic{
+			Name: topicName("test-project", topic),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	return dash, mux
+}
+
+func importFixture(t *testing.T, mux *http.ServeMux, target, contentType string, body []byte) (*httptest.ResponseRecorder, fixtures.Report) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var report fixtures.Report
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+			t.Fatalf("Invalid import report: %v", err)
+		}
+	}
+	return w, report
+}
+
+func TestHandleImportMessages_NDJSON(t *testing.T) {
+	dash, mux := setupFixtureTest(t, "orders", "audit")
+
+	body := `{"topic":"orders","data":"{\"id\":1}","attributes":{"type":"OrderCreated"}}
+{"topic":"projects/test-project/topics/audit","data":"seen","attr_user":"ann"}
+{"topic":"missing","data":"x"}
+{"topic":"projects/other/topics/orders","data":"x"}
+broken
+`
+	w, report := importFixture(t, mux, "/api/messages/import", "", []byte(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if report.Total != 5 || report.Published != 2 || report.Failed != 3 || report.Topics["orders"] != 1 || report.Topics["audit"] != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(report.Errors) != 3 || report.Errors[0].Line != 5 || !strings.Contains(report.Errors[2].Error, "not in project") {
+		t.Errorf("Unexpected errors: %+v", report.Errors)
+	}
+
+	msgs := dash.GetMessages()
+	if len(msgs) != 2 || msgs[0].Topic != "orders" || msgs[0].Attributes["type"] != "OrderCreated" || msgs[1].Attributes["user"] != "ann" {
+		t.Errorf("Expected the published messages recorded, got %+v", msgs)
+	}
+}
+
+func TestHandleImportMessages_CSVUpload(t *testing.T) {
+	dash, mux := setupFixtureTest(t, "orders", "copy")
+
+	csv := "id,topic,publish_time,body,attr_type,tenant\n" +
+		"m1,orders,2024-05-01T12:00:00Z,one,OrderCreated,acme\n" +
+		"m2,orders,2024-05-01T12:00:00.05Z,two,,\n"
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "orders.csv")
+	_, _ = fw.Write([]byte(csv))
+	_ = mw.Close()
+
+	target := "/api/messages/import?topic=copy&data_column=body&attr_column=tenant&timing=original&speed=2"
+	w, report := importFixture(t, mux, target, mw.FormDataContentType(), body.Bytes())
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if report.Published != 2 || report.Topics["copy"] != 2 {
+		t.Errorf("Expected both rows on the override topic, got %+v", report)
+	}
+	msgs := dash.GetMessages()
+	if len(msgs) != 2 || msgs[0].Data != "one" || msgs[0].Attributes["tenant"] != "acme" || msgs[1].Attributes != nil {
+		t.Errorf("Unexpected recorded messages: %+v", msgs)
+	}
+}
+
+func TestHandleImportMessages_Invalid(t *testing.T) {
+	_, mux := setupFixtureTest(t)
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   int
+	}{
+		{"bad format", "/api/messages/import?format=xml", "data\nx\n", http.StatusBadRequest},
+		{"bad timing", "/api/messages/import?timing=fast", "data\nx\n", http.StatusBadRequest},
+		{"bad speed", "/api/messages/import?speed=0", "data\nx\n", http.StatusBadRequest},
+		{"bad topic", "/api/messages/import?topic=1bad", "data\nx\n", http.StatusBadRequest},
+		{"no data column", "/api/messages/import?format=csv", "id,topic\n1,t\n", http.StatusBadRequest},
+		{"empty", "/api/messages/import", "", http.StatusBadRequest},
+		{"unknown project", "/api/messages/import?project=unknown", "data\nx\n", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w, _ := importFixture(t, mux, tt.target, "", []byte(tt.body)); w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/messages/import", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
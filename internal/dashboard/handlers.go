@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/pubsub/v2"
 	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/health"
 	"github.com/dipjyotimetia/pubsub-emulator/internal/web"
 )
 
@@ -28,6 +31,8 @@ const (
 	maxRequestBodyBytes = 1 << 20
 	// maxSearchTermLength caps the search query length.
 	maxSearchTermLength = 1000
+	// maxImportBodyBytes caps an uploaded state archive.
+	maxImportBodyBytes = 512 << 20
 )
 
 // resourceIDPattern matches valid Pub/Sub topic/subscription IDs: it must start
@@ -63,35 +68,13 @@ func (d *Dashboard) handleSearchMessages(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	query := r.URL.Query()
-	searchTerm := strings.ToLower(strings.TrimSpace(query.Get("q")))
-	if len(searchTerm) > maxSearchTermLength {
-		http.Error(w, "Search term too long", http.StatusBadRequest)
+	filter, ok := d.parseMessageFilter(w, r)
+	if !ok {
 		return
 	}
-	topicFilter := query.Get("topic")
-
-	d.messagesMutex.RLock()
-	defer d.messagesMutex.RUnlock()
+	filtered := d.filterMessages(filter)
 
-	filtered := make([]MessageInfo, 0)
-	for _, msg := range d.messages {
-		if topicFilter != "" && msg.Topic != topicFilter {
-			continue
-		}
-
-		if searchTerm != "" {
-			dataLower := strings.ToLower(msg.Data)
-			idLower := strings.ToLower(msg.ID)
-			if !strings.Contains(dataLower, searchTerm) && !strings.Contains(idLower, searchTerm) {
-				continue
-			}
-		}
-
-		filtered = append(filtered, msg)
-	}
-
-	d.log.With("search_term", searchTerm, "topic_filter", topicFilter, "results_count", len(filtered)).
+	d.log.With("search_term", filter.term, "topic_filter", filter.topic, "results_count", len(filtered)).
 		Info("Message search completed")
 
 	w.Header().Set("Content-Type", "application/json")
@@ -102,6 +85,88 @@ func (d *Dashboard) handleSearchMessages(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// messageFilter selects messages for search and export: those in project,
+// on topic if set, whose ID, data, note or a tag contains term
+// (case-insensitively), with every tag in tags and, if pinned is set,
+// pinned.
+type messageFilter struct {
+	project string
+	topic   string
+	term    string
+	tags    []string
+	pinned  bool
+}
+
+// parseMessageFilter reads a messageFilter from the request's project and
+// its q, topic, tag (repeatable) and pinned parameters, writing a 400
+// response if they are invalid.
+func (d *Dashboard) parseMessageFilter(w http.ResponseWriter, r *http.Request) (messageFilter, bool) {
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return messageFilter{}, false
+	}
+
+	query := r.URL.Query()
+	searchTerm := strings.ToLower(strings.TrimSpace(query.Get("q")))
+	if len(searchTerm) > maxSearchTermLength {
+		http.Error(w, "Search term too long", http.StatusBadRequest)
+		return messageFilter{}, false
+	}
+	pinned := false
+	if p := query.Get("pinned"); p != "" {
+		var err error
+		if pinned, err = strconv.ParseBool(p); err != nil {
+			http.Error(w, "pinned must be true or false", http.StatusBadRequest)
+			return messageFilter{}, false
+		}
+	}
+	return messageFilter{
+		project: project,
+		topic:   query.Get("topic"),
+		term:    searchTerm,
+		tags:    query["tag"],
+		pinned:  pinned,
+	}, true
+}
+
+func (d *Dashboard) matchesFilter(msg *MessageInfo, f messageFilter) bool {
+	if d.projectOf(msg) != f.project {
+		return false
+	}
+	if f.topic != "" && msg.Topic != f.topic {
+		return false
+	}
+	if f.pinned && !msg.Pinned {
+		return false
+	}
+	for _, tag := range f.tags {
+		if !slices.Contains(msg.Tags, tag) {
+			return false
+		}
+	}
+	if f.term != "" {
+		dataLower := strings.ToLower(msg.Data)
+		idLower := strings.ToLower(msg.ID)
+		noteLower := strings.ToLower(msg.Note)
+		if !strings.Contains(dataLower, f.term) && !strings.Contains(idLower, f.term) && !strings.Contains(noteLower, f.term) &&
+			!slices.ContainsFunc(msg.Tags, func(tag string) bool { return strings.Contains(strings.ToLower(tag), f.term) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterMessages returns copies of the messages f selects, oldest first.
+func (d *Dashboard) filterMessages(f messageFilter) []MessageInfo {
+	filtered := make([]MessageInfo, 0)
+	for _, m := range d.snapshot() {
+		if d.matchesFilter(m, f) {
+			filtered = append(filtered, *m)
+		}
+	}
+	return filtered
+}
+
 // handleCreateTopic creates a new Pub/Sub topic
 func (d *Dashboard) handleCreateTopic(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -109,6 +174,11 @@ func (d *Dashboard) handleCreateTopic(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
 	// Validate Content-Type
 	contentType := r.Header.Get("Content-Type")
 	if contentType != "application/json" && contentType != "" {
@@ -134,7 +204,7 @@ func (d *Dashboard) handleCreateTopic(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	topic, err := d.client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{
-		Name: fmt.Sprintf("projects/%s/topics/%s", d.projectID, req.TopicID),
+		Name: topicName(project, req.TopicID),
 	})
 	if err != nil {
 		d.log.With("topic_id", req.TopicID, "error", err.Error()).
@@ -164,6 +234,11 @@ func (d *Dashboard) handleCreateSubscription(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
 	// Validate Content-Type
 	contentType := r.Header.Get("Content-Type")
 	if contentType != "application/json" && contentType != "" {
@@ -196,6 +271,16 @@ func (d *Dashboard) handleCreateSubscription(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Subscriptions may attach to a topic in any served project.
+	topicProject := project
+	if req.TopicProject != "" {
+		if !slices.Contains(d.Projects(), req.TopicProject) {
+			http.Error(w, fmt.Sprintf("Unknown topic project %q", req.TopicProject), http.StatusBadRequest)
+			return
+		}
+		topicProject = req.TopicProject
+	}
+
 	if req.AckDeadlineSeconds <= 0 {
 		req.AckDeadlineSeconds = defaultAckDeadlineSeconds
 	}
@@ -206,8 +291,8 @@ func (d *Dashboard) handleCreateSubscription(w http.ResponseWriter, r *http.Requ
 
 	ctx := r.Context()
 	sub, err := d.client.SubscriptionAdminClient.CreateSubscription(ctx, &pubsubpb.Subscription{
-		Name:               fmt.Sprintf("projects/%s/subscriptions/%s", d.projectID, req.SubscriptionID),
-		Topic:              fmt.Sprintf("projects/%s/topics/%s", d.projectID, req.TopicID),
+		Name:               subscriptionName(project, req.SubscriptionID),
+		Topic:              topicName(topicProject, req.TopicID),
 		AckDeadlineSeconds: req.AckDeadlineSeconds,
 	})
 	if err != nil {
@@ -238,6 +323,11 @@ func (d *Dashboard) handlePublish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
 	// Validate Content-Type
 	contentType := r.Header.Get("Content-Type")
 	if contentType != "application/json" && contentType != "" {
@@ -253,6 +343,10 @@ func (d *Dashboard) handlePublish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Template != "" && !d.applyTemplate(w, &req) {
+		return
+	}
+
 	if req.TopicID == "" {
 		http.Error(w, "Topic ID is required", http.StatusBadRequest)
 		return
@@ -268,7 +362,8 @@ func (d *Dashboard) handlePublish(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	publisher := d.client.Publisher(req.TopicID)
+	topic := topicName(project, req.TopicID)
+	publisher := d.client.Publisher(topic)
 
 	msg := &pubsub.Message{
 		Data:       []byte(req.Data),
@@ -287,8 +382,9 @@ func (d *Dashboard) handlePublish(w http.ResponseWriter, r *http.Request) {
 	}
 
 	msg.ID = msgID
-	msg.PublishTime = time.Now()
-	d.AddMessage(msg, req.TopicID)
+	msg.PublishTime = d.now()
+	d.AddMessage(msg, topic)
+	msgID = d.messageID(msgID)
 
 	d.log.With("topic_id", req.TopicID, "message_id", msgID, "data_size", len(req.Data)).
 		Info("Message published successfully")
@@ -311,34 +407,35 @@ func (d *Dashboard) handleReplay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
 	messageID := r.URL.Query().Get("id")
 	if messageID == "" {
 		http.Error(w, "Message ID required", http.StatusBadRequest)
 		return
 	}
 
-	d.messagesMutex.RLock()
-	var originalMsg MessageInfo
-	var found bool
-	for i := range d.messages {
-		if d.messages[i].ID == messageID {
-			originalMsg = d.messages[i] // Copy the value
-			found = true
-			break
-		}
-	}
-	d.messagesMutex.RUnlock()
-
-	if !found {
+	originalMsg := d.GetMessageByID(messageID)
+	if originalMsg == nil || d.projectOf(originalMsg) != project {
 		http.Error(w, "Message not found", http.StatusNotFound)
 		return
 	}
 
 	ctx := r.Context()
-	publisher := d.client.Publisher(originalMsg.Topic)
+	topic := topicName(project, originalMsg.Topic)
+	publisher := d.client.Publisher(topic)
 
+	data, err := d.fullData(originalMsg)
+	if err != nil {
+		d.log.With("original_message_id", messageID, "error", err.Error()).Error("Failed to read payload")
+		http.Error(w, "Payload no longer available", http.StatusGone)
+		return
+	}
 	msg := &pubsub.Message{
-		Data:       []byte(originalMsg.Data),
+		Data:       []byte(data),
 		Attributes: originalMsg.Attributes,
 	}
 
@@ -354,8 +451,9 @@ func (d *Dashboard) handleReplay(w http.ResponseWriter, r *http.Request) {
 	}
 
 	msg.ID = msgID
-	msg.PublishTime = time.Now()
-	d.AddMessage(msg, originalMsg.Topic)
+	msg.PublishTime = d.now()
+	d.recordReplay(msg, topic, messageID)
+	msgID = d.messageID(msgID)
 
 	d.log.With("original_message_id", messageID, "new_message_id", msgID, "topic", originalMsg.Topic).
 		Info("Message replayed successfully")
@@ -379,8 +477,13 @@ func (d *Dashboard) handleStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
 	ctx := r.Context()
-	stats, err := d.GetStats(ctx)
+	stats, err := d.GetProjectStats(ctx, project)
 	if err != nil {
 		d.log.With("error", err.Error()).
 			Error("Failed to get stats")
@@ -406,10 +509,12 @@ func (d *Dashboard) handleMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	d.messagesMutex.RLock()
-	messages := make([]MessageInfo, len(d.messages))
-	copy(messages, d.messages)
-	d.messagesMutex.RUnlock()
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	messages := d.GetProjectMessages(project)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(messages); err != nil {
@@ -419,6 +524,141 @@ func (d *Dashboard) handleMessages(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleMessageByID returns a single message together with its lifecycle
+// timeline (publish, deliveries, acks/nacks/expiries, dead-lettering), or
+// with PATCH changes its annotations
+func (d *Dashboard) handleMessageByID(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		d.handleAnnotateMessage(w, r)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	messageID := r.PathValue("id")
+	if messageID == "" {
+		http.Error(w, "Message ID required", http.StatusBadRequest)
+		return
+	}
+
+	msg := d.GetMessageByID(messageID)
+	if msg == nil || d.projectOf(msg) != project {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		d.log.Error("Failed to encode message response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleExport downloads the emulator state as JSON (default) or, with
+// ?format=tar.gz, as a gzip-compressed tar of per-section JSON files
+func (d *Dashboard) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "tgz" {
+		format = "tar.gz"
+	}
+	if format != "" && format != "json" && format != "tar.gz" {
+		http.Error(w, "format must be json or tar.gz", http.StatusBadRequest)
+		return
+	}
+
+	archive, err := d.ExportState(r.Context(), project)
+	if err != nil {
+		d.log.With("error", err.Error()).Error("Failed to export state")
+		http.Error(w, fmt.Sprintf("Failed to export state: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Writing a large history can outlast the server's write timeout.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("pubsub-state-%s-%s", project, archive.ExportedAt.Format("20060102-150405"))
+	if format == "tar.gz" {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".tar.gz"))
+		if err := writeStateTarGz(w, archive); err != nil {
+			d.log.Error("Failed to write export archive: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+	if err := json.NewEncoder(w).Encode(archive); err != nil {
+		d.log.Error("Failed to encode export response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleImport recreates the state from an archive produced by /api/export.
+// ?skip_messages=true leaves the message history alone and ?project=ID
+// recreates resources under a different project.
+func (d *Dashboard) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Any valid project may be the target, not only the configured ones, so
+	// a state dump can seed a new project; the dashboard serves it from then
+	// on.
+	query := r.URL.Query()
+	opts := ImportOptions{
+		SkipMessages: query.Get("skip_messages") == "true",
+		ProjectID:    query.Get("project"),
+	}
+	if opts.ProjectID == "" {
+		opts.ProjectID = d.projectID
+	} else if !validateResourceID(w, "Project ID", opts.ProjectID) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	archive, err := readStateArchive(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid archive: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := d.ImportState(r.Context(), archive, opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to import state: %v", err), http.StatusBadRequest)
+		return
+	}
+	d.addProject(result.ProjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		d.log.Error("Failed to encode import response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
 // handleHealth returns health check status
 func (d *Dashboard) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -437,6 +677,43 @@ func (d *Dashboard) handleHealth(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleLivez reports that the process is up and serving HTTP. It checks
+// nothing else, so orchestrators only restart a process that has hung.
+func (d *Dashboard) handleLivez(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": health.StatusOK}); err != nil {
+		d.log.Error("Failed to encode liveness response: %v", err)
+	}
+}
+
+// handleReadyz runs the readiness checks and answers 200 when all pass or
+// 503 with the failing checks, so healthchecks and CI can gate on it.
+func (d *Dashboard) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := &health.Report{Status: health.StatusOK, Time: time.Now(), Checks: []health.Result{}}
+	if d.readiness != nil {
+		report = d.readiness.Check(r.Context())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		d.log.Error("Failed to encode readiness response: %v", err)
+	}
+}
+
 // handleIndex serves the main dashboard HTML page
 func (d *Dashboard) handleIndex(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -451,11 +728,32 @@ func (d *Dashboard) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/stats", d.handleStats)
 	mux.HandleFunc("/api/messages", d.handleMessages)
 	mux.HandleFunc("/api/messages/search", d.handleSearchMessages)
+	mux.HandleFunc("/api/messages/diff", d.handleMessageDiff)
+	mux.HandleFunc("/api/messages/export", d.handleExportMessages)
+	mux.HandleFunc("/api/messages/import", d.handleImportMessages)
+	mux.HandleFunc("/api/messages/{id}", d.handleMessageByID)
+	mux.HandleFunc("/api/messages/{id}/data", d.handleMessageData)
 	mux.HandleFunc("/api/topics", d.handleCreateTopic)
 	mux.HandleFunc("/api/subscriptions", d.handleCreateSubscription)
 	mux.HandleFunc("/api/publish", d.handlePublish)
 	mux.HandleFunc("/api/replay", d.handleReplay)
+	mux.HandleFunc("/api/replays", d.handleReplays)
+	mux.HandleFunc("/api/replays/{id}", d.handleReplaySession)
+	mux.HandleFunc("/api/replays/{id}/{action}", d.handleReplayAction)
+	mux.HandleFunc("/api/export", d.handleExport)
+	mux.HandleFunc("/api/import", d.handleImport)
+	mux.HandleFunc("/api/clock", d.handleClock)
+	mux.HandleFunc("/api/chaos", d.handleChaos)
+	mux.HandleFunc("/api/chaos/{id}", d.handleChaosRule)
+	mux.HandleFunc("/api/loadgen", d.handleLoadGen)
+	mux.HandleFunc("/api/loadgen/{id}", d.handleLoadJob)
+	mux.HandleFunc("/api/templates", d.handleTemplates)
+	mux.HandleFunc("/api/templates/{name}", d.handleTemplate)
+	mux.HandleFunc("/api/expectations", d.handleExpectations)
+	mux.HandleFunc("/api/expectations/{id}", d.handleExpectation)
 	mux.HandleFunc("/api/health", d.handleHealth)
+	mux.HandleFunc("/livez", d.handleLivez)
+	mux.HandleFunc("/readyz", d.handleReadyz)
 
 	mux.Handle("/static/", web.StaticHandler())
 
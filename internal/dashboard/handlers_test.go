@@ -6,12 +6,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/pubsub/v2"
 	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
 	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/health"
 	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
@@ -92,6 +94,58 @@ func TestHandleHealth_MethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestHandleLivez(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	dash.handleLivez(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok"`) {
+		t.Errorf("Expected 200 ok, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	dash.handleLivez(w, httptest.NewRequest(http.MethodPost, "/livez", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandleReadyz(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	readyz := func() (int, health.Report) {
+		w := httptest.NewRecorder()
+		dash.handleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report health.Report
+		if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return w.Code, report
+	}
+
+	// Without checks the server is ready as soon as it answers.
+	if code, report := readyz(); code != http.StatusOK || report.Status != health.StatusOK {
+		t.Errorf("Expected ready without checks, got %d %+v", code, report)
+	}
+
+	var startup health.Gate
+	checker := health.New(0)
+	checker.Add("startup", startup.CheckFunc("startup in progress"))
+	dash.SetReadiness(checker)
+
+	code, report := readyz()
+	if code != http.StatusServiceUnavailable || report.Checks[0].Error != "startup in progress" {
+		t.Errorf("Expected 503 during startup, got %d %+v", code, report)
+	}
+
+	startup.Open()
+	if code, _ := readyz(); code != http.StatusOK {
+		t.Errorf("Expected 200 after startup, got %d", code)
+	}
+}
+
 func TestHandleMessages(t *testing.T) {
 	dash, cleanup := setupHandlerTest(t)
 	defer cleanup()
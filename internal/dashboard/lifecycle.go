@@ -0,0 +1,156 @@
+package dashboard
+
+import (
+	"slices"
+
+	"cloud.google.com/go/pubsub/v2"
+)
+
+// maxLifecycleEvents bounds the events kept per message so a redelivery storm
+// cannot grow a single history entry without limit. The oldest events are
+// dropped first; the summary counters keep counting.
+const maxLifecycleEvents = 200
+
+// deadLetterSourceAttribute is set by Pub/Sub on messages forwarded to a
+// dead-letter topic, naming the subscription that gave up on them.
+const deadLetterSourceAttribute = "CloudPubSubDeadLetterSourceSubscription"
+
+// RecordDelivery records that msg was delivered to subscription. Messages the
+// dashboard has not seen yet (e.g. published by another client directly to the
+// emulator) are added to the history first. The first delivery also sets the
+// message's Received time.
+//
+// A message is marked dead-lettered when it carries the dead-letter source
+// attribute, or when a message already recorded on another topic arrives on
+// this one: the emulator forwards dead letters with their message ID but
+// without the attribute.
+func (d *Dashboard) RecordDelivery(msg *pubsub.Message, topic, subscription string) {
+	now := d.now()
+	id := d.messageID(msg.ID)
+
+	// An unknown message's payload is spilled before the history is
+	// locked for writing.
+	d.messagesMutex.RLock()
+	_, known := d.history.find(id)
+	d.messagesMutex.RUnlock()
+	var recorded, stored MessageInfo
+	if known == nil {
+		// Expectations see the whole payload, before any truncation.
+		recorded = d.newMessageInfo(msg, topic)
+		stored = recorded
+		d.truncate(&stored)
+	}
+
+	d.messagesMutex.Lock()
+	defer d.messagesMutex.Unlock()
+
+	if _, info := d.history.find(id); info == nil {
+		if known != nil {
+			// Evicted since the check; kept whole rather than spilled
+			// under the lock.
+			recorded = d.newMessageInfo(msg, topic)
+			stored = recorded
+		}
+		d.appendMessage(stored)
+		d.observe(&recorded)
+	} else {
+		// Recorded by another delivery since the check.
+		d.discardSpill(&stored)
+	}
+
+	d.updateMessageLocked(id, func(info *MessageInfo) {
+		lc := &info.Lifecycle
+		// The subscription that gave up on a forwarded message is the one
+		// it was last delivered on.
+		source, forwarded := "", topic != "" && info.Topic != topic
+		if forwarded {
+			for _, ev := range slices.Backward(lc.Events) {
+				if ev.Type == EventDelivered {
+					source = ev.Subscription
+					break
+				}
+			}
+		}
+		if attr, ok := msg.Attributes[deadLetterSourceAttribute]; ok {
+			source, forwarded = extractID(attr), true
+		}
+
+		if !slices.Contains(lc.Subscriptions, subscription) {
+			lc.Subscriptions = append(lc.Subscriptions, subscription)
+			if len(lc.Subscriptions) == 1 {
+				info.Received = now
+			}
+		}
+		lc.DeliveryAttempts++
+
+		// Pub/Sub only reports attempts for subscriptions with a dead-letter
+		// policy; otherwise count deliveries to this subscription ourselves.
+		attempt := 1
+		if msg.DeliveryAttempt != nil {
+			attempt = *msg.DeliveryAttempt
+		} else {
+			for _, ev := range lc.Events {
+				if ev.Type == EventDelivered && ev.Subscription == subscription {
+					attempt++
+				}
+			}
+		}
+		lc.appendEvent(LifecycleEvent{
+			Type:         EventDelivered,
+			Subscription: subscription,
+			Attempt:      attempt,
+			Time:         now,
+		})
+
+		if forwarded && !lc.DeadLettered {
+			lc.DeadLettered = true
+			lc.appendEvent(LifecycleEvent{
+				Type:         EventDeadLettered,
+				Subscription: source,
+				Time:         now,
+			})
+		}
+	})
+}
+
+// RecordAck records that subscription acknowledged the message.
+func (d *Dashboard) RecordAck(messageID, subscription string) {
+	d.recordEvent(messageID, EventAcked, subscription)
+}
+
+// RecordNack records that subscription negatively acknowledged the message.
+func (d *Dashboard) RecordNack(messageID, subscription string) {
+	d.recordEvent(messageID, EventNacked, subscription)
+}
+
+// RecordExpiry records that the message's ack deadline on subscription
+// expired without an ack or nack.
+func (d *Dashboard) RecordExpiry(messageID, subscription string) {
+	d.recordEvent(messageID, EventExpired, subscription)
+}
+
+// recordEvent appends an event to a known message; events for messages that
+// have already been evicted from history are dropped.
+func (d *Dashboard) recordEvent(messageID string, eventType LifecycleEventType, subscription string) {
+	now := d.now()
+
+	d.messagesMutex.Lock()
+	defer d.messagesMutex.Unlock()
+
+	d.updateMessageLocked(d.messageID(messageID), func(info *MessageInfo) {
+		info.Lifecycle.appendEvent(LifecycleEvent{
+			Type:         eventType,
+			Subscription: subscription,
+			Time:         now,
+		})
+	})
+}
+
+// appendEvent adds ev, trimming to maxLifecycleEvents. The trimmed slice is
+// copied so snapshots handed out earlier never observe the mutation.
+func (lc *MessageLifecycle) appendEvent(ev LifecycleEvent) {
+	lc.Events = append(lc.Events, ev)
+	if len(lc.Events) > maxLifecycleEvents {
+		lc.Events = slices.Clone(lc.Events[len(lc.Events)-maxLifecycleEvents:])
+	}
+}
@@ -0,0 +1,205 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+func TestAddMessage_SeedsLifecycle(t *testing.T) {
+	dash := New(nil, "test-project", logger.New())
+	publishTime := time.Now().Add(-time.Minute)
+
+	dash.AddMessage(&pubsub.Message{ID: "msg-1", Data: []byte("x"), PublishTime: publishTime}, "topic1")
+
+	msg := dash.GetMessageByID("msg-1")
+	if msg == nil {
+		t.Fatal("Expected message to be stored")
+	}
+	if !msg.Lifecycle.PublishedAt.Equal(publishTime) {
+		t.Errorf("Expected PublishedAt %v, got %v", publishTime, msg.Lifecycle.PublishedAt)
+	}
+	if len(msg.Lifecycle.Events) != 1 || msg.Lifecycle.Events[0].Type != EventPublished {
+		t.Errorf("Expected a single published event, got %+v", msg.Lifecycle.Events)
+	}
+}
+
+func TestRecordDelivery_ExistingMessage(t *testing.T) {
+	dash := New(nil, "test-project", logger.New())
+	publishTime := time.Now().Add(-time.Minute)
+	msg := &pubsub.Message{ID: "msg-1", Data: []byte("x"), PublishTime: publishTime}
+
+	dash.AddMessage(msg, "topic1")
+	dash.RecordDelivery(msg, "topic1", "sub1")
+	dash.RecordAck("msg-1", "sub1")
+	dash.RecordDelivery(msg, "topic1", "sub2")
+	dash.RecordNack("msg-1", "sub2")
+	dash.RecordDelivery(msg, "topic1", "sub2")
+	dash.RecordExpiry("msg-1", "sub2")
+
+	if got := len(dash.GetMessages()); got != 1 {
+		t.Fatalf("Expected deliveries to update the existing entry, got %d entries", got)
+	}
+
+	info := dash.GetMessageByID("msg-1")
+	lc := info.Lifecycle
+	if lc.DeliveryAttempts != 3 {
+		t.Errorf("Expected 3 delivery attempts, got %d", lc.DeliveryAttempts)
+	}
+	if len(lc.Subscriptions) != 2 || lc.Subscriptions[0] != "sub1" || lc.Subscriptions[1] != "sub2" {
+		t.Errorf("Expected subscriptions [sub1 sub2], got %v", lc.Subscriptions)
+	}
+	if !info.Received.After(publishTime) {
+		t.Error("Expected Received to be set to the first delivery time")
+	}
+
+	want := []LifecycleEventType{EventPublished, EventDelivered, EventAcked, EventDelivered, EventNacked, EventDelivered, EventExpired}
+	if len(lc.Events) != len(want) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(want), len(lc.Events), lc.Events)
+	}
+	for i, ev := range lc.Events {
+		if ev.Type != want[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, want[i], ev.Type)
+		}
+	}
+	if lc.Events[5].Attempt != 2 || lc.Events[5].Subscription != "sub2" {
+		t.Errorf("Expected second delivery to sub2 to be attempt 2, got %+v", lc.Events[5])
+	}
+}
+
+func TestRecordDelivery_UnknownMessageIsAdded(t *testing.T) {
+	dash := New(nil, "test-project", logger.New())
+	attempt := 4
+	msg := &pubsub.Message{ID: "ext-1", Data: []byte("x"), PublishTime: time.Now(), DeliveryAttempt: &attempt}
+
+	dash.RecordDelivery(msg, "topic1", "sub1")
+
+	info := dash.GetMessageByID("ext-1")
+	if info == nil {
+		t.Fatal("Expected externally published message to be added")
+	}
+	if info.Topic != "topic1" {
+		t.Errorf("Expected topic 'topic1', got '%s'", info.Topic)
+	}
+	last := info.Lifecycle.Events[len(info.Lifecycle.Events)-1]
+	if last.Type != EventDelivered || last.Attempt != 4 {
+		t.Errorf("Expected delivered event with attempt 4, got %+v", last)
+	}
+}
+
+func TestRecordDelivery_DeadLetterAttribute(t *testing.T) {
+	dash := New(nil, "test-project", logger.New())
+	msg := &pubsub.Message{
+		ID:          "dlq-1",
+		Data:        []byte("x"),
+		PublishTime: time.Now(),
+		Attributes: map[string]string{
+			deadLetterSourceAttribute: "projects/test-project/subscriptions/orders-sub",
+		},
+	}
+
+	dash.RecordDelivery(msg, "orders-dlq", "orders-dlq-sub")
+
+	lc := dash.GetMessageByID("dlq-1").Lifecycle
+	if !lc.DeadLettered {
+		t.Error("Expected message to be marked dead-lettered")
+	}
+	last := lc.Events[len(lc.Events)-1]
+	if last.Type != EventDeadLettered || last.Subscription != "orders-sub" {
+		t.Errorf("Expected dead_lettered event from orders-sub, got %+v", last)
+	}
+}
+
+func TestRecordDelivery_ForwardedToDeadLetterTopic(t *testing.T) {
+	dash := New(nil, "test-project", logger.New())
+	msg := &pubsub.Message{ID: "msg-1", Data: []byte("x"), PublishTime: time.Now()}
+
+	dash.RecordDelivery(msg, "orders", "orders-sub")
+	dash.RecordNack("msg-1", "orders-sub")
+	dash.RecordDelivery(msg, "orders", "orders-sub")
+	dash.RecordDelivery(msg, "orders-dlq", "orders-dlq-sub")
+
+	info := dash.GetMessageByID("msg-1")
+	if !info.Lifecycle.DeadLettered || info.Topic != "orders" {
+		t.Errorf("Expected the orders message marked dead-lettered, got %+v", info)
+	}
+	var dead []LifecycleEvent
+	for _, ev := range info.Lifecycle.Events {
+		if ev.Type == EventDeadLettered {
+			dead = append(dead, ev)
+		}
+	}
+	if len(dead) != 1 || dead[0].Subscription != "orders-sub" {
+		t.Errorf("Expected one dead_lettered event from orders-sub, got %+v", dead)
+	}
+}
+
+func TestRecordEvent_UnknownMessageIgnored(t *testing.T) {
+	dash := New(nil, "test-project", logger.New())
+
+	dash.RecordAck("missing", "sub1")
+	dash.RecordNack("missing", "sub1")
+
+	if got := len(dash.GetMessages()); got != 0 {
+		t.Errorf("Expected no messages, got %d", got)
+	}
+}
+
+func TestAppendEvent_BoundsHistory(t *testing.T) {
+	var lc MessageLifecycle
+	for i := range maxLifecycleEvents + 10 {
+		lc.appendEvent(LifecycleEvent{Type: EventDelivered, Attempt: i + 1})
+	}
+
+	if len(lc.Events) != maxLifecycleEvents {
+		t.Fatalf("Expected %d events, got %d", maxLifecycleEvents, len(lc.Events))
+	}
+	if lc.Events[0].Attempt != 11 {
+		t.Errorf("Expected oldest events to be dropped, first attempt is %d", lc.Events[0].Attempt)
+	}
+}
+
+func TestHandleMessageByID(t *testing.T) {
+	dash := New(nil, "test-project", logger.New())
+	msg := &pubsub.Message{ID: "msg-1", Data: []byte("hello"), PublishTime: time.Now()}
+	dash.AddMessage(msg, "topic1")
+	dash.RecordDelivery(msg, "topic1", "sub1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/messages/{id}", dash.handleMessageByID)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/messages/msg-1", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var got MessageInfo
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got.ID != "msg-1" || got.Lifecycle.DeliveryAttempts != 1 {
+		t.Errorf("Unexpected message: %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/messages/missing", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown message, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/messages/msg-1", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,153 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/loadgen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// loadgenDisabled is the error returned by the /api/loadgen routes without a
+// manager.
+const loadgenDisabled = "Load generation is disabled"
+
+// SetLoadGen enables the /api/loadgen routes, which start, report on and
+// stop synthetic load jobs. Without it they return 404.
+func (d *Dashboard) SetLoadGen(m *loadgen.Manager) {
+	d.loadgen = m
+}
+
+// projectJobs returns the load jobs publishing to topics in project.
+func (d *Dashboard) projectJobs(project string) []loadgen.Status {
+	prefix := "projects/" + project + "/"
+	jobs := []loadgen.Status{}
+	for _, job := range d.loadgen.Jobs() {
+		if strings.HasPrefix(job.Topic, prefix) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// handleLoadGen lists (GET) or starts (POST) the load jobs of the request's
+// project
+func (d *Dashboard) handleLoadGen(w http.ResponseWriter, r *http.Request) {
+	if d.loadgen == nil {
+		http.Error(w, loadgenDisabled, http.StatusNotFound)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		d.writeLoadGenResponse(w, http.StatusOK, d.projectJobs(project))
+	case http.MethodPost:
+		d.startLoadJob(w, r, project)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (d *Dashboard) startLoadJob(w http.ResponseWriter, r *http.Request, project string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var req LoadGenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !validateResourceID(w, "Topic ID", req.TopicID) {
+		return
+	}
+
+	spec := loadgen.Spec{
+		Topic:       topicName(project, req.TopicID),
+		Rate:        req.Rate,
+		Concurrency: req.Concurrency,
+		Count:       req.Count,
+		Template:    req.Template,
+		Attributes:  req.Attributes,
+		Seed:        req.Seed,
+	}
+	if req.Duration != "" {
+		var err error
+		if spec.Duration, err = time.ParseDuration(req.Duration); err != nil {
+			http.Error(w, "duration must be a duration such as 30s or 5m", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	if _, err := d.client.TopicAdminClient.GetTopic(ctx, &pubsubpb.GetTopicRequest{Topic: spec.Topic}); err != nil {
+		if status.Code(err) == codes.NotFound {
+			http.Error(w, "Topic not found", http.StatusNotFound)
+			return
+		}
+		d.log.Error("Failed to look up topic %s: %v", spec.Topic, err)
+		http.Error(w, "Failed to look up topic", http.StatusInternalServerError)
+		return
+	}
+
+	job, err := d.loadgen.Start(spec)
+	switch {
+	case errors.Is(err, loadgen.ErrTooManyJobs):
+		http.Error(w, "Too many load jobs running; stop one first", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Invalid load job: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d.log.With("job_id", job.ID, "topic", job.Topic, "rate", job.Rate, "concurrency", job.Concurrency, "duration", job.Duration, "count", job.Count).
+		Info("Load job started")
+	d.writeLoadGenResponse(w, http.StatusCreated, job)
+}
+
+// handleLoadJob reports on (GET) or stops (DELETE) one load job
+func (d *Dashboard) handleLoadJob(w http.ResponseWriter, r *http.Request) {
+	if d.loadgen == nil {
+		http.Error(w, loadgenDisabled, http.StatusNotFound)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	job, ok := d.loadgen.Get(r.PathValue("id"))
+	if !ok || !strings.HasPrefix(job.Status().Topic, "projects/"+project+"/") {
+		http.Error(w, "Load job not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		job.Stop()
+		<-job.Done()
+		d.log.With("job_id", r.PathValue("id")).Info("Load job stopped")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.writeLoadGenResponse(w, http.StatusOK, job.Status())
+}
+
+func (d *Dashboard) writeLoadGenResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		d.log.Error("Failed to encode load job response: %v", err)
+	}
+}
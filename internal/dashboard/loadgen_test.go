@@ -0,0 +1,135 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/loadgen"
+)
+
+// clientPublisher adapts a client library publisher to loadgen.Publisher.
+type clientPublisher struct {
+	publisher *pubsub.Publisher
+}
+
+func (p clientPublisher) Publish(ctx context.Context, msg *pubsub.Message) (string, error) {
+	return p.publisher.Publish(ctx, msg).Get(ctx)
+}
+
+func (p clientPublisher) Stop() { p.publisher.Stop() }
+
+func TestHandleLoadGen_Disabled(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	for _, target := range []string{"/api/loadgen", "/api/loadgen/1"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404 without a manager, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestHandleLoadGen(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.SetProjects([]string{"billing"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dash.SetLoadGen(loadgen.NewManager(ctx, func(topic string) loadgen.Publisher {
+		return clientPublisher{dash.client.Publisher(topic)}
+	}))
+	_, _ = dash.client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: "projects/test-project/topics/orders"})
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	do := func(method, target string, body any) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+
+	w := do(http.MethodPost, "/api/loadgen", LoadGenRequest{
+		TopicID:    "orders",
+		Count:      20,
+		Template:   `{"n":{{seq}}}`,
+		Attributes: map[string]string{"kind": "{{pick:a|b}}"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var job loadgen.Status
+	decode(w, &job)
+	if job.ID == "" || job.Topic != "projects/test-project/topics/orders" || job.State != loadgen.StateRunning {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.State == loadgen.StateRunning && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		decode(do(http.MethodGet, "/api/loadgen/"+job.ID, nil), &job)
+	}
+	if job.State != loadgen.StateCompleted || job.Published != 20 {
+		t.Errorf("Expected 20 messages published, got %+v", job)
+	}
+
+	w = do(http.MethodPost, "/api/loadgen", LoadGenRequest{TopicID: "orders", Duration: "1m"})
+	var running loadgen.Status
+	decode(w, &running)
+	w = do(http.MethodDelete, "/api/loadgen/"+running.ID, nil)
+	decode(w, &running)
+	if w.Code != http.StatusOK || running.State != loadgen.StateStopped {
+		t.Errorf("Expected the job to be stopped, got %d %+v", w.Code, running)
+	}
+
+	var jobs []loadgen.Status
+	decode(do(http.MethodGet, "/api/loadgen", nil), &jobs)
+	if len(jobs) != 2 {
+		t.Errorf("Expected 2 jobs, got %d", len(jobs))
+	}
+	decode(do(http.MethodGet, "/api/loadgen?project=billing", nil), &jobs)
+	if len(jobs) != 0 {
+		t.Errorf("Expected no jobs in billing, got %d", len(jobs))
+	}
+	if w := do(http.MethodGet, "/api/loadgen/"+job.ID+"?project=billing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another project's job to be hidden, got %d", w.Code)
+	}
+
+	invalid := []struct {
+		req  LoadGenRequest
+		code int
+	}{
+		{LoadGenRequest{TopicID: "missing"}, http.StatusNotFound},
+		{LoadGenRequest{TopicID: "1bad"}, http.StatusBadRequest},
+		{LoadGenRequest{TopicID: "orders", Duration: "soon"}, http.StatusBadRequest},
+		{LoadGenRequest{TopicID: "orders", Rate: -1}, http.StatusBadRequest},
+		{LoadGenRequest{TopicID: "orders", Template: "{{nope}}"}, http.StatusBadRequest},
+	}
+	for _, tt := range invalid {
+		if w := do(http.MethodPost, "/api/loadgen", tt.req); w.Code != tt.code {
+			t.Errorf("Expected status %d for %+v, got %d", tt.code, tt.req, w.Code)
+		}
+	}
+}
@@ -3,27 +3,106 @@ package dashboard
 import (
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
 )
 
-// CORSMiddleware adds CORS headers to responses
-func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+// CORSConfig controls the cross-origin policy of the dashboard API
+type CORSConfig struct {
+	// AllowedOrigins lists origins allowed to call the API. "*" allows any
+	// origin, but never with credentials.
+	AllowedOrigins []string
+	// AllowedMethods and AllowedHeaders are advertised on preflight responses.
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and HTTP auth cross-origin
+	// from the origins listed explicitly in AllowedOrigins.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// DefaultCORSConfig returns the policy used when nothing is configured: any
+// origin may read, and the headers needed for auth and CSRF are allowed.
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Token", csrfHeaderName},
+		MaxAge:         10 * time.Minute,
+	}
+}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+// allowsAnyOrigin reports whether the policy contains the "*" wildcard.
+func (c CORSConfig) allowsAnyOrigin() bool {
+	return slices.Contains(c.AllowedOrigins, "*")
+}
 
-		next.ServeHTTP(w, r)
+// allowsOrigin reports whether origin is explicitly listed (case-insensitive).
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	return slices.ContainsFunc(c.AllowedOrigins, func(o string) bool {
+		return o != "*" && strings.EqualFold(o, origin)
 	})
 }
 
+// CORSMiddleware adds CORS headers to responses using DefaultCORSConfig
+func CORSMiddleware(next http.Handler) http.Handler {
+	return NewCORSMiddleware(DefaultCORSConfig())(next)
+}
+
+// NewCORSMiddleware returns middleware applying cfg. Requests from origins the
+// policy does not allow get no CORS headers, so browsers block the response.
+// Preflight (OPTIONS) requests are answered directly.
+func NewCORSMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	wildcard := cfg.allowsAnyOrigin()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+
+			allowOrigin := ""
+			switch {
+			case origin != "" && cfg.allowsOrigin(origin):
+				allowOrigin = origin
+			case wildcard:
+				// Never echoed with credentials: any page could then read
+				// authenticated responses.
+				allowOrigin = "*"
+			}
+
+			if allowOrigin != "" {
+				h := w.Header()
+				h.Set("Access-Control-Allow-Origin", allowOrigin)
+				if allowOrigin != "*" {
+					h.Add("Vary", "Origin")
+				}
+				h.Set("Access-Control-Allow-Methods", methods)
+				h.Set("Access-Control-Allow-Headers", headers)
+				if cfg.AllowCredentials && allowOrigin != "*" {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if r.Method == http.MethodOptions && cfg.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", maxAge)
+				}
+			}
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // HTTPLoggingMiddleware logs HTTP requests with structured fields
 func HTTPLoggingMiddleware(log *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -57,3 +136,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// handlers can flush streams and lift the write deadline.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
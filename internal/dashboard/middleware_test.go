@@ -3,7 +3,10 @@ package dashboard
 import (
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
 )
@@ -82,3 +85,103 @@ func TestResponseWriter_CapturesStatusCode(t *testing.T) {
 		t.Errorf("expected underlying recorder status 404, got %d", rec.Code)
 	}
 }
+
+func TestResponseWriter_Flushes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := &responseWriter{ResponseWriter: rec, statusCode: http.StatusOK}
+
+	if err := http.NewResponseController(rw).Flush(); err != nil {
+		t.Fatalf("expected flush through the wrapper, got %v", err)
+	}
+	if !rec.Flushed {
+		t.Error("expected underlying recorder flushed")
+	}
+}
+
+func TestNewCORSMiddleware_AllowedOrigin(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.AllowedOrigins = []string{"http://app.local:3000"}
+	cfg.AllowCredentials = true
+	handler := NewCORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.Header.Set("Origin", "http://app.local:3000")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://app.local:3000" {
+		t.Errorf("expected origin to be echoed, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("expected credentials to be allowed, got %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("expected Vary: Origin, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Authorization") {
+		t.Errorf("expected Authorization in allowed headers, got %q", got)
+	}
+}
+
+func TestNewCORSMiddleware_DisallowedOrigin(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.AllowedOrigins = []string{"http://app.local:3000"}
+	handler := NewCORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no CORS headers for disallowed origin, got %q", got)
+	}
+}
+
+func TestNewCORSMiddleware_PreflightMaxAge(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.MaxAge = 90 * time.Second
+	handler := NewCORSMiddleware(cfg)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("expected preflight not to reach the handler")
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/publish", nil)
+	req.Header.Set("Origin", "http://app.local")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "90" {
+		t.Errorf("expected Access-Control-Max-Age 90, got %q", got)
+	}
+}
+
+func TestNewCORSMiddleware_WildcardNeverAllowsCredentials(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.AllowCredentials = true
+	handler := NewCORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.Header.Set("Origin", "http://anywhere.local")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected * instead of the echoed origin, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("expected no credentials for a wildcard origin, got %q", got)
+	}
+}
+
+func TestDefaultCORSConfig_AllowsAPIMethods(t *testing.T) {
+	methods := DefaultCORSConfig().AllowedMethods
+	for _, m := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if !slices.Contains(methods, m) {
+			t.Errorf("expected %s in the default methods, got %v", m, methods)
+		}
+	}
+}
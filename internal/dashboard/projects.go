@@ -0,0 +1,89 @@
+package dashboard
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SetProjects sets the projects the dashboard serves. The default project
+// passed to New is always included and listed first.
+func (d *Dashboard) SetProjects(projects []string) {
+	list := []string{d.projectID}
+	for _, p := range projects {
+		if p != "" && !slices.Contains(list, p) {
+			list = append(list, p)
+		}
+	}
+	d.projectsMutex.Lock()
+	d.projects = list
+	d.projectsMutex.Unlock()
+}
+
+// addProject starts serving project, e.g. one seeded by an import.
+func (d *Dashboard) addProject(project string) {
+	d.projectsMutex.Lock()
+	defer d.projectsMutex.Unlock()
+	if len(d.projects) == 0 {
+		d.projects = []string{d.projectID}
+	}
+	if !slices.Contains(d.projects, project) {
+		d.projects = append(d.projects, project)
+	}
+}
+
+// Projects returns the projects the dashboard serves, default first.
+func (d *Dashboard) Projects() []string {
+	d.projectsMutex.RLock()
+	defer d.projectsMutex.RUnlock()
+	if len(d.projects) == 0 {
+		return []string{d.projectID}
+	}
+	return slices.Clone(d.projects)
+}
+
+// requestProject returns the project an API request is scoped to: the
+// ?project= query parameter, or the default project when it is absent.
+// Unknown projects get a 404 and ok=false.
+func (d *Dashboard) requestProject(w http.ResponseWriter, r *http.Request) (string, bool) {
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		return d.projectID, true
+	}
+	if !slices.Contains(d.Projects(), project) {
+		http.Error(w, fmt.Sprintf("Unknown project %q", project), http.StatusNotFound)
+		return "", false
+	}
+	return project, true
+}
+
+// resolveTopic splits a topic reference into project and topic ID. A full
+// projects/<p>/topics/<id> name keeps its project; a bare ID belongs to the
+// default project.
+func (d *Dashboard) resolveTopic(topic string) (project, id string) {
+	parts := strings.Split(topic, "/")
+	if len(parts) == 4 && parts[0] == "projects" && parts[2] == "topics" {
+		return parts[1], parts[3]
+	}
+	return d.projectID, topic
+}
+
+// projectOf returns the project a stored message belongs to; entries
+// recorded before projects were tracked belong to the default project.
+func (d *Dashboard) projectOf(msg *MessageInfo) string {
+	if msg.Project == "" {
+		return d.projectID
+	}
+	return msg.Project
+}
+
+// topicName returns the full resource name of a topic in project.
+func topicName(project, topicID string) string {
+	return fmt.Sprintf("projects/%s/topics/%s", project, topicID)
+}
+
+// subscriptionName returns the full resource name of a subscription in project.
+func subscriptionName(project, subscriptionID string) string {
+	return fmt.Sprintf("projects/%s/subscriptions/%s", project, subscriptionID)
+}
@@ -0,0 +1,143 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+)
+
+func TestProjects(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	if got := dash.Projects(); !slices.Equal(got, []string{"test-project"}) {
+		t.Errorf("Expected only the default project, got %v", got)
+	}
+
+	dash.SetProjects([]string{"billing", "test-project", "", "billing", "shared"})
+	if got := dash.Projects(); !slices.Equal(got, []string{"test-project", "billing", "shared"}) {
+		t.Errorf("Expected default project first without duplicates, got %v", got)
+	}
+}
+
+func TestHandlers_UnknownProject(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+
+	for _, path := range []string{"/api/stats", "/api/messages", "/api/messages/search", "/api/export"} {
+		req := httptest.NewRequest(http.MethodGet, path+"?project=nope", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404 for an unknown project, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestHandlers_ProjectScoping(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.SetProjects([]string{"billing"})
+
+	post := func(handler http.HandlerFunc, target string, body any) int {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Code
+	}
+
+	if code := post(dash.handleCreateTopic, "/api/topics?project=billing", CreateTopicRequest{TopicID: "invoices"}); code != http.StatusOK {
+		t.Fatalf("Expected topic creation in billing to succeed, got %d", code)
+	}
+	if code := post(dash.handlePublish, "/api/publish?project=billing", PublishRequest{TopicID: "invoices", Data: "hi"}); code != http.StatusOK {
+		t.Fatalf("Expected publish in billing to succeed, got %d", code)
+	}
+	// The topic only exists in billing, so publishing to the default project fails.
+	if code := post(dash.handlePublish, "/api/publish", PublishRequest{TopicID: "invoices", Data: "hi"}); code == http.StatusOK {
+		t.Error("Expected publish to the default project to fail")
+	}
+
+	ctx := context.Background()
+	billing, err := dash.GetProjectStats(ctx, "billing")
+	if err != nil {
+		t.Fatalf("GetProjectStats failed: %v", err)
+	}
+	if !slices.Equal(billing.TopicList, []string{"invoices"}) || billing.MessageCount != 1 {
+		t.Errorf("Expected billing to hold invoices and one message, got %v and %d", billing.TopicList, billing.MessageCount)
+	}
+	if billing.RecentMessages[0].Project != "billing" || billing.RecentMessages[0].Topic != "invoices" {
+		t.Errorf("Expected message recorded against billing/invoices, got %+v", billing.RecentMessages[0])
+	}
+
+	def, err := dash.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if def.TopicCount != 0 || def.MessageCount != 0 {
+		t.Errorf("Expected the default project to be empty, got %d topics and %d messages", def.TopicCount, def.MessageCount)
+	}
+
+	// A message is only visible through its own project.
+	id := billing.RecentMessages[0].ID
+	for project, want := range map[string]int{"billing": http.StatusOK, "test-project": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodGet, "/api/messages/"+id+"?project="+project, nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		dash.handleMessageByID(w, req)
+		if w.Code != want {
+			t.Errorf("Expected status %d fetching the message via %s, got %d", want, project, w.Code)
+		}
+	}
+}
+
+func TestHandleCreateSubscription_CrossProject(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.SetProjects([]string{"shared"})
+
+	ctx := context.Background()
+	_, _ = dash.client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{
+		Name: "projects/shared/topics/events",
+	})
+
+	create := func(topicProject string) int {
+		body, _ := json.Marshal(CreateSubscriptionRequest{
+			SubscriptionID: "events-" + topicProject,
+			TopicID:        "events",
+			TopicProject:   topicProject,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/subscriptions", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		dash.handleCreateSubscription(w, req)
+		return w.Code
+	}
+
+	if code := create("unknown"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown topic project, got %d", code)
+	}
+	if code := create("shared"); code != http.StatusOK {
+		t.Fatalf("Expected cross-project subscription to succeed, got %d", code)
+	}
+
+	sub, err := dash.client.SubscriptionAdminClient.GetSubscription(ctx, &pubsubpb.GetSubscriptionRequest{
+		Subscription: "projects/test-project/subscriptions/events-shared",
+	})
+	if err != nil {
+		t.Fatalf("Expected subscription in the default project: %v", err)
+	}
+	if sub.Topic != "projects/shared/topics/events" {
+		t.Errorf("Expected subscription attached to the shared topic, got %s", sub.Topic)
+	}
+}
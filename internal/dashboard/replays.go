@@ -0,0 +1,267 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/fixtures"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/replay"
+)
+
+// replaysDisabled is the error returned by the /api/replays routes without
+// a manager.
+const replaysDisabled = "Replay sessions are disabled"
+
+// replayKeepAlive is how often an idle progress stream sends a comment so
+// proxies keep the connection open.
+const replayKeepAlive = 15 * time.Second
+
+// SetReplays enables the /api/replays routes, which republish recorded or
+// uploaded messages with their original timing. Without it they return 404.
+func (d *Dashboard) SetReplays(m *replay.Manager) {
+	d.replays = m
+}
+
+// handleReplays lists (GET) or starts (POST) the replay sessions of the
+// request's project.
+func (d *Dashboard) handleReplays(w http.ResponseWriter, r *http.Request) {
+	if d.replays == nil {
+		http.Error(w, replaysDisabled, http.StatusNotFound)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		sessions := []replay.Status{}
+		for _, s := range d.replays.Sessions() {
+			if s.Project == project {
+				sessions = append(sessions, s)
+			}
+		}
+		d.writeReplayResponse(w, http.StatusOK, sessions)
+	case http.MethodPost:
+		d.startReplay(w, r, project)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// startReplay starts a session replaying either the recorded messages that
+// match the search filters (q, topic, tag and pinned), or the NDJSON or CSV file
+// uploaded as the multipart file "file" (read as /api/messages/import
+// reads it). target_topic republishes every message to one topic, and
+// speed scales the original gaps.
+func (d *Dashboard) startReplay(w http.ResponseWriter, r *http.Request, project string) {
+	q := r.URL.Query()
+	spec := replay.Spec{Project: project, Topic: q.Get("target_topic")}
+	if spec.Topic != "" && !validateResourceID(w, "Target topic ID", spec.Topic) {
+		return
+	}
+	var ok bool
+	if spec.Speed, ok = parseSpeed(w, q.Get("speed")); !ok {
+		return
+	}
+
+	fromFile := strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+	if fromFile {
+		var name string
+		if spec.Records, spec.Invalid, name, ok = readFixture(w, r); !ok {
+			return
+		}
+		d.localizeTopics(project, spec.Records)
+		spec.Source = "file " + name
+	} else {
+		filter, ok := d.parseMessageFilter(w, r)
+		if !ok {
+			return
+		}
+		messages := d.filterMessages(filter)
+		d.expandPayloads(messages)
+		spec.Records = historyRecords(messages)
+		spec.Source = "history"
+		if filter.topic != "" {
+			spec.Source += " topic=" + filter.topic
+		}
+		if filter.term != "" {
+			spec.Source += " q=" + filter.term
+		}
+		for _, tag := range filter.tags {
+			spec.Source += " tag=" + tag
+		}
+		if filter.pinned {
+			spec.Source += " pinned"
+		}
+	}
+
+	// Messages replayed from the history are linked to their originals.
+	publish, stop := d.fixturePublisher(project, !fromFile)
+	session, err := d.replays.Start(spec, publish)
+	if err != nil {
+		stop()
+		if errors.Is(err, replay.ErrTooManySessions) {
+			http.Error(w, "Too many replay sessions running; cancel one first", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Invalid replay: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	go func() {
+		<-session.Done()
+		stop()
+	}()
+
+	status := session.Status()
+	d.log.With("replay_id", status.ID, "source", status.Source, "messages", status.Total, "speed", status.Speed).
+		Info("Replay session started")
+	d.writeReplayResponse(w, http.StatusCreated, status)
+}
+
+// historyRecords converts recorded messages to records in publish order.
+func historyRecords(messages []MessageInfo) []fixtures.Record {
+	records := make([]fixtures.Record, len(messages))
+	for i := range messages {
+		m := &messages[i]
+		records[i] = fixtures.Record{
+			Line:        i + 1,
+			ID:          m.ID,
+			Topic:       m.Topic,
+			Data:        []byte(m.Data),
+			Attributes:  m.Attributes,
+			PublishTime: m.PublishTime,
+		}
+	}
+	slices.SortStableFunc(records, func(a, b fixtures.Record) int {
+		return a.PublishTime.Compare(b.PublishTime)
+	})
+	return records
+}
+
+// replaySession returns the session named by the request's {id} in its
+// project, writing a 404 response if there is none.
+func (d *Dashboard) replaySession(w http.ResponseWriter, r *http.Request) (*replay.Session, bool) {
+	if d.replays == nil {
+		http.Error(w, replaysDisabled, http.StatusNotFound)
+		return nil, false
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	session, ok := d.replays.Get(r.PathValue("id"))
+	if !ok || session.Status().Project != project {
+		http.Error(w, "Replay session not found", http.StatusNotFound)
+		return nil, false
+	}
+	return session, true
+}
+
+// handleReplaySession reports on (GET) or cancels (DELETE) one replay
+// session.
+func (d *Dashboard) handleReplaySession(w http.ResponseWriter, r *http.Request) {
+	session, ok := d.replaySession(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		session.Cancel()
+		d.log.With("replay_id", r.PathValue("id")).Info("Replay session cancelled")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.writeReplayResponse(w, http.StatusOK, session.Status())
+}
+
+// handleReplayAction pauses (POST pause) or resumes (POST resume) a replay
+// session, or streams its progress (GET events) as server-sent events.
+func (d *Dashboard) handleReplayAction(w http.ResponseWriter, r *http.Request) {
+	session, ok := d.replaySession(w, r)
+	if !ok {
+		return
+	}
+
+	action := r.PathValue("action")
+	switch {
+	case action == "events" && r.Method == http.MethodGet:
+		d.streamReplay(w, r, session)
+		return
+	case action == "pause" && r.Method == http.MethodPost:
+		ok = session.Pause() == nil
+	case action == "resume" && r.Method == http.MethodPost:
+		ok = session.Resume() == nil
+	case action == "events" || action == "pause" || action == "resume":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if !ok {
+		http.Error(w, "Replay session has finished", http.StatusConflict)
+		return
+	}
+	d.log.With("replay_id", r.PathValue("id"), "action", action).Info("Replay session updated")
+	d.writeReplayResponse(w, http.StatusOK, session.Status())
+}
+
+// streamReplay sends the session's status as a "status" event now and
+// after each change until the session ends or the client goes away.
+func (d *Dashboard) streamReplay(w http.ResponseWriter, r *http.Request, session *replay.Session) {
+	rc := http.NewResponseController(w)
+	// A replay can run for as long as the original traffic did.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	updates, stop := session.Watch()
+	defer stop()
+	keepAlive := time.NewTicker(replayKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case status, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(status)
+			_, err = fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (d *Dashboard) writeReplayResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		d.log.Error("Failed to encode replay response: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/replay"
+)
+
+func setupReplayTest(t *testing.T, topics ...string) (*Dashboard, *http.ServeMux) {
+	t.Helper()
+	dash, mux := setupFixtureTest(t, topics...)
+	dash.SetReplays(replay.NewManager(t.Context()))
+	return dash, mux
+}
+
+func replayRequest(t *testing.T, mux *http.ServeMux, method, target, contentType string, body []byte) (*httptest.ResponseRecorder, replay.Status) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var status replay.Status
+	if w.Code == http.StatusOK || w.Code == http.StatusCreated {
+		if err := json.NewDecoder(bytes.NewReader(w.Body.Bytes())).Decode(&status); err != nil {
+			t.Fatalf("Invalid replay status: %v", err)
+		}
+	}
+	return w, status
+}
+
+func waitReplay(t *testing.T, dash *Dashboard, id string) {
+	t.Helper()
+	session, ok := dash.replays.Get(id)
+	if !ok {
+		t.Fatalf("Replay session %s not found", id)
+	}
+	select {
+	case <-session.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Replay session %s did not finish", id)
+	}
+}
+
+func TestHandleReplays_History(t *testing.T) {
+	dash, mux := setupReplayTest(t, "orders", "audit")
+
+	start := time.Now().Add(-time.Minute)
+	dash.AddMessage(&pubsub.Message{ID: "b", Data: []byte("two"), PublishTime: start.Add(100 * time.Millisecond)}, topicName("test-project", "orders"))
+	dash.AddMessage(&pubsub.Message{ID: "a", Data: []byte("one"), PublishTime: start}, topicName("test-project", "orders"))
+	dash.AddMessage(&pubsub.Message{ID: "c", Data: []byte("other"), PublishTime: start}, topicName("test-project", "audit"))
+
+	began := time.Now()
+	w, status := replayRequest(t, mux, http.MethodPost, "/api/replays?topic=orders&speed=2", "", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if status.Total != 2 || status.Speed != 2 || status.Source != "history topic=orders" || status.ExpectedSeconds != 0.05 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	waitReplay(t, dash, status.ID)
+	if elapsed := time.Since(began); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the gap kept at twice the speed, took %v", elapsed)
+	}
+
+	w, status = replayRequest(t, mux, http.MethodGet, "/api/replays/"+status.ID, "", nil)
+	if w.Code != http.StatusOK || status.State != replay.StateCompleted || status.Published != 2 || status.EndedAt == nil {
+		t.Errorf("Expected a completed session, got %d %+v", w.Code, status)
+	}
+
+	msgs := dash.GetMessages()
+	if len(msgs) != 5 || msgs[3].ReplayOf != "a" || msgs[4].ReplayOf != "b" || msgs[4].Data != "two" {
+		t.Errorf("Expected the replays linked to their originals in order, got %+v", msgs)
+	}
+
+	// The stream of a finished session sends its final status and ends.
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/replays/"+status.ID+"/events", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", ct)
+	}
+	if body := w.Body.String(); !strings.HasPrefix(body, "event: status\ndata: {") || !strings.Contains(body, `"state":"completed"`) {
+		t.Errorf("Unexpected event stream: %q", body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/replays", nil))
+	var sessions []replay.Status
+	if err := json.NewDecoder(w.Body).Decode(&sessions); err != nil || len(sessions) != 1 {
+		t.Errorf("Expected one session listed, got %v %+v", err, sessions)
+	}
+}
+
+func TestHandleReplays_PauseResumeCancel(t *testing.T) {
+	dash, mux := setupReplayTest(t, "orders")
+
+	start := time.Now()
+	dash.AddMessage(&pubsub.Message{ID: "a", Data: []byte("one"), PublishTime: start}, topicName("test-project", "orders"))
+	dash.AddMessage(&pubsub.Message{ID: "b", Data: []byte("two"), PublishTime: start.Add(time.Hour)}, topicName("test-project", "orders"))
+
+	w, status := replayRequest(t, mux, http.MethodPost, "/api/replays", "", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	base := "/api/replays/" + status.ID
+
+	if w, status = replayRequest(t, mux, http.MethodPost, base+"/pause", "", nil); w.Code != http.StatusOK || status.State != replay.StatePaused {
+		t.Errorf("Expected a paused session, got %d %+v", w.Code, status)
+	}
+	if w, status = replayRequest(t, mux, http.MethodPost, base+"/resume", "", nil); w.Code != http.StatusOK || status.State != replay.StateRunning {
+		t.Errorf("Expected a running session, got %d %+v", w.Code, status)
+	}
+	if w, status = replayRequest(t, mux, http.MethodDelete, base, "", nil); w.Code != http.StatusOK || status.State != replay.StateCancelled || status.Published > 1 {
+		t.Errorf("Expected a cancelled session, got %d %+v", w.Code, status)
+	}
+	if w, _ = replayRequest(t, mux, http.MethodPost, base+"/pause", "", nil); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 pausing a finished session, got %d", w.Code)
+	}
+}
+
+func TestHandleReplays_File(t *testing.T) {
+	dash, mux := setupReplayTest(t, "copy")
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "orders.ndjson")
+	_, _ = fw.Write([]byte(`{"id":"m1","topic":"orders","publish_time":"2024-05-01T12:00:00Z","data":"one"}
+{"id":"m2","topic":"orders","publish_time":"2024-05-01T12:00:00.02Z","data":"two"}
+`))
+	_ = mw.Close()
+
+	w, status := replayRequest(t, mux, http.MethodPost, "/api/replays?target_topic=copy", mw.FormDataContentType(), body.Bytes())
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if status.Source != "file orders.ndjson" || status.Topic != "copy" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	waitReplay(t, dash, status.ID)
+
+	msgs := dash.GetMessages()
+	if len(msgs) != 2 || msgs[0].Topic != "copy" || msgs[0].ReplayOf != "" {
+		t.Errorf("Expected the file's messages published unlinked, got %+v", msgs)
+	}
+}
+
+func TestHandleReplays_Invalid(t *testing.T) {
+	_, mux := setupReplayTest(t, "orders")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		want   int
+	}{
+		{"no messages", http.MethodPost, "/api/replays", http.StatusBadRequest},
+		{"bad speed", http.MethodPost, "/api/replays?speed=-1", http.StatusBadRequest},
+		{"bad target topic", http.MethodPost, "/api/replays?target_topic=1bad", http.StatusBadRequest},
+		{"bad method", http.MethodPut, "/api/replays", http.StatusMethodNotAllowed},
+		{"unknown session", http.MethodGet, "/api/replays/99", http.StatusNotFound},
+		{"pause unknown session", http.MethodPost, "/api/replays/99/pause", http.StatusNotFound},
+		{"unknown project", http.MethodGet, "/api/replays?project=unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w, _ := replayRequest(t, mux, tt.method, tt.target, "", nil); w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandleReplays_Disabled(t *testing.T) {
+	_, mux := setupFixtureTest(t)
+
+	for _, target := range []string{"/api/replays", "/api/replays/1", "/api/replays/1/events"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/retention"
+)
+
+// Eviction reasons reported in HistoryStats.Evicted.
+const (
+	evictCount      = "count"
+	evictMemory     = "memory"
+	evictTopicCount = "topic_count"
+	evictTopicBytes = "topic_bytes"
+	evictAge        = "age"
+)
+
+// messageOverhead approximates the memory a recorded message takes besides
+// its strings: the struct itself, its lifecycle and the map headers.
+const messageOverhead = 512
+
+// historyExpiryInterval is how often messages past their topic's max age
+// are dropped.
+const historyExpiryInterval = 10 * time.Second
+
+// topicUsage is one topic's share of the history.
+type topicUsage struct {
+	messages  int
+	bytes     int64
+	truncated int
+	evicted   int64
+}
+
+// SetRetention applies a retention policy to the message history: it
+// bounds the history globally (count and memory) and per topic (count, age
+// and bytes). With a spill directory, payloads over the policy's
+// MaxPayloadBytes keep only a prefix in memory and are read back from disk
+// on demand; without one they are kept whole. The policy applies to
+// messages recorded from now on.
+func (d *Dashboard) SetRetention(policy retention.Policy, spill *retention.Spill) {
+	d.messagesMutex.Lock()
+	defer d.messagesMutex.Unlock()
+
+	d.retention = policy
+	d.spill = spill
+	if policy.MaxMessages > 0 {
+		d.maxMessages = policy.MaxMessages
+		d.history.resize(d.maxMessages)
+	}
+}
+
+// messageBytes estimates the memory m takes. Only fields that do not change
+// once recorded are counted, so a message is released with the size it was
+// accounted with.
+func messageBytes(m *MessageInfo) int64 {
+	n := messageOverhead + len(m.ID) + len(m.Data) + len(m.Topic) + len(m.Project)
+	for k, v := range m.Attributes {
+		n += len(k) + len(v)
+	}
+	return int64(n)
+}
+
+// usageKey names the topic m counts against.
+func (d *Dashboard) usageKey(m *MessageInfo) string {
+	return d.projectOf(m) + "/" + m.Topic
+}
+
+// accountLocked adds m to (delta 1) or removes it from (delta -1) the
+// history's usage. Callers must hold messagesMutex for writing.
+func (d *Dashboard) accountLocked(m *MessageInfo, delta int) {
+	key := d.usageKey(m)
+	u := d.usage[key]
+	if u == nil {
+		u = &topicUsage{}
+		d.usage[key] = u
+	}
+	size := messageBytes(m)
+	u.messages += delta
+	u.bytes += int64(delta) * size
+	if m.Truncated {
+		u.truncated += delta
+	}
+	d.historyBytes += int64(delta) * size
+}
+
+// truncate moves a payload over MaxPayloadBytes to a spill file of its own,
+// keeping a prefix (cut at a character boundary) in m. It writes the file
+// before messagesMutex is taken for writing, so disk I/O does not stall the
+// history; callers that end up not recording m must call discardSpill.
+func (d *Dashboard) truncate(m *MessageInfo) {
+	if m.Truncated {
+		return
+	}
+	d.messagesMutex.RLock()
+	limit, spill := d.retention.MaxPayloadBytes, d.spill
+	d.messagesMutex.RUnlock()
+
+	m.Size = len(m.Data)
+	if spill == nil || limit <= 0 || len(m.Data) <= limit {
+		return
+	}
+	key := strconv.FormatUint(d.spillKeys.Add(1), 10)
+	if err := spill.Put(key, []byte(m.Data)); err != nil {
+		d.log.With("message_id", m.ID, "error", err.Error()).Warn("Failed to spill payload; keeping it in memory")
+		return
+	}
+	for limit > 0 && !utf8.RuneStart(m.Data[limit]) {
+		limit--
+	}
+	// Clone so the prefix does not keep the whole payload alive.
+	m.Data = strings.Clone(m.Data[:limit])
+	m.Truncated = true
+	m.spillKey = key
+}
+
+// discardSpill deletes the spill file truncate wrote for a message that was
+// not recorded after all.
+func (d *Dashboard) discardSpill(m *MessageInfo) {
+	if m.spillKey != "" && d.spill != nil {
+		d.spill.Delete(m.spillKey)
+	}
+}
+
+// enforceLocked applies the retention limits after newest was appended.
+// Per-topic limits and age only evict unpinned messages; the global count
+// and memory budget are hard limits and evict pinned messages when nothing
+// else is left. The newest message is always kept. Callers must hold
+// messagesMutex for writing.
+func (d *Dashboard) enforceLocked(newest *MessageInfo) {
+	key := d.usageKey(newest)
+	limits := d.retention.For(d.projectOf(newest), newest.Topic)
+	u := d.usage[key]
+	inTopic := func(m *MessageInfo) bool { return m != newest && d.usageKey(m) == key }
+	notNewest := func(m *MessageInfo) bool { return m != newest }
+
+	if limits.MaxCount > 0 && u.messages > limits.MaxCount {
+		d.evictLocked(evictTopicCount, inTopic, func(int) bool { return u.messages > limits.MaxCount }, false)
+	}
+	if limits.MaxBytes > 0 && u.bytes > limits.MaxBytes {
+		d.evictLocked(evictTopicBytes, inTopic, func(int) bool { return u.bytes > limits.MaxBytes }, false)
+	}
+	if d.history.len() > d.maxMessages {
+		d.evictLocked(evictCount, notNewest, func(dropped int) bool { return d.history.len()-dropped > d.maxMessages }, true)
+	}
+	if budget := d.retention.MemoryBytes; budget > 0 && d.historyBytes > budget {
+		d.evictLocked(evictMemory, notNewest, func(int) bool { return d.historyBytes > budget }, true)
+	}
+}
+
+// expireLocked drops unpinned messages older than their topic's max age.
+// Callers must hold messagesMutex for writing.
+func (d *Dashboard) expireLocked(now time.Time) {
+	expired := func(m *MessageInfo) bool {
+		maxAge := d.retention.For(d.projectOf(m), m.Topic).MaxAge
+		return maxAge > 0 && now.Sub(m.Received) > maxAge
+	}
+	d.evictLocked(evictAge, expired, func(int) bool { return true }, false)
+}
+
+// ExpireHistory drops messages past their topic's max age every
+// historyExpiryInterval until ctx is cancelled.
+func (d *Dashboard) ExpireHistory(ctx context.Context) {
+	ticker := time.NewTicker(historyExpiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.messagesMutex.Lock()
+			d.expireLocked(d.now())
+			d.messagesMutex.Unlock()
+		}
+	}
+}
+
+// evictLocked drops the oldest unpinned messages that match, one at a time,
+// while over, given how many were dropped so far, reports the history over
+// a limit. With pinned set, matching pinned messages go too once no
+// unpinned one is left. Callers must hold messagesMutex for writing.
+func (d *Dashboard) evictLocked(reason string, match func(*MessageInfo) bool, over func(dropped int) bool, pinned bool) {
+	var drop []int
+	for _, wantPinned := range []bool{false, true} {
+		if wantPinned && !pinned {
+			break
+		}
+		d.history.each(func(s int, m *MessageInfo) bool {
+			if !over(len(drop)) {
+				return false
+			}
+			if m.Pinned == wantPinned && match(m) {
+				drop = append(drop, s)
+				d.releaseLocked(m, reason)
+			}
+			return true
+		})
+	}
+	for _, s := range drop {
+		d.history.remove(s)
+	}
+}
+
+// releaseLocked removes an evicted message from the usage and the spill
+// directory. Callers must hold messagesMutex for writing.
+func (d *Dashboard) releaseLocked(m *MessageInfo, reason string) {
+	d.accountLocked(m, -1)
+	d.usage[d.usageKey(m)].evicted++
+	d.evictions[reason]++
+	if m.Truncated {
+		d.discardSpill(m)
+	}
+}
+
+// fullData returns m's whole payload, reading it back from the spill
+// directory when only a prefix is kept in memory.
+func (d *Dashboard) fullData(m *MessageInfo) (string, error) {
+	if !m.Truncated {
+		return m.Data, nil
+	}
+	if d.spill == nil || m.spillKey == "" {
+		return "", fmt.Errorf("payload of message %s is not available", m.ID)
+	}
+	data, err := d.spill.Get(m.spillKey)
+	if err != nil {
+		return "", fmt.Errorf("payload of message %s is not available: %w", m.ID, err)
+	}
+	return string(data), nil
+}
+
+// expandPayload returns m with its whole payload, or m itself (logging why)
+// if the payload cannot be read back.
+func (d *Dashboard) expandPayload(m *MessageInfo) *MessageInfo {
+	if !m.Truncated {
+		return m
+	}
+	data, err := d.fullData(m)
+	if err != nil {
+		d.log.With("message_id", m.ID, "error", err.Error()).Warn("Using truncated payload")
+		return m
+	}
+	expanded := *m
+	expanded.Data = data
+	expanded.Truncated = false
+	return &expanded
+}
+
+// expandPayloads replaces truncated payloads in messages with whole ones.
+func (d *Dashboard) expandPayloads(messages []MessageInfo) {
+	for i := range messages {
+		messages[i] = *d.expandPayload(&messages[i])
+	}
+}
+
+// historyStats reports the history's usage, with the topics of project.
+func (d *Dashboard) historyStats(project string) HistoryStats {
+	d.messagesMutex.RLock()
+	defer d.messagesMutex.RUnlock()
+
+	stats := HistoryStats{
+		Messages:        d.history.len(),
+		MaxMessages:     d.maxMessages,
+		Bytes:           d.historyBytes,
+		MemoryBudget:    d.retention.MemoryBytes,
+		MaxPayloadBytes: d.retention.MaxPayloadBytes,
+		Evicted:         make(map[string]int64, len(d.evictions)),
+		Topics:          []TopicHistory{},
+	}
+	for reason, n := range d.evictions {
+		stats.Evicted[reason] = n
+	}
+	prefix := project + "/"
+	for key, u := range d.usage {
+		topic, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		limits := d.retention.For(project, topic)
+		stats.Topics = append(stats.Topics, TopicHistory{
+			Topic:         topic,
+			Messages:      u.messages,
+			Bytes:         u.bytes,
+			Truncated:     u.truncated,
+			Evicted:       u.evicted,
+			MaxCount:      limits.MaxCount,
+			MaxAgeSeconds: limits.MaxAge.Seconds(),
+			MaxBytes:      limits.MaxBytes,
+		})
+	}
+	slices.SortFunc(stats.Topics, func(a, b TopicHistory) int {
+		return strings.Compare(a.Topic, b.Topic)
+	})
+	return stats
+}
+
+// handleMessageData serves a message's whole payload, including the part
+// left out of truncated messages, as GET /api/messages/{id}/data.
+func (d *Dashboard) handleMessageData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	project, ok := d.requestProject(w, r)
+	if !ok {
+		return
+	}
+
+	msg := d.GetMessageByID(r.PathValue("id"))
+	if msg == nil || d.projectOf(msg) != project {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+	data, err := d.fullData(msg)
+	if err != nil {
+		d.log.With("message_id", msg.ID, "error", err.Error()).Error("Failed to read payload")
+		http.Error(w, "Payload no longer available", http.StatusGone)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write([]byte(data))
+}
@@ -0,0 +1,212 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/retention"
+)
+
+func historyIDs(dash *Dashboard) string {
+	var ids []string
+	for _, m := range dash.GetMessages() {
+		ids = append(ids, m.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestRetention_TopicLimits(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.SetRetention(retention.Policy{
+		MaxMessages: 10,
+		Topics: map[string]retention.Limits{
+			"chatty": {MaxCount: 2},
+			"big":    {MaxBytes: 2 * (messageOverhead + 100)},
+		},
+	}, nil)
+
+	dash.AddMessage(&pubsub.Message{ID: "a1"}, "audit")
+	for i := range 4 {
+		dash.AddMessage(&pubsub.Message{ID: fmt.Sprintf("c%d", i)}, "chatty")
+	}
+	if got := historyIDs(dash); got != "a1,c2,c3" {
+		t.Errorf("Expected the chatty topic capped without touching audit, got %s", got)
+	}
+
+	payload := []byte(strings.Repeat("x", 80))
+	for i := range 3 {
+		dash.AddMessage(&pubsub.Message{ID: fmt.Sprintf("b%d", i), Data: payload}, "big")
+	}
+	if got := historyIDs(dash); got != "a1,c2,c3,b1,b2" {
+		t.Errorf("Expected the big topic kept under its byte limit, got %s", got)
+	}
+
+	stats := dash.historyStats("test-project")
+	if stats.Messages != 5 || stats.Evicted[evictTopicCount] != 2 || stats.Evicted[evictTopicBytes] != 1 {
+		t.Errorf("Unexpected history stats: %+v", stats)
+	}
+	if len(stats.Topics) != 3 || stats.Topics[2].Topic != "chatty" || stats.Topics[2].Messages != 2 || stats.Topics[2].Evicted != 2 || stats.Topics[2].MaxCount != 2 {
+		t.Errorf("Unexpected topic stats: %+v", stats.Topics)
+	}
+}
+
+func TestRetention_MemoryBudget(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	dash.SetRetention(retention.Policy{MaxMessages: 10, MemoryBytes: 3 * (messageOverhead + 1100)}, nil)
+
+	payload := []byte(strings.Repeat("x", 990))
+	for i := range 4 {
+		dash.AddMessage(&pubsub.Message{ID: fmt.Sprintf("m%d", i), Data: payload}, "orders")
+	}
+	if w, _ := annotate(t, mux, "m1", `{"pinned":true}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected m1 pinned, got %d", w.Code)
+	}
+	dash.AddMessage(&pubsub.Message{ID: "m4", Data: payload}, "orders")
+	if got := historyIDs(dash); got != "m1,m3,m4" {
+		t.Errorf("Expected the oldest unpinned messages evicted, got %s", got)
+	}
+
+	// A message over the whole budget is kept; pinned ones go when nothing
+	// else is left.
+	dash.AddMessage(&pubsub.Message{ID: "huge", Data: []byte(strings.Repeat("x", 4000))}, "orders")
+	if got := historyIDs(dash); got != "huge" {
+		t.Errorf("Expected only the newest message kept, got %s", got)
+	}
+	if stats := dash.historyStats("test-project"); stats.Evicted[evictMemory] != 5 || stats.Bytes > int64(messageOverhead+4100) {
+		t.Errorf("Unexpected history stats: %+v", stats)
+	}
+}
+
+func TestRetention_Expire(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	dash.SetRetention(retention.Policy{Topics: map[string]retention.Limits{
+		"*":     {MaxAge: time.Hour},
+		"audit": {MaxAge: 24 * time.Hour},
+	}}, nil)
+
+	now := time.Now()
+	dash.AddMessage(&pubsub.Message{ID: "old", PublishTime: now.Add(-2 * time.Hour)}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "pinned", PublishTime: now.Add(-2 * time.Hour)}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "audit", PublishTime: now.Add(-2 * time.Hour)}, "audit")
+	dash.AddMessage(&pubsub.Message{ID: "new", PublishTime: now}, "orders")
+	annotate(t, mux, "pinned", `{"pinned":true}`)
+
+	dash.messagesMutex.Lock()
+	dash.expireLocked(now)
+	dash.messagesMutex.Unlock()
+	if got := historyIDs(dash); got != "pinned,audit,new" {
+		t.Errorf("Expected the expired unpinned message dropped, got %s", got)
+	}
+	if n := dash.historyStats("test-project").Evicted[evictAge]; n != 1 {
+		t.Errorf("Expected one age eviction, got %d", n)
+	}
+}
+
+func TestRetention_Truncation(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	spill, err := retention.NewSpill(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create spill: %v", err)
+	}
+	dash.SetRetention(retention.Policy{MaxMessages: 2, MaxPayloadBytes: 8}, spill)
+
+	full := "héllo wörld, this is long"
+	dash.AddMessage(&pubsub.Message{ID: "big", Data: []byte(full)}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "small", Data: []byte("tiny")}, "orders")
+
+	msg := dash.GetMessageByID("big")
+	if !msg.Truncated || msg.Data != "héllo w" || msg.Size != len(full) {
+		t.Errorf("Expected the payload cut at a character boundary, got %q (%d bytes, truncated %v)", msg.Data, msg.Size, msg.Truncated)
+	}
+	if small := dash.GetMessageByID("small"); small.Truncated || small.Size != 4 {
+		t.Errorf("Expected a small payload kept whole, got %+v", small)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/messages/big/data", nil))
+	if w.Code != http.StatusOK || w.Body.String() != full {
+		t.Errorf("Expected the whole payload, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/messages/export?format=ndjson", nil))
+	var exported MessageInfo
+	if err := json.NewDecoder(w.Body).Decode(&exported); err != nil || exported.Data != full || exported.Truncated {
+		t.Errorf("Expected the export to hold the whole payload, got %+v (%v)", exported, err)
+	}
+
+	// Evicting a truncated message deletes its payload.
+	dash.AddMessage(&pubsub.Message{ID: "next"}, "orders")
+	entries, _ := os.ReadDir(spill.Dir())
+	if len(entries) != 0 {
+		t.Errorf("Expected the evicted payload deleted, found %d files", len(entries))
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/messages/big/data", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an evicted message, got %d", w.Code)
+	}
+}
+
+func TestRetention_SpillPerEntry(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	spill, err := retention.NewSpill(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create spill: %v", err)
+	}
+	dash.SetRetention(retention.Policy{MaxMessages: 2, MaxPayloadBytes: 4}, spill)
+
+	// Two entries share an ID; evicting the older must not delete the
+	// payload of the newer.
+	dash.AddMessage(&pubsub.Message{ID: "dup", Data: []byte("first payload")}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "dup", Data: []byte("second payload")}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "next"}, "orders")
+
+	data, err := dash.fullData(dash.GetMessageByID("dup"))
+	if err != nil || data != "second payload" {
+		t.Errorf("Expected the newer payload kept, got %q (%v)", data, err)
+	}
+	if entries, _ := os.ReadDir(spill.Dir()); len(entries) != 1 {
+		t.Errorf("Expected only the evicted entry's file deleted, found %d files", len(entries))
+	}
+}
+
+func TestHandleStats_History(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	dash.SetRetention(retention.Policy{MaxMessages: 1, MemoryBytes: 1 << 20}, nil)
+
+	dash.AddMessage(&pubsub.Message{ID: "m1"}, "orders")
+	dash.AddMessage(&pubsub.Message{ID: "m2"}, "orders")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	var stats DashboardStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Invalid stats: %v", err)
+	}
+	h := stats.History
+	if h.Messages != 1 || h.MaxMessages != 1 || h.MemoryBudget != 1<<20 || h.Evicted[evictCount] != 1 || h.Bytes == 0 {
+		t.Errorf("Unexpected history stats: %+v", h)
+	}
+}
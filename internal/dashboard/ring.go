@@ -0,0 +1,207 @@
+package dashboard
+
+// minRingSlots is the fewest slots a messageRing has.
+const minRingSlots = 16
+
+// messageRing holds the message history, oldest first, in a fixed set of
+// slots that are reused as messages are evicted, with an index from message
+// ID to slot. Evicting the oldest message only moves the head. Messages
+// evicted out of order (older than a pinned message, or by a per-topic
+// limit) leave holes, which are squeezed out when the slots run out. The
+// ring has twice as many slots as the history holds messages, so that
+// happens at most once per history's worth of messages.
+//
+// Stored messages are never changed in place; set stores a changed copy.
+// A snapshot of the pointers can therefore be read after the lock that
+// guards the ring is released.
+type messageRing struct {
+	slots []*MessageInfo
+	// spare is the slot array from before the last compaction, reused by
+	// the next one.
+	spare []*MessageInfo
+	// head is the slot of the oldest position. used counts positions from
+	// head, holes included; live counts messages.
+	head   int
+	used   int
+	live   int
+	pinned int
+	// index maps each ID to its newest message.
+	index map[string]ringEntry
+}
+
+// ringEntry locates the newest message with an ID and counts the messages
+// sharing that ID, which may be several (a message recorded again, or
+// imported twice).
+type ringEntry struct {
+	slot   int
+	copies int
+}
+
+// newMessageRing returns a ring sized for limit messages.
+func newMessageRing(limit int) *messageRing {
+	return &messageRing{
+		slots: make([]*MessageInfo, ringSlots(limit)),
+		index: make(map[string]ringEntry, limit),
+	}
+}
+
+// ringSlots returns the number of slots for a history of limit messages.
+func ringSlots(limit int) int {
+	return max(2*limit, minRingSlots)
+}
+
+// len returns the number of messages held.
+func (r *messageRing) len() int {
+	return r.live
+}
+
+// slot returns the slot of the position pos places after the head.
+func (r *messageRing) slot(pos int) int {
+	s := r.head + pos
+	if s >= len(r.slots) {
+		s -= len(r.slots)
+	}
+	return s
+}
+
+// push adds m as the newest message. When the slots run out, holes are
+// squeezed out, and only if there are none does the ring grow.
+func (r *messageRing) push(m *MessageInfo) {
+	if r.used == len(r.slots) {
+		n := len(r.slots)
+		if r.live == n {
+			n *= 2
+		}
+		r.compact(n)
+	}
+	s := r.slot(r.used)
+	r.slots[s] = m
+	r.used++
+	r.live++
+	if m.Pinned {
+		r.pinned++
+	}
+	r.index[m.ID] = ringEntry{slot: s, copies: r.index[m.ID].copies + 1}
+}
+
+// find returns the newest message with id and its slot, or nil.
+func (r *messageRing) find(id string) (int, *MessageInfo) {
+	e, ok := r.index[id]
+	if !ok {
+		return -1, nil
+	}
+	return e.slot, r.slots[e.slot]
+}
+
+// set replaces the message in slot s with m, a changed copy with the same
+// ID.
+func (r *messageRing) set(s int, m *MessageInfo) {
+	if r.slots[s].Pinned {
+		r.pinned--
+	}
+	if m.Pinned {
+		r.pinned++
+	}
+	r.slots[s] = m
+}
+
+// remove evicts the message in slot s. If it was the newest of several
+// with its ID, the index moves to the next newest.
+func (r *messageRing) remove(s int) {
+	m := r.slots[s]
+	r.slots[s] = nil
+	r.live--
+	if m.Pinned {
+		r.pinned--
+	}
+	switch e := r.index[m.ID]; {
+	case e.copies <= 1:
+		delete(r.index, m.ID)
+	case e.slot == s:
+		r.index[m.ID] = ringEntry{slot: r.newestSlot(m.ID), copies: e.copies - 1}
+	default:
+		e.copies--
+		r.index[m.ID] = e
+	}
+	for r.used > 0 && r.slots[r.head] == nil {
+		r.head = r.slot(1)
+		r.used--
+	}
+	if r.used == 0 {
+		r.head = 0
+	}
+}
+
+// newestSlot returns the slot of the newest message with id by scanning the
+// ring, or -1.
+func (r *messageRing) newestSlot(id string) int {
+	for pos := r.used - 1; pos >= 0; pos-- {
+		s := r.slot(pos)
+		if m := r.slots[s]; m != nil && m.ID == id {
+			return s
+		}
+	}
+	return -1
+}
+
+// newest returns the newest message, or nil if the ring is empty.
+func (r *messageRing) newest() *MessageInfo {
+	for pos := r.used - 1; pos >= 0; pos-- {
+		if m := r.slots[r.slot(pos)]; m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// each calls fn with each message and its slot, oldest first, until fn
+// returns false. fn must not change the ring.
+func (r *messageRing) each(fn func(s int, m *MessageInfo) bool) {
+	for pos := range r.used {
+		s := r.slot(pos)
+		if m := r.slots[s]; m != nil && !fn(s, m) {
+			return
+		}
+	}
+}
+
+// appendTo appends the messages to dst, oldest first.
+func (r *messageRing) appendTo(dst []*MessageInfo) []*MessageInfo {
+	r.each(func(_ int, m *MessageInfo) bool {
+		dst = append(dst, m)
+		return true
+	})
+	return dst
+}
+
+// resize gives the ring the slots for a history of limit messages, keeping
+// every message it holds.
+func (r *messageRing) resize(limit int) {
+	r.compact(max(ringSlots(limit), r.live))
+}
+
+// compact moves the messages, oldest first, to the start of n slots,
+// dropping the holes between them.
+func (r *messageRing) compact(n int) {
+	slots := r.spare
+	if len(slots) != n {
+		slots = make([]*MessageInfo, n)
+	}
+	i := 0
+	for pos := range r.used {
+		s := r.slot(pos)
+		m := r.slots[s]
+		if m == nil {
+			continue
+		}
+		slots[i] = m
+		if e := r.index[m.ID]; e.slot == s {
+			e.slot = i
+			r.index[m.ID] = e
+		}
+		i++
+	}
+	clear(r.slots)
+	r.spare = r.slots
+	r.slots, r.head, r.used = slots, 0, i
+}
@@ -0,0 +1,277 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+func ringIDs(r *messageRing) string {
+	var ids []string
+	for _, m := range r.appendTo(nil) {
+		ids = append(ids, m.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestMessageRing_Wraps(t *testing.T) {
+	r := newMessageRing(4)
+	for i := range 40 {
+		r.push(&MessageInfo{ID: fmt.Sprintf("m%d", i)})
+		if r.len() > 4 {
+			s, _ := r.find(fmt.Sprintf("m%d", i-4))
+			r.remove(s)
+		}
+	}
+	if got := ringIDs(r); got != "m36,m37,m38,m39" {
+		t.Errorf("Expected the newest four messages, got %s", got)
+	}
+	if len(r.slots) != ringSlots(4) {
+		t.Errorf("Expected the ring not to grow, got %d slots", len(r.slots))
+	}
+	if _, m := r.find("m35"); m != nil {
+		t.Errorf("Expected an evicted message out of the index, got %+v", m)
+	}
+	if r.newest().ID != "m39" {
+		t.Errorf("Expected m39 newest, got %s", r.newest().ID)
+	}
+}
+
+func TestMessageRing_Holes(t *testing.T) {
+	r := newMessageRing(8)
+	for i := range 16 {
+		r.push(&MessageInfo{ID: fmt.Sprintf("m%d", i), Pinned: i == 0})
+	}
+	// Drop every odd message, leaving holes behind the pinned head.
+	for i := 1; i < 16; i += 2 {
+		s, _ := r.find(fmt.Sprintf("m%d", i))
+		r.remove(s)
+	}
+	if r.len() != 8 || r.pinned != 1 {
+		t.Fatalf("Expected 8 messages with 1 pinned, got %d with %d", r.len(), r.pinned)
+	}
+
+	// The slots are all used, so the next push squeezes out the holes
+	// instead of growing.
+	r.push(&MessageInfo{ID: "m16"})
+	if len(r.slots) != 16 {
+		t.Errorf("Expected compaction instead of growth, got %d slots", len(r.slots))
+	}
+	if got := ringIDs(r); got != "m0,m2,m4,m6,m8,m10,m12,m14,m16" {
+		t.Errorf("Expected the order kept, got %s", got)
+	}
+	for _, id := range []string{"m0", "m8", "m16"} {
+		if _, m := r.find(id); m == nil || m.ID != id {
+			t.Errorf("Expected %s found after compaction, got %+v", id, m)
+		}
+	}
+
+	// With no holes left the ring grows.
+	for i := 17; i < 25; i++ {
+		r.push(&MessageInfo{ID: fmt.Sprintf("m%d", i)})
+	}
+	if len(r.slots) != 32 || r.len() != 17 {
+		t.Errorf("Expected the ring grown to 32 slots holding 17 messages, got %d holding %d", len(r.slots), r.len())
+	}
+}
+
+func TestMessageRing_DuplicateIDs(t *testing.T) {
+	r := newMessageRing(4)
+	r.push(&MessageInfo{ID: "dup", Data: "first"})
+	r.push(&MessageInfo{ID: "other"})
+	r.push(&MessageInfo{ID: "dup", Data: "second"})
+
+	s, m := r.find("dup")
+	if m.Data != "second" {
+		t.Errorf("Expected the newest duplicate, got %q", m.Data)
+	}
+
+	// Evicting the older duplicate keeps the newer one indexed.
+	other, _ := r.find("other")
+	r.remove(other - 1)
+	if _, m := r.find("dup"); m == nil || m.Data != "second" {
+		t.Errorf("Expected the newer duplicate still found, got %+v", m)
+	}
+
+	r.set(s, &MessageInfo{ID: "dup", Data: "second", Pinned: true})
+	if r.pinned != 1 {
+		t.Errorf("Expected 1 pinned message, got %d", r.pinned)
+	}
+	r.remove(s)
+	if _, m := r.find("dup"); m != nil || r.pinned != 0 {
+		t.Errorf("Expected dup gone and nothing pinned, got %+v with %d pinned", m, r.pinned)
+	}
+}
+
+func TestMessageRing_RemoveNewestDuplicate(t *testing.T) {
+	r := newMessageRing(4)
+	r.push(&MessageInfo{ID: "dup", Data: "first"})
+	r.push(&MessageInfo{ID: "dup", Data: "second"})
+	r.push(&MessageInfo{ID: "other"})
+	r.push(&MessageInfo{ID: "dup", Data: "third"})
+
+	// Removing the indexed copy falls back to the next newest one.
+	for _, want := range []string{"second", "first"} {
+		s, _ := r.find("dup")
+		r.remove(s)
+		if _, m := r.find("dup"); m == nil || m.Data != want {
+			t.Fatalf("Expected the %s copy found, got %+v", want, m)
+		}
+	}
+	s, _ := r.find("dup")
+	r.remove(s)
+	if _, m := r.find("dup"); m != nil {
+		t.Errorf("Expected no copy left, got %+v", m)
+	}
+	if got := ringIDs(r); got != "other" {
+		t.Errorf("Expected only other left, got %s", got)
+	}
+}
+
+func TestMessageRing_Resize(t *testing.T) {
+	r := newMessageRing(2)
+	for i := range 10 {
+		r.push(&MessageInfo{ID: fmt.Sprintf("m%d", i)})
+	}
+	r.resize(100)
+	if len(r.slots) != ringSlots(100) || ringIDs(r) != "m0,m1,m2,m3,m4,m5,m6,m7,m8,m9" {
+		t.Errorf("Expected every message kept in %d slots, got %d slots holding %s", ringSlots(100), len(r.slots), ringIDs(r))
+	}
+	// Shrinking never drops messages; the retention limits do that.
+	r.resize(1)
+	if r.len() != 10 || len(r.slots) < 10 {
+		t.Errorf("Expected 10 messages kept, got %d in %d slots", r.len(), len(r.slots))
+	}
+}
+
+func TestSnapshot_NotChangedByUpdates(t *testing.T) {
+	dash := New(nil, "test-project", logger.New())
+	dash.AddMessage(&pubsub.Message{ID: "m1"}, "orders")
+
+	before := dash.snapshot()
+	dash.RecordDelivery(&pubsub.Message{ID: "m1"}, "orders", "orders-sub")
+	if n := len(before[0].Lifecycle.Subscriptions); n != 0 {
+		t.Errorf("Expected the snapshot unchanged, got %d subscriptions", n)
+	}
+	if got := dash.GetMessageByID("m1"); len(got.Lifecycle.Subscriptions) != 1 {
+		t.Errorf("Expected the delivery recorded, got %+v", got.Lifecycle)
+	}
+}
+
+func newBenchmarkDashboard(b *testing.B) *Dashboard {
+	b.Helper()
+	dash := New(nil, "test-project", logger.New())
+	dash.maxMessages = 10000
+	dash.history.resize(dash.maxMessages)
+	return dash
+}
+
+func benchmarkMessages(n int) []*pubsub.Message {
+	msgs := make([]*pubsub.Message, n)
+	data := []byte(strings.Repeat("x", 256))
+	now := time.Now()
+	for i := range msgs {
+		msgs[i] = &pubsub.Message{
+			ID:          fmt.Sprintf("m%d", i),
+			Data:        data,
+			Attributes:  map[string]string{"type": "order"},
+			PublishTime: now,
+		}
+	}
+	return msgs
+}
+
+func reportThroughput(b *testing.B) {
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+}
+
+// BenchmarkAddMessage measures recording into a full history, so every
+// message evicts the oldest.
+func BenchmarkAddMessage(b *testing.B) {
+	dash := newBenchmarkDashboard(b)
+	msgs := benchmarkMessages(4 * dash.maxMessages)
+	for _, msg := range msgs[:dash.maxMessages] {
+		dash.AddMessage(msg, "orders")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		dash.AddMessage(msgs[i%len(msgs)], "orders")
+	}
+	reportThroughput(b)
+}
+
+// BenchmarkAddMessage_WithReaders records while other goroutines poll the
+// history the way the dashboard UI does.
+func BenchmarkAddMessage_WithReaders(b *testing.B) {
+	dash := newBenchmarkDashboard(b)
+	msgs := benchmarkMessages(4 * dash.maxMessages)
+	for _, msg := range msgs[:dash.maxMessages] {
+		dash.AddMessage(msg, "orders")
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := range 4 {
+		wg.Go(func() {
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if r == 0 {
+					_ = dash.GetMessages()
+				} else {
+					_ = dash.GetMessageByID(msgs[i%len(msgs)].ID)
+				}
+			}
+		})
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		dash.AddMessage(msgs[i%len(msgs)], "orders")
+	}
+	b.StopTimer()
+	close(stop)
+	wg.Wait()
+	reportThroughput(b)
+}
+
+func BenchmarkGetMessageByID(b *testing.B) {
+	dash := newBenchmarkDashboard(b)
+	msgs := benchmarkMessages(dash.maxMessages)
+	for _, msg := range msgs {
+		dash.AddMessage(msg, "orders")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		if dash.GetMessageByID(msgs[i%len(msgs)].ID) == nil {
+			b.Fatal("message not found")
+		}
+	}
+}
+
+func BenchmarkGetMessages(b *testing.B) {
+	dash := newBenchmarkDashboard(b)
+	for _, msg := range benchmarkMessages(dash.maxMessages) {
+		dash.AddMessage(msg, "orders")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if len(dash.GetMessages()) != dash.maxMessages {
+			b.Fatal("history not full")
+		}
+	}
+}
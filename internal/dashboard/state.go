@@ -0,0 +1,389 @@
+package dashboard
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// stateFormatVersion is bumped when the archive layout changes
+	// incompatibly; newer archives are rejected on import.
+	stateFormatVersion = 1
+	// maxArchiveFileBytes caps each file inside an imported tar.gz archive.
+	maxArchiveFileBytes = 512 << 20
+)
+
+// archiveFiles names the files of a tar.gz state archive, in write order.
+var archiveFiles = []string{
+	"manifest.json",
+	"schemas.json",
+	"topics.json",
+	"subscriptions.json",
+	"snapshots.json",
+	"messages.json",
+}
+
+// ExportState captures the topics, subscriptions, schemas, snapshots and
+// message history of projectID. Schemas and snapshots are omitted (with a
+// warning) when the backend does not implement them.
+func (d *Dashboard) ExportState(ctx context.Context, projectID string) (*StateArchive, error) {
+	archive := &StateArchive{
+		Version:       stateFormatVersion,
+		ProjectID:     projectID,
+		ExportedAt:    d.now().UTC(),
+		Topics:        []json.RawMessage{},
+		Subscriptions: []json.RawMessage{},
+		Schemas:       []json.RawMessage{},
+		Snapshots:     []json.RawMessage{},
+		Messages:      d.GetProjectMessages(projectID),
+	}
+	d.expandPayloads(archive.Messages)
+	project := fmt.Sprintf("projects/%s", projectID)
+
+	topics := d.client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{Project: project})
+	if err := collect(&archive.Topics, topics.Next); err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+
+	subs := d.client.SubscriptionAdminClient.ListSubscriptions(ctx, &pubsubpb.ListSubscriptionsRequest{Project: project})
+	if err := collect(&archive.Subscriptions, subs.Next); err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	if d.schemas != nil {
+		schemas := d.schemas.ListSchemas(ctx, &pubsubpb.ListSchemasRequest{
+			Parent: project,
+			View:   pubsubpb.SchemaView_FULL,
+		})
+		if err := collect(&archive.Schemas, schemas.Next); err != nil {
+			if !isUnimplemented(err) {
+				return nil, fmt.Errorf("failed to list schemas: %w", err)
+			}
+			d.log.Warn("Schemas not exported: not supported by the emulator")
+		}
+	}
+
+	snapshots := d.client.SubscriptionAdminClient.ListSnapshots(ctx, &pubsubpb.ListSnapshotsRequest{Project: project})
+	if err := collect(&archive.Snapshots, snapshots.Next); err != nil {
+		if !isUnimplemented(err) {
+			return nil, fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		d.log.Warn("Snapshots not exported: not supported by the emulator")
+	}
+
+	return archive, nil
+}
+
+// collect drains an admin API iterator, appending each resource in its
+// protobuf JSON form.
+func collect[T proto.Message](dst *[]json.RawMessage, next func() (T, error)) error {
+	for {
+		item, err := next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		raw, err := protojson.Marshal(item)
+		if err != nil {
+			return err
+		}
+		*dst = append(*dst, raw)
+	}
+}
+
+// ImportState recreates the resources in archive, creating schemas, topics,
+// subscriptions and snapshots in dependency order, then restores the
+// message history. Resource names are moved from the archive's project to
+// opts.ProjectID (or the dashboard's project). Resources that already exist
+// are skipped rather than overwritten, so importing twice is harmless.
+func (d *Dashboard) ImportState(ctx context.Context, archive *StateArchive, opts ImportOptions) (*ImportResult, error) {
+	if archive.Version > stateFormatVersion {
+		return nil, fmt.Errorf("archive version %d is newer than supported version %d", archive.Version, stateFormatVersion)
+	}
+
+	target := opts.ProjectID
+	if target == "" {
+		target = d.projectID
+	}
+	rename := func(name string) string {
+		return renameProject(name, archive.ProjectID, target)
+	}
+
+	res := &ImportResult{ProjectID: target, Skipped: []string{}, Errors: []string{}}
+	// record classifies the outcome of creating one resource.
+	record := func(kind, name string, err error, count *int) {
+		switch {
+		case err == nil:
+			*count++
+		case status.Code(err) == codes.AlreadyExists:
+			res.Skipped = append(res.Skipped, fmt.Sprintf("%s %s already exists", kind, name))
+		case isUnimplemented(err):
+			res.Skipped = append(res.Skipped, fmt.Sprintf("%s %s: not supported by the emulator", kind, name))
+		default:
+			res.Errors = append(res.Errors, fmt.Sprintf("%s %s: %v", kind, name, err))
+		}
+	}
+
+	for _, raw := range archive.Schemas {
+		schema := &pubsubpb.Schema{}
+		if err := protojson.Unmarshal(raw, schema); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("schema: invalid JSON: %v", err))
+			continue
+		}
+		if d.schemas == nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("schema %s: schema client not configured", extractID(schema.Name)))
+			continue
+		}
+		// Some backends add a revision instead of failing when the schema
+		// exists, so check first to keep imports idempotent.
+		schemaID := extractID(schema.Name)
+		if _, err := d.schemas.GetSchema(ctx, &pubsubpb.GetSchemaRequest{Name: rename(schema.Name)}); err == nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("schema %s already exists", schemaID))
+			continue
+		}
+		_, err := d.schemas.CreateSchema(ctx, &pubsubpb.CreateSchemaRequest{
+			Parent:   fmt.Sprintf("projects/%s", target),
+			SchemaId: schemaID,
+			Schema:   &pubsubpb.Schema{Type: schema.Type, Definition: schema.Definition},
+		})
+		record("schema", schemaID, err, &res.Schemas)
+	}
+
+	for _, raw := range archive.Topics {
+		topic := &pubsubpb.Topic{}
+		if err := protojson.Unmarshal(raw, topic); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("topic: invalid JSON: %v", err))
+			continue
+		}
+		topic.Name = rename(topic.Name)
+		if topic.SchemaSettings != nil {
+			topic.SchemaSettings.Schema = rename(topic.SchemaSettings.Schema)
+		}
+		topic.State = pubsubpb.Topic_STATE_UNSPECIFIED // output only
+		_, err := d.client.TopicAdminClient.CreateTopic(ctx, topic)
+		record("topic", extractID(topic.Name), err, &res.Topics)
+	}
+
+	// Subscriptions by topic, for choosing a source when recreating snapshots.
+	subsByTopic := make(map[string]string)
+	for _, raw := range archive.Subscriptions {
+		sub := &pubsubpb.Subscription{}
+		if err := protojson.Unmarshal(raw, sub); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("subscription: invalid JSON: %v", err))
+			continue
+		}
+		sub.Name = rename(sub.Name)
+		sub.Topic = rename(sub.Topic)
+		if sub.DeadLetterPolicy != nil {
+			sub.DeadLetterPolicy.DeadLetterTopic = rename(sub.DeadLetterPolicy.DeadLetterTopic)
+		}
+		// Output-only fields are rejected on create.
+		sub.State = pubsubpb.Subscription_STATE_UNSPECIFIED
+		sub.TopicMessageRetentionDuration = nil
+		sub.AnalyticsHubSubscriptionInfo = nil
+
+		_, err := d.client.SubscriptionAdminClient.CreateSubscription(ctx, sub)
+		record("subscription", extractID(sub.Name), err, &res.Subscriptions)
+		if err == nil || status.Code(err) == codes.AlreadyExists {
+			if _, ok := subsByTopic[sub.Topic]; !ok {
+				subsByTopic[sub.Topic] = sub.Name
+			}
+		}
+	}
+
+	// A snapshot's backlog cannot be transferred, so it is recreated from a
+	// subscription on the same topic and starts from that subscription's
+	// current state.
+	for _, raw := range archive.Snapshots {
+		snapshot := &pubsubpb.Snapshot{}
+		if err := protojson.Unmarshal(raw, snapshot); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("snapshot: invalid JSON: %v", err))
+			continue
+		}
+		name := rename(snapshot.Name)
+		source, ok := subsByTopic[rename(snapshot.Topic)]
+		if !ok {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("snapshot %s: no subscription on its topic", extractID(name)))
+			continue
+		}
+		_, err := d.client.SubscriptionAdminClient.CreateSnapshot(ctx, &pubsubpb.CreateSnapshotRequest{
+			Name:         name,
+			Subscription: source,
+			Labels:       snapshot.Labels,
+		})
+		record("snapshot", extractID(name), err, &res.Snapshots)
+	}
+
+	if !opts.SkipMessages {
+		res.Messages = d.restoreMessages(archive.Messages, archive.ProjectID, target)
+	}
+
+	d.log.With("project", target, "topics", res.Topics, "subscriptions", res.Subscriptions,
+		"schemas", res.Schemas, "snapshots", res.Snapshots, "messages", res.Messages,
+		"skipped", len(res.Skipped), "errors", len(res.Errors)).
+		Info("State imported")
+
+	return res, nil
+}
+
+// restoreMessages appends archived messages whose IDs are not already in the
+// history, oldest first, and returns how many were added. Messages of the
+// archive's project (or with no project) are moved to project to.
+func (d *Dashboard) restoreMessages(msgs []MessageInfo, from, to string) int {
+	// Large payloads are spilled before the history is locked.
+	for i := range msgs {
+		d.truncate(&msgs[i])
+	}
+
+	d.messagesMutex.Lock()
+	defer d.messagesMutex.Unlock()
+
+	seen := make(map[string]bool, len(msgs))
+	added := 0
+	for _, msg := range msgs {
+		if msg.ID == "" || seen[msg.ID] {
+			d.discardSpill(&msg)
+			continue
+		}
+		seen[msg.ID] = true
+		if _, known := d.history.find(msg.ID); known != nil {
+			d.discardSpill(&msg)
+			continue
+		}
+		if msg.Project == "" || msg.Project == from {
+			msg.Project = to
+		}
+		d.appendMessage(msg)
+		added++
+	}
+	return added
+}
+
+// renameProject moves a resource name such as projects/a/topics/t from
+// project from to project to. Names in other projects are left unchanged.
+func renameProject(name, from, to string) string {
+	prefix := "projects/" + from + "/"
+	if from == to || !strings.HasPrefix(name, prefix) {
+		return name
+	}
+	return "projects/" + to + "/" + strings.TrimPrefix(name, prefix)
+}
+
+// isUnimplemented reports whether err means the backend lacks the API.
+func isUnimplemented(err error) bool {
+	return status.Code(err) == codes.Unimplemented
+}
+
+// writeStateTarGz writes archive as a gzip-compressed tar with one JSON file
+// per section, which is easier to inspect and diff than a single document.
+func writeStateTarGz(w io.Writer, archive *StateArchive) error {
+	manifest := map[string]any{
+		"version":     archive.Version,
+		"project_id":  archive.ProjectID,
+		"exported_at": archive.ExportedAt,
+	}
+	sections := map[string]any{
+		"manifest.json":      manifest,
+		"schemas.json":       archive.Schemas,
+		"topics.json":        archive.Topics,
+		"subscriptions.json": archive.Subscriptions,
+		"snapshots.json":     archive.Snapshots,
+		"messages.json":      archive.Messages,
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range archiveFiles {
+		data, err := json.MarshalIndent(sections[name], "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: archive.ExportedAt,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readStateArchive decodes an archive in either format, detecting tar.gz by
+// the gzip magic bytes.
+func readStateArchive(r io.Reader) (*StateArchive, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		archive := &StateArchive{}
+		if err := json.NewDecoder(br).Decode(archive); err != nil {
+			return nil, fmt.Errorf("invalid JSON archive: %w", err)
+		}
+		if archive.Version == 0 {
+			return nil, fmt.Errorf("archive has no version")
+		}
+		return archive, nil
+	}
+
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip archive: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	archive := &StateArchive{}
+	targets := map[string]any{
+		"manifest.json":      archive,
+		"schemas.json":       &archive.Schemas,
+		"topics.json":        &archive.Topics,
+		"subscriptions.json": &archive.Subscriptions,
+		"snapshots.json":     &archive.Snapshots,
+		"messages.json":      &archive.Messages,
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+		target, ok := targets[hdr.Name]
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := json.NewDecoder(io.LimitReader(tr, maxArchiveFileBytes)).Decode(target); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", hdr.Name, err)
+		}
+	}
+	if archive.Version == 0 {
+		return nil, fmt.Errorf("archive has no manifest.json")
+	}
+	return archive, nil
+}
@@ -0,0 +1,327 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	vkit "cloud.google.com/go/pubsub/v2/apiv1"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// setupStateTest returns a dashboard with a schema client on a fresh fake
+// server for projectID.
+func setupStateTest(t *testing.T, projectID string) *Dashboard {
+	t.Helper()
+
+	srv := pstest.NewServer()
+	ctx := context.Background()
+	conn, err := grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	gcpClient, err := pubsub.NewClient(ctx, projectID, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	schemas, err := vkit.NewSchemaClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("Failed to create schema client: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = schemas.Close()
+		_ = gcpClient.Close()
+		_ = conn.Close()
+		_ = srv.Close()
+	})
+
+	dash := New(gcpClient, projectID, logger.New())
+	dash.SetSchemaClient(schemas)
+	return dash
+}
+
+// seedState creates a schema, two topics (one validated by the schema), a
+// subscription with a filter and dead-letter policy, and one message.
+func seedState(t *testing.T, dash *Dashboard) {
+	t.Helper()
+	ctx := context.Background()
+	project := "projects/" + dash.projectID
+
+	if _, err := dash.schemas.CreateSchema(ctx, &pubsubpb.CreateSchemaRequest{
+		Parent:   project,
+		SchemaId: "order-schema",
+		Schema: &pubsubpb.Schema{
+			Type:       pubsubpb.Schema_AVRO,
+			Definition: `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`,
+		},
+	}); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	if _, err := dash.client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{
+		Name:   project + "/topics/orders",
+		Labels: map[string]string{"team": "checkout"},
+		SchemaSettings: &pubsubpb.SchemaSettings{
+			Schema:   project + "/schemas/order-schema",
+			Encoding: pubsubpb.Encoding_JSON,
+		},
+	}); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+	if _, err := dash.client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: project + "/topics/orders-dlq"}); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+	if _, err := dash.client.SubscriptionAdminClient.CreateSubscription(ctx, &pubsubpb.Subscription{
+		Name:                  project + "/subscriptions/orders-sub",
+		Topic:                 project + "/topics/orders",
+		AckDeadlineSeconds:    42,
+		Filter:                `attributes.region = "eu"`,
+		EnableMessageOrdering: true,
+		DeadLetterPolicy: &pubsubpb.DeadLetterPolicy{
+			DeadLetterTopic:     project + "/topics/orders-dlq",
+			MaxDeliveryAttempts: 5,
+		},
+	}); err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+
+	dash.AddMessage(&pubsub.Message{
+		ID:          "msg-1",
+		Data:        []byte(`{"id":"1"}`),
+		Attributes:  map[string]string{"region": "eu"},
+		PublishTime: time.Now(),
+	}, "orders")
+}
+
+func TestExportImportState_RoundTrip(t *testing.T) {
+	src := setupStateTest(t, "source-project")
+	seedState(t, src)
+
+	pinned, tags := true, []string{"golden"}
+	if _, err := src.annotateMessage("source-project", "msg-1", AnnotateRequest{Pinned: &pinned, Tags: &tags}); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := src.ExportState(context.Background(), "source-project")
+	if err != nil {
+		t.Fatalf("ExportState failed: %v", err)
+	}
+	if len(archive.Topics) != 2 || len(archive.Subscriptions) != 1 || len(archive.Schemas) != 1 || len(archive.Messages) != 1 {
+		t.Fatalf("Unexpected archive contents: %d topics, %d subscriptions, %d schemas, %d messages",
+			len(archive.Topics), len(archive.Subscriptions), len(archive.Schemas), len(archive.Messages))
+	}
+
+	// Round-trip through the serialised form, as a user sharing a file would.
+	data, err := json.Marshal(archive)
+	if err != nil {
+		t.Fatalf("Failed to marshal archive: %v", err)
+	}
+	decoded, err := readStateArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("readStateArchive failed: %v", err)
+	}
+
+	dst := setupStateTest(t, "target-project")
+	res, err := dst.ImportState(context.Background(), decoded, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportState failed: %v", err)
+	}
+	if len(res.Errors) != 0 {
+		t.Fatalf("Expected no import errors, got %v", res.Errors)
+	}
+	if res.ProjectID != "target-project" || res.Schemas != 1 || res.Topics != 2 || res.Subscriptions != 1 || res.Messages != 1 {
+		t.Errorf("Unexpected import result: %+v", res)
+	}
+
+	ctx := context.Background()
+	topic, err := dst.client.TopicAdminClient.GetTopic(ctx, &pubsubpb.GetTopicRequest{Topic: "projects/target-project/topics/orders"})
+	if err != nil {
+		t.Fatalf("Expected imported topic: %v", err)
+	}
+	if topic.Labels["team"] != "checkout" || topic.SchemaSettings.GetSchema() != "projects/target-project/schemas/order-schema" {
+		t.Errorf("Expected topic settings to be preserved and renamed, got %v", topic)
+	}
+
+	sub, err := dst.client.SubscriptionAdminClient.GetSubscription(ctx, &pubsubpb.GetSubscriptionRequest{
+		Subscription: "projects/target-project/subscriptions/orders-sub",
+	})
+	if err != nil {
+		t.Fatalf("Expected imported subscription: %v", err)
+	}
+	if sub.AckDeadlineSeconds != 42 || sub.Filter != `attributes.region = "eu"` || !sub.EnableMessageOrdering {
+		t.Errorf("Expected subscription settings to be preserved, got %v", sub)
+	}
+	if sub.DeadLetterPolicy.GetDeadLetterTopic() != "projects/target-project/topics/orders-dlq" || sub.DeadLetterPolicy.GetMaxDeliveryAttempts() != 5 {
+		t.Errorf("Expected dead-letter policy to be renamed, got %v", sub.DeadLetterPolicy)
+	}
+
+	if msg := dst.GetMessageByID("msg-1"); msg == nil || msg.Attributes["region"] != "eu" || !msg.Pinned || len(msg.Tags) != 1 {
+		t.Errorf("Expected message history to be restored, got %v", msg)
+	}
+
+	// Importing again creates nothing and reports everything as skipped.
+	res, err = dst.ImportState(ctx, decoded, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Second ImportState failed: %v", err)
+	}
+	if res.Topics+res.Subscriptions+res.Schemas+res.Messages != 0 || len(res.Skipped) != 4 {
+		t.Errorf("Expected second import to skip everything, got %+v", res)
+	}
+}
+
+func TestImportState_ProjectAndSkipMessages(t *testing.T) {
+	src := setupStateTest(t, "source-project")
+	seedState(t, src)
+	archive, err := src.ExportState(context.Background(), "source-project")
+	if err != nil {
+		t.Fatalf("ExportState failed: %v", err)
+	}
+
+	dst := setupStateTest(t, "target-project")
+	res, err := dst.ImportState(context.Background(), archive, ImportOptions{SkipMessages: true, ProjectID: "renamed"})
+	if err != nil {
+		t.Fatalf("ImportState failed: %v", err)
+	}
+	if res.ProjectID != "renamed" || res.Messages != 0 || len(dst.GetMessages()) != 0 {
+		t.Errorf("Expected messages to be skipped, got %+v", res)
+	}
+	if _, err := dst.client.TopicAdminClient.GetTopic(context.Background(), &pubsubpb.GetTopicRequest{
+		Topic: "projects/renamed/topics/orders",
+	}); err != nil {
+		t.Errorf("Expected topic under renamed project: %v", err)
+	}
+}
+
+func TestImportState_RejectsNewerVersion(t *testing.T) {
+	dash := setupStateTest(t, "test-project")
+	if _, err := dash.ImportState(context.Background(), &StateArchive{Version: stateFormatVersion + 1}, ImportOptions{}); err == nil {
+		t.Error("Expected error for a newer archive version")
+	}
+}
+
+func TestRenameProject(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"projects/a/topics/t", "projects/b/topics/t"},
+		{"projects/other/topics/t", "projects/other/topics/t"},
+		{"projects/ab/topics/t", "projects/ab/topics/t"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := renameProject(tt.name, "a", "b"); got != tt.want {
+			t.Errorf("renameProject(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandleExportImport_TarGz(t *testing.T) {
+	src := setupStateTest(t, "source-project")
+	seedState(t, src)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export?format=tar.gz", nil)
+	w := httptest.NewRecorder()
+	src.handleExport(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/gzip" {
+		t.Errorf("Expected gzip content type, got %s", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, ".tar.gz") {
+		t.Errorf("Expected tar.gz attachment, got %s", cd)
+	}
+
+	dst := setupStateTest(t, "target-project")
+	// The target project need not be one the dashboard is configured with.
+	req = httptest.NewRequest(http.MethodPost, "/api/import?skip_messages=true&project=staging", bytes.NewReader(w.Body.Bytes()))
+	req.Header.Set("Content-Type", "application/gzip")
+	w = httptest.NewRecorder()
+	dst.handleImport(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var res ImportResult
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if res.ProjectID != "staging" || res.Topics != 2 || res.Subscriptions != 1 || res.Schemas != 1 || res.Messages != 0 {
+		t.Errorf("Unexpected import result: %+v", res)
+	}
+}
+
+func TestHandleImport_NewProjectIsServed(t *testing.T) {
+	src := setupStateTest(t, "source-project")
+	seedState(t, src)
+	w := httptest.NewRecorder()
+	src.handleExport(w, httptest.NewRequest(http.MethodGet, "/api/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	dst := setupStateTest(t, "target-project")
+	mux := http.NewServeMux()
+	dst.RegisterRoutes(mux)
+	req := httptest.NewRequest(http.MethodPost, "/api/import?project=staging", bytes.NewReader(w.Body.Bytes()))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/messages?project=staging", nil))
+	var messages []MessageInfo
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the imported project served, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := json.NewDecoder(w.Body).Decode(&messages); err != nil || len(messages) != 1 || messages[0].ID != "msg-1" {
+		t.Errorf("Expected the imported message, got %+v (%v)", messages, err)
+	}
+	if !slices.Contains(dst.Projects(), "staging") {
+		t.Errorf("Expected staging listed, got %v", dst.Projects())
+	}
+}
+
+func TestHandleExportImport_Errors(t *testing.T) {
+	dash := setupStateTest(t, "test-project")
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		body    string
+		want    int
+	}{
+		{"export wrong method", dash.handleExport, http.MethodPost, "/api/export", "", http.StatusMethodNotAllowed},
+		{"export bad format", dash.handleExport, http.MethodGet, "/api/export?format=zip", "", http.StatusBadRequest},
+		{"import wrong method", dash.handleImport, http.MethodGet, "/api/import", "", http.StatusMethodNotAllowed},
+		{"import invalid body", dash.handleImport, http.MethodPost, "/api/import", "not json", http.StatusBadRequest},
+		{"import missing version", dash.handleImport, http.MethodPost, "/api/import", `{"topics":[]}`, http.StatusBadRequest},
+		{"import invalid project", dash.handleImport, http.MethodPost, "/api/import?project=1bad", `{"version":1}`, http.StatusBadRequest},
+		{"import truncated gzip", dash.handleImport, http.MethodPost, "/api/import", "\x1f\x8bgarbage", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
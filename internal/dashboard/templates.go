@@ -0,0 +1,155 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/templates"
+)
+
+// SetTemplates enables the /api/templates routes, which manage the publish
+// templates /api/publish can render. Without it they return 404.
+func (d *Dashboard) SetTemplates(store *templates.Store) {
+	d.templates = store
+}
+
+// handleTemplates lists (GET) or creates (POST) publish templates
+func (d *Dashboard) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	if d.templates == nil {
+		http.Error(w, "Templates are disabled", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		d.writeTemplateResponse(w, http.StatusOK, d.templates.List())
+	case http.MethodPost:
+		t, ok := decodeTemplate(w, r)
+		if !ok {
+			return
+		}
+		err := d.templates.Create(t)
+		if errors.Is(err, templates.ErrExists) {
+			http.Error(w, "Template already exists", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Invalid template: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		d.log.With("template", t.Name, "topic_id", t.Topic).Info("Template created")
+		d.writeTemplateResponse(w, http.StatusCreated, t)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTemplate reads (GET), creates or replaces (PUT) or deletes (DELETE)
+// one publish template
+func (d *Dashboard) handleTemplate(w http.ResponseWriter, r *http.Request) {
+	if d.templates == nil {
+		http.Error(w, "Templates are disabled", http.StatusNotFound)
+		return
+	}
+
+	name := r.PathValue("name")
+	switch r.Method {
+	case http.MethodGet:
+		t, ok := d.templates.Get(name)
+		if !ok {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		d.writeTemplateResponse(w, http.StatusOK, t)
+	case http.MethodPut:
+		t, ok := decodeTemplate(w, r)
+		if !ok {
+			return
+		}
+		if t.Name == "" {
+			t.Name = name
+		}
+		if t.Name != name {
+			http.Error(w, "Template name does not match the URL", http.StatusBadRequest)
+			return
+		}
+		added, err := d.templates.Put(t)
+		if err != nil {
+			http.Error(w, "Invalid template: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		d.log.With("template", t.Name, "topic_id", t.Topic).Info("Template saved")
+		status := http.StatusOK
+		if added {
+			status = http.StatusCreated
+		}
+		d.writeTemplateResponse(w, status, t)
+	case http.MethodDelete:
+		if !d.templates.Delete(name) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		d.log.With("template", name).Info("Template deleted")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeTemplate reads a template from the request body, writing a 400
+// response and returning false if it is malformed or names an invalid topic.
+func decodeTemplate(w http.ResponseWriter, r *http.Request) (templates.Template, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPublishBodyBytes)
+
+	var t templates.Template
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return t, false
+	}
+	if len(t.Data) > maxPublishDataBytes {
+		http.Error(w, "Template data too large (max 10MB)", http.StatusBadRequest)
+		return t, false
+	}
+	if t.Topic != "" && !validateResourceID(w, "Topic ID", t.Topic) {
+		return t, false
+	}
+	return t, true
+}
+
+func (d *Dashboard) writeTemplateResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		d.log.Error("Failed to encode template response: %v", err)
+	}
+}
+
+// applyTemplate fills in req from the template it names, writing an error
+// response and returning false if templates are disabled or it is missing.
+func (d *Dashboard) applyTemplate(w http.ResponseWriter, req *PublishRequest) bool {
+	if d.templates == nil {
+		http.Error(w, "Templates are disabled", http.StatusNotFound)
+		return false
+	}
+	msg, err := d.templates.Render(req.Template, d.now())
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return false
+	}
+	if req.TopicID == "" {
+		req.TopicID = msg.Topic
+	}
+	if req.Data == "" {
+		req.Data = msg.Data
+	}
+	if len(req.Attributes) > 0 {
+		if msg.Attributes == nil {
+			msg.Attributes = make(map[string]string, len(req.Attributes))
+		}
+		maps.Copy(msg.Attributes, req.Attributes)
+	}
+	req.Attributes = msg.Attributes
+	return true
+}
@@ -0,0 +1,150 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/templates"
+)
+
+func TestHandleTemplates_Disabled(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	for _, target := range []string{"/api/templates", "/api/templates/order"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404 without a store, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestHandleTemplates(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	dash.SetTemplates(templates.NewStore())
+
+	mux := http.NewServeMux()
+	dash.RegisterRoutes(mux)
+	do := func(method, target string, body any) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewReader(data)))
+		return w
+	}
+
+	order := templates.Template{Name: "order", Topic: "orders", Data: `{"id":"{{uuid}}"}`}
+	if w := do(http.MethodPost, "/api/templates", order); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/templates", order); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate, got %d", w.Code)
+	}
+	for _, bad := range []templates.Template{
+		{Name: "bad", Topic: "orders", Data: "{{nope}}"},
+		{Name: "bad", Topic: "<script>", Data: "x"},
+		{Name: "bad name", Topic: "orders", Data: "x"},
+	} {
+		if w := do(http.MethodPost, "/api/templates", bad); w.Code != http.StatusBadRequest {
+			t.Errorf("%+v: expected status 400, got %d", bad, w.Code)
+		}
+	}
+
+	// PUT takes the name from the URL and reports whether it created.
+	if w := do(http.MethodPut, "/api/templates/audit", templates.Template{Topic: "audit", Data: "x"}); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 for a new template, got %d: %s", w.Code, w.Body.String())
+	}
+	order.Data = "updated"
+	if w := do(http.MethodPut, "/api/templates/order", order); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a replaced template, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/templates/other", order); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a name mismatch, got %d", w.Code)
+	}
+
+	w := do(http.MethodGet, "/api/templates/order", nil)
+	var got templates.Template
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil || got.Data != "updated" {
+		t.Errorf("Expected the updated template, got %+v (%v)", got, err)
+	}
+	w = do(http.MethodGet, "/api/templates", nil)
+	var list []templates.Template
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil || len(list) != 2 {
+		t.Errorf("Expected 2 templates, got %+v (%v)", list, err)
+	}
+
+	if w := do(http.MethodDelete, "/api/templates/audit", nil); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/templates/audit", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", w.Code)
+	}
+	if w := do(http.MethodPatch, "/api/templates/order", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandlePublish_Template(t *testing.T) {
+	dash, cleanup := setupHandlerTest(t)
+	defer cleanup()
+	ctx := context.Background()
+	for _, id := range []string{"orders", "audit"} {
+		if _, err := dash.client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: "projects/test-project/topics/" + id}); err != nil {
+			t.Fatalf("Failed to create topic: %v", err)
+		}
+	}
+
+	publish := func(req PublishRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		dash.handlePublish(w, httptest.NewRequest(http.MethodPost, "/api/publish", bytes.NewReader(body)))
+		return w
+	}
+	if w := publish(PublishRequest{Template: "order"}); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without a store, got %d", w.Code)
+	}
+
+	store := templates.NewStore()
+	dash.SetTemplates(store)
+	if err := store.Create(templates.Template{
+		Name:       "order",
+		Topic:      "orders",
+		Data:       `{"seq":{{seq}}}`,
+		Attributes: map[string]string{"type": "order", "n": "{{seq}}"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := publish(PublishRequest{Template: "missing"}); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing template, got %d", w.Code)
+	}
+	if w := publish(PublishRequest{Template: "order"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	// Request fields override the template's.
+	if w := publish(PublishRequest{Template: "order", TopicID: "audit", Attributes: map[string]string{"type": "override"}}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	msgs := dash.GetMessages()
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 recorded messages, got %d", len(msgs))
+	}
+	if msgs[0].Topic != "orders" || msgs[0].Data != `{"seq":1}` || msgs[0].Attributes["type"] != "order" || msgs[0].Attributes["n"] != "1" {
+		t.Errorf("Unexpected first message: %+v", msgs[0])
+	}
+	if msgs[1].Topic != "audit" || msgs[1].Data != `{"seq":2}` || msgs[1].Attributes["type"] != "override" || msgs[1].Attributes["n"] != "2" {
+		t.Errorf("Unexpected second message: %+v", msgs[1])
+	}
+}
@@ -1,7 +1,10 @@
 package dashboard
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/expect"
 )
 
 // MessageInfo represents a Pub/Sub message in the dashboard
@@ -11,7 +14,58 @@ type MessageInfo struct {
 	Attributes  map[string]string `json:"attributes"`
 	PublishTime time.Time         `json:"publish_time"`
 	Topic       string            `json:"topic"`
+	Project     string            `json:"project"`
 	Received    time.Time         `json:"received"`
+	Lifecycle   MessageLifecycle  `json:"lifecycle"`
+	// ReplayOf is the ID of the message this one replays, if any.
+	ReplayOf string `json:"replay_of,omitempty"`
+	// Pinned messages are kept when the history is full; Tags and Note
+	// are free-form annotations. All three are set through
+	// PATCH /api/messages/{id}.
+	Pinned bool     `json:"pinned,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Note   string   `json:"note,omitempty"`
+	// Size is the payload size in bytes. Truncated payloads keep only a
+	// prefix in Data; the whole payload is served by
+	// GET /api/messages/{id}/data.
+	Size      int  `json:"size"`
+	Truncated bool `json:"truncated,omitempty"`
+	// spillKey names the spill file holding a truncated payload. Each
+	// recorded entry gets its own, as the history may hold several
+	// entries with the same ID.
+	spillKey string
+}
+
+// LifecycleEventType names a step in a message's lifecycle
+type LifecycleEventType string
+
+// Lifecycle event types recorded for each message
+const (
+	EventPublished    LifecycleEventType = "published"
+	EventDelivered    LifecycleEventType = "delivered"
+	EventAcked        LifecycleEventType = "acked"
+	EventNacked       LifecycleEventType = "nacked"
+	EventExpired      LifecycleEventType = "expired"
+	EventDeadLettered LifecycleEventType = "dead_lettered"
+)
+
+// LifecycleEvent is a single timestamped step in a message's lifecycle
+type LifecycleEvent struct {
+	Type         LifecycleEventType `json:"type"`
+	Subscription string             `json:"subscription,omitempty"`
+	Attempt      int                `json:"attempt,omitempty"`
+	Time         time.Time          `json:"time"`
+}
+
+// MessageLifecycle summarises how a message moved through the emulator:
+// when it was published, which subscriptions it reached, how many delivery
+// attempts were made and whether it ended up dead-lettered.
+type MessageLifecycle struct {
+	PublishedAt      time.Time        `json:"published_at"`
+	Subscriptions    []string         `json:"subscriptions"`
+	DeliveryAttempts int              `json:"delivery_attempts"`
+	DeadLettered     bool             `json:"dead_lettered"`
+	Events           []LifecycleEvent `json:"events"`
 }
 
 // TopicInfo represents topic information
@@ -40,13 +94,55 @@ type DashboardStats struct {
 	LastMessageTime  *time.Time         `json:"last_message_time,omitempty"`
 	TopicList        []string           `json:"topic_list"`
 	SubscriptionList []string           `json:"subscription_list"`
+	Project          string             `json:"project"`
+	Projects         []string           `json:"projects"`
+	History          HistoryStats       `json:"history"`
 }
 
-// PublishRequest represents a request to publish a message
+// HistoryStats reports how full the message history is and how many
+// messages each retention limit has evicted. Totals cover every project;
+// Topics lists the stats project's topics.
+type HistoryStats struct {
+	Messages        int   `json:"messages"`
+	MaxMessages     int   `json:"max_messages"`
+	Bytes           int64 `json:"bytes"`
+	MemoryBudget    int64 `json:"memory_budget,omitempty"`
+	MaxPayloadBytes int   `json:"max_payload_bytes,omitempty"`
+	// Evicted counts evictions by limit: count and memory for the global
+	// limits, topic_count, topic_bytes and age for per-topic ones.
+	Evicted map[string]int64 `json:"evicted"`
+	Topics  []TopicHistory   `json:"topics"`
+}
+
+// TopicHistory reports one topic's share of the message history and its
+// retention limits; zero limits are unlimited.
+type TopicHistory struct {
+	Topic         string  `json:"topic"`
+	Messages      int     `json:"messages"`
+	Bytes         int64   `json:"bytes"`
+	Truncated     int     `json:"truncated"`
+	Evicted       int64   `json:"evicted"`
+	MaxCount      int     `json:"max_count,omitempty"`
+	MaxAgeSeconds float64 `json:"max_age_seconds,omitempty"`
+	MaxBytes      int64   `json:"max_bytes,omitempty"`
+}
+
+// PublishRequest represents a request to publish a message. With Template
+// set, the named template is rendered: its topic is used unless TopicID is
+// set, its data unless Data is set, and Attributes override its attributes.
 type PublishRequest struct {
 	TopicID    string            `json:"topic_id"`
 	Data       string            `json:"data"`
 	Attributes map[string]string `json:"attributes,omitempty"`
+	Template   string            `json:"template,omitempty"`
+}
+
+// AnnotateRequest changes a message's annotations; fields left out are
+// unchanged. Tags replaces every tag, and an empty Note removes the note.
+type AnnotateRequest struct {
+	Pinned *bool     `json:"pinned,omitempty"`
+	Tags   *[]string `json:"tags,omitempty"`
+	Note   *string   `json:"note,omitempty"`
 }
 
 // CreateTopicRequest represents a request to create a topic
@@ -59,4 +155,103 @@ type CreateSubscriptionRequest struct {
 	SubscriptionID     string `json:"subscription_id"`
 	TopicID            string `json:"topic_id"`
 	AckDeadlineSeconds int32  `json:"ack_deadline_seconds"`
+	// TopicProject attaches the subscription to a topic in another served
+	// project; empty means the subscription's own project.
+	TopicProject string `json:"topic_project,omitempty"`
+}
+
+// ChaosRuleRequest represents a request to add a fault injection rule.
+// Exactly one of TopicID and SubscriptionID is set; both are IDs in the
+// request's project.
+type ChaosRuleRequest struct {
+	Action         string  `json:"action"`
+	TopicID        string  `json:"topic_id,omitempty"`
+	SubscriptionID string  `json:"subscription_id,omitempty"`
+	Code           string  `json:"code,omitempty"`
+	LatencyMs      int64   `json:"latency_ms,omitempty"`
+	Probability    float64 `json:"probability,omitempty"`
+	TTLSeconds     int     `json:"ttl_seconds,omitempty"`
+}
+
+// LoadGenRequest starts a synthetic load job publishing to TopicID in the
+// request's project. Template and Attributes values may contain placeholders
+// such as {{seq}}; Duration is a duration such as "30s".
+type LoadGenRequest struct {
+	TopicID     string            `json:"topic_id"`
+	Rate        float64           `json:"rate,omitempty"`
+	Concurrency int               `json:"concurrency,omitempty"`
+	Duration    string            `json:"duration,omitempty"`
+	Count       int64             `json:"count,omitempty"`
+	Template    string            `json:"template,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Seed        uint64            `json:"seed,omitempty"`
+}
+
+// ExpectationRequest registers an expectation on the request's project:
+// Count messages (default 1) on TopicID (any topic if empty) whose
+// attributes and JSON payload fields satisfy the predicates, within Timeout
+// (a duration such as "5s"). Attributes and Fields are either arrays of
+// {"key", "op", "value"} predicates or objects of values that must be equal.
+type ExpectationRequest struct {
+	TopicID    string            `json:"topic_id,omitempty"`
+	Attributes expect.Predicates `json:"attributes,omitempty"`
+	Fields     expect.Predicates `json:"fields,omitempty"`
+	Count      int               `json:"count,omitempty"`
+	Timeout    string            `json:"timeout,omitempty"`
+}
+
+// ClockState reports the clock used for recorded times and, in test mode,
+// the message ID sequence
+type ClockState struct {
+	// Mode is "system" (wall clock) or "manual" (test mode).
+	Mode          string    `json:"mode"`
+	Now           time.Time `json:"now"`
+	Seed          int64     `json:"seed,omitempty"`
+	NextMessageID string    `json:"next_message_id,omitempty"`
+}
+
+// ClockRequest changes the test-mode clock. Reset restores the start time
+// and restarts message IDs; Set moves the clock to a time; Advance moves it
+// forward by a duration such as "90s". Reset or Set is applied before
+// Advance.
+type ClockRequest struct {
+	Set     *time.Time `json:"set,omitempty"`
+	Advance string     `json:"advance,omitempty"`
+	Reset   bool       `json:"reset,omitempty"`
+}
+
+// StateArchive is a portable copy of the emulator's state, produced by
+// /api/export and consumed by /api/import. Resources are stored in their
+// protobuf JSON form so every setting survives the round trip.
+type StateArchive struct {
+	Version       int               `json:"version"`
+	ProjectID     string            `json:"project_id"`
+	ExportedAt    time.Time         `json:"exported_at"`
+	Topics        []json.RawMessage `json:"topics"`
+	Subscriptions []json.RawMessage `json:"subscriptions"`
+	Schemas       []json.RawMessage `json:"schemas"`
+	Snapshots     []json.RawMessage `json:"snapshots"`
+	Messages      []MessageInfo     `json:"messages"`
+}
+
+// ImportOptions controls how a StateArchive is applied
+type ImportOptions struct {
+	// SkipMessages leaves the dashboard's message history untouched.
+	SkipMessages bool
+	// ProjectID is the project resources are recreated in; empty means the
+	// dashboard's own project.
+	ProjectID string
+}
+
+// ImportResult reports what an import created. Resources that already
+// existed are listed in Skipped; failures in Errors.
+type ImportResult struct {
+	ProjectID     string   `json:"project_id"`
+	Schemas       int      `json:"schemas"`
+	Topics        int      `json:"topics"`
+	Subscriptions int      `json:"subscriptions"`
+	Snapshots     int      `json:"snapshots"`
+	Messages      int      `json:"messages"`
+	Skipped       []string `json:"skipped"`
+	Errors        []string `json:"errors"`
 }
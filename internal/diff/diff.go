@@ -0,0 +1,306 @@
+// Package diff compares messages: the JSON structure of their payloads,
+// their attributes, and whole message histories grouped by topic.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// Kind says how a value changed from A to B.
+type Kind string
+
+// Change kinds.
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// Change is one difference between A and B.
+type Change struct {
+	// Path locates the value: an attribute name, or a dot-separated path
+	// into the JSON payload such as "order.items.0.sku" (the same form
+	// expectations use). It is empty for the payload as a whole.
+	Path   string `json:"path"`
+	Kind   Kind   `json:"kind"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Payload format of a data diff.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Payload is the difference between two message payloads.
+type Payload struct {
+	// Format is FormatJSON when both payloads are JSON and were compared
+	// structurally, and FormatText when they were compared as strings.
+	Format  string   `json:"format"`
+	Changes []Change `json:"changes"`
+}
+
+// Data compares two payloads, structurally when both are JSON. Whitespace
+// and key order do not count as changes.
+func Data(a, b string) Payload {
+	va, errA := decode(a)
+	vb, errB := decode(b)
+	if errA != nil || errB != nil {
+		p := Payload{Format: FormatText, Changes: []Change{}}
+		if a != b {
+			p.Changes = append(p.Changes, Change{Kind: Changed, Before: a, After: b})
+		}
+		return p
+	}
+	return Payload{Format: FormatJSON, Changes: Values(va, vb)}
+}
+
+// decode parses a JSON payload, keeping numbers as written so that large
+// integers compare exactly.
+func decode(s string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// Values compares two decoded JSON values. Objects are compared key by key
+// in sorted order and arrays element by element.
+func Values(a, b any) []Change {
+	changes := []Change{}
+	walk("", a, b, &changes)
+	return changes
+}
+
+func walk(path string, a, b any, changes *[]Change) {
+	switch va := a.(type) {
+	case map[string]any:
+		vb, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		for _, k := range keys(va, vb) {
+			child := join(path, k)
+			x, inA := va[k]
+			y, inB := vb[k]
+			switch {
+			case !inB:
+				*changes = append(*changes, Change{Path: child, Kind: Removed, Before: x})
+			case !inA:
+				*changes = append(*changes, Change{Path: child, Kind: Added, After: y})
+			default:
+				walk(child, x, y, changes)
+			}
+		}
+		return
+	case []any:
+		vb, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := range max(len(va), len(vb)) {
+			child := join(path, strconv.Itoa(i))
+			switch {
+			case i >= len(vb):
+				*changes = append(*changes, Change{Path: child, Kind: Removed, Before: va[i]})
+			case i >= len(va):
+				*changes = append(*changes, Change{Path: child, Kind: Added, After: vb[i]})
+			default:
+				walk(child, va[i], vb[i], changes)
+			}
+		}
+		return
+	}
+	if !equal(a, b) {
+		*changes = append(*changes, Change{Path: path, Kind: Changed, Before: a, After: b})
+	}
+}
+
+// equal compares scalars and values of different JSON types.
+func equal(a, b any) bool {
+	switch a.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	switch b.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return a == b
+}
+
+// keys returns the keys of a and b, sorted.
+func keys[V any](a, b map[string]V) []string {
+	ks := slices.Collect(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			ks = append(ks, k)
+		}
+	}
+	slices.Sort(ks)
+	return ks
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Attributes compares two attribute maps, in key order.
+func Attributes(a, b map[string]string) []Change {
+	changes := []Change{}
+	for _, k := range keys(a, b) {
+		x, inA := a[k]
+		y, inB := b[k]
+		switch {
+		case !inB:
+			changes = append(changes, Change{Path: k, Kind: Removed, Before: x})
+		case !inA:
+			changes = append(changes, Change{Path: k, Kind: Added, After: y})
+		case x != y:
+			changes = append(changes, Change{Path: k, Kind: Changed, Before: x, After: y})
+		}
+	}
+	return changes
+}
+
+// Message is a message to compare.
+type Message struct {
+	ID         string            `json:"id"`
+	Topic      string            `json:"topic"`
+	Data       string            `json:"data"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// MessageDiff is the difference between messages A and B.
+type MessageDiff struct {
+	A         string `json:"a"`
+	B         string `json:"b"`
+	Identical bool   `json:"identical"`
+	// Topic is set when the messages were published to different topics.
+	Topic      *Change  `json:"topic,omitempty"`
+	Data       Payload  `json:"data"`
+	Attributes []Change `json:"attributes"`
+}
+
+// Messages compares two messages. IDs and publish times are expected to
+// differ and are not compared.
+func Messages(a, b Message) MessageDiff {
+	d := MessageDiff{
+		A:          a.ID,
+		B:          b.ID,
+		Data:       Data(a.Data, b.Data),
+		Attributes: Attributes(a.Attributes, b.Attributes),
+	}
+	if a.Topic != b.Topic {
+		d.Topic = &Change{Path: "topic", Kind: Changed, Before: a.Topic, After: b.Topic}
+	}
+	d.Identical = d.Topic == nil && len(d.Data.Changes) == 0 && len(d.Attributes) == 0
+	return d
+}
+
+// TopicDiff compares the messages one topic received in two histories.
+type TopicDiff struct {
+	Topic  string `json:"topic"`
+	CountA int    `json:"count_a"`
+	CountB int    `json:"count_b"`
+	// Unchanged counts the paired messages that are identical.
+	Unchanged int           `json:"unchanged"`
+	Changed   []MessageDiff `json:"changed"`
+	// OnlyInA and OnlyInB list the IDs of messages with no counterpart.
+	OnlyInA []string `json:"only_in_a"`
+	OnlyInB []string `json:"only_in_b"`
+}
+
+// HistoryDiff is the difference between two message histories.
+type HistoryDiff struct {
+	Identical bool        `json:"identical"`
+	Key       string      `json:"key,omitempty"`
+	Topics    []TopicDiff `json:"topics"`
+}
+
+// Histories compares two message histories, such as the messages of two
+// exports taken before and after a code change, topic by topic. IDs differ
+// between runs, so messages are paired by position within each topic: the
+// first message on a topic in A with the first in B, and so on. With key
+// set, messages are paired by that attribute instead (in order among
+// messages with the same value), so a run that publishes in a different
+// order still lines up.
+func Histories(a, b []Message, key string) HistoryDiff {
+	byTopicA, byTopicB := groupByTopic(a), groupByTopic(b)
+	h := HistoryDiff{Identical: true, Key: key, Topics: []TopicDiff{}}
+	for _, topic := range keys(byTopicA, byTopicB) {
+		td := compareTopic(topic, byTopicA[topic], byTopicB[topic], key)
+		if len(td.Changed) > 0 || len(td.OnlyInA) > 0 || len(td.OnlyInB) > 0 {
+			h.Identical = false
+		}
+		h.Topics = append(h.Topics, td)
+	}
+	return h
+}
+
+func groupByTopic(msgs []Message) map[string][]Message {
+	groups := make(map[string][]Message)
+	for _, m := range msgs {
+		groups[m.Topic] = append(groups[m.Topic], m)
+	}
+	return groups
+}
+
+func compareTopic(topic string, a, b []Message, key string) TopicDiff {
+	td := TopicDiff{
+		Topic:   topic,
+		CountA:  len(a),
+		CountB:  len(b),
+		Changed: []MessageDiff{},
+		OnlyInA: []string{},
+		OnlyInB: []string{},
+	}
+
+	// Queue B's messages by pairing key; without a key they share one queue
+	// and pair by position.
+	queues := make(map[string][]Message)
+	for _, m := range b {
+		k := m.Attributes[key]
+		queues[k] = append(queues[k], m)
+	}
+	paired := make(map[string]int)
+	for _, m := range a {
+		k := m.Attributes[key]
+		if paired[k] == len(queues[k]) {
+			td.OnlyInA = append(td.OnlyInA, m.ID)
+			continue
+		}
+		counterpart := queues[k][paired[k]]
+		paired[k]++
+		if d := Messages(m, counterpart); d.Identical {
+			td.Unchanged++
+		} else {
+			td.Changed = append(td.Changed, d)
+		}
+	}
+
+	// The rest of each queue has no counterpart in A.
+	seen := make(map[string]int)
+	for _, m := range b {
+		k := m.Attributes[key]
+		if seen[k]++; seen[k] > paired[k] {
+			td.OnlyInB = append(td.OnlyInB, m.ID)
+		}
+	}
+	return td
+}
@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestData_JSON(t *testing.T) {
+	a := `{"id":1,"status":"new","items":[{"sku":"A"},{"sku":"B"}],"note":"x","big":12345678901234567890}`
+	b := `{ "status": "paid", "id": 1, "items": [{"sku": "A"}], "coupon": null, "big": 12345678901234567891 }`
+
+	p := Data(a, b)
+	if p.Format != FormatJSON {
+		t.Fatalf("Expected a JSON diff, got %s", p.Format)
+	}
+	want := []Change{
+		{Path: "big", Kind: Changed, Before: json.Number("12345678901234567890"), After: json.Number("12345678901234567891")},
+		{Path: "coupon", Kind: Added},
+		{Path: "items.1", Kind: Removed, Before: map[string]any{"sku": "B"}},
+		{Path: "note", Kind: Removed, Before: "x"},
+		{Path: "status", Kind: Changed, Before: "new", After: "paid"},
+	}
+	if !reflect.DeepEqual(p.Changes, want) {
+		t.Errorf("Unexpected changes:\n got %+v\nwant %+v", p.Changes, want)
+	}
+
+	if p := Data(`{"a":[1,2]}`, `{"a": [1, 2]}`); len(p.Changes) != 0 {
+		t.Errorf("Expected formatting differences to be ignored, got %+v", p.Changes)
+	}
+	if p := Data(`{"a":{"b":1}}`, `{"a":[1]}`); len(p.Changes) != 1 || p.Changes[0].Path != "a" {
+		t.Errorf("Expected a type change reported at a, got %+v", p.Changes)
+	}
+}
+
+func TestData_Text(t *testing.T) {
+	p := Data("hello", `{"greeting":"hello"}`)
+	if p.Format != FormatText || len(p.Changes) != 1 || p.Changes[0].Before != "hello" {
+		t.Errorf("Expected a text diff, got %+v", p)
+	}
+	if p := Data("same", "same"); p.Format != FormatText || len(p.Changes) != 0 {
+		t.Errorf("Expected equal text to have no changes, got %+v", p)
+	}
+	if p := Data(`{"a":1} {"b":2}`, `{"a":1}`); p.Format != FormatText {
+		t.Errorf("Expected trailing data to fall back to text, got %s", p.Format)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	a := Message{ID: "1", Topic: "orders", Data: `{"total":10}`, Attributes: map[string]string{"type": "OrderCreated", "v": "1"}}
+	b := Message{ID: "2", Topic: "orders", Data: `{"total":10}`, Attributes: map[string]string{"type": "OrderCreated", "v": "1"}}
+	if d := Messages(a, b); !d.Identical || d.A != "1" || d.B != "2" {
+		t.Errorf("Expected a replay with the same content to be identical, got %+v", d)
+	}
+
+	b.Topic = "orders-v2"
+	b.Attributes = map[string]string{"type": "OrderCreated", "v": "2", "trace": "t"}
+	d := Messages(a, b)
+	if d.Identical || d.Topic == nil || d.Topic.After != "orders-v2" {
+		t.Errorf("Expected a topic change, got %+v", d)
+	}
+	want := []Change{
+		{Path: "trace", Kind: Added, After: "t"},
+		{Path: "v", Kind: Changed, Before: "1", After: "2"},
+	}
+	if !reflect.DeepEqual(d.Attributes, want) {
+		t.Errorf("Unexpected attribute changes: %+v", d.Attributes)
+	}
+}
+
+func TestHistories_Positional(t *testing.T) {
+	before := []Message{
+		{ID: "a1", Topic: "orders", Data: `{"n":1}`},
+		{ID: "a2", Topic: "audit", Data: "x"},
+		{ID: "a3", Topic: "orders", Data: `{"n":2}`},
+		{ID: "a4", Topic: "orders", Data: `{"n":3}`},
+	}
+	after := []Message{
+		{ID: "b1", Topic: "orders", Data: `{"n":1}`},
+		{ID: "b2", Topic: "orders", Data: `{"n":20}`},
+		{ID: "b3", Topic: "payments", Data: "p"},
+	}
+
+	h := Histories(before, after, "")
+	if h.Identical || len(h.Topics) != 3 {
+		t.Fatalf("Expected 3 differing topics, got %+v", h)
+	}
+	audit, orders, payments := h.Topics[0], h.Topics[1], h.Topics[2]
+	if audit.Topic != "audit" || !reflect.DeepEqual(audit.OnlyInA, []string{"a2"}) {
+		t.Errorf("Expected audit only in A, got %+v", audit)
+	}
+	if orders.CountA != 3 || orders.CountB != 2 || orders.Unchanged != 1 || len(orders.Changed) != 1 {
+		t.Fatalf("Unexpected orders diff: %+v", orders)
+	}
+	if c := orders.Changed[0]; c.A != "a3" || c.B != "b2" || c.Data.Changes[0].Path != "n" {
+		t.Errorf("Expected a3 paired with b2, got %+v", c)
+	}
+	if !reflect.DeepEqual(orders.OnlyInA, []string{"a4"}) || len(orders.OnlyInB) != 0 {
+		t.Errorf("Expected a4 unpaired, got %+v / %+v", orders.OnlyInA, orders.OnlyInB)
+	}
+	if !reflect.DeepEqual(payments.OnlyInB, []string{"b3"}) {
+		t.Errorf("Expected payments only in B, got %+v", payments)
+	}
+
+	if h := Histories(before, before, ""); !h.Identical {
+		t.Errorf("Expected a history to equal itself, got %+v", h)
+	}
+}
+
+func TestHistories_Key(t *testing.T) {
+	msg := func(id, order, data string) Message {
+		return Message{ID: id, Topic: "orders", Data: data, Attributes: map[string]string{"order": order}}
+	}
+	before := []Message{msg("a1", "1", "x"), msg("a2", "2", "y"), msg("a3", "3", "z")}
+	after := []Message{msg("b1", "2", "y"), msg("b2", "1", "x!"), msg("b3", "4", "w")}
+
+	h := Histories(before, after, "order")
+	orders := h.Topics[0]
+	if orders.Unchanged != 1 || len(orders.Changed) != 1 || orders.Changed[0].A != "a1" || orders.Changed[0].B != "b2" {
+		t.Errorf("Expected messages paired by the order attribute, got %+v", orders)
+	}
+	if !reflect.DeepEqual(orders.OnlyInA, []string{"a3"}) || !reflect.DeepEqual(orders.OnlyInB, []string{"b3"}) {
+		t.Errorf("Expected a3 and b3 unpaired, got %+v / %+v", orders.OnlyInA, orders.OnlyInB)
+	}
+}
@@ -0,0 +1,398 @@
+// Package expect evaluates consumer contract expectations for integration
+// tests: "topic X receives a message with attribute type=OrderCreated within
+// 5s". An expectation is registered with a Registry, checked against every
+// message the emulator records from then on, and ends satisfied once enough
+// messages match or failed when its timeout passes, with the closest
+// near-misses explaining why.
+package expect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limits and defaults applied to a Spec.
+const (
+	DefaultTimeout = 5 * time.Second
+	MaxTimeout     = 5 * time.Minute
+	// MaxPending bounds the expectations waiting at once.
+	MaxPending = 1000
+	// maxFinished is how many finished expectations are kept for reading.
+	maxFinished = 200
+	// maxNearMisses is how many of the closest non-matching messages an
+	// expectation reports.
+	maxNearMisses = 5
+)
+
+// ErrTooMany is returned by Add when MaxPending expectations are waiting.
+var ErrTooMany = fmt.Errorf("too many pending expectations (max %d)", MaxPending)
+
+// Spec describes the messages an expectation waits for.
+type Spec struct {
+	// Project scopes the expectation; messages of other projects are not
+	// considered.
+	Project string
+	// Topic is a topic ID; empty matches any topic in Project.
+	Topic string
+	// Attributes and Fields check message attributes and JSON payload
+	// fields; every predicate must hold.
+	Attributes Predicates
+	Fields     Predicates
+	// Count is how many matching messages satisfy the expectation (at
+	// least one).
+	Count int
+	// Timeout is how long to wait, from registration.
+	Timeout time.Duration
+}
+
+func (s *Spec) compile() error {
+	if s.Project == "" {
+		return errors.New("project is required")
+	}
+	if s.Count == 0 {
+		s.Count = 1
+	}
+	if s.Count < 0 {
+		return fmt.Errorf("count cannot be negative, got %d", s.Count)
+	}
+	if s.Timeout == 0 {
+		s.Timeout = DefaultTimeout
+	}
+	if s.Timeout < 0 || s.Timeout > MaxTimeout {
+		return fmt.Errorf("timeout must be between 0 and %s, got %s", MaxTimeout, s.Timeout)
+	}
+	for i := range s.Attributes {
+		if err := s.Attributes[i].compile(); err != nil {
+			return fmt.Errorf("attribute %w", err)
+		}
+	}
+	for i := range s.Fields {
+		if err := s.Fields[i].compile(); err != nil {
+			return fmt.Errorf("field %w", err)
+		}
+	}
+	return nil
+}
+
+// Message is a recorded message an expectation is checked against.
+type Message struct {
+	ID         string
+	Project    string
+	Topic      string
+	Data       string
+	Attributes map[string]string
+}
+
+// Mismatch is one check a near-miss failed.
+type Mismatch struct {
+	// Target is "topic", "attribute", "field" or "payload" (the payload
+	// is not JSON).
+	Target   string `json:"target"`
+	Key      string `json:"key,omitempty"`
+	Op       Op     `json:"op,omitempty"`
+	Expected any    `json:"expected,omitempty"`
+	Actual   any    `json:"actual,omitempty"`
+	Missing  bool   `json:"missing,omitempty"`
+}
+
+// NearMiss is a message that failed some of an expectation's checks.
+type NearMiss struct {
+	MessageID  string     `json:"message_id"`
+	Topic      string     `json:"topic"`
+	Mismatches []Mismatch `json:"mismatches"`
+}
+
+// check compares msg with the spec and returns the failed checks.
+func (s *Spec) check(msg Message) []Mismatch {
+	var out []Mismatch
+	if s.Topic != "" && msg.Topic != s.Topic {
+		out = append(out, Mismatch{Target: "topic", Op: OpEq, Expected: s.Topic, Actual: msg.Topic})
+	}
+	for _, p := range s.Attributes {
+		v, ok := msg.Attributes[p.Key]
+		if !p.match(v, ok) {
+			out = append(out, mismatch("attribute", p, v, ok))
+		}
+	}
+	if len(s.Fields) == 0 {
+		return out
+	}
+	var doc any
+	if err := json.Unmarshal([]byte(msg.Data), &doc); err != nil {
+		return append(out, Mismatch{Target: "payload", Expected: "JSON", Actual: err.Error()})
+	}
+	for _, p := range s.Fields {
+		v, ok := lookup(doc, p.Key)
+		if !p.match(v, ok) {
+			out = append(out, mismatch("field", p, v, ok))
+		}
+	}
+	return out
+}
+
+func mismatch(target string, p Predicate, actual any, present bool) Mismatch {
+	m := Mismatch{Target: target, Key: p.Key, Op: p.Op, Expected: p.Value, Missing: !present}
+	if present {
+		m.Actual = actual
+	}
+	return m
+}
+
+// State is an expectation's state.
+type State string
+
+// Expectation states.
+const (
+	StatePending   State = "pending"
+	StateSatisfied State = "satisfied"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Status is a snapshot of an expectation.
+type Status struct {
+	ID         string     `json:"id"`
+	State      State      `json:"state"`
+	Project    string     `json:"project"`
+	Topic      string     `json:"topic,omitempty"`
+	Attributes Predicates `json:"attributes,omitempty"`
+	Fields     Predicates `json:"fields,omitempty"`
+	Count      int        `json:"count"`
+	Timeout    string     `json:"timeout"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Deadline   time.Time  `json:"deadline"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"`
+	// Matched lists the IDs of the messages that matched.
+	Matched []string `json:"matched"`
+	// Evaluated counts the messages checked.
+	Evaluated  int        `json:"evaluated"`
+	NearMisses []NearMiss `json:"near_misses"`
+}
+
+// expectation is a registered Spec and its progress.
+type expectation struct {
+	id        string
+	spec      Spec
+	created   time.Time
+	ended     time.Time
+	state     State
+	matched   []string
+	evaluated int
+	near      []NearMiss
+	timer     *time.Timer
+	done      chan struct{}
+}
+
+// observe checks msg and records the outcome.
+func (e *expectation) observe(msg Message, now time.Time) {
+	if msg.Project != e.spec.Project {
+		return
+	}
+	e.evaluated++
+	mismatches := e.spec.check(msg)
+	if len(mismatches) == 0 {
+		e.matched = append(e.matched, msg.ID)
+		if len(e.matched) >= e.spec.Count {
+			e.finish(StateSatisfied, now)
+		}
+		return
+	}
+	// Keep the closest misses, earliest first among equals.
+	i, _ := slices.BinarySearchFunc(e.near, len(mismatches), func(n NearMiss, size int) int {
+		if len(n.Mismatches) <= size {
+			return -1
+		}
+		return 1
+	})
+	if i < maxNearMisses {
+		e.near = slices.Insert(e.near, i, NearMiss{MessageID: msg.ID, Topic: msg.Topic, Mismatches: mismatches})
+		if len(e.near) > maxNearMisses {
+			e.near = e.near[:maxNearMisses]
+		}
+	}
+}
+
+func (e *expectation) finish(state State, now time.Time) {
+	if e.state != StatePending {
+		return
+	}
+	e.state, e.ended = state, now
+	e.timer.Stop()
+	close(e.done)
+}
+
+func (e *expectation) status() Status {
+	s := Status{
+		ID:         e.id,
+		State:      e.state,
+		Project:    e.spec.Project,
+		Topic:      e.spec.Topic,
+		Attributes: e.spec.Attributes,
+		Fields:     e.spec.Fields,
+		Count:      e.spec.Count,
+		Timeout:    e.spec.Timeout.String(),
+		CreatedAt:  e.created,
+		Deadline:   e.created.Add(e.spec.Timeout),
+		Matched:    slices.Clone(e.matched),
+		Evaluated:  e.evaluated,
+		NearMisses: slices.Clone(e.near),
+	}
+	if s.Matched == nil {
+		s.Matched = []string{}
+	}
+	if s.NearMisses == nil {
+		s.NearMisses = []NearMiss{}
+	}
+	if !e.ended.IsZero() {
+		ended := e.ended
+		s.EndedAt = &ended
+	}
+	return s
+}
+
+// Registry holds expectations and checks them against observed messages.
+// Timeouts run on the wall clock. It is safe for concurrent use.
+type Registry struct {
+	mu     sync.Mutex
+	nextID int
+	items  []*expectation
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add validates spec and starts waiting for matching messages.
+func (r *Registry) Add(spec Spec) (Status, error) {
+	if err := spec.compile(); err != nil {
+		return Status{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending := 0
+	for _, e := range r.items {
+		if e.state == StatePending {
+			pending++
+		}
+	}
+	if pending >= MaxPending {
+		return Status{}, ErrTooMany
+	}
+
+	r.nextID++
+	e := &expectation{
+		id:      strconv.Itoa(r.nextID),
+		spec:    spec,
+		created: time.Now(),
+		state:   StatePending,
+		done:    make(chan struct{}),
+	}
+	e.timer = time.AfterFunc(spec.Timeout, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		e.finish(StateFailed, time.Now())
+	})
+	r.items = append(r.items, e)
+	r.pruneLocked()
+	return e.status(), nil
+}
+
+// Observe checks msg against every pending expectation.
+func (r *Registry) Observe(msg Message) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.items {
+		if e.state == StatePending {
+			e.observe(msg, now)
+		}
+	}
+}
+
+// Get returns the expectation with id.
+func (r *Registry) Get(id string) (Status, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e := r.findLocked(id); e != nil {
+		return e.status(), true
+	}
+	return Status{}, false
+}
+
+// Wait blocks until the expectation with id ends or ctx is done, then
+// returns its status.
+func (r *Registry) Wait(ctx context.Context, id string) (Status, bool) {
+	r.mu.Lock()
+	e := r.findLocked(id)
+	r.mu.Unlock()
+	if e == nil {
+		return Status{}, false
+	}
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return e.status(), true
+}
+
+// List returns the expectations of project in the order they were added.
+func (r *Registry) List(project string) []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := []Status{}
+	for _, e := range r.items {
+		if e.spec.Project == project {
+			list = append(list, e.status())
+		}
+	}
+	return list
+}
+
+// Remove cancels and deletes the expectation with id, reporting whether it
+// existed. Callers waiting on it see it cancelled.
+func (r *Registry) Remove(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.findLocked(id)
+	if e == nil {
+		return false
+	}
+	e.finish(StateCancelled, time.Now())
+	r.items = slices.DeleteFunc(r.items, func(x *expectation) bool { return x == e })
+	return true
+}
+
+func (r *Registry) findLocked(id string) *expectation {
+	for _, e := range r.items {
+		if e.id == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// pruneLocked drops the oldest finished expectations beyond maxFinished.
+func (r *Registry) pruneLocked() {
+	finished := 0
+	for _, e := range r.items {
+		if e.state != StatePending {
+			finished++
+		}
+	}
+	r.items = slices.DeleteFunc(r.items, func(e *expectation) bool {
+		if finished > maxFinished && e.state != StatePending {
+			finished--
+			return true
+		}
+		return false
+	})
+}
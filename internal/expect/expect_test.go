@@ -0,0 +1,222 @@
+package expect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPredicates_UnmarshalJSON(t *testing.T) {
+	var object Predicates
+	if err := json.Unmarshal([]byte(`{"type":"OrderCreated","count":2}`), &object); err != nil {
+		t.Fatal(err)
+	}
+	if len(object) != 2 || object[0].Key != "count" || object[0].Value != 2.0 || object[1].Op != OpEq {
+		t.Errorf("Unexpected predicates from an object: %+v", object)
+	}
+
+	var list Predicates
+	if err := json.Unmarshal([]byte(`[{"key":"total","op":"gt","value":10}]`), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Op != OpGt {
+		t.Errorf("Unexpected predicates from an array: %+v", list)
+	}
+}
+
+func TestPredicate_Match(t *testing.T) {
+	doc := map[string]any{
+		"type":  "OrderCreated",
+		"total": 42.5,
+		"paid":  true,
+		"tags":  []any{"new", "vip"},
+		"items": []any{map[string]any{"sku": "A-1"}},
+	}
+	tests := []struct {
+		key  string
+		op   Op
+		want any
+		ok   bool
+	}{
+		{"type", OpEq, "OrderCreated", true},
+		{"type", OpNe, "OrderCreated", false},
+		{"total", OpEq, 42.5, true},
+		{"paid", OpEq, true, true},
+		{"total", OpGt, 40.0, true},
+		{"total", OpLte, 42.0, false},
+		{"tags", OpContains, "vip", true},
+		{"type", OpContains, "Created", true},
+		{"type", OpPrefix, "Order", true},
+		{"type", OpRegex, "^Order(Created|Updated)$", true},
+		{"items.0.sku", OpEq, "A-1", true},
+		{"items.1.sku", OpExists, nil, false},
+		{"missing", OpAbsent, nil, true},
+		{"missing", OpNe, "x", true},
+		{"missing", OpEq, "x", false},
+	}
+	for _, tt := range tests {
+		p := Predicate{Key: tt.key, Op: tt.op, Value: tt.want}
+		if err := p.compile(); err != nil {
+			t.Fatalf("%s %s: %v", tt.key, tt.op, err)
+		}
+		v, present := lookup(doc, tt.key)
+		if got := p.match(v, present); got != tt.ok {
+			t.Errorf("%s %s %v: expected %v, got %v", tt.key, tt.op, tt.want, tt.ok, got)
+		}
+	}
+
+	// Attributes are strings but compare with numbers and booleans.
+	for _, p := range []Predicate{{Key: "n", Value: 5.0}, {Key: "n", Op: OpGte, Value: 5.0}} {
+		if err := p.compile(); err != nil {
+			t.Fatal(err)
+		}
+		if !p.match("5", true) {
+			t.Errorf("Expected attribute \"5\" to satisfy %s %v", p.Op, p.Value)
+		}
+	}
+}
+
+func TestSpec_Invalid(t *testing.T) {
+	tests := []Spec{
+		{},
+		{Project: "p", Count: -1},
+		{Project: "p", Timeout: time.Hour},
+		{Project: "p", Attributes: Predicates{{Key: "k", Op: "like", Value: "x"}}},
+		{Project: "p", Attributes: Predicates{{Key: "k"}}},
+		{Project: "p", Fields: Predicates{{Key: "k", Op: OpRegex, Value: "("}}},
+		{Project: "p", Fields: Predicates{{Key: "k", Op: OpGt, Value: "ten"}}},
+		{Project: "p", Fields: Predicates{{Op: OpExists}}},
+	}
+	for _, spec := range tests {
+		if _, err := NewRegistry().Add(spec); err == nil {
+			t.Errorf("Expected %+v to be rejected", spec)
+		}
+	}
+}
+
+func TestRegistry_Satisfied(t *testing.T) {
+	r := NewRegistry()
+	s, err := r.Add(Spec{
+		Project:    "p",
+		Topic:      "orders",
+		Attributes: Predicates{{Key: "type", Value: "OrderCreated"}},
+		Fields:     Predicates{{Key: "total", Op: OpGt, Value: 10.0}},
+		Count:      2,
+		Timeout:    time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := Message{Project: "p", Topic: "orders", Data: `{"total":20}`, Attributes: map[string]string{"type": "OrderCreated"}}
+
+	r.Observe(Message{ID: "other-project", Project: "q", Topic: "orders", Data: `{"total":20}`, Attributes: match.Attributes})
+	m := match
+	m.ID = "1"
+	r.Observe(m)
+	if got, _ := r.Get(s.ID); got.State != StatePending || got.Evaluated != 1 {
+		t.Errorf("Expected one evaluated message and the expectation pending, got %+v", got)
+	}
+	m.ID = "2"
+	r.Observe(m)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got, ok := r.Wait(ctx, s.ID)
+	if !ok || got.State != StateSatisfied || len(got.Matched) != 2 || got.EndedAt == nil {
+		t.Errorf("Expected the expectation satisfied by 2 messages, got %+v", got)
+	}
+}
+
+func TestRegistry_FailedWithNearMisses(t *testing.T) {
+	r := NewRegistry()
+	s, err := r.Add(Spec{
+		Project:    "p",
+		Topic:      "orders",
+		Attributes: Predicates{{Key: "type", Value: "OrderCreated"}},
+		Fields:     Predicates{{Key: "total", Op: OpGt, Value: 10.0}},
+		Timeout:    50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Observe(Message{ID: "far", Project: "p", Topic: "audit", Data: "not json"})
+	r.Observe(Message{ID: "close", Project: "p", Topic: "orders", Data: `{"total":5}`, Attributes: map[string]string{"type": "OrderCreated"}})
+
+	got, _ := r.Wait(context.Background(), s.ID)
+	if got.State != StateFailed {
+		t.Fatalf("Expected the expectation to fail on timeout, got %s", got.State)
+	}
+	if len(got.NearMisses) != 2 || got.NearMisses[0].MessageID != "close" || got.NearMisses[1].MessageID != "far" {
+		t.Fatalf("Expected near misses ordered closest first, got %+v", got.NearMisses)
+	}
+	miss := got.NearMisses[0].Mismatches
+	if len(miss) != 1 || miss[0].Target != "field" || miss[0].Key != "total" || miss[0].Actual != 5.0 {
+		t.Errorf("Expected the total to be reported, got %+v", miss)
+	}
+	far := got.NearMisses[1].Mismatches
+	if len(far) != 3 || far[0].Target != "topic" || !far[1].Missing || far[2].Target != "payload" {
+		t.Errorf("Expected topic, attribute and payload mismatches, got %+v", far)
+	}
+
+	// A finished expectation ignores later messages.
+	r.Observe(Message{ID: "late", Project: "p", Topic: "orders", Data: `{"total":20}`, Attributes: map[string]string{"type": "OrderCreated"}})
+	if after, _ := r.Get(s.ID); after.State != StateFailed || len(after.Matched) != 0 {
+		t.Errorf("Expected the failed expectation unchanged, got %+v", after)
+	}
+}
+
+func TestRegistry_NearMissesBounded(t *testing.T) {
+	r := NewRegistry()
+	s, _ := r.Add(Spec{Project: "p", Topic: "orders", Timeout: time.Minute})
+	for range 2 * maxNearMisses {
+		r.Observe(Message{ID: "x", Project: "p", Topic: "audit"})
+	}
+	if got, _ := r.Get(s.ID); len(got.NearMisses) != maxNearMisses || got.Evaluated != 2*maxNearMisses {
+		t.Errorf("Expected %d near misses of %d evaluated, got %d of %d", maxNearMisses, 2*maxNearMisses, len(got.NearMisses), got.Evaluated)
+	}
+}
+
+func TestRegistry_RemoveAndList(t *testing.T) {
+	r := NewRegistry()
+	a, _ := r.Add(Spec{Project: "p", Timeout: time.Minute})
+	_, _ = r.Add(Spec{Project: "q", Timeout: time.Minute})
+
+	waited := make(chan Status)
+	go func() {
+		s, _ := r.Wait(context.Background(), a.ID)
+		waited <- s
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if !r.Remove(a.ID) || r.Remove(a.ID) {
+		t.Error("Expected Remove to report whether the expectation existed")
+	}
+	select {
+	case s := <-waited:
+		if s.State != StateCancelled {
+			t.Errorf("Expected a waiter to see the expectation cancelled, got %s", s.State)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Remove to release waiters")
+	}
+
+	if list := r.List("q"); len(list) != 1 || list[0].Project != "q" {
+		t.Errorf("Expected only project q's expectation, got %+v", list)
+	}
+	if _, ok := r.Wait(context.Background(), a.ID); ok {
+		t.Error("Expected Wait on a removed expectation to report it missing")
+	}
+}
+
+func TestRegistry_TooMany(t *testing.T) {
+	r := NewRegistry()
+	for range MaxPending {
+		if _, err := r.Add(Spec{Project: "p", Timeout: time.Minute}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Add(Spec{Project: "p"}); !errors.Is(err, ErrTooMany) {
+		t.Errorf("Expected ErrTooMany, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package expect
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Op is a predicate's comparison.
+type Op string
+
+// Supported comparisons. Gt, Gte, Lt and Lte compare numbers (attribute
+// values are parsed as numbers); Contains matches a substring of a string or
+// an element of an array; Prefix and Regex apply to strings.
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpExists   Op = "exists"
+	OpAbsent   Op = "absent"
+	OpContains Op = "contains"
+	OpPrefix   Op = "prefix"
+	OpRegex    Op = "regex"
+	OpGt       Op = "gt"
+	OpGte      Op = "gte"
+	OpLt       Op = "lt"
+	OpLte      Op = "lte"
+)
+
+// Ops lists the supported comparisons.
+var Ops = []Op{OpEq, OpNe, OpExists, OpAbsent, OpContains, OpPrefix, OpRegex, OpGt, OpGte, OpLt, OpLte}
+
+// Predicate checks one attribute or JSON field of a message.
+type Predicate struct {
+	// Key is the attribute name or, for field predicates, a dot-separated
+	// path into the JSON payload such as "order.items.0.sku".
+	Key string `json:"key"`
+	// Op defaults to eq.
+	Op    Op  `json:"op,omitempty"`
+	Value any `json:"value,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Predicates is a list of predicates. In JSON it is either an array of
+// predicates or an object of key/value pairs that must be equal.
+type Predicates []Predicate
+
+// UnmarshalJSON accepts both forms of Predicates.
+func (ps *Predicates) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return json.Unmarshal(data, (*[]Predicate)(ps))
+	}
+	var pairs map[string]any
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+	*ps = make(Predicates, 0, len(pairs))
+	for key, value := range pairs {
+		*ps = append(*ps, Predicate{Key: key, Op: OpEq, Value: value})
+	}
+	slices.SortFunc(*ps, func(a, b Predicate) int { return strings.Compare(a.Key, b.Key) })
+	return nil
+}
+
+// compile validates the predicate and fills in defaults.
+func (p *Predicate) compile() error {
+	if p.Key == "" {
+		return errors.New("key is required")
+	}
+	if p.Op == "" {
+		p.Op = OpEq
+	}
+	if !slices.Contains(Ops, p.Op) {
+		return fmt.Errorf("%s: unknown op %q", p.Key, p.Op)
+	}
+	switch p.Op {
+	case OpExists, OpAbsent:
+		return nil
+	case OpPrefix, OpRegex:
+		s, ok := p.Value.(string)
+		if !ok {
+			return fmt.Errorf("%s: %s needs a string value", p.Key, p.Op)
+		}
+		if p.Op == OpRegex {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p.Key, err)
+			}
+			p.re = re
+		}
+	case OpGt, OpGte, OpLt, OpLte:
+		if _, ok := p.Value.(float64); !ok {
+			return fmt.Errorf("%s: %s needs a number value", p.Key, p.Op)
+		}
+	default:
+		if p.Value == nil {
+			return fmt.Errorf("%s: %s needs a value", p.Key, p.Op)
+		}
+	}
+	return nil
+}
+
+// match reports whether actual, which is absent unless present, satisfies
+// the predicate.
+func (p *Predicate) match(actual any, present bool) bool {
+	switch p.Op {
+	case OpExists:
+		return present
+	case OpAbsent:
+		return !present
+	case OpNe:
+		return !present || !equal(actual, p.Value)
+	}
+	if !present {
+		return false
+	}
+	switch p.Op {
+	case OpEq:
+		return equal(actual, p.Value)
+	case OpContains:
+		if list, ok := actual.([]any); ok {
+			return slices.ContainsFunc(list, func(v any) bool { return equal(v, p.Value) })
+		}
+		s, ok := actual.(string)
+		return ok && strings.Contains(s, text(p.Value))
+	case OpPrefix:
+		s, ok := actual.(string)
+		return ok && strings.HasPrefix(s, p.Value.(string))
+	case OpRegex:
+		s, ok := actual.(string)
+		return ok && p.re.MatchString(s)
+	}
+
+	n, ok := number(actual)
+	if !ok {
+		return false
+	}
+	want := p.Value.(float64)
+	switch p.Op {
+	case OpGt:
+		return n > want
+	case OpGte:
+		return n >= want
+	case OpLt:
+		return n < want
+	default:
+		return n <= want
+	}
+}
+
+// equal compares JSON values. A string, such as an attribute, also equals a
+// number or boolean written the same way.
+func equal(actual, want any) bool {
+	if s, ok := actual.(string); ok {
+		return s == text(want)
+	}
+	return reflect.DeepEqual(actual, want)
+}
+
+// text renders a JSON value as a string; strings are left unquoted.
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// lookup returns the value at a dot-separated path in a decoded JSON
+// document. Numeric segments index arrays.
+func lookup(doc any, path string) (any, bool) {
+	v := doc
+	for _, seg := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[seg]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
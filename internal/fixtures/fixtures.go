@@ -0,0 +1,372 @@
+// Package fixtures reads captured messages from NDJSON or CSV files, in the
+// shape /api/messages/export writes them, and publishes them to their
+// topics, either back to back or keeping their original relative timing
+// scaled by a speed factor.
+package fixtures
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the encoding of a fixture file.
+type Format string
+
+// Supported formats.
+const (
+	// FormatNDJSON has one JSON object per line.
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV has a header row and one message per row.
+	FormatCSV Format = "csv"
+)
+
+// AttributePrefix marks the columns (or NDJSON fields) holding an
+// attribute: column "attr_type" is attribute "type".
+const AttributePrefix = "attr_"
+
+// IDColumn holds a message's original ID, as the export writes it.
+const IDColumn = "id"
+
+// Annotation columns, as the export writes them. TagsColumn is a list of
+// tags in NDJSON and TagSeparator-separated in CSV.
+const (
+	PinnedColumn = "pinned"
+	TagsColumn   = "tags"
+	NoteColumn   = "note"
+	TagSeparator = ","
+)
+
+// Default column names, matching the export.
+const (
+	DefaultDataColumn  = "data"
+	DefaultTopicColumn = "topic"
+	DefaultTimeColumn  = "publish_time"
+)
+
+// ParseFormat returns the format named s ("ndjson", "jsonl" or "csv").
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "ndjson", "jsonl":
+		return FormatNDJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unknown format %q: must be ndjson or csv", s)
+}
+
+// DetectFormat guesses the format of a file from its name or content type
+// and, failing that, from its first non-blank byte: NDJSON starts with '{'.
+func DetectFormat(name string, head []byte) Format {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".csv"), strings.Contains(name, "text/csv"):
+		return FormatCSV
+	case strings.HasSuffix(name, ".ndjson"), strings.HasSuffix(name, ".jsonl"), strings.Contains(name, "ndjson"):
+		return FormatNDJSON
+	}
+	if trimmed := bytes.TrimLeft(head, " \t\r\n\ufeff"); len(trimmed) > 0 && trimmed[0] == '{' {
+		return FormatNDJSON
+	}
+	return FormatCSV
+}
+
+// Mapping says which columns (CSV) or fields (NDJSON) of a fixture hold a
+// message's parts. Empty names use the defaults. Columns prefixed with
+// AttributePrefix always become attributes, as does an NDJSON "attributes"
+// object.
+type Mapping struct {
+	// Data holds the payload. An NDJSON value that is not a string is
+	// published as its JSON encoding.
+	Data string
+	// Topic holds the topic ID or full name; it may be absent when the
+	// publisher supplies a topic.
+	Topic string
+	// Time holds the RFC 3339 publish time used to keep the original
+	// timing; it may be absent.
+	Time string
+	// Attributes are further columns copied into attributes under their
+	// own name, e.g. a "tenant" column.
+	Attributes []string
+}
+
+func (m Mapping) withDefaults() Mapping {
+	if m.Data == "" {
+		m.Data = DefaultDataColumn
+	}
+	if m.Topic == "" {
+		m.Topic = DefaultTopicColumn
+	}
+	if m.Time == "" {
+		m.Time = DefaultTimeColumn
+	}
+	return m
+}
+
+// Record is one message read from a fixture.
+type Record struct {
+	// Line is the 1-based line (NDJSON) or row (CSV, counting the header)
+	// the record came from.
+	Line int
+	// ID is the message's original ID, when the fixture has one.
+	ID          string
+	Topic       string
+	Data        []byte
+	Attributes  map[string]string
+	PublishTime time.Time
+	// Pinned, Tags and Note are the annotations the dashboard exported
+	// with the message, if any.
+	Pinned bool
+	Tags   []string
+	Note   string
+}
+
+// LineError reports a line of a fixture that could not be read or
+// published.
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Read parses a fixture. Lines that cannot be parsed are returned as
+// LineErrors so the rest can still be published; the error is only set
+// when the file as a whole is unreadable.
+func Read(r io.Reader, format Format, m Mapping) ([]Record, []LineError, error) {
+	m = m.withDefaults()
+	switch format {
+	case FormatNDJSON:
+		return readNDJSON(r, m)
+	case FormatCSV:
+		return readCSV(r, m)
+	}
+	return nil, nil, fmt.Errorf("unknown format %q", format)
+}
+
+// maxLineBytes bounds one NDJSON line, which holds at most one message.
+const maxLineBytes = 16 << 20
+
+func readNDJSON(r io.Reader, m Mapping) ([]Record, []LineError, error) {
+	var (
+		records []Record
+		errs    []LineError
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if line == 1 {
+			text = bytes.TrimPrefix(text, []byte("\ufeff"))
+		}
+		if len(text) == 0 {
+			continue
+		}
+		rec, err := parseObject(text, m)
+		if err != nil {
+			errs = append(errs, LineError{Line: line, Error: err.Error()})
+			continue
+		}
+		rec.Line = line
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return records, errs, nil
+}
+
+func parseObject(text []byte, m Mapping) (Record, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(text, &fields); err != nil {
+		return Record{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var rec Record
+	raw, ok := fields[m.Data]
+	if !ok {
+		return Record{}, fmt.Errorf("missing %q field", m.Data)
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		rec.Data = []byte(s)
+	} else {
+		rec.Data = []byte(raw)
+	}
+	if raw, ok := fields[IDColumn]; ok {
+		_ = json.Unmarshal(raw, &rec.ID)
+	}
+	if raw, ok := fields[PinnedColumn]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &rec.Pinned); err != nil {
+			return Record{}, fmt.Errorf("%s must be a boolean", PinnedColumn)
+		}
+	}
+	if raw, ok := fields[TagsColumn]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &rec.Tags); err != nil {
+			return Record{}, fmt.Errorf("%s must be an array of strings", TagsColumn)
+		}
+	}
+	if raw, ok := fields[NoteColumn]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &rec.Note); err != nil {
+			return Record{}, fmt.Errorf("%s must be a string", NoteColumn)
+		}
+	}
+	if raw, ok := fields[m.Topic]; ok {
+		if err := json.Unmarshal(raw, &rec.Topic); err != nil {
+			return Record{}, fmt.Errorf("%s must be a string", m.Topic)
+		}
+	}
+	if raw, ok := fields[m.Time]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &rec.PublishTime); err != nil {
+			return Record{}, fmt.Errorf("%s must be an RFC 3339 time", m.Time)
+		}
+	}
+
+	attrs := map[string]string{}
+	if raw, ok := fields["attributes"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &attrs); err != nil {
+			return Record{}, errors.New("attributes must be an object of strings")
+		}
+	}
+	for name, raw := range fields {
+		key, isAttr := strings.CutPrefix(name, AttributePrefix)
+		if !isAttr {
+			if !slices.Contains(m.Attributes, name) {
+				continue
+			}
+			key = name
+		}
+		if v, ok := attributeValue(raw); ok {
+			attrs[key] = v
+		}
+	}
+	if len(attrs) > 0 {
+		rec.Attributes = attrs
+	}
+	return rec, nil
+}
+
+// attributeValue converts an NDJSON field to an attribute value: strings as
+// they are, other values as their JSON encoding; null is left out.
+func attributeValue(raw json.RawMessage) (string, bool) {
+	if string(raw) == "null" {
+		return "", false
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, s != ""
+	}
+	return string(raw), true
+}
+
+func readCSV(r io.Reader, m Mapping) ([]Record, []LineError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("empty file")
+		}
+		return nil, nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	idCol, dataCol, topicCol, timeCol := -1, -1, -1, -1
+	pinnedCol, tagsCol, noteCol := -1, -1, -1
+	attrCols := map[int]string{}
+	for i, name := range header {
+		switch {
+		case name == m.Data:
+			dataCol = i
+		case name == m.Topic:
+			topicCol = i
+		case name == m.Time:
+			timeCol = i
+		case name == IDColumn:
+			idCol = i
+		case name == PinnedColumn:
+			pinnedCol = i
+		case name == TagsColumn:
+			tagsCol = i
+		case name == NoteColumn:
+			noteCol = i
+		case strings.HasPrefix(name, AttributePrefix):
+			attrCols[i] = strings.TrimPrefix(name, AttributePrefix)
+		case slices.Contains(m.Attributes, name):
+			attrCols[i] = name
+		}
+	}
+	if dataCol < 0 {
+		return nil, nil, fmt.Errorf("missing %q column", m.Data)
+	}
+
+	var (
+		records []Record
+		errs    []LineError
+	)
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return nil, nil, err
+			}
+			errs = append(errs, LineError{Line: perr.StartLine, Error: perr.Err.Error()})
+			continue
+		}
+		if len(row) != len(header) {
+			errs = append(errs, LineError{Line: line, Error: fmt.Sprintf("expected %d columns, got %d", len(header), len(row))})
+			continue
+		}
+
+		rec := Record{Line: line, Data: []byte(row[dataCol])}
+		if idCol >= 0 {
+			rec.ID = row[idCol]
+		}
+		if topicCol >= 0 {
+			rec.Topic = row[topicCol]
+		}
+		if timeCol >= 0 && row[timeCol] != "" {
+			if rec.PublishTime, err = time.Parse(time.RFC3339Nano, row[timeCol]); err != nil {
+				errs = append(errs, LineError{Line: line, Error: fmt.Sprintf("%s must be an RFC 3339 time", m.Time)})
+				continue
+			}
+		}
+		if pinnedCol >= 0 && row[pinnedCol] != "" {
+			if rec.Pinned, err = strconv.ParseBool(row[pinnedCol]); err != nil {
+				errs = append(errs, LineError{Line: line, Error: fmt.Sprintf("%s must be true or false", PinnedColumn)})
+				continue
+			}
+		}
+		if tagsCol >= 0 && row[tagsCol] != "" {
+			rec.Tags = strings.Split(row[tagsCol], TagSeparator)
+		}
+		if noteCol >= 0 {
+			rec.Note = row[noteCol]
+		}
+		for i, key := range attrCols {
+			// Exports leave a message's missing attributes empty.
+			if row[i] == "" {
+				continue
+			}
+			if rec.Attributes == nil {
+				rec.Attributes = map[string]string{}
+			}
+			rec.Attributes[key] = row[i]
+		}
+		records = append(records, rec)
+	}
+	return records, errs, nil
+}
@@ -0,0 +1,298 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRead_NDJSON(t *testing.T) {
+	input := `{"id":"m1","topic":"orders","publish_time":"2024-05-01T12:00:00Z","data":"{\"id\":1}","attributes":{"type":"OrderCreated"}}
+
+{"topic":"orders","data":{"id":2},"attr_region":"eu","tenant":"acme","count":3}
+not json
+{"topic":"orders"}
+{"data":"x","publish_time":"yesterday"}
+`
+	records, errs, err := Read(strings.NewReader(input), FormatNDJSON, Mapping{Attributes: []string{"tenant", "count"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %+v", records)
+	}
+
+	want := Record{
+		Line:        1,
+		ID:          "m1",
+		Topic:       "orders",
+		Data:        []byte(`{"id":1}`),
+		Attributes:  map[string]string{"type": "OrderCreated"},
+		PublishTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(records[0], want) {
+		t.Errorf("Unexpected first record:\n got %+v\nwant %+v", records[0], want)
+	}
+	if r := records[1]; r.Line != 3 || string(r.Data) != `{"id":2}` ||
+		!reflect.DeepEqual(r.Attributes, map[string]string{"region": "eu", "tenant": "acme", "count": "3"}) {
+		t.Errorf("Expected a JSON payload and mapped attributes, got %+v", r)
+	}
+
+	var lines []int
+	for _, e := range errs {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{4, 5, 6}) {
+		t.Errorf("Expected errors on lines 4-6, got %+v", errs)
+	}
+}
+
+func TestRead_CSV(t *testing.T) {
+	input := "id,project,topic,publish_time,data,attr_region,attr_type,tenant\n" +
+		"m1,p,orders,2024-05-01T12:00:00Z,\"line one\nline \"\"two\"\"\",eu,OrderCreated,acme\n" +
+		"m2,p,audit,,plain,,Note,\n" +
+		"m3,p,audit\n" +
+		"m4,p,audit,soon,x,,,\n"
+
+	records, errs, err := Read(strings.NewReader(input), FormatCSV, Mapping{Attributes: []string{"tenant"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %+v", records)
+	}
+	if r := records[0]; r.Line != 2 || r.ID != "m1" || r.Topic != "orders" || string(r.Data) != "line one\nline \"two\"" ||
+		!reflect.DeepEqual(r.Attributes, map[string]string{"region": "eu", "type": "OrderCreated", "tenant": "acme"}) {
+		t.Errorf("Unexpected first record: %+v", r)
+	}
+	if r := records[1]; r.Line != 4 || !r.PublishTime.IsZero() || !reflect.DeepEqual(r.Attributes, map[string]string{"type": "Note"}) {
+		t.Errorf("Expected empty columns left out, got %+v", r)
+	}
+	if len(errs) != 2 || errs[0].Line != 5 || errs[1].Line != 6 {
+		t.Errorf("Expected errors on rows 5 and 6, got %+v", errs)
+	}
+
+	// Columns can be renamed.
+	records, _, err = Read(strings.NewReader("body,at\nhello,2024-05-01T12:00:00Z\n"), FormatCSV, Mapping{Data: "body", Time: "at"})
+	if err != nil || len(records) != 1 || string(records[0].Data) != "hello" || records[0].PublishTime.IsZero() {
+		t.Errorf("Expected mapped columns, got %+v, %v", records, err)
+	}
+
+	if _, _, err := Read(strings.NewReader("id,topic\nm1,orders\n"), FormatCSV, Mapping{}); err == nil {
+		t.Error("Expected an error without a data column")
+	}
+	if _, _, err := Read(strings.NewReader(""), FormatCSV, Mapping{}); err == nil {
+		t.Error("Expected an error for an empty file")
+	}
+}
+
+func TestRead_Annotations(t *testing.T) {
+	ndjson := `{"data":"a","pinned":true,"tags":["golden","orders"],"note":"first order"}
+{"data":"b","pinned":"yes"}
+`
+	records, errs, err := Read(strings.NewReader(ndjson), FormatNDJSON, Mapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || !records[0].Pinned || !reflect.DeepEqual(records[0].Tags, []string{"golden", "orders"}) ||
+		records[0].Note != "first order" || records[0].Attributes != nil {
+		t.Errorf("Expected the annotations read, got %+v", records)
+	}
+	if len(errs) != 1 || errs[0].Line != 2 {
+		t.Errorf("Expected an error for a non-boolean pinned, got %+v", errs)
+	}
+
+	csv := "data,pinned,tags,note\n" +
+		"a,true,\"golden,orders\",first order\n" +
+		"b,false,,\n" +
+		"c,maybe,,\n"
+	records, errs, err = Read(strings.NewReader(csv), FormatCSV, Mapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || !records[0].Pinned || !reflect.DeepEqual(records[0].Tags, []string{"golden", "orders"}) ||
+		records[0].Note != "first order" || records[1].Pinned || records[1].Tags != nil {
+		t.Errorf("Expected the annotations read, got %+v", records)
+	}
+	if len(errs) != 1 || errs[0].Line != 4 {
+		t.Errorf("Expected an error for a non-boolean pinned, got %+v", errs)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want Format
+	}{
+		{"fixture.csv", `{"data":1}`, FormatCSV},
+		{"fixture.jsonl", "data\n", FormatNDJSON},
+		{"application/x-ndjson", "", FormatNDJSON},
+		{"", "\n  {\"data\":1}", FormatNDJSON},
+		{"", "data,topic\n", FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.name, []byte(tt.head)); got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %s, want %s", tt.name, tt.head, got, tt.want)
+		}
+	}
+}
+
+type published struct {
+	topic string
+	data  string
+	at    time.Time
+}
+
+type recorder struct {
+	mu  sync.Mutex
+	got []published
+}
+
+func (r *recorder) publish(_ context.Context, topic string, rec *Record) (string, error) {
+	msg := rec.Message()
+	if string(msg.Data) == "fail" {
+		return "", errors.New("rejected")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.got = append(r.got, published{topic, string(msg.Data), time.Now()})
+	return "id", nil
+}
+
+func TestPublish(t *testing.T) {
+	records := []Record{
+		{Line: 2, Topic: "orders", Data: []byte("a")},
+		{Line: 3, Data: []byte("b")},
+		{Line: 4, Topic: "orders", Data: []byte("fail")},
+		{Line: 5, Topic: "audit", Data: []byte("c")},
+	}
+	rec := &recorder{}
+	var progress []int
+	report, err := Publish(context.Background(), records, []LineError{{Line: 6, Error: "invalid JSON"}}, rec.publish, Options{
+		Progress: func(r Report) { progress = append(progress, r.Published+r.Failed) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 5 || report.Published != 2 || report.Failed != 3 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if !reflect.DeepEqual(report.Topics, map[string]int{"orders": 1, "audit": 1}) {
+		t.Errorf("Unexpected topic counts: %v", report.Topics)
+	}
+	want := []LineError{{6, "invalid JSON"}, {3, "no topic"}, {4, "rejected"}}
+	if !reflect.DeepEqual(report.Errors, want) {
+		t.Errorf("Unexpected errors: %+v", report.Errors)
+	}
+	if !reflect.DeepEqual(progress, []int{2, 3, 4, 5}) {
+		t.Errorf("Expected progress after each record, got %v", progress)
+	}
+
+	// A topic override applies to every record.
+	rec = &recorder{}
+	if _, err := Publish(context.Background(), records[:2], nil, rec.publish, Options{Topic: "copy"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.got) != 2 || rec.got[0].topic != "copy" || rec.got[1].topic != "copy" {
+		t.Errorf("Expected both records on the override topic, got %+v", rec.got)
+	}
+}
+
+func TestPublish_OriginalTiming(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Line: 1, Topic: "t", Data: []byte("a"), PublishTime: base},
+		{Line: 2, Topic: "t", Data: []byte("b"), PublishTime: base.Add(2 * time.Second)},
+		{Line: 3, Topic: "t", Data: []byte("c"), PublishTime: base.Add(4 * time.Second)},
+	}
+
+	rec := &recorder{}
+	if _, err := Publish(context.Background(), records, nil, rec.publish, Options{Timing: TimingOriginal, Speed: 40}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.got) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(rec.got))
+	}
+	// 2s gaps at 40x are 50ms.
+	if gap := rec.got[2].at.Sub(rec.got[0].at); gap < 100*time.Millisecond || gap > time.Second {
+		t.Errorf("Expected about 100ms between the first and last message, got %v", gap)
+	}
+
+	// Without timing the gaps are dropped.
+	rec = &recorder{}
+	report, _ := Publish(context.Background(), records, nil, rec.publish, Options{Timing: TimingNone})
+	if report.ElapsedSeconds > 0.05 {
+		t.Errorf("Expected no waiting, took %vs", report.ElapsedSeconds)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rec = &recorder{}
+	report, err := Publish(ctx, records, nil, rec.publish, Options{Timing: TimingOriginal})
+	if !errors.Is(err, context.DeadlineExceeded) || report.Published != 1 {
+		t.Errorf("Expected cancellation after the first message, got %+v, %v", report, err)
+	}
+}
+
+func TestParseTiming(t *testing.T) {
+	if got, err := ParseTiming(""); err != nil || got != TimingNone {
+		t.Errorf("Expected none by default, got %s, %v", got, err)
+	}
+	if got, err := ParseTiming("Original"); err != nil || got != TimingOriginal {
+		t.Errorf("Expected original, got %s, %v", got, err)
+	}
+	if _, err := ParseTiming("fast"); err == nil {
+		t.Error("Expected an error for an unknown timing")
+	}
+}
+
+func TestPublish_Pause(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Line: 1, Topic: "t", Data: []byte("a"), PublishTime: base},
+		{Line: 2, Topic: "t", Data: []byte("b"), PublishTime: base.Add(50 * time.Millisecond)},
+		{Line: 3, Topic: "t", Data: []byte("c"), PublishTime: base.Add(100 * time.Millisecond)},
+	}
+
+	gate := &Gate{}
+	rec := &recorder{}
+	published := make(chan int, len(records))
+	done := make(chan Report)
+	go func() {
+		report, _ := Publish(context.Background(), records, nil, rec.publish, Options{
+			Timing:   TimingOriginal,
+			Gate:     gate,
+			Progress: func(r Report) { published <- r.Published },
+		})
+		done <- report
+	}()
+
+	<-published
+	if !gate.Pause() || gate.Pause() || !gate.Paused() {
+		t.Fatal("Expected the first Pause to close the gate and the second to be a no-op")
+	}
+	select {
+	case <-published:
+		t.Fatal("Expected no message while paused")
+	case <-time.After(150 * time.Millisecond):
+	}
+	resumed := time.Now()
+	if !gate.Resume() || gate.Resume() {
+		t.Fatal("Expected the first Resume to open the gate and the second to be a no-op")
+	}
+
+	report := <-done
+	if report.Published != 3 {
+		t.Fatalf("Expected all messages after resuming, got %+v", report)
+	}
+	// The pause shifts the schedule rather than collapsing it, so c still
+	// comes about 100ms after the resume.
+	if gap := rec.got[2].at.Sub(resumed); gap < 80*time.Millisecond {
+		t.Errorf("Expected the remaining gap kept after resuming, got %v", gap)
+	}
+}
@@ -0,0 +1,265 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+)
+
+// Timing says whether Publish keeps the gaps between the records' publish
+// times.
+type Timing string
+
+// Supported timings.
+const (
+	// TimingNone publishes records back to back.
+	TimingNone Timing = "none"
+	// TimingOriginal waits between records as long as between their
+	// original publish times, divided by the speed.
+	TimingOriginal Timing = "original"
+)
+
+// ParseTiming returns the timing named s; empty means TimingNone.
+func ParseTiming(s string) (Timing, error) {
+	switch Timing(strings.ToLower(s)) {
+	case "", TimingNone:
+		return TimingNone, nil
+	case TimingOriginal:
+		return TimingOriginal, nil
+	}
+	return "", fmt.Errorf("unknown timing %q: must be none or original", s)
+}
+
+// MaxReportedErrors caps Report.Errors; Failed still counts every failure.
+const MaxReportedErrors = 100
+
+// PublishFunc publishes rec to topic and returns its message ID.
+type PublishFunc func(ctx context.Context, topic string, rec *Record) (string, error)
+
+// Message returns the Pub/Sub message for rec.
+func (rec *Record) Message() *pubsub.Message {
+	return &pubsub.Message{Data: rec.Data, Attributes: maps.Clone(rec.Attributes)}
+}
+
+// Options control Publish.
+type Options struct {
+	// Topic, when set, replaces the topic of every record.
+	Topic string
+	// Timing defaults to TimingNone.
+	Timing Timing
+	// Speed scales TimingOriginal: 2 replays twice as fast, 0.5 at half
+	// speed. Zero means 1.
+	Speed float64
+	// Progress, when set, is called with the running report after each
+	// record.
+	Progress func(Report)
+	// Gate, when set, lets another goroutine pause and resume Publish.
+	Gate *Gate
+}
+
+// Report is the outcome of Publish.
+type Report struct {
+	Total     int `json:"total"`
+	Published int `json:"published"`
+	Failed    int `json:"failed"`
+	// Topics counts the messages published to each topic.
+	Topics map[string]int `json:"topics"`
+	// Errors lists the first MaxReportedErrors failures by line.
+	Errors         []LineError `json:"errors,omitempty"`
+	ElapsedSeconds float64     `json:"elapsed_seconds"`
+}
+
+func (r *Report) fail(e LineError) {
+	r.Failed++
+	if len(r.Errors) < MaxReportedErrors {
+		r.Errors = append(r.Errors, e)
+	}
+}
+
+func (r Report) clone() Report {
+	r.Topics = maps.Clone(r.Topics)
+	r.Errors = append([]LineError(nil), r.Errors...)
+	return r
+}
+
+// Publish publishes records in order. invalid are the lines Read could not
+// parse; they are reported as failures. A record that fails to publish is
+// reported and skipped; Publish only stops early, returning the report so
+// far and the context's error, when ctx is done.
+func Publish(ctx context.Context, records []Record, invalid []LineError, publish PublishFunc, opts Options) (Report, error) {
+	if opts.Speed < 0 {
+		return Report{}, fmt.Errorf("speed cannot be negative, got %v", opts.Speed)
+	}
+	if opts.Speed == 0 {
+		opts.Speed = 1
+	}
+
+	report := Report{Total: len(records) + len(invalid), Topics: map[string]int{}}
+	for _, e := range invalid {
+		report.fail(e)
+	}
+
+	start := time.Now()
+	done := func(err error) (Report, error) {
+		report.ElapsedSeconds = time.Since(start).Seconds()
+		return report, err
+	}
+
+	// Gaps are measured from the first timed record, so a late record
+	// catches up rather than pushing every later one back. Time spent
+	// paused is added to every later deadline.
+	var first, firstAt time.Time
+	for i := range records {
+		rec := &records[i]
+		var due time.Time
+		if opts.Timing == TimingOriginal && !rec.PublishTime.IsZero() {
+			if first.IsZero() {
+				first, firstAt = rec.PublishTime, time.Now().Add(-opts.Gate.pausedFor())
+			} else if offset := rec.PublishTime.Sub(first); offset > 0 {
+				due = firstAt.Add(time.Duration(float64(offset) / opts.Speed))
+			}
+		}
+		if err := opts.Gate.wait(ctx, due); err != nil {
+			return done(err)
+		}
+
+		topic := rec.Topic
+		if opts.Topic != "" {
+			topic = opts.Topic
+		}
+		if topic == "" {
+			report.fail(LineError{Line: rec.Line, Error: "no topic"})
+		} else if _, err := publish(ctx, topic, rec); err != nil {
+			if ctx.Err() != nil {
+				return done(ctx.Err())
+			}
+			report.fail(LineError{Line: rec.Line, Error: err.Error()})
+		} else {
+			report.Published++
+			report.Topics[topic]++
+		}
+
+		if opts.Progress != nil {
+			report.ElapsedSeconds = time.Since(start).Seconds()
+			opts.Progress(report.clone())
+		}
+	}
+	return done(nil)
+}
+
+// Gate pauses a Publish between records. Time spent paused shifts the
+// rest of the schedule, so the original gaps are kept after a resume. A nil
+// Gate never pauses. It is safe for concurrent use.
+type Gate struct {
+	mu      sync.Mutex
+	paused  bool
+	since   time.Time
+	total   time.Duration
+	changed chan struct{}
+}
+
+// Pause stops Publish before its next record. It reports whether the gate
+// was open.
+func (g *Gate) Pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		return false
+	}
+	g.paused, g.since = true, time.Now()
+	g.notifyLocked()
+	return true
+}
+
+// Resume lets a paused Publish continue. It reports whether the gate was
+// paused.
+func (g *Gate) Resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.paused {
+		return false
+	}
+	g.paused = false
+	g.total += time.Since(g.since)
+	g.notifyLocked()
+	return true
+}
+
+// Paused reports whether the gate is paused.
+func (g *Gate) Paused() bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+func (g *Gate) notifyLocked() {
+	if g.changed != nil {
+		close(g.changed)
+		g.changed = nil
+	}
+}
+
+// state returns whether the gate is paused, the total time spent paused
+// and a channel closed at the next Pause or Resume.
+func (g *Gate) state() (paused bool, pausedFor time.Duration, changed <-chan struct{}) {
+	if g == nil {
+		return false, 0, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.changed == nil {
+		g.changed = make(chan struct{})
+	}
+	pausedFor = g.total
+	if g.paused {
+		pausedFor += time.Since(g.since)
+	}
+	return g.paused, pausedFor, g.changed
+}
+
+func (g *Gate) pausedFor() time.Duration {
+	_, d, _ := g.state()
+	return d
+}
+
+// wait blocks while the gate is paused and, when due is set, until due
+// shifted by the time spent paused, or until ctx is done.
+func (g *Gate) wait(ctx context.Context, due time.Time) error {
+	for {
+		paused, pausedFor, changed := g.state()
+		var timer *time.Timer
+		var fired <-chan time.Time
+		if !paused {
+			if due.IsZero() {
+				return ctx.Err()
+			}
+			d := time.Until(due.Add(pausedFor))
+			if d <= 0 {
+				return ctx.Err()
+			}
+			timer = time.NewTimer(d)
+			fired = timer.C
+		}
+		select {
+		case <-fired:
+			return nil
+		case <-changed:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		}
+	}
+}
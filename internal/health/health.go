@@ -0,0 +1,148 @@
+// Package health runs the readiness checks behind /readyz: named probes of
+// the emulator backend and of the state the process set up, evaluated
+// concurrently under a shared timeout.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds one readiness evaluation so a hung backend makes the
+// probe fail instead of blocking the caller.
+const DefaultTimeout = 2 * time.Second
+
+// Status values reported for the whole report and for each check.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc probes one dependency; a nil error means it is ready.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of one check.
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of a readiness evaluation.
+type Report struct {
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+	Checks []Result  `json:"checks"`
+}
+
+// Ready reports whether every check passed.
+func (r *Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker holds the readiness checks. Checks may be added at any time; each
+// evaluation runs the checks registered at that moment.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks []check
+}
+
+// New creates a Checker whose evaluations time out after timeout
+// (DefaultTimeout when zero or negative).
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a named check. Checks are reported in the order added.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Check runs every check concurrently and reports the results. A check that
+// does not return before the timeout fails with the context's error.
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.Lock()
+	checks := append([]check(nil), c.checks...)
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := &Report{Status: StatusOK, Time: time.Now(), Checks: make([]Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Go(func() {
+			start := time.Now()
+			err := run(ctx, chk.fn)
+			res := Result{Name: chk.name, Status: StatusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+			if err != nil {
+				res.Status, res.Error = StatusFail, err.Error()
+			}
+			report.Checks[i] = res
+		})
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status != StatusOK {
+			report.Status = StatusFail
+			break
+		}
+	}
+	return report
+}
+
+// run calls fn, returning early with the context's error if fn ignores
+// cancellation.
+func run(ctx context.Context, fn CheckFunc) error {
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Gate is a readiness condition that starts closed and is opened once, e.g.
+// when startup has finished. Its zero value is closed.
+type Gate struct {
+	open atomic.Bool
+}
+
+// Open marks the condition as met.
+func (g *Gate) Open() {
+	g.open.Store(true)
+}
+
+// IsOpen reports whether Open has been called.
+func (g *Gate) IsOpen() bool {
+	return g.open.Load()
+}
+
+// CheckFunc returns a check that fails with reason until the gate is open.
+func (g *Gate) CheckFunc(reason string) CheckFunc {
+	return func(context.Context) error {
+		if !g.IsOpen() {
+			return errors.New(reason)
+		}
+		return nil
+	}
+}
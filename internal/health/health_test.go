@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChecker_Check(t *testing.T) {
+	c := New(0)
+	c.Add("ok", func(context.Context) error { return nil })
+
+	report := c.Check(context.Background())
+	if !report.Ready() || len(report.Checks) != 1 || report.Checks[0].Status != StatusOK {
+		t.Fatalf("Expected a passing report, got %+v", report)
+	}
+
+	c.Add("broken", func(context.Context) error { return errors.New("backend down") })
+	report = c.Check(context.Background())
+	if report.Ready() {
+		t.Error("Expected the report to fail when one check fails")
+	}
+	if got := report.Checks[1]; got.Name != "broken" || got.Status != StatusFail || got.Error != "backend down" {
+		t.Errorf("Expected the failing check in order with its error, got %+v", got)
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	c := New(50 * time.Millisecond)
+	block := make(chan struct{})
+	defer close(block)
+	c.Add("hung", func(context.Context) error {
+		<-block // ignores cancellation
+		return nil
+	})
+
+	start := time.Now()
+	report := c.Check(context.Background())
+	if time.Since(start) > time.Second {
+		t.Error("Expected Check to return at the timeout")
+	}
+	if report.Ready() || !strings.Contains(report.Checks[0].Error, "deadline exceeded") {
+		t.Errorf("Expected a deadline failure, got %+v", report.Checks[0])
+	}
+}
+
+func TestGate(t *testing.T) {
+	var g Gate
+	check := g.CheckFunc("startup in progress")
+	if err := check(context.Background()); err == nil || err.Error() != "startup in progress" {
+		t.Errorf("Expected closed gate to fail, got %v", err)
+	}
+	g.Open()
+	if err := check(context.Background()); err != nil {
+		t.Errorf("Expected open gate to pass, got %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+// Package loadgen publishes synthetic load to a topic: messages rendered
+// from templates, at a target rate or concurrency, for a duration or count,
+// with throughput, latency and error statistics available while it runs.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Limits and defaults applied to a Spec.
+const (
+	DefaultConcurrency = 8
+	DefaultDuration    = 10 * time.Second
+	DefaultTemplate    = `{"seq":{{seq}},"id":"{{uuid}}","value":{{int:1:100}}}`
+	MaxConcurrency     = 256
+	MaxDuration        = time.Hour
+)
+
+// Publisher publishes to the job's topic. *pubsub.TopicPublisher from the
+// emulator's pubsub package implements it.
+type Publisher interface {
+	// Publish publishes msg and waits for its message ID.
+	Publish(ctx context.Context, msg *pubsub.Message) (string, error)
+	// Stop flushes pending messages; it is called once the job ends.
+	Stop()
+}
+
+// Spec describes a load job.
+type Spec struct {
+	// Topic is the full topic name.
+	Topic string
+	// Rate is the target messages per second across all workers; zero
+	// publishes as fast as Concurrency allows.
+	Rate float64
+	// Concurrency is the number of publishes in flight at once.
+	Concurrency int
+	// Duration bounds how long the job runs. It is required unless Count is
+	// set, and capped at MaxDuration.
+	Duration time.Duration
+	// Count, when positive, stops the job after that many messages.
+	Count int64
+	// Template renders each message's data; Attributes values are templates
+	// too, so they can vary per message.
+	Template   string
+	Attributes map[string]string
+	// Seed makes the random placeholders repeatable: the same seed renders
+	// the same message for the same sequence number. Zero picks one at
+	// random.
+	Seed uint64
+}
+
+// compiled is a validated Spec with its templates parsed.
+type compiled struct {
+	Spec
+	data  *Template
+	attrs map[string]*Template
+}
+
+func (s Spec) compile() (*compiled, error) {
+	if s.Topic == "" {
+		return nil, errors.New("topic is required")
+	}
+	if s.Rate < 0 {
+		return nil, fmt.Errorf("rate cannot be negative, got %v", s.Rate)
+	}
+	if s.Concurrency == 0 {
+		s.Concurrency = DefaultConcurrency
+	}
+	if s.Concurrency < 0 || s.Concurrency > MaxConcurrency {
+		return nil, fmt.Errorf("concurrency must be between 1 and %d, got %d", MaxConcurrency, s.Concurrency)
+	}
+	if s.Count < 0 {
+		return nil, fmt.Errorf("count cannot be negative, got %d", s.Count)
+	}
+	if s.Duration < 0 || s.Duration > MaxDuration {
+		return nil, fmt.Errorf("duration must be between 0 and %s, got %s", MaxDuration, s.Duration)
+	}
+	if s.Duration == 0 && s.Count == 0 {
+		s.Duration = DefaultDuration
+	}
+	if s.Template == "" {
+		s.Template = DefaultTemplate
+	}
+	if s.Seed == 0 {
+		s.Seed = rand.Uint64()
+	}
+
+	c := &compiled{Spec: s, attrs: make(map[string]*Template, len(s.Attributes))}
+	var err error
+	if c.data, err = ParseTemplate(s.Template); err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	for k, v := range s.Attributes {
+		if c.attrs[k], err = ParseTemplate(v); err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", k, err)
+		}
+	}
+	return c, nil
+}
+
+// message renders message seq.
+func (c *compiled) message(seq int64, now time.Time) *pubsub.Message {
+	g := &generator{seq: seq, rand: rand.New(rand.NewPCG(c.Seed, uint64(seq))), now: now}
+	msg := &pubsub.Message{Data: []byte(c.data.render(g))}
+	if len(c.attrs) > 0 {
+		msg.Attributes = make(map[string]string, len(c.attrs))
+		for k, t := range c.attrs {
+			msg.Attributes[k] = t.render(g)
+		}
+	}
+	return msg
+}
+
+// State is a job's lifecycle state.
+type State string
+
+// Job states.
+const (
+	StateRunning   State = "running"
+	StateCompleted State = "completed"
+	StateStopped   State = "stopped"
+	StateFailed    State = "failed"
+)
+
+// Job is a running or finished load job.
+type Job struct {
+	id     string
+	spec   *compiled
+	stats  *stats
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	state   State
+	err     error
+	started time.Time
+	ended   time.Time
+}
+
+// Start validates spec and runs it in the background, publishing through
+// pub until the duration passes, Count messages are published, Stop is
+// called or ctx is cancelled.
+func Start(ctx context.Context, spec Spec, pub Publisher) (*Job, error) {
+	c, err := spec.compile()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	j := &Job{
+		spec:    c,
+		stats:   newStats(),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		state:   StateRunning,
+		started: time.Now(),
+	}
+	go j.run(ctx, pub)
+	return j, nil
+}
+
+// Stop ends the job early; in-flight publishes are abandoned.
+func (j *Job) Stop() {
+	j.cancel()
+}
+
+// Done is closed once the job has ended.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+func (j *Job) run(ctx context.Context, pub Publisher) {
+	defer close(j.done)
+	defer j.cancel()
+	// parent ends only when the job is stopped or its context cancelled;
+	// ctx also ends when the duration passes.
+	parent := ctx
+	if j.spec.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.spec.Duration)
+		defer cancel()
+	}
+
+	var limiter *rate.Limiter
+	if j.spec.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(j.spec.Rate), 1)
+	}
+	var (
+		seq   atomic.Int64
+		fatal error
+		once  sync.Once
+		wg    sync.WaitGroup
+	)
+	for range j.spec.Concurrency {
+		wg.Go(func() {
+			for {
+				n := seq.Add(1)
+				if j.spec.Count > 0 && n > j.spec.Count {
+					return
+				}
+				if limiter != nil && limiter.Wait(ctx) != nil {
+					return
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				start := time.Now()
+				_, err := pub.Publish(ctx, j.spec.message(n, start))
+				if err != nil && ctx.Err() != nil {
+					// Cut short by the end of the job, not a publish failure.
+					return
+				}
+				j.stats.record(time.Since(start), err)
+				if status.Code(err) == codes.NotFound {
+					// The topic is gone; every further publish would fail.
+					once.Do(func() { fatal = err })
+					j.cancel()
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	pub.Stop()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.ended = time.Now()
+	switch {
+	case fatal != nil:
+		j.state, j.err = StateFailed, fatal
+	case parent.Err() != nil:
+		j.state = StateStopped
+	default:
+		j.state = StateCompleted
+	}
+}
+
+// Status is a snapshot of a job's configuration and statistics.
+type Status struct {
+	ID          string            `json:"id"`
+	Topic       string            `json:"topic"`
+	State       State             `json:"state"`
+	Error       string            `json:"error,omitempty"`
+	Rate        float64           `json:"rate,omitempty"`
+	Concurrency int               `json:"concurrency"`
+	Duration    string            `json:"duration,omitempty"`
+	Count       int64             `json:"count,omitempty"`
+	Template    string            `json:"template"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Seed        uint64            `json:"seed"`
+	StartedAt   time.Time         `json:"started_at"`
+	EndedAt     *time.Time        `json:"ended_at,omitempty"`
+	// ElapsedSeconds is how long the job has been (or was) running.
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Stats
+}
+
+// Status returns the job's current state and statistics.
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	state, err, ended := j.state, j.err, j.ended
+	j.mu.Unlock()
+
+	s := Status{
+		ID:          j.id,
+		Topic:       j.spec.Topic,
+		State:       state,
+		Rate:        j.spec.Rate,
+		Concurrency: j.spec.Concurrency,
+		Count:       j.spec.Count,
+		Template:    j.spec.Template,
+		Attributes:  j.spec.Attributes,
+		Seed:        j.spec.Seed,
+		StartedAt:   j.started,
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	if j.spec.Duration > 0 {
+		s.Duration = j.spec.Duration.String()
+	}
+	end := time.Now()
+	if !ended.IsZero() {
+		end = ended
+		s.EndedAt = &ended
+	}
+	elapsed := end.Sub(j.started)
+	s.ElapsedSeconds = elapsed.Seconds()
+	s.Stats = j.stats.snapshot(elapsed)
+	return s
+}
@@ -0,0 +1,212 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testTopic = "projects/test-project/topics/orders"
+
+// fakePublisher records published messages, failing those fail selects.
+type fakePublisher struct {
+	delay time.Duration
+	fail  func(seq int) error
+
+	mu      sync.Mutex
+	msgs    []*pubsub.Message
+	stopped bool
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, msg *pubsub.Message) (string, error) {
+	if f.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(f.delay):
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail != nil {
+		if err := f.fail(len(f.msgs)); err != nil {
+			f.msgs = append(f.msgs, nil)
+			return "", err
+		}
+	}
+	f.msgs = append(f.msgs, msg)
+	return "id", nil
+}
+
+func (f *fakePublisher) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+}
+
+func wait(t *testing.T, j *Job) Status {
+	t.Helper()
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected job to finish")
+	}
+	return j.Status()
+}
+
+func TestStart_Count(t *testing.T) {
+	pub := &fakePublisher{}
+	j, err := Start(context.Background(), Spec{
+		Topic:       testTopic,
+		Count:       50,
+		Concurrency: 4,
+		Template:    "msg {{seq}}",
+		Attributes:  map[string]string{"region": "{{pick:eu|us}}"},
+		Seed:        7,
+	}, pub)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s := wait(t, j)
+
+	if s.State != StateCompleted || s.Published != 50 || s.Errors != 0 {
+		t.Errorf("Expected 50 messages published, got %+v", s)
+	}
+	if !pub.stopped {
+		t.Error("Expected the publisher to be stopped")
+	}
+	seen := make(map[string]bool)
+	for _, m := range pub.msgs {
+		seen[string(m.Data)] = true
+		if r := m.Attributes["region"]; r != "eu" && r != "us" {
+			t.Errorf("Expected region eu or us, got %q", r)
+		}
+	}
+	if len(seen) != 50 || !seen["msg 1"] || !seen["msg 50"] {
+		t.Errorf("Expected messages 1 to 50 once each, got %d distinct", len(seen))
+	}
+	if s.Latency.P50 < 0 || s.Latency.Max < s.Latency.P50 || s.Throughput <= 0 || s.EndedAt == nil {
+		t.Errorf("Unexpected statistics: %+v", s)
+	}
+}
+
+func TestStart_RateAndDuration(t *testing.T) {
+	pub := &fakePublisher{}
+	j, err := Start(context.Background(), Spec{Topic: testTopic, Rate: 100, Duration: 300 * time.Millisecond}, pub)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s := wait(t, j)
+
+	// 100/s for 0.3s is about 30; allow for timer slack.
+	if s.State != StateCompleted || s.Published < 15 || s.Published > 45 {
+		t.Errorf("Expected about 30 messages at 100/s, got %d", s.Published)
+	}
+}
+
+func TestStart_ErrorsAndStop(t *testing.T) {
+	pub := &fakePublisher{delay: time.Millisecond, fail: func(seq int) error {
+		if seq%2 == 0 {
+			return status.Error(codes.Unavailable, "try again")
+		}
+		return nil
+	}}
+	j, err := Start(context.Background(), Spec{Topic: testTopic, Concurrency: 1, Duration: time.Minute}, pub)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if s := j.Status(); s.State != StateRunning || s.Published == 0 {
+		t.Errorf("Expected a running job with live statistics, got %+v", s)
+	}
+	j.Stop()
+	s := wait(t, j)
+
+	if s.State != StateStopped {
+		t.Errorf("Expected state stopped, got %s", s.State)
+	}
+	if s.Errors == 0 || s.ErrorCodes["Unavailable"] != s.Errors || !strings.Contains(s.LastError, "try again") {
+		t.Errorf("Expected UNAVAILABLE errors to be counted, got %+v", s.Stats)
+	}
+}
+
+func TestStart_MissingTopicFails(t *testing.T) {
+	pub := &fakePublisher{fail: func(int) error { return status.Error(codes.NotFound, "topic not found") }}
+	j, err := Start(context.Background(), Spec{Topic: testTopic, Duration: time.Minute}, pub)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s := wait(t, j); s.State != StateFailed || !strings.Contains(s.Error, "topic not found") {
+		t.Errorf("Expected the job to fail on a missing topic, got %+v", s)
+	}
+}
+
+func TestStart_Validation(t *testing.T) {
+	invalid := []Spec{
+		{},
+		{Topic: testTopic, Rate: -1},
+		{Topic: testTopic, Concurrency: MaxConcurrency + 1},
+		{Topic: testTopic, Duration: 2 * MaxDuration},
+		{Topic: testTopic, Count: -1},
+		{Topic: testTopic, Template: "{{nope}}"},
+		{Topic: testTopic, Attributes: map[string]string{"k": "{{int:9:1}}"}},
+	}
+	for _, spec := range invalid {
+		if _, err := Start(context.Background(), spec, &fakePublisher{}); err == nil {
+			t.Errorf("Expected an error for %+v", spec)
+		}
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var opened []string
+	m := NewManager(ctx, func(topic string) Publisher {
+		opened = append(opened, topic)
+		return &fakePublisher{delay: time.Millisecond}
+	})
+
+	first, err := m.Start(Spec{Topic: testTopic, Count: 3})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first.ID != "1" || first.Concurrency != DefaultConcurrency || first.Template != DefaultTemplate {
+		t.Errorf("Unexpected job: %+v", first)
+	}
+	j, ok := m.Get("1")
+	if !ok {
+		t.Fatal("Expected job 1 to exist")
+	}
+	wait(t, j)
+
+	for i := range MaxRunningJobs {
+		if _, err := m.Start(Spec{Topic: testTopic, Duration: time.Minute}); err != nil {
+			t.Fatalf("Job %d: expected no error, got %v", i, err)
+		}
+	}
+	if _, err := m.Start(Spec{Topic: testTopic}); !errors.Is(err, ErrTooManyJobs) {
+		t.Errorf("Expected ErrTooManyJobs, got %v", err)
+	}
+	if _, err := m.Start(Spec{}); err == nil {
+		t.Error("Expected an invalid spec to be rejected")
+	}
+	if len(opened) != MaxRunningJobs+1 {
+		t.Errorf("Expected a publisher per started job, got %d", len(opened))
+	}
+
+	cancel()
+	for _, s := range m.Jobs()[1:] {
+		j, _ := m.Get(s.ID)
+		if s := wait(t, j); s.State != StateStopped {
+			t.Errorf("Expected job %s to end when the context is cancelled, got %s", s.ID, s.State)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Manager limits.
+const (
+	// MaxRunningJobs bounds how many jobs may run at once.
+	MaxRunningJobs = 8
+	// maxFinishedJobs bounds how many finished jobs are kept for reporting;
+	// the oldest are forgotten first.
+	maxFinishedJobs = 50
+)
+
+// ErrTooManyJobs is returned by Manager.Start when MaxRunningJobs are
+// already running.
+var ErrTooManyJobs = errors.New("too many load jobs running")
+
+// Manager runs load jobs and keeps them for reporting. It is safe for
+// concurrent use.
+type Manager struct {
+	ctx  context.Context
+	open func(topic string) Publisher
+
+	mu     sync.Mutex
+	jobs   []*Job
+	nextID int
+}
+
+// NewManager creates a Manager whose jobs publish through publishers made by
+// open and are stopped when ctx is cancelled.
+func NewManager(ctx context.Context, open func(topic string) Publisher) *Manager {
+	return &Manager{ctx: ctx, open: open}
+}
+
+// Start validates spec and starts a job for it with the next sequential ID.
+func (m *Manager) Start(spec Spec) (Status, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running := 0
+	for _, j := range m.jobs {
+		if !isDone(j) {
+			running++
+		}
+	}
+	if running >= MaxRunningJobs {
+		return Status{}, ErrTooManyJobs
+	}
+	// Validate before opening a publisher that would otherwise leak.
+	if _, err := spec.compile(); err != nil {
+		return Status{}, err
+	}
+
+	j, err := Start(m.ctx, spec, m.open(spec.Topic))
+	if err != nil {
+		return Status{}, err
+	}
+	m.nextID++
+	j.id = strconv.Itoa(m.nextID)
+	m.jobs = append(m.jobs, j)
+	m.pruneLocked()
+	return j.Status(), nil
+}
+
+// Get returns the job with id.
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.id == id {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// Jobs returns the status of every kept job in the order they started.
+func (m *Manager) Jobs() []Status {
+	m.mu.Lock()
+	jobs := slices.Clone(m.jobs)
+	m.mu.Unlock()
+	out := make([]Status, len(jobs))
+	for i, j := range jobs {
+		out[i] = j.Status()
+	}
+	return out
+}
+
+// pruneLocked forgets the oldest finished jobs beyond maxFinishedJobs.
+func (m *Manager) pruneLocked() {
+	finished := 0
+	for _, j := range m.jobs {
+		if isDone(j) {
+			finished++
+		}
+	}
+	m.jobs = slices.DeleteFunc(m.jobs, func(j *Job) bool {
+		if finished > maxFinishedJobs && isDone(j) {
+			finished--
+			return true
+		}
+		return false
+	})
+}
+
+func isDone(j *Job) bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,110 @@
+package loadgen
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// latencySamples is how many of the most recent publish latencies the
+// percentiles are computed over.
+const latencySamples = 4096
+
+// Stats are a job's publish statistics.
+type Stats struct {
+	Published int64 `json:"published"`
+	Errors    int64 `json:"errors"`
+	// ErrorCodes counts failed publishes by gRPC code.
+	ErrorCodes map[string]int64 `json:"error_codes,omitempty"`
+	LastError  string           `json:"last_error,omitempty"`
+	// Throughput is successful publishes per second since the job started.
+	Throughput float64 `json:"throughput"`
+	Latency    Latency `json:"latency_ms"`
+}
+
+// Latency summarises publish latencies in milliseconds. Percentiles cover
+// the most recent publishes; Mean and Max cover the whole job.
+type Latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// stats accumulates publish outcomes. It is safe for concurrent use.
+type stats struct {
+	mu        sync.Mutex
+	published int64
+	errors    int64
+	codes     map[string]int64
+	lastError string
+	total     time.Duration
+	max       time.Duration
+	samples   []time.Duration
+	next      int
+}
+
+func newStats() *stats {
+	return &stats{codes: make(map[string]int64), samples: make([]time.Duration, 0, latencySamples)}
+}
+
+// record counts one publish that took latency and failed with err, if set.
+func (s *stats) record(latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.errors++
+		s.codes[status.Code(err).String()]++
+		s.lastError = err.Error()
+		return
+	}
+	s.published++
+	s.total += latency
+	s.max = max(s.max, latency)
+	if len(s.samples) < latencySamples {
+		s.samples = append(s.samples, latency)
+	} else {
+		s.samples[s.next] = latency
+		s.next = (s.next + 1) % latencySamples
+	}
+}
+
+// snapshot returns the statistics for a job that has run for elapsed.
+func (s *stats) snapshot(elapsed time.Duration) Stats {
+	s.mu.Lock()
+	out := Stats{Published: s.published, Errors: s.errors, LastError: s.lastError}
+	if len(s.codes) > 0 {
+		out.ErrorCodes = maps.Clone(s.codes)
+	}
+	samples := slices.Clone(s.samples)
+	if s.published > 0 {
+		out.Latency.Mean = ms(s.total / time.Duration(s.published))
+		out.Latency.Max = ms(s.max)
+	}
+	s.mu.Unlock()
+
+	if elapsed > 0 {
+		out.Throughput = float64(out.Published) / elapsed.Seconds()
+	}
+	if len(samples) > 0 {
+		slices.Sort(samples)
+		out.Latency.P50 = ms(percentile(samples, 0.50))
+		out.Latency.P95 = ms(percentile(samples, 0.95))
+		out.Latency.P99 = ms(percentile(samples, 0.99))
+	}
+	return out
+}
+
+// percentile returns the p-th percentile of sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
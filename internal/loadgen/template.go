@@ -0,0 +1,204 @@
+package loadgen
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Template is a payload or attribute value with placeholders such as
+// {{seq}} or {{int:1:100}}, filled in for each message.
+//
+// Placeholders:
+//
+//	{{seq}}             the message's sequence number, starting at 1
+//	{{uuid}}            a random UUID
+//	{{int}}             a random integer in [0, 1000]; {{int:min:max}} sets the range
+//	{{float}}           a random number in [0, 1); {{float:min:max}} sets the range
+//	{{bool}}            true or false
+//	{{hex:n}}           n random hex digits (default 16)
+//	{{pick:a|b|c}}      one of the listed values
+//	{{word}}            a random word
+//	{{name}}            a random full name
+//	{{email}}           a random email address
+//	{{now}}             the publish time in RFC 3339
+//	{{unix_ms}}         the publish time in Unix milliseconds
+type Template struct {
+	parts []part
+}
+
+// part renders one literal or placeholder.
+type part func(b *strings.Builder, g *generator)
+
+// generator is the per-message state placeholders draw from.
+type generator struct {
+	seq  int64
+	rand *rand.Rand
+	now  time.Time
+}
+
+// ParseTemplate compiles s, failing on unknown or malformed placeholders.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{}
+	for s != "" {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			t.literal(s)
+			break
+		}
+		t.literal(s[:start])
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder at %q", s[start:])
+		}
+		p, err := placeholder(strings.TrimSpace(s[start+2 : start+end]))
+		if err != nil {
+			return nil, err
+		}
+		t.parts = append(t.parts, p)
+		s = s[start+end+2:]
+	}
+	return t, nil
+}
+
+func (t *Template) literal(s string) {
+	if s != "" {
+		t.parts = append(t.parts, func(b *strings.Builder, _ *generator) { b.WriteString(s) })
+	}
+}
+
+// Render fills in the template for message seq published at now, drawing
+// random values from r.
+func (t *Template) Render(seq int64, now time.Time, r *rand.Rand) string {
+	return t.render(&generator{seq: seq, rand: r, now: now})
+}
+
+// render fills in the template for one message.
+func (t *Template) render(g *generator) string {
+	var b strings.Builder
+	for _, p := range t.parts {
+		p(&b, g)
+	}
+	return b.String()
+}
+
+func placeholder(spec string) (part, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+	noArg := func(p part) (part, error) {
+		if hasArg {
+			return nil, fmt.Errorf("{{%s}} takes no arguments", name)
+		}
+		return p, nil
+	}
+
+	switch name {
+	case "seq":
+		return noArg(func(b *strings.Builder, g *generator) { b.WriteString(strconv.FormatInt(g.seq, 10)) })
+	case "uuid":
+		return noArg(func(b *strings.Builder, g *generator) { writeUUID(b, g.rand) })
+	case "bool":
+		return noArg(func(b *strings.Builder, g *generator) { b.WriteString(strconv.FormatBool(g.rand.IntN(2) == 1)) })
+	case "word":
+		return noArg(pickFrom(words))
+	case "name":
+		return noArg(func(b *strings.Builder, g *generator) {
+			b.WriteString(firstNames[g.rand.IntN(len(firstNames))])
+			b.WriteByte(' ')
+			b.WriteString(lastNames[g.rand.IntN(len(lastNames))])
+		})
+	case "email":
+		return noArg(func(b *strings.Builder, g *generator) {
+			b.WriteString(strings.ToLower(firstNames[g.rand.IntN(len(firstNames))]))
+			b.WriteByte('.')
+			b.WriteString(strings.ToLower(lastNames[g.rand.IntN(len(lastNames))]))
+			b.WriteByte('@')
+			b.WriteString(domains[g.rand.IntN(len(domains))])
+		})
+	case "now":
+		return noArg(func(b *strings.Builder, g *generator) { b.WriteString(g.now.Format(time.RFC3339Nano)) })
+	case "unix_ms":
+		return noArg(func(b *strings.Builder, g *generator) { b.WriteString(strconv.FormatInt(g.now.UnixMilli(), 10)) })
+	case "int":
+		lo, hi := int64(0), int64(1000)
+		if hasArg {
+			var err error
+			if lo, hi, err = parseRange(name, arg, strconv.ParseInt); err != nil {
+				return nil, err
+			}
+		}
+		return func(b *strings.Builder, g *generator) {
+			b.WriteString(strconv.FormatInt(lo+g.rand.Int64N(hi-lo+1), 10))
+		}, nil
+	case "float":
+		lo, hi := 0.0, 1.0
+		if hasArg {
+			var err error
+			parse := func(s string, _ int, _ int) (float64, error) { return strconv.ParseFloat(s, 64) }
+			if lo, hi, err = parseRange(name, arg, parse); err != nil {
+				return nil, err
+			}
+		}
+		return func(b *strings.Builder, g *generator) {
+			b.WriteString(strconv.FormatFloat(lo+g.rand.Float64()*(hi-lo), 'f', -1, 64))
+		}, nil
+	case "hex":
+		n := 16
+		if hasArg {
+			var err error
+			if n, err = strconv.Atoi(arg); err != nil || n <= 0 || n > 256 {
+				return nil, fmt.Errorf("{{hex:n}} needs n between 1 and 256, got %q", arg)
+			}
+		}
+		return func(b *strings.Builder, g *generator) {
+			const digits = "0123456789abcdef"
+			for range n {
+				b.WriteByte(digits[g.rand.IntN(16)])
+			}
+		}, nil
+	case "pick":
+		if !hasArg || arg == "" {
+			return nil, fmt.Errorf("{{pick:a|b}} needs at least one value")
+		}
+		return pickFrom(strings.Split(arg, "|")), nil
+	default:
+		return nil, fmt.Errorf("unknown placeholder {{%s}}", spec)
+	}
+}
+
+// parseRange parses "min:max" with parse, requiring min <= max.
+func parseRange[T int64 | float64](name, arg string, parse func(string, int, int) (T, error)) (T, T, error) {
+	loStr, hiStr, ok := strings.Cut(arg, ":")
+	lo, errLo := parse(loStr, 10, 64)
+	hi, errHi := parse(hiStr, 10, 64)
+	if !ok || errLo != nil || errHi != nil || lo > hi {
+		return 0, 0, fmt.Errorf("{{%s:min:max}} needs min <= max, got %q", name, arg)
+	}
+	return lo, hi, nil
+}
+
+func pickFrom(values []string) part {
+	return func(b *strings.Builder, g *generator) { b.WriteString(values[g.rand.IntN(len(values))]) }
+}
+
+// writeUUID writes a random (version 4) UUID.
+func writeUUID(b *strings.Builder, r *rand.Rand) {
+	var u [16]byte
+	for i := 0; i < 16; i += 8 {
+		v := r.Uint64()
+		for j := range 8 {
+			u[i+j] = byte(v >> (8 * j))
+		}
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	fmt.Fprintf(b, "%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+var (
+	words      = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliet", "kilo", "lima", "mike", "november", "oscar", "papa"}
+	firstNames = []string{"Ada", "Alan", "Barbara", "Dennis", "Edsger", "Frances", "Grace", "John", "Ken", "Linus", "Margaret", "Niklaus", "Radia", "Rob", "Sophie", "Tim"}
+	lastNames  = []string{"Allen", "Backus", "Hopper", "Kernighan", "Knuth", "Lamport", "Liskov", "Lovelace", "Perlman", "Pike", "Ritchie", "Thompson", "Torvalds", "Turing", "Wilson", "Wirth"}
+	domains    = []string{"example.com", "example.net", "example.org"}
+)
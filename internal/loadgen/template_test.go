@@ -0,0 +1,75 @@
+package loadgen
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func render(t *testing.T, tmpl string, seq int64, seed uint64) string {
+	t.Helper()
+	tp, err := ParseTemplate(tmpl)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", tmpl, err)
+	}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return tp.render(&generator{seq: seq, rand: rand.New(rand.NewPCG(seed, uint64(seq))), now: now})
+}
+
+func TestTemplate_Placeholders(t *testing.T) {
+	tests := []struct {
+		template string
+		pattern  string
+	}{
+		{"plain text", `^plain text$`},
+		{`{"seq":{{seq}}}`, `^\{"seq":42\}$`},
+		{"{{ seq }}", `^42$`},
+		{"{{uuid}}", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"{{int}}", `^\d{1,4}$`},
+		{"{{int:-5:-5}}", `^-5$`},
+		{"{{float:1.5:1.5}}", `^1\.5$`},
+		{"{{bool}}", `^(true|false)$`},
+		{"{{hex:4}}", `^[0-9a-f]{4}$`},
+		{"{{pick:eu|us}}", `^(eu|us)$`},
+		{"{{word}}", `^[a-z]+$`},
+		{"{{name}}", `^[A-Z][a-z]+ [A-Z][a-z]+$`},
+		{"{{email}}", `^[a-z]+\.[a-z]+@example\.(com|net|org)$`},
+		{"{{now}}", `^2026-01-02T03:04:05Z$`},
+		{"{{unix_ms}}", `^` + strconv.FormatInt(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli(), 10) + `$`},
+	}
+	for _, tt := range tests {
+		got := render(t, tt.template, 42, 1)
+		if !regexp.MustCompile(tt.pattern).MatchString(got) {
+			t.Errorf("%s: expected output matching %s, got %q", tt.template, tt.pattern, got)
+		}
+	}
+}
+
+func TestTemplate_Repeatable(t *testing.T) {
+	const tmpl = "{{uuid}} {{int}} {{name}}"
+	if a, b := render(t, tmpl, 7, 99), render(t, tmpl, 7, 99); a != b {
+		t.Errorf("Expected the same seed and sequence to render the same, got %q and %q", a, b)
+	}
+	if a, b := render(t, tmpl, 7, 99), render(t, tmpl, 8, 99); a == b {
+		t.Errorf("Expected different sequence numbers to differ, got %q twice", a)
+	}
+}
+
+func TestParseTemplate_Errors(t *testing.T) {
+	for _, tmpl := range []string{
+		"{{seq",
+		"{{nope}}",
+		"{{seq:1}}",
+		"{{int:5:1}}",
+		"{{int:a:b}}",
+		"{{float:1}}",
+		"{{hex:0}}",
+		"{{pick:}}",
+	} {
+		if _, err := ParseTemplate(tmpl); err == nil {
+			t.Errorf("%s: expected an error", tmpl)
+		}
+	}
+}
@@ -2,29 +2,44 @@ package pubsub
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"cloud.google.com/go/pubsub/v2"
+	vkit "cloud.google.com/go/pubsub/v2/apiv1"
 	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
 	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
 )
 
 // Client wraps the Google Cloud Pub/Sub client with additional functionality
 type Client struct {
 	client    *pubsub.Client
+	schemas   *vkit.SchemaClient
 	projectID string
 	log       *logger.Logger
 }
 
-// NewClient creates a new Pub/Sub client wrapper
-func NewClient(ctx context.Context, projectID string, log *logger.Logger) (*Client, error) {
-	client, err := pubsub.NewClient(ctx, projectID)
+// NewClient creates a new Pub/Sub client wrapper. Options are passed to the
+// underlying client (e.g. option.WithGRPCConn to target an in-process server).
+func NewClient(ctx context.Context, projectID string, log *logger.Logger, opts ...option.ClientOption) (*Client, error) {
+	client, err := pubsub.NewClient(ctx, projectID, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pubsub client: %w", err)
 	}
 
+	// The schema service is not part of pubsub.Client; it honours
+	// PUBSUB_EMULATOR_HOST the same way.
+	schemas, err := vkit.NewSchemaClient(ctx, opts...)
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to create schema client: %w", err)
+	}
+
 	return &Client{
 		client:    client,
+		schemas:   schemas,
 		projectID: projectID,
 		log:       log,
 	}, nil
@@ -32,6 +47,9 @@ func NewClient(ctx context.Context, projectID string, log *logger.Logger) (*Clie
 
 // Close closes the underlying Pub/Sub client
 func (c *Client) Close() error {
+	if c.schemas != nil {
+		_ = c.schemas.Close()
+	}
 	return c.client.Close()
 }
 
@@ -40,6 +58,12 @@ func (c *Client) GetClient() *pubsub.Client {
 	return c.client
 }
 
+// SchemaClient returns the schema service client (nil if not created by
+// NewClient)
+func (c *Client) SchemaClient() *vkit.SchemaClient {
+	return c.schemas
+}
+
 // ProjectID returns the project ID
 func (c *Client) ProjectID() string {
 	return c.projectID
@@ -48,7 +72,7 @@ func (c *Client) ProjectID() string {
 // CreateTopic creates a new Pub/Sub topic
 func (c *Client) CreateTopic(ctx context.Context, topicID string) (*pubsubpb.Topic, error) {
 	topic, err := c.client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{
-		Name: fmt.Sprintf("projects/%s/topics/%s", c.projectID, topicID),
+		Name: TopicName(c.projectID, topicID),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create topic %s: %w", topicID, err)
@@ -60,8 +84,8 @@ func (c *Client) CreateTopic(ctx context.Context, topicID string) (*pubsubpb.Top
 // CreateSubscription creates a new Pub/Sub subscription
 func (c *Client) CreateSubscription(ctx context.Context, subscriptionID, topicID string, ackDeadlineSeconds int32) (*pubsubpb.Subscription, error) {
 	sub, err := c.client.SubscriptionAdminClient.CreateSubscription(ctx, &pubsubpb.Subscription{
-		Name:               fmt.Sprintf("projects/%s/subscriptions/%s", c.projectID, subscriptionID),
-		Topic:              fmt.Sprintf("projects/%s/topics/%s", c.projectID, topicID),
+		Name:               SubscriptionName(c.projectID, subscriptionID),
+		Topic:              TopicName(c.projectID, topicID),
 		AckDeadlineSeconds: ackDeadlineSeconds,
 	})
 	if err != nil {
@@ -95,3 +119,78 @@ func (c *Client) CreateTopicsAndSubscriptions(ctx context.Context, topicIDs, sub
 
 	return nil
 }
+
+// DeleteTopic deletes a Pub/Sub topic
+func (c *Client) DeleteTopic(ctx context.Context, topicID string) error {
+	err := c.client.TopicAdminClient.DeleteTopic(ctx, &pubsubpb.DeleteTopicRequest{
+		Topic: TopicName(c.projectID, topicID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete topic %s: %w", topicID, err)
+	}
+	c.log.Info("Deleted topic: %s", topicID)
+	return nil
+}
+
+// DeleteSubscription deletes a Pub/Sub subscription
+func (c *Client) DeleteSubscription(ctx context.Context, subscriptionID string) error {
+	err := c.client.SubscriptionAdminClient.DeleteSubscription(ctx, &pubsubpb.DeleteSubscriptionRequest{
+		Subscription: SubscriptionName(c.projectID, subscriptionID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription %s: %w", subscriptionID, err)
+	}
+	c.log.Info("Deleted subscription: %s", subscriptionID)
+	return nil
+}
+
+// Ping checks that the emulator answers admin requests by listing at most
+// one topic in the default project.
+func (c *Client) Ping(ctx context.Context) error {
+	it := c.client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{
+		Project:  "projects/" + c.projectID,
+		PageSize: 1,
+	})
+	if _, err := it.Next(); err != nil && !errors.Is(err, iterator.Done) {
+		return fmt.Errorf("emulator unreachable: %w", err)
+	}
+	return nil
+}
+
+// ListTopicNames returns the full names of all topics in project
+func (c *Client) ListTopicNames(ctx context.Context, project string) ([]string, error) {
+	it := c.client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{
+		Project: "projects/" + project,
+	})
+	var names []string
+	for {
+		topic, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list topics in %s: %w", project, err)
+		}
+		names = append(names, topic.Name)
+	}
+}
+
+// ListSubscriptionTopics returns the full name of every subscription in
+// project mapped to the full name of its topic, which may live in another
+// project.
+func (c *Client) ListSubscriptionTopics(ctx context.Context, project string) (map[string]string, error) {
+	it := c.client.SubscriptionAdminClient.ListSubscriptions(ctx, &pubsubpb.ListSubscriptionsRequest{
+		Project: "projects/" + project,
+	})
+	subs := make(map[string]string)
+	for {
+		sub, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return subs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list subscriptions in %s: %w", project, err)
+		}
+		subs[sub.Name] = sub.Topic
+	}
+}
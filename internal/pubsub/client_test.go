@@ -233,3 +233,58 @@ func TestClient_CreateTopicsAndSubscriptions_EmptySlices(t *testing.T) {
 		t.Errorf("Expected no error for empty slices, got %v", err)
 	}
 }
+
+func TestClient_Ping(t *testing.T) {
+	_, client, cleanup := setupTestServer(t)
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("Expected ping to succeed, got %v", err)
+	}
+
+	cleanup()
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("Expected ping to fail once the connection is closed")
+	}
+}
+
+func TestClient_ListAndDelete(t *testing.T) {
+	_, client, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := client.CreateTopicsAndSubscriptions(ctx, []string{"topic-a", "topic-b"}, []string{"sub-a", "sub-b"}); err != nil {
+		t.Fatalf("Failed to create resources: %v", err)
+	}
+
+	topics, err := client.ListTopicNames(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("ListTopicNames failed: %v", err)
+	}
+	if len(topics) != 2 {
+		t.Errorf("Expected 2 topics, got %v", topics)
+	}
+
+	subs, err := client.ListSubscriptionTopics(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("ListSubscriptionTopics failed: %v", err)
+	}
+	if subs["projects/test-project/subscriptions/sub-a"] != "projects/test-project/topics/topic-a" ||
+		subs["projects/test-project/subscriptions/sub-b"] != "projects/test-project/topics/topic-b" {
+		t.Errorf("Unexpected subscription topics: %v", subs)
+	}
+
+	if err := client.DeleteSubscription(ctx, "sub-a"); err != nil {
+		t.Fatalf("DeleteSubscription failed: %v", err)
+	}
+	if err := client.DeleteTopic(ctx, "topic-a"); err != nil {
+		t.Fatalf("DeleteTopic failed: %v", err)
+	}
+	if err := client.DeleteTopic(ctx, "topic-a"); err == nil {
+		t.Error("Expected error deleting a missing topic")
+	}
+
+	subs, _ = client.ListSubscriptionTopics(ctx, "test-project")
+	if _, ok := subs["projects/test-project/subscriptions/sub-a"]; ok || len(subs) != 1 {
+		t.Errorf("Expected only sub-b to remain, got %v", subs)
+	}
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TopicName returns the full resource name of topic. A bare ID is resolved
+// in project; a name that is already projects/*/topics/* is returned as is,
+// which is how resources in other projects are referenced.
+func TopicName(project, topic string) string {
+	return resourceName(project, "topics", topic)
+}
+
+// SubscriptionName returns the full resource name of subscription, resolving
+// a bare ID in project like TopicName.
+func SubscriptionName(project, subscription string) string {
+	return resourceName(project, "subscriptions", subscription)
+}
+
+// ParseName splits a full resource name such as projects/p/topics/t into its
+// project and ID. ok is false for anything else, including bare IDs.
+func ParseName(name string) (project, id string, ok bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 || parts[0] != "projects" || parts[1] == "" || parts[3] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[3], true
+}
+
+func resourceName(project, collection, nameOrID string) string {
+	if strings.HasPrefix(nameOrID, "projects/") {
+		return nameOrID
+	}
+	return fmt.Sprintf("projects/%s/%s/%s", project, collection, nameOrID)
+}
@@ -0,0 +1,41 @@
+package pubsub
+
+import "testing"
+
+func TestResourceNames(t *testing.T) {
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{TopicName("app", "orders"), "projects/app/topics/orders"},
+		{TopicName("app", "projects/other/topics/orders"), "projects/other/topics/orders"},
+		{SubscriptionName("app", "orders-sub"), "projects/app/subscriptions/orders-sub"},
+		{SubscriptionName("app", "projects/other/subscriptions/orders-sub"), "projects/other/subscriptions/orders-sub"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, tt.got)
+		}
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		id      string
+		ok      bool
+	}{
+		{"projects/app/topics/orders", "app", "orders", true},
+		{"projects/other/subscriptions/orders-sub", "other", "orders-sub", true},
+		{"orders", "", "", false},
+		{"projects//topics/orders", "", "", false},
+		{"projects/app/topics", "", "", false},
+	}
+	for _, tt := range tests {
+		project, id, ok := ParseName(tt.name)
+		if project != tt.project || id != tt.id || ok != tt.ok {
+			t.Errorf("ParseName(%q) = (%q, %q, %v), want (%q, %q, %v)", tt.name, project, id, ok, tt.project, tt.id, tt.ok)
+		}
+	}
+}
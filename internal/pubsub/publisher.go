@@ -63,6 +63,29 @@ func (p *Publisher) PublishToTopics(ctx context.Context, topicIDs []string, data
 	return messageIDs, nil
 }
 
+// TopicPublisher publishes a stream of messages to one topic through a
+// single client publisher, so messages are batched as the client library
+// does. Unlike PublishMessage it does not log each message.
+type TopicPublisher struct {
+	publisher *pubsub.Publisher
+}
+
+// Topic returns a TopicPublisher for topicID, which may also be a full topic
+// name. Stop it when done.
+func (p *Publisher) Topic(topicID string) *TopicPublisher {
+	return &TopicPublisher{publisher: p.client.client.Publisher(topicID)}
+}
+
+// Publish publishes msg and waits for its message ID.
+func (t *TopicPublisher) Publish(ctx context.Context, msg *pubsub.Message) (string, error) {
+	return t.publisher.Publish(ctx, msg).Get(ctx)
+}
+
+// Stop flushes pending messages and releases the publisher.
+func (t *TopicPublisher) Stop() {
+	t.publisher.Stop()
+}
+
 // CreateMessageInfo creates a MessageInfo from a pubsub.Message
 func CreateMessageInfo(msg *pubsub.Message, topicID string) MessageInfo {
 	return MessageInfo{
@@ -358,3 +358,25 @@ func TestPublisher_PublishMessage_WithAttributes(t *testing.T) {
 		}
 	}
 }
+
+func TestPublisher_Topic(t *testing.T) {
+	srv, pub, cleanup := setupPublisherTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if _, err := pub.client.CreateTopic(ctx, "test-topic"); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+
+	topic := pub.Topic("projects/test-project/topics/test-topic")
+	for _, data := range []string{"one", "two"} {
+		if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte(data)}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	topic.Stop()
+
+	if msgs := srv.Messages(); len(msgs) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(msgs))
+	}
+}
@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Receivers runs one receive loop per subscription and lets callers start
+// and stop them individually while the process runs, e.g. when a config
+// reload adds or removes subscriptions.
+type Receivers struct {
+	subscriber *Subscriber
+	ctx        context.Context
+	handler    MessageHandler
+
+	mu      sync.Mutex
+	running map[string]*receiver
+	wg      sync.WaitGroup
+}
+
+type receiver struct {
+	topicID string
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewReceivers returns an empty set of receivers. Every receiver stops when
+// ctx is cancelled; Wait then blocks until they have all drained.
+func (s *Subscriber) NewReceivers(ctx context.Context, handler MessageHandler) *Receivers {
+	return &Receivers{
+		subscriber: s,
+		ctx:        ctx,
+		handler:    handler,
+		running:    make(map[string]*receiver),
+	}
+}
+
+// Start begins receiving from subscriptionID unless a receiver for it is
+// already running. It reports whether a receiver was started.
+func (r *Receivers) Start(subscriptionID, topicID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.running[subscriptionID]; ok || r.ctx.Err() != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	rc := &receiver{topicID: topicID, cancel: cancel, done: make(chan struct{})}
+	r.running[subscriptionID] = rc
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer close(rc.done)
+
+		err := r.subscriber.receive(ctx, Delivery{SubscriptionID: subscriptionID, TopicID: topicID}, r.handler)
+		if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+			r.subscriber.log.Error("Error receiving from subscription %s: %v", subscriptionID, err)
+		}
+
+		// Forget receivers that stopped on their own (e.g. the subscription
+		// was deleted) so a later Start can bring them back.
+		r.mu.Lock()
+		if r.running[subscriptionID] == rc {
+			delete(r.running, subscriptionID)
+		}
+		r.mu.Unlock()
+		cancel()
+	}()
+	return true
+}
+
+// Stop cancels the receiver for subscriptionID and waits for in-flight
+// messages to finish. It reports whether a receiver was running.
+func (r *Receivers) Stop(subscriptionID string) bool {
+	r.mu.Lock()
+	rc, ok := r.running[subscriptionID]
+	delete(r.running, subscriptionID)
+	r.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	rc.cancel()
+	<-rc.done
+	return true
+}
+
+// Running returns the subscriptions with an active receiver, mapped to the
+// topic each was started with.
+func (r *Receivers) Running() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs := make(map[string]string, len(r.running))
+	for id, rc := range r.running {
+		subs[id] = rc.topicID
+	}
+	return subs
+}
+
+// Wait blocks until every receiver has stopped.
+func (r *Receivers) Wait() {
+	r.wg.Wait()
+}
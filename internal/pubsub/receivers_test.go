@@ -0,0 +1,101 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+)
+
+func TestReceivers_StartStop(t *testing.T) {
+	_, sub, pub, cleanup := setupSubscriberTest(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := sub.client.CreateTopic(ctx, "test-topic"); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+	if _, err := sub.client.CreateSubscription(ctx, "test-sub", "test-topic", 20); err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+
+	received := make(chan Delivery, 1)
+	receivers := sub.NewReceivers(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		d, _ := DeliveryFromContext(ctx)
+		received <- d
+	})
+
+	if !receivers.Start("test-sub", "test-topic") {
+		t.Fatal("Expected receiver to start")
+	}
+	if receivers.Start("test-sub", "test-topic") {
+		t.Error("Expected duplicate Start to be a no-op")
+	}
+	if got := receivers.Running(); got["test-sub"] != "test-topic" {
+		t.Errorf("Expected test-sub running on test-topic, got %v", got)
+	}
+
+	if _, err := pub.PublishMessage(ctx, "test-topic", "hello", nil); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	select {
+	case d := <-received:
+		if d.SubscriptionID != "test-sub" || d.TopicID != "test-topic" {
+			t.Errorf("Unexpected delivery %+v", d)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for message")
+	}
+
+	if !receivers.Stop("test-sub") {
+		t.Error("Expected Stop to report a running receiver")
+	}
+	if receivers.Stop("test-sub") {
+		t.Error("Expected second Stop to be a no-op")
+	}
+	if len(receivers.Running()) != 0 {
+		t.Errorf("Expected no running receivers, got %v", receivers.Running())
+	}
+
+	// A stopped subscription can be started again.
+	if !receivers.Start("test-sub", "test-topic") {
+		t.Error("Expected receiver to restart")
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		receivers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for receivers to drain")
+	}
+	if receivers.Start("test-sub", "test-topic") {
+		t.Error("Expected Start after cancellation to be refused")
+	}
+}
+
+func TestReceivers_ForgetsFailedReceiver(t *testing.T) {
+	_, sub, _, cleanup := setupSubscriberTest(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receivers := sub.NewReceivers(ctx, func(context.Context, *pubsub.Message) {})
+	receivers.Start("missing-sub", "")
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(receivers.Running()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected receiver for a missing subscription to be removed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -13,10 +13,27 @@ import (
 // MessageHandler is a function that processes received messages
 type MessageHandler func(ctx context.Context, msg *pubsub.Message)
 
+// Delivery identifies the subscription (and, when known, the topic) a message
+// was received from. It is attached to the context passed to a MessageHandler.
+type Delivery struct {
+	SubscriptionID string
+	TopicID        string
+}
+
+type deliveryKey struct{}
+
+// DeliveryFromContext returns the Delivery attached to a handler's context by
+// Subscribe or SubscribeToAll.
+func DeliveryFromContext(ctx context.Context) (Delivery, bool) {
+	d, ok := ctx.Value(deliveryKey{}).(Delivery)
+	return d, ok
+}
+
 // Subscriber handles message subscriptions
 type Subscriber struct {
 	client *Client
 	log    *logger.Logger
+	onAck  MessageHandler
 }
 
 // NewSubscriber creates a new subscriber
@@ -27,15 +44,15 @@ func NewSubscriber(client *Client, log *logger.Logger) *Subscriber {
 	}
 }
 
+// OnAck registers a callback invoked after each received message has been
+// acknowledged. The callback's context carries the message's Delivery.
+func (s *Subscriber) OnAck(fn MessageHandler) {
+	s.onAck = fn
+}
+
 // Subscribe starts receiving messages from a subscription
 func (s *Subscriber) Subscribe(ctx context.Context, subscriptionID string, handler MessageHandler) error {
-	sub := s.client.client.Subscriber(subscriptionID)
-
-	err := sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-		s.log.Info("Received message from %s: %s", subscriptionID, string(msg.Data))
-		handler(ctx, msg)
-		msg.Ack()
-	})
+	err := s.receive(ctx, Delivery{SubscriptionID: subscriptionID}, handler)
 
 	if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
 		return fmt.Errorf("error receiving from subscription %s: %w", subscriptionID, err)
@@ -62,12 +79,7 @@ func (s *Subscriber) SubscribeToAll(ctx context.Context, subscriptionIDs, topicI
 		wg.Add(1)
 		go func(subscriptionID, topic string) {
 			defer wg.Done()
-			sub := s.client.client.Subscriber(subscriptionID)
-			err := sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-				s.log.Info("Received message from %s: %s", subscriptionID, string(msg.Data))
-				handler(ctx, msg)
-				msg.Ack()
-			})
+			err := s.receive(ctx, Delivery{SubscriptionID: subscriptionID, TopicID: topic}, handler)
 			if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
 				s.log.Error("Error receiving from subscription %s: %v", subscriptionID, err)
 			}
@@ -75,3 +87,19 @@ func (s *Subscriber) SubscribeToAll(ctx context.Context, subscriptionIDs, topicI
 	}
 	return &wg
 }
+
+// receive runs the blocking receive loop for one subscription, tagging each
+// handler context with the delivery and acking once the handler returns.
+func (s *Subscriber) receive(ctx context.Context, delivery Delivery, handler MessageHandler) error {
+	sub := s.client.client.Subscriber(delivery.SubscriptionID)
+
+	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		ctx = context.WithValue(ctx, deliveryKey{}, delivery)
+		s.log.Info("Received message from %s: %s", delivery.SubscriptionID, string(msg.Data))
+		handler(ctx, msg)
+		msg.Ack()
+		if s.onAck != nil {
+			s.onAck(ctx, msg)
+		}
+	})
+}
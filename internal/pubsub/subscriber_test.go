@@ -370,3 +370,54 @@ func TestSubscriber_Subscribe_NonexistentSubscription(t *testing.T) {
 		t.Error("Expected error when subscribing to non-existent subscription")
 	}
 }
+
+func TestSubscriber_DeliveryContextAndOnAck(t *testing.T) {
+	_, sub, pub, cleanup := setupSubscriberTest(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := sub.client.CreateTopic(ctx, "orders"); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+	if _, err := sub.client.CreateSubscription(ctx, "orders-sub", "orders", 20); err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+	if _, err := pub.PublishMessage(ctx, "orders", "hello", nil); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	var handled Delivery
+	acked := make(chan Delivery, 1)
+
+	sub.OnAck(func(ctx context.Context, msg *pubsub.Message) {
+		d, _ := DeliveryFromContext(ctx)
+		acked <- d
+		cancel()
+	})
+	wg := sub.SubscribeToAll(ctx, []string{"orders-sub"}, []string{"orders"}, func(ctx context.Context, msg *pubsub.Message) {
+		handled, _ = DeliveryFromContext(ctx)
+	})
+
+	select {
+	case d := <-acked:
+		want := Delivery{SubscriptionID: "orders-sub", TopicID: "orders"}
+		if d != want {
+			t.Errorf("Expected ack delivery %+v, got %+v", want, d)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for ack callback")
+	}
+	wg.Wait()
+
+	if handled.SubscriptionID != "orders-sub" || handled.TopicID != "orders" {
+		t.Errorf("Expected handler context to carry the delivery, got %+v", handled)
+	}
+}
+
+func TestDeliveryFromContext_Missing(t *testing.T) {
+	if _, ok := DeliveryFromContext(context.Background()); ok {
+		t.Error("Expected no delivery on a bare context")
+	}
+}
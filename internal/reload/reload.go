@@ -0,0 +1,331 @@
+// Package reload applies configuration changes to a running emulator: it
+// reconciles topics, subscriptions and message receivers with the desired
+// configuration on SIGHUP or when the config file changes.
+package reload
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/config"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/pubsub"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+// Options configures a Reloader.
+type Options struct {
+	Client    *pubsub.Client
+	Receivers *pubsub.Receivers
+	// Load reads the desired configuration (config.LoadFromEnv in production).
+	Load func() (*config.Config, error)
+	// Initial is the configuration the process started with.
+	Initial *config.Config
+	// AckDeadlineSeconds is applied to subscriptions created by a reload.
+	AckDeadlineSeconds int32
+	Logger             *logger.Logger
+}
+
+// Result lists what a reload changed.
+type Result struct {
+	TopicsCreated        []string
+	TopicsDeleted        []string
+	SubscriptionsCreated []string
+	SubscriptionsDeleted []string
+	ReceiversStarted     []string
+	ReceiversStopped     []string
+}
+
+// Reloader reconciles the emulator with the configuration. Reloads are
+// serialised; the dashboard and its message history are left untouched.
+type Reloader struct {
+	client      *pubsub.Client
+	receivers   *pubsub.Receivers
+	load        func() (*config.Config, error)
+	ackDeadline int32
+	log         *logger.Logger
+
+	mu      sync.Mutex
+	current *config.Config
+}
+
+// New creates a Reloader.
+func New(opts Options) *Reloader {
+	return &Reloader{
+		client:      opts.Client,
+		receivers:   opts.Receivers,
+		load:        opts.Load,
+		ackDeadline: opts.AckDeadlineSeconds,
+		log:         opts.Logger,
+		current:     opts.Initial,
+	}
+}
+
+// Reload loads the configuration and applies it.
+func (r *Reloader) Reload(ctx context.Context) (*Result, error) {
+	cfg, err := r.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return r.Apply(ctx, cfg)
+}
+
+// Apply reconciles topics, subscriptions and receivers with cfg. Missing
+// resources are created and receivers started for every configured
+// subscription. Subscriptions dropped from the configuration have their
+// receivers stopped; when cfg.ReloadDeleteRemoved is set they (and dropped
+// topics) are also deleted. Resources that were never in the configuration,
+// such as those created from the dashboard, are left alone.
+func (r *Reloader) Apply(ctx context.Context, cfg *config.Config) (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cfg.ProjectID != r.current.ProjectID {
+		return nil, fmt.Errorf("PUBSUB_PROJECT cannot change from %s to %s without a restart", r.current.ProjectID, cfg.ProjectID)
+	}
+	if !sameStaticSettings(r.current, cfg) {
+		r.log.Warn("Config reload: only topics and subscriptions are reloaded; restart to apply other changes")
+	}
+
+	// Configured names may be bare IDs (in the default project) or full
+	// names in any project; the emulator is compared on full names.
+	topicName := func(id string) string { return pubsub.TopicName(cfg.ProjectID, id) }
+	subName := func(id string) string { return pubsub.SubscriptionName(cfg.ProjectID, id) }
+
+	existingTopics := make(map[string]bool)
+	subs := make(map[string]string)
+	for _, project := range projects(r.current, cfg) {
+		topics, err := r.client.ListTopicNames(ctx, project)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range topics {
+			existingTopics[name] = true
+		}
+		projectSubs, err := r.client.ListSubscriptionTopics(ctx, project)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(subs, projectSubs)
+	}
+
+	desired := pairs(cfg)
+	running := r.receivers.Running()
+
+	res := &Result{}
+	var errs []error
+
+	for _, topicID := range cfg.TopicIDs {
+		if existingTopics[topicName(topicID)] {
+			continue
+		}
+		if _, err := r.client.CreateTopic(ctx, topicID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		existingTopics[topicName(topicID)] = true
+		res.TopicsCreated = append(res.TopicsCreated, topicID)
+	}
+
+	for _, subID := range cfg.SubscriptionIDs {
+		topicID := desired[subID]
+		actualTopic, exists := subs[subName(subID)]
+
+		if exists && actualTopic != topicName(topicID) {
+			// A subscription's topic is immutable: replace it only when
+			// deletion is allowed, otherwise keep the existing one.
+			if !cfg.ReloadDeleteRemoved {
+				r.log.Warn("Subscription %s is attached to %s, not %s; set PUBSUB_RELOAD_DELETE=true to recreate it",
+					subID, actualTopic, topicID)
+				topicID = actualTopic
+				if project, id, ok := pubsub.ParseName(actualTopic); ok && project == cfg.ProjectID {
+					topicID = id
+				}
+			} else {
+				if r.receivers.Stop(subID) {
+					res.ReceiversStopped = append(res.ReceiversStopped, subID)
+				}
+				if err := r.client.DeleteSubscription(ctx, subID); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				res.SubscriptionsDeleted = append(res.SubscriptionsDeleted, subID)
+				exists = false
+			}
+		}
+
+		if !exists {
+			if _, err := r.client.CreateSubscription(ctx, subID, topicID, r.ackDeadline); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			res.SubscriptionsCreated = append(res.SubscriptionsCreated, subID)
+		}
+
+		if runningTopic, ok := running[subID]; ok && runningTopic != topicID {
+			if r.receivers.Stop(subID) {
+				res.ReceiversStopped = append(res.ReceiversStopped, subID)
+			}
+		}
+		if r.receivers.Start(subID, topicID) {
+			res.ReceiversStarted = append(res.ReceiversStarted, subID)
+		}
+	}
+
+	for _, subID := range r.current.SubscriptionIDs {
+		if _, ok := desired[subID]; ok {
+			continue
+		}
+		if r.receivers.Stop(subID) {
+			res.ReceiversStopped = append(res.ReceiversStopped, subID)
+		}
+		if _, exists := subs[subName(subID)]; !exists || !cfg.ReloadDeleteRemoved {
+			continue
+		}
+		if err := r.client.DeleteSubscription(ctx, subID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		res.SubscriptionsDeleted = append(res.SubscriptionsDeleted, subID)
+	}
+
+	if cfg.ReloadDeleteRemoved {
+		wanted := make(map[string]bool, len(cfg.TopicIDs))
+		for _, topicID := range cfg.TopicIDs {
+			wanted[topicName(topicID)] = true
+		}
+		for _, topicID := range r.current.TopicIDs {
+			name := topicName(topicID)
+			if wanted[name] || !existingTopics[name] {
+				continue
+			}
+			existingTopics[name] = false
+			if err := r.client.DeleteTopic(ctx, topicID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			res.TopicsDeleted = append(res.TopicsDeleted, topicID)
+		}
+	}
+
+	r.current = cfg
+	return res, errors.Join(errs...)
+}
+
+// Watch reloads on every value received from sighup and, if the initial
+// configuration names a config file with a poll interval, whenever the
+// file's contents change. It returns when ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context, sighup <-chan os.Signal) {
+	r.mu.Lock()
+	path, interval := r.current.ConfigFile, r.current.ConfigPollInterval
+	r.mu.Unlock()
+
+	var tick <-chan time.Time
+	var lastHash [sha256.Size]byte
+	if path != "" && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+		lastHash, _ = hashFile(path)
+		r.log.Info("Watching %s for configuration changes every %s", path, interval)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			if path != "" {
+				lastHash, _ = hashFile(path)
+			}
+			r.reload(ctx, "SIGHUP")
+		case <-tick:
+			// A missing or unreadable file is usually mid-replacement;
+			// try again on the next tick.
+			h, err := hashFile(path)
+			if err != nil || h == lastHash {
+				continue
+			}
+			lastHash = h
+			r.reload(ctx, "config file change")
+		}
+	}
+}
+
+// reload runs Reload and logs the outcome.
+func (r *Reloader) reload(ctx context.Context, trigger string) {
+	r.log.Info("Reloading configuration (%s)", trigger)
+	res, err := r.Reload(ctx)
+	if res != nil {
+		r.log.With(
+			"topics_created", res.TopicsCreated,
+			"topics_deleted", res.TopicsDeleted,
+			"subscriptions_created", res.SubscriptionsCreated,
+			"subscriptions_deleted", res.SubscriptionsDeleted,
+			"receivers_started", res.ReceiversStarted,
+			"receivers_stopped", res.ReceiversStopped,
+		).Info("Configuration reloaded")
+	}
+	if err != nil {
+		r.log.Error("Config reload failed: %v", err)
+	}
+}
+
+// pairs maps each configured subscription to its topic.
+func pairs(cfg *config.Config) map[string]string {
+	m := make(map[string]string, len(cfg.SubscriptionIDs))
+	for i, subID := range cfg.SubscriptionIDs {
+		if i < len(cfg.TopicIDs) {
+			m[subID] = cfg.TopicIDs[i]
+		}
+	}
+	return m
+}
+
+// projects returns every project the configurations refer to, either as a
+// configured project or through a fully qualified topic or subscription.
+func projects(cfgs ...*config.Config) []string {
+	var out []string
+	add := func(project string) {
+		if project != "" && !slices.Contains(out, project) {
+			out = append(out, project)
+		}
+	}
+	for _, cfg := range cfgs {
+		add(cfg.ProjectID)
+		for _, project := range cfg.ProjectIDs {
+			add(project)
+		}
+		for _, name := range slices.Concat(cfg.TopicIDs, cfg.SubscriptionIDs) {
+			project, _, _ := pubsub.ParseName(name)
+			add(project)
+		}
+	}
+	return out
+}
+
+// sameStaticSettings reports whether a and b differ only in reloadable
+// fields (the topology and the delete-on-reload switch).
+func sameStaticSettings(a, b *config.Config) bool {
+	x, y := *a, *b
+	for _, c := range []*config.Config{&x, &y} {
+		c.TopicIDs, c.SubscriptionIDs, c.ProjectIDs, c.ReloadDeleteRemoved = nil, nil, nil, false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// hashFile returns the SHA-256 of a file's contents.
+func hashFile(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from operator configuration
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
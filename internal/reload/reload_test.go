@@ -0,0 +1,262 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gcppubsub "cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/config"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/pubsub"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func setupReloadTest(t *testing.T, initial *config.Config) (*Reloader, *pubsub.Client, *pubsub.Receivers) {
+	t.Helper()
+
+	srv := pstest.NewServer()
+	conn, err := grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	log := logger.New()
+	client, err := pubsub.NewClient(ctx, "test-project", log, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.CreateTopicsAndSubscriptions(ctx, initial.TopicIDs, initial.SubscriptionIDs); err != nil {
+		t.Fatalf("Failed to create initial topology: %v", err)
+	}
+
+	receivers := pubsub.NewSubscriber(client, log).NewReceivers(ctx, func(context.Context, *gcppubsub.Message) {})
+	for i, subID := range initial.SubscriptionIDs {
+		receivers.Start(subID, initial.TopicIDs[i])
+	}
+
+	t.Cleanup(func() {
+		cancel()
+		receivers.Wait()
+		_ = client.Close()
+		_ = conn.Close()
+		_ = srv.Close()
+	})
+
+	r := New(Options{
+		Client:             client,
+		Receivers:          receivers,
+		Initial:            initial,
+		AckDeadlineSeconds: 20,
+		Logger:             log,
+	})
+	return r, client, receivers
+}
+
+func newConfig(topics, subs []string) *config.Config {
+	return &config.Config{ProjectID: "test-project", TopicIDs: topics, SubscriptionIDs: subs}
+}
+
+func runningIDs(r *pubsub.Receivers) []string {
+	var ids []string
+	for id := range r.Running() {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestApply_AddsAndStopsWithoutDeleting(t *testing.T) {
+	r, client, receivers := setupReloadTest(t, newConfig([]string{"orders"}, []string{"orders-sub"}))
+	ctx := context.Background()
+
+	res, err := r.Apply(ctx, newConfig([]string{"payments"}, []string{"payments-sub"}))
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !slices.Equal(res.TopicsCreated, []string{"payments"}) || !slices.Equal(res.SubscriptionsCreated, []string{"payments-sub"}) {
+		t.Errorf("Expected payments to be created, got %+v", res)
+	}
+	if !slices.Equal(res.ReceiversStopped, []string{"orders-sub"}) || !slices.Equal(res.ReceiversStarted, []string{"payments-sub"}) {
+		t.Errorf("Expected receivers to move to payments-sub, got %+v", res)
+	}
+	if len(res.SubscriptionsDeleted) != 0 || len(res.TopicsDeleted) != 0 {
+		t.Errorf("Expected nothing deleted without PUBSUB_RELOAD_DELETE, got %+v", res)
+	}
+	if got := runningIDs(receivers); !slices.Equal(got, []string{"payments-sub"}) {
+		t.Errorf("Expected only payments-sub running, got %v", got)
+	}
+
+	subs, _ := client.ListSubscriptionTopics(ctx, "test-project")
+	if _, ok := subs["projects/test-project/subscriptions/orders-sub"]; !ok {
+		t.Error("Expected orders-sub to be kept")
+	}
+
+	// Re-applying the same configuration is a no-op.
+	res, err = r.Apply(ctx, newConfig([]string{"payments"}, []string{"payments-sub"}))
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(res.TopicsCreated)+len(res.SubscriptionsCreated)+len(res.ReceiversStarted)+len(res.ReceiversStopped) != 0 {
+		t.Errorf("Expected no changes, got %+v", res)
+	}
+}
+
+func TestApply_DeletesRemoved(t *testing.T) {
+	r, client, _ := setupReloadTest(t, newConfig([]string{"orders", "payments"}, []string{"orders-sub", "payments-sub"}))
+	ctx := context.Background()
+
+	// Not part of the configuration, so never deleted by a reload.
+	if _, err := client.CreateSubscription(ctx, "adhoc-sub", "orders", 20); err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+
+	next := newConfig([]string{"payments"}, []string{"payments-sub"})
+	next.ReloadDeleteRemoved = true
+	res, err := r.Apply(ctx, next)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !slices.Equal(res.SubscriptionsDeleted, []string{"orders-sub"}) || !slices.Equal(res.TopicsDeleted, []string{"orders"}) {
+		t.Errorf("Expected orders resources to be deleted, got %+v", res)
+	}
+
+	subs, _ := client.ListSubscriptionTopics(ctx, "test-project")
+	if _, ok := subs["projects/test-project/subscriptions/adhoc-sub"]; !ok {
+		t.Error("Expected unmanaged subscription to survive")
+	}
+}
+
+func TestApply_SubscriptionTopicChange(t *testing.T) {
+	r, client, receivers := setupReloadTest(t, newConfig([]string{"orders"}, []string{"shared-sub"}))
+	ctx := context.Background()
+
+	res, err := r.Apply(ctx, newConfig([]string{"payments"}, []string{"shared-sub"}))
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(res.SubscriptionsDeleted) != 0 || receivers.Running()["shared-sub"] != "orders" {
+		t.Errorf("Expected shared-sub to stay on orders without delete, got %+v", res)
+	}
+
+	next := newConfig([]string{"payments"}, []string{"shared-sub"})
+	next.ReloadDeleteRemoved = true
+	if _, err := r.Apply(ctx, next); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	subs, _ := client.ListSubscriptionTopics(ctx, "test-project")
+	if got := subs["projects/test-project/subscriptions/shared-sub"]; got != "projects/test-project/topics/payments" {
+		t.Errorf("Expected shared-sub to be recreated on payments, got %s", got)
+	}
+	if receivers.Running()["shared-sub"] != "payments" {
+		t.Errorf("Expected receiver restarted on payments, got %v", receivers.Running())
+	}
+}
+
+func TestApply_CrossProject(t *testing.T) {
+	r, client, receivers := setupReloadTest(t, newConfig([]string{"orders"}, []string{"orders-sub"}))
+	ctx := context.Background()
+
+	// A subscription in the default project on a topic in another project,
+	// and a topic/subscription pair that lives entirely in the other project.
+	next := newConfig(
+		[]string{"orders", "projects/shared/topics/events", "projects/shared/topics/events"},
+		[]string{"orders-sub", "events-sub", "projects/shared/subscriptions/audit-sub"},
+	)
+	res, err := r.Apply(ctx, next)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !slices.Equal(res.TopicsCreated, []string{"projects/shared/topics/events"}) ||
+		!slices.Equal(res.SubscriptionsCreated, []string{"events-sub", "projects/shared/subscriptions/audit-sub"}) {
+		t.Errorf("Expected the shared topic and both subscriptions to be created, got %+v", res)
+	}
+	if got := receivers.Running()["events-sub"]; got != "projects/shared/topics/events" {
+		t.Errorf("Expected events-sub receiving from the shared topic, got %s", got)
+	}
+
+	subs, _ := client.ListSubscriptionTopics(ctx, "test-project")
+	if got := subs["projects/test-project/subscriptions/events-sub"]; got != "projects/shared/topics/events" {
+		t.Errorf("Expected events-sub attached to the shared topic, got %s", got)
+	}
+	shared, _ := client.ListSubscriptionTopics(ctx, "shared")
+	if _, ok := shared["projects/shared/subscriptions/audit-sub"]; !ok {
+		t.Errorf("Expected audit-sub in the shared project, got %v", shared)
+	}
+
+	// Re-applying is a no-op now that the shared project is listed too.
+	res, err = r.Apply(ctx, next)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(res.TopicsCreated)+len(res.SubscriptionsCreated)+len(res.ReceiversStarted)+len(res.ReceiversStopped) != 0 {
+		t.Errorf("Expected no changes, got %+v", res)
+	}
+}
+
+func TestApply_RejectsProjectChange(t *testing.T) {
+	r, _, _ := setupReloadTest(t, newConfig([]string{"orders"}, []string{"orders-sub"}))
+
+	next := newConfig([]string{"orders"}, []string{"orders-sub"})
+	next.ProjectID = "other-project"
+	if _, err := r.Apply(context.Background(), next); err == nil {
+		t.Error("Expected error when the project changes")
+	}
+}
+
+func TestWatch_SighupAndFileChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pubsub.env")
+	if err := os.WriteFile(path, []byte("PUBSUB_TOPIC=orders\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	initial := newConfig([]string{"orders"}, []string{"orders-sub"})
+	initial.ConfigFile = path
+	initial.ConfigPollInterval = 20 * time.Millisecond
+	r, _, _ := setupReloadTest(t, initial)
+
+	var loads atomic.Int32
+	r.load = func() (*config.Config, error) {
+		loads.Add(1)
+		next := *initial
+		return &next, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sighup := make(chan os.Signal, 1)
+	go r.Watch(ctx, sighup)
+
+	waitFor := func(n int32) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for loads.Load() < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d reloads, got %d", n, loads.Load())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	sighup <- os.Interrupt
+	waitFor(1)
+
+	if err := os.WriteFile(path, []byte("PUBSUB_TOPIC=orders,payments\n"), 0o600); err != nil {
+		t.Fatalf("Failed to rewrite config: %v", err)
+	}
+	waitFor(2)
+
+	// An unchanged file does not trigger further reloads.
+	time.Sleep(100 * time.Millisecond)
+	if n := loads.Load(); n != 2 {
+		t.Errorf("Expected 2 reloads, got %d", n)
+	}
+}
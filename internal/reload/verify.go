@@ -0,0 +1,72 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/pubsub"
+)
+
+// CheckResources reports an error naming every configured topic or
+// subscription that does not exist in the emulator. It follows the current
+// configuration, so resources added by a reload are included.
+func (r *Reloader) CheckResources(ctx context.Context) error {
+	r.mu.Lock()
+	cfg := r.current
+	r.mu.Unlock()
+
+	topics := make(map[string]bool)
+	subs := make(map[string]string)
+	for _, project := range projects(cfg) {
+		names, err := r.client.ListTopicNames(ctx, project)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			topics[name] = true
+		}
+		projectSubs, err := r.client.ListSubscriptionTopics(ctx, project)
+		if err != nil {
+			return err
+		}
+		maps.Copy(subs, projectSubs)
+	}
+
+	var missing []string
+	for _, topicID := range cfg.TopicIDs {
+		if !topics[pubsub.TopicName(cfg.ProjectID, topicID)] {
+			missing = append(missing, "topic "+topicID)
+		}
+	}
+	for _, subID := range cfg.SubscriptionIDs {
+		if _, ok := subs[pubsub.SubscriptionName(cfg.ProjectID, subID)]; !ok {
+			missing = append(missing, "subscription "+subID)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// CheckReceivers reports an error naming every configured subscription
+// without a running receiver.
+func (r *Reloader) CheckReceivers(context.Context) error {
+	r.mu.Lock()
+	cfg := r.current
+	r.mu.Unlock()
+
+	running := r.receivers.Running()
+	var stopped []string
+	for _, subID := range cfg.SubscriptionIDs {
+		if _, ok := running[subID]; !ok {
+			stopped = append(stopped, subID)
+		}
+	}
+	if len(stopped) > 0 {
+		return fmt.Errorf("receivers not running for %s", strings.Join(stopped, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package reload
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCheckResourcesAndReceivers(t *testing.T) {
+	r, client, receivers := setupReloadTest(t, newConfig([]string{"orders"}, []string{"orders-sub"}))
+	ctx := context.Background()
+
+	if err := r.CheckResources(ctx); err != nil {
+		t.Errorf("Expected configured resources to exist, got %v", err)
+	}
+	if err := r.CheckReceivers(ctx); err != nil {
+		t.Errorf("Expected receivers to be running, got %v", err)
+	}
+
+	if err := client.DeleteSubscription(ctx, "orders-sub"); err != nil {
+		t.Fatalf("DeleteSubscription failed: %v", err)
+	}
+	if err := r.CheckResources(ctx); err == nil || !strings.Contains(err.Error(), "subscription orders-sub") {
+		t.Errorf("Expected missing subscription to be reported, got %v", err)
+	}
+
+	receivers.Stop("orders-sub")
+	if err := r.CheckReceivers(ctx); err == nil || !strings.Contains(err.Error(), "orders-sub") {
+		t.Errorf("Expected stopped receiver to be reported, got %v", err)
+	}
+}
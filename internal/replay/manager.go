@@ -0,0 +1,116 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/fixtures"
+)
+
+// Manager limits.
+const (
+	// MaxRunningSessions bounds how many sessions may run at once.
+	MaxRunningSessions = 8
+	// maxFinishedSessions bounds how many finished sessions are kept for
+	// reporting; the oldest are forgotten first.
+	maxFinishedSessions = 50
+)
+
+// ErrTooManySessions is returned by Manager.Start when MaxRunningSessions
+// are already running.
+var ErrTooManySessions = errors.New("too many replay sessions running")
+
+// Manager runs replay sessions and keeps them for reporting. It is safe for
+// concurrent use.
+type Manager struct {
+	ctx context.Context
+
+	mu       sync.Mutex
+	sessions []*Session
+	nextID   int
+}
+
+// NewManager creates a Manager whose sessions are cancelled when ctx is.
+func NewManager(ctx context.Context) *Manager {
+	return &Manager{ctx: ctx}
+}
+
+// Start validates spec and starts a session for it with the next
+// sequential ID.
+func (m *Manager) Start(spec Spec, publish fixtures.PublishFunc) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running := 0
+	for _, s := range m.sessions {
+		if !isDone(s) {
+			running++
+		}
+	}
+	if running >= MaxRunningSessions {
+		return nil, ErrTooManySessions
+	}
+
+	s, err := start(m.ctx, strconv.Itoa(m.nextID+1), spec, publish)
+	if err != nil {
+		return nil, err
+	}
+	m.nextID++
+	m.sessions = append(m.sessions, s)
+	m.pruneLocked()
+	return s, nil
+}
+
+// Get returns the session with id.
+func (m *Manager) Get(id string) (*Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		if s.id == id {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Sessions returns the status of every kept session in the order they
+// started.
+func (m *Manager) Sessions() []Status {
+	m.mu.Lock()
+	sessions := slices.Clone(m.sessions)
+	m.mu.Unlock()
+	out := make([]Status, len(sessions))
+	for i, s := range sessions {
+		out[i] = s.Status()
+	}
+	return out
+}
+
+// pruneLocked forgets the oldest finished sessions beyond
+// maxFinishedSessions.
+func (m *Manager) pruneLocked() {
+	finished := 0
+	for _, s := range m.sessions {
+		if isDone(s) {
+			finished++
+		}
+	}
+	m.sessions = slices.DeleteFunc(m.sessions, func(s *Session) bool {
+		if finished > maxFinishedSessions && isDone(s) {
+			finished--
+			return true
+		}
+		return false
+	})
+}
+
+func isDone(s *Session) bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
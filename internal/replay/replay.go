@@ -0,0 +1,295 @@
+// Package replay republishes recorded or imported messages as sessions that
+// keep the original gaps between them, scaled by a speed factor, so bursty
+// traffic can be reproduced against consumers. Sessions can be paused,
+// resumed and cancelled, and report their progress to watchers.
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/fixtures"
+)
+
+// progressInterval is the least time between progress updates sent to
+// watchers; state changes are sent at once.
+const progressInterval = 100 * time.Millisecond
+
+// ErrFinished is returned when pausing or resuming a session that has
+// ended.
+var ErrFinished = errors.New("replay session has finished")
+
+// Spec describes a replay session.
+type Spec struct {
+	// Project is the project the session publishes to.
+	Project string
+	// Source describes where the messages came from, such as "history" or
+	// an uploaded file name.
+	Source string
+	// Records are republished in order, keeping the gaps between their
+	// publish times.
+	Records []fixtures.Record
+	// Invalid are lines of an imported file that could not be read; they
+	// are reported as failures.
+	Invalid []fixtures.LineError
+	// Topic, when set, republishes every message to this topic instead of
+	// its own.
+	Topic string
+	// Speed multiplies the pace: 10 replays ten times faster, 0.5 at half
+	// speed. Zero means 1.
+	Speed float64
+}
+
+func (s *Spec) validate() error {
+	if len(s.Records) == 0 {
+		return errors.New("no messages to replay")
+	}
+	if s.Speed < 0 {
+		return fmt.Errorf("speed must be positive, got %v", s.Speed)
+	}
+	if s.Speed == 0 {
+		s.Speed = 1
+	}
+	return nil
+}
+
+// span returns how long the records took originally.
+func (s *Spec) span() time.Duration {
+	var first, last time.Time
+	for i := range s.Records {
+		t := s.Records[i].PublishTime
+		if t.IsZero() {
+			continue
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last.Sub(first)
+}
+
+// State is a session's lifecycle state.
+type State string
+
+// Session states.
+const (
+	StateRunning   State = "running"
+	StatePaused    State = "paused"
+	StateCompleted State = "completed"
+	StateCancelled State = "cancelled"
+)
+
+// Session is a running or finished replay.
+type Session struct {
+	id     string
+	spec   Spec
+	span   time.Duration
+	gate   *fixtures.Gate
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	report  fixtures.Report
+	started time.Time
+	ended   time.Time
+	// cancelled is set when the session ended before every record.
+	cancelled bool
+	notified  time.Time
+	watchers  map[chan Status]struct{}
+}
+
+// Start validates spec and replays it in the background through publish
+// until every record is published, Cancel is called or ctx is cancelled.
+func Start(ctx context.Context, spec Spec, publish fixtures.PublishFunc) (*Session, error) {
+	return start(ctx, "", spec, publish)
+}
+
+func start(ctx context.Context, id string, spec Spec, publish fixtures.PublishFunc) (*Session, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Session{
+		id:       id,
+		spec:     spec,
+		span:     spec.span(),
+		gate:     &fixtures.Gate{},
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		report:   fixtures.Report{Total: len(spec.Records) + len(spec.Invalid)},
+		started:  time.Now(),
+		watchers: map[chan Status]struct{}{},
+	}
+	go s.run(ctx, publish)
+	return s, nil
+}
+
+func (s *Session) run(ctx context.Context, publish fixtures.PublishFunc) {
+	defer close(s.done)
+	defer s.cancel()
+
+	report, err := fixtures.Publish(ctx, s.spec.Records, s.spec.Invalid, publish, fixtures.Options{
+		Topic:  s.spec.Topic,
+		Timing: fixtures.TimingOriginal,
+		Speed:  s.spec.Speed,
+		Gate:   s.gate,
+		Progress: func(r fixtures.Report) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.report = r
+			if time.Since(s.notified) >= progressInterval {
+				s.notifyLocked()
+			}
+		},
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report = report
+	s.ended = time.Now()
+	s.cancelled = err != nil
+	s.notifyLocked()
+	for ch := range s.watchers {
+		close(ch)
+	}
+	s.watchers = nil
+}
+
+// Pause holds the session before its next message. Pausing a paused
+// session does nothing.
+func (s *Session) Pause() error {
+	return s.setPaused(true)
+}
+
+// Resume continues a paused session, keeping the remaining gaps. Resuming
+// a running session does nothing.
+func (s *Session) Resume() error {
+	return s.setPaused(false)
+}
+
+func (s *Session) setPaused(paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended.IsZero() {
+		return ErrFinished
+	}
+	changed := false
+	if paused {
+		changed = s.gate.Pause()
+	} else {
+		changed = s.gate.Resume()
+	}
+	if changed {
+		s.notifyLocked()
+	}
+	return nil
+}
+
+// Cancel stops the session and waits for it to end.
+func (s *Session) Cancel() {
+	s.cancel()
+	<-s.done
+}
+
+// Done is closed once the session has ended.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Watch returns a channel that receives the session's status now and after
+// each change. A slow reader skips intermediate updates but always gets the
+// latest; the channel is closed after the final status. Call stop when no
+// longer reading.
+func (s *Session) Watch() (updates <-chan Status, stop func()) {
+	ch := make(chan Status, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch <- s.statusLocked()
+	if s.watchers == nil {
+		close(ch)
+		return ch, func() {}
+	}
+	s.watchers[ch] = struct{}{}
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.watchers, ch)
+	}
+}
+
+// notifyLocked sends the current status to every watcher, replacing any
+// update it has not read yet. Callers must hold mu.
+func (s *Session) notifyLocked() {
+	s.notified = time.Now()
+	st := s.statusLocked()
+	for ch := range s.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- st
+	}
+}
+
+// Status is a snapshot of a session's configuration and progress.
+type Status struct {
+	ID      string  `json:"id"`
+	Project string  `json:"project"`
+	Source  string  `json:"source"`
+	Topic   string  `json:"topic,omitempty"`
+	Speed   float64 `json:"speed"`
+	State   State   `json:"state"`
+	// SpanSeconds is how long the messages took originally, and
+	// ExpectedSeconds how long replaying them takes at Speed, not counting
+	// pauses.
+	SpanSeconds     float64    `json:"span_seconds"`
+	ExpectedSeconds float64    `json:"expected_seconds"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	// ElapsedSeconds is how long the session has been (or was) running.
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	fixtures.Report
+}
+
+// Status returns the session's current state and progress.
+func (s *Session) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statusLocked()
+}
+
+func (s *Session) statusLocked() Status {
+	st := Status{
+		ID:              s.id,
+		Project:         s.spec.Project,
+		Source:          s.spec.Source,
+		Topic:           s.spec.Topic,
+		Speed:           s.spec.Speed,
+		SpanSeconds:     s.span.Seconds(),
+		ExpectedSeconds: s.span.Seconds() / s.spec.Speed,
+		StartedAt:       s.started,
+		Report:          s.report,
+	}
+	end := time.Now()
+	switch {
+	case !s.ended.IsZero():
+		end = s.ended
+		st.EndedAt = &end
+		st.State = StateCompleted
+		if s.cancelled {
+			st.State = StateCancelled
+		}
+	case s.gate.Paused():
+		st.State = StatePaused
+	default:
+		st.State = StateRunning
+	}
+	st.ElapsedSeconds = end.Sub(s.started).Seconds()
+	return st
+}
@@ -0,0 +1,176 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/fixtures"
+)
+
+// recorder records the topic and time of each published record.
+type recorder struct {
+	mu    sync.Mutex
+	times []time.Time
+	data  []string
+}
+
+func (r *recorder) publish(_ context.Context, _ string, rec *fixtures.Record) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.times = append(r.times, time.Now())
+	r.data = append(r.data, string(rec.Data))
+	return "id", nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.times)
+}
+
+// burst returns n records 100ms apart.
+func burst(n int) []fixtures.Record {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	records := make([]fixtures.Record, n)
+	for i := range records {
+		records[i] = fixtures.Record{Line: i + 1, Topic: "orders", Data: []byte{byte('a' + i)}, PublishTime: base.Add(time.Duration(i) * 100 * time.Millisecond)}
+	}
+	return records
+}
+
+func wait(t *testing.T, s *Session) Status {
+	t.Helper()
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected session to finish")
+	}
+	return s.Status()
+}
+
+func TestStart_Speed(t *testing.T) {
+	rec := &recorder{}
+	s, err := Start(context.Background(), Spec{Project: "p", Source: "history", Records: burst(3), Speed: 4}, rec.publish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := wait(t, s)
+	if st.State != StateCompleted || st.Published != 3 || st.EndedAt == nil {
+		t.Fatalf("Unexpected final status: %+v", st)
+	}
+	if st.SpanSeconds != 0.2 || st.ExpectedSeconds != 0.05 {
+		t.Errorf("Expected a 0.2s span replayed in 0.05s, got %v and %v", st.SpanSeconds, st.ExpectedSeconds)
+	}
+	// 100ms gaps at 4x are 25ms.
+	if gap := rec.times[2].Sub(rec.times[0]); gap < 40*time.Millisecond || gap > time.Second {
+		t.Errorf("Expected about 50ms from first to last message, got %v", gap)
+	}
+}
+
+func TestSession_PauseResumeCancel(t *testing.T) {
+	rec := &recorder{}
+	s, err := Start(context.Background(), Spec{Records: burst(5), Speed: 2}, rec.publish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Status(); st.State != StatePaused {
+		t.Errorf("Expected paused, got %s", st.State)
+	}
+	n := rec.count()
+	time.Sleep(150 * time.Millisecond)
+	if rec.count() != n {
+		t.Errorf("Expected no messages while paused, got %d more", rec.count()-n)
+	}
+
+	if err := s.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Status(); st.State != StateRunning {
+		t.Errorf("Expected running after resume, got %s", st.State)
+	}
+	s.Cancel()
+	st := s.Status()
+	if st.State != StateCancelled || st.Published == 5 {
+		t.Errorf("Expected the session cancelled part way, got %+v", st)
+	}
+	if err := s.Pause(); !errors.Is(err, ErrFinished) {
+		t.Errorf("Expected ErrFinished pausing a finished session, got %v", err)
+	}
+}
+
+func TestSession_Watch(t *testing.T) {
+	rec := &recorder{}
+	s, err := Start(context.Background(), Spec{Records: burst(3), Speed: 10}, rec.publish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates, stop := s.Watch()
+	defer stop()
+
+	var last Status
+	count := 0
+	for st := range updates {
+		last = st
+		count++
+	}
+	if count == 0 || last.State != StateCompleted || last.Published != 3 {
+		t.Errorf("Expected updates ending with the final status, got %d ending %+v", count, last)
+	}
+
+	// Watching a finished session gets its final status.
+	updates, _ = s.Watch()
+	if st, ok := <-updates; !ok || st.State != StateCompleted {
+		t.Errorf("Expected the final status, got %+v", st)
+	}
+	if _, ok := <-updates; ok {
+		t.Error("Expected the channel closed after the final status")
+	}
+}
+
+func TestStart_Validation(t *testing.T) {
+	rec := &recorder{}
+	if _, err := Start(context.Background(), Spec{}, rec.publish); err == nil {
+		t.Error("Expected an error without records")
+	}
+	if _, err := Start(context.Background(), Spec{Records: burst(1), Speed: -1}, rec.publish); err == nil {
+		t.Error("Expected an error for a negative speed")
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewManager(ctx)
+	rec := &recorder{}
+
+	var sessions []*Session
+	for range MaxRunningSessions {
+		s, err := m.Start(Spec{Records: burst(2), Speed: 0.01}, rec.publish)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, s)
+	}
+	if _, err := m.Start(Spec{Records: burst(1)}, rec.publish); !errors.Is(err, ErrTooManySessions) {
+		t.Errorf("Expected ErrTooManySessions, got %v", err)
+	}
+	if s, ok := m.Get("2"); !ok || s != sessions[1] {
+		t.Error("Expected sessions to get sequential IDs")
+	}
+	if got := m.Sessions(); len(got) != MaxRunningSessions || got[0].ID != "1" {
+		t.Errorf("Unexpected sessions: %+v", got)
+	}
+
+	// Cancelling the manager's context cancels its sessions.
+	cancel()
+	for _, s := range sessions {
+		if st := wait(t, s); st.State != StateCancelled {
+			t.Errorf("Expected session %s cancelled, got %s", st.ID, st.State)
+		}
+	}
+}
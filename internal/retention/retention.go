@@ -0,0 +1,166 @@
+// Package retention describes how much message history the dashboard keeps:
+// a global message count and memory budget, per-topic limits on count, age
+// and bytes, and the payload size above which only a prefix is kept in
+// memory while the full payload waits on disk (see Spill).
+package retention
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AllTopics is the Topics key whose limits apply to every topic.
+const AllTopics = "*"
+
+// Limits bounds the history kept for one topic. Zero fields are unlimited.
+type Limits struct {
+	MaxCount int
+	MaxAge   time.Duration
+	MaxBytes int64
+}
+
+// IsZero reports whether l sets no limit.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Policy is the retention policy of the whole history.
+type Policy struct {
+	// MaxMessages bounds the number of messages kept across every topic;
+	// 0 keeps the dashboard's default.
+	MaxMessages int
+	// MemoryBytes bounds the estimated size of the history; 0 is unlimited.
+	MemoryBytes int64
+	// MaxPayloadBytes is the payload size above which only a prefix is
+	// kept in memory; 0 keeps every payload whole.
+	MaxPayloadBytes int
+	// Topics holds limits by topic ID, by project/topic ID, or for every
+	// topic under AllTopics.
+	Topics map[string]Limits
+}
+
+// For returns the limits of topic in project. Fields set by the most
+// specific entry (project/topic, then topic, then AllTopics) win.
+func (p Policy) For(project, topic string) Limits {
+	var l Limits
+	for _, key := range []string{AllTopics, topic, project + "/" + topic} {
+		t, ok := p.Topics[key]
+		if !ok {
+			continue
+		}
+		if t.MaxCount > 0 {
+			l.MaxCount = t.MaxCount
+		}
+		if t.MaxAge > 0 {
+			l.MaxAge = t.MaxAge
+		}
+		if t.MaxBytes > 0 {
+			l.MaxBytes = t.MaxBytes
+		}
+	}
+	return l
+}
+
+// sizeUnits are the suffixes ParseSize accepts, in powers of 1024.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+}
+
+// ParseSize parses a byte size such as 512, 64KB or 1GiB. Units are powers
+// of 1024 and case-insensitive.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if i == 0 || !ok {
+		return 0, fmt.Errorf("invalid size %q: want a number of bytes with an optional unit such as KB, MB or GB", s)
+	}
+	n, err := strconv.ParseInt(s[:i], 10, 64)
+	if err != nil || n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	return n * unit, nil
+}
+
+// ParseTopics parses per-topic limits written as entries separated by
+// semicolons, each a key and comma-separated limits:
+//
+//	orders:count=500,age=1h,bytes=64MB;billing/audit:count=100;*:age=24h
+//
+// Keys are topic IDs, project/topic IDs or * for every topic; ages are
+// durations such as 90s or 24h and bytes are sizes as ParseSize reads them.
+func ParseTopics(s string) (map[string]Limits, error) {
+	topics := make(map[string]Limits)
+	for entry := range strings.SplitSeq(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, spec, ok := strings.Cut(entry, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsFunc(key, unicode.IsSpace) {
+			return nil, fmt.Errorf("invalid entry %q: want topic:count=N,age=D,bytes=S", entry)
+		}
+		if _, dup := topics[key]; dup {
+			return nil, fmt.Errorf("duplicate entry for %q", key)
+		}
+		l, err := parseLimits(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		topics[key] = l
+	}
+	return topics, nil
+}
+
+// parseLimits parses comma-separated name=value limits.
+func parseLimits(spec string) (Limits, error) {
+	var l Limits
+	for field := range strings.SplitSeq(spec, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(name) {
+		case "count":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return Limits{}, fmt.Errorf("count must be a positive integer, got %q", value)
+			}
+			l.MaxCount = n
+		case "age":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return Limits{}, fmt.Errorf("age must be a positive duration such as 1h, got %q", value)
+			}
+			l.MaxAge = d
+		case "bytes":
+			n, err := ParseSize(value)
+			if err != nil || n <= 0 {
+				return Limits{}, fmt.Errorf("bytes must be a positive size such as 64MB, got %q", value)
+			}
+			l.MaxBytes = n
+		default:
+			return Limits{}, fmt.Errorf("unknown limit %q: want count, age or bytes", field)
+		}
+	}
+	if l.IsZero() {
+		return Limits{}, fmt.Errorf("no limits set")
+	}
+	return l, nil
+}
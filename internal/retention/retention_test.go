@@ -0,0 +1,109 @@
+package retention
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":    512,
+		"512B":   512,
+		"64KB":   64 << 10,
+		"64 kb":  64 << 10,
+		"10M":    10 << 20,
+		"256MiB": 256 << 20,
+		"1GB":    1 << 30,
+	}
+	for in, want := range tests {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "-1", "1.5MB", "10TB", "99999999999GB"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("Expected ParseSize(%q) to fail", in)
+		}
+	}
+}
+
+func TestParseTopics(t *testing.T) {
+	topics, err := ParseTopics(" orders:count=500,age=1h,bytes=64MB; billing/audit:count=100 ;*:age=24h;")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	want := map[string]Limits{
+		"orders":        {MaxCount: 500, MaxAge: time.Hour, MaxBytes: 64 << 20},
+		"billing/audit": {MaxCount: 100},
+		"*":             {MaxAge: 24 * time.Hour},
+	}
+	if len(topics) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), topics)
+	}
+	for key, l := range want {
+		if topics[key] != l {
+			t.Errorf("%s: expected %+v, got %+v", key, l, topics[key])
+		}
+	}
+
+	for _, in := range []string{
+		"orders",
+		":count=1",
+		"orders:",
+		"orders:count=0",
+		"orders:age=soon",
+		"orders:bytes=lots",
+		"orders:size=1",
+		"orders:count=1;orders:age=1h",
+	} {
+		if _, err := ParseTopics(in); err == nil {
+			t.Errorf("Expected ParseTopics(%q) to fail", in)
+		}
+	}
+}
+
+func TestPolicy_For(t *testing.T) {
+	p := Policy{Topics: map[string]Limits{
+		"*":             {MaxAge: time.Hour, MaxCount: 1000},
+		"orders":        {MaxCount: 10},
+		"billing/audit": {MaxBytes: 1 << 20},
+	}}
+
+	if got := p.For("app", "orders"); got != (Limits{MaxCount: 10, MaxAge: time.Hour}) {
+		t.Errorf("Expected the topic count over the default age, got %+v", got)
+	}
+	if got := p.For("billing", "audit"); got != (Limits{MaxCount: 1000, MaxAge: time.Hour, MaxBytes: 1 << 20}) {
+		t.Errorf("Expected the project/topic entry merged with the default, got %+v", got)
+	}
+	if got := p.For("app", "audit"); got != (Limits{MaxCount: 1000, MaxAge: time.Hour}) {
+		t.Errorf("Expected the default limits, got %+v", got)
+	}
+	if got := (Policy{}).For("app", "orders"); !got.IsZero() {
+		t.Errorf("Expected no limits without entries, got %+v", got)
+	}
+}
+
+func TestSpill(t *testing.T) {
+	s, err := NewSpill(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create spill: %v", err)
+	}
+	if err := s.Put("projects/p/messages/1", []byte("full payload")); err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+	if got, err := s.Get("projects/p/messages/1"); err != nil || string(got) != "full payload" {
+		t.Errorf("Expected the payload back, got %q, %v", got, err)
+	}
+	s.Delete("projects/p/messages/1")
+	if _, err := s.Get("projects/p/messages/1"); !os.IsNotExist(err) {
+		t.Errorf("Expected the payload deleted, got %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if _, err := os.Stat(s.Dir()); !os.IsNotExist(err) {
+		t.Errorf("Expected the spill directory removed, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package retention
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Spill keeps the full payloads of truncated messages on disk so they can be
+// fetched on demand. Files are named by a hash of their key; the dashboard
+// gives every truncated history entry a key of its own.
+type Spill struct {
+	dir string
+}
+
+// NewSpill creates a spill directory under parent (the system temporary
+// directory if empty). Close removes it.
+func NewSpill(parent string) (*Spill, error) {
+	dir, err := os.MkdirTemp(parent, "pubsub-history-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	return &Spill{dir: dir}, nil
+}
+
+// Dir returns the spill directory.
+func (s *Spill) Dir() string {
+	return s.dir
+}
+
+func (s *Spill) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// Put stores the payload under key, replacing any earlier one.
+func (s *Spill) Put(key string, data []byte) error {
+	return os.WriteFile(s.path(key), data, 0o600)
+}
+
+// Get returns the payload stored under key.
+func (s *Spill) Get(key string) ([]byte, error) {
+	return os.ReadFile(s.path(key))
+}
+
+// Delete removes the payload stored under key, if any.
+func (s *Spill) Delete(key string) {
+	_ = os.Remove(s.path(key))
+}
+
+// Close removes the spill directory and every payload in it.
+func (s *Spill) Close() error {
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// unixScheme prefixes addresses that name a Unix domain socket.
+	unixScheme = "unix://"
+	// DefaultSocketMode lets the owner and group (e.g. sibling containers
+	// sharing a volume) connect to the dashboard socket.
+	DefaultSocketMode fs.FileMode = 0o660
+	// staleSocketProbeTimeout bounds the dial used to detect a live socket.
+	staleSocketProbeTimeout = time.Second
+)
+
+// listen binds addr, which is either a TCP host:port or unix:///path.sock.
+// Socket files are chmod'ed to mode; a stale socket left by a previous run is
+// removed, but one that still accepts connections is reported as in use.
+func listen(addr string, mode fs.FileMode) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixScheme)
+	if !ok {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		return ln, nil
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("unix socket address %q has no path", addr)
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	if mode == 0 {
+		mode = DefaultSocketMode
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// removeStaleSocket deletes a leftover socket file at path. It refuses to
+// touch regular files or sockets another process is still serving.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", path, err)
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, staleSocketProbeTimeout); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("socket %s is already in use", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+	return nil
+}
+
+// displayURL renders a listener address as something a user can open. TCP
+// wildcard hosts are shown as localhost; sockets keep their unix:// form.
+func displayURL(scheme string, addr net.Addr) string {
+	if addr.Network() == "unix" {
+		return unixScheme + addr.String()
+	}
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return scheme + "://" + addr.String()
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
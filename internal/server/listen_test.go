@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/dashboard"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+// socketDir returns a short temporary directory; t.TempDir paths can exceed
+// the ~104 byte limit on Unix socket paths.
+func socketDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "dash")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func TestDisplayURL(t *testing.T) {
+	tests := []struct {
+		network, addr, scheme, want string
+	}{
+		{"tcp", "[::]:8080", "http", "http://localhost:8080"},
+		{"tcp", "0.0.0.0:8080", "https", "https://localhost:8080"},
+		{"tcp", "127.0.0.1:9000", "http", "http://127.0.0.1:9000"},
+		{"tcp", "[::1]:9000", "http", "http://[::1]:9000"},
+		{"unix", "/run/dash.sock", "http", "unix:///run/dash.sock"},
+	}
+	for _, tt := range tests {
+		got := displayURL(tt.scheme, testAddr{tt.network, tt.addr})
+		if got != tt.want {
+			t.Errorf("displayURL(%s, %s) = %s, want %s", tt.scheme, tt.addr, got, tt.want)
+		}
+	}
+}
+
+type testAddr struct{ network, addr string }
+
+func (a testAddr) Network() string { return a.network }
+func (a testAddr) String() string  { return a.addr }
+
+func TestListen_EphemeralPortURL(t *testing.T) {
+	log := logger.New()
+	srv := New(&Config{
+		Addr:      "127.0.0.1:0",
+		Dashboard: dashboard.New(nil, "test-project", log),
+		Logger:    log,
+	})
+	if srv.URL() != "" {
+		t.Errorf("Expected empty URL before Listen, got %s", srv.URL())
+	}
+	if err := srv.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer func() { _ = srv.ln.Close() }()
+
+	url := srv.URL()
+	if !strings.HasPrefix(url, "http://127.0.0.1:") || strings.HasSuffix(url, ":0") {
+		t.Errorf("Expected resolved loopback URL, got %s", url)
+	}
+}
+
+func TestStart_UnixSocket(t *testing.T) {
+	path := filepath.Join(socketDir(t), "dash.sock")
+	log := logger.New()
+	srv := New(&Config{
+		Addr:       "unix://" + path,
+		SocketMode: 0o600,
+		Dashboard:  dashboard.New(nil, "test-project", log),
+		Logger:     log,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx) }()
+	time.Sleep(200 * time.Millisecond)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected socket file, got %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected socket mode 0600, got %o", info.Mode().Perm())
+	}
+
+	client := &http.Client{
+		Timeout: 2 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	resp, err := client.Get("http://dashboard/api/health")
+	if err != nil {
+		t.Fatalf("Request over socket failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("Expected socket file to be removed on shutdown")
+	}
+}
+
+func TestListen_SocketConflicts(t *testing.T) {
+	dir := socketDir(t)
+
+	regular := filepath.Join(dir, "file.sock")
+	if err := os.WriteFile(regular, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := listen("unix://"+regular, 0); err == nil {
+		t.Error("Expected error when path is a regular file")
+	}
+
+	live := filepath.Join(dir, "live.sock")
+	ln, err := listen("unix://"+live, 0)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	if info, _ := os.Stat(live); info.Mode().Perm() != DefaultSocketMode {
+		t.Errorf("Expected default socket mode %o, got %o", DefaultSocketMode, info.Mode().Perm())
+	}
+	if _, err := listen("unix://"+live, 0); err == nil {
+		t.Error("Expected error when socket is in use")
+	}
+	_ = ln.Close()
+
+	// Simulate a socket left behind by a crashed process.
+	stale := filepath.Join(dir, "stale.sock")
+	ul, err := net.ListenUnix("unix", &net.UnixAddr{Name: stale, Net: "unix"})
+	if err != nil {
+		t.Fatalf("ListenUnix failed: %v", err)
+	}
+	ul.SetUnlinkOnClose(false)
+	_ = ul.Close()
+	if info, err := os.Lstat(stale); err != nil || info.Mode()&fs.ModeSocket == 0 {
+		t.Fatalf("Expected stale socket file, got %v", err)
+	}
+	ln, err = listen("unix://"+stale, 0)
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got %v", err)
+	}
+	_ = ln.Close()
+
+	if _, err := listen("unix://", 0); err == nil {
+		t.Error("Expected error for socket address without path")
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/dipjyotimetia/pubsub-emulator/internal/auth"
 	"github.com/dipjyotimetia/pubsub-emulator/internal/dashboard"
 	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
 )
@@ -22,26 +25,87 @@ const (
 type Server struct {
 	dashboard *dashboard.Dashboard
 	port      string
+	addr      string
+	mode      fs.FileMode
+	auth      auth.Authenticator
+	cors      dashboard.CORSConfig
+	csrf      dashboard.CSRFConfig
+	tls       TLSConfig
 	log       *logger.Logger
 	srv       *http.Server
+	ln        net.Listener
 }
 
 // Config holds the server configuration
 type Config struct {
-	Port      string
-	Dashboard *dashboard.Dashboard
-	Logger    *logger.Logger
+	Port string
+	// Addr is a host:port or unix:///path.sock to bind; it takes precedence
+	// over Port, which otherwise binds all interfaces.
+	Addr string
+	// SocketMode sets the permissions of a Unix socket (default 0660).
+	SocketMode fs.FileMode
+	Dashboard  *dashboard.Dashboard
+	Logger     *logger.Logger
+	// Auth, when non-nil, requires credentials and enforces roles on the API.
+	Auth auth.Authenticator
+	// CORS is the cross-origin policy; nil uses dashboard.DefaultCORSConfig.
+	CORS *dashboard.CORSConfig
+	// CSRF configures protection of state-changing routes (zero value: off).
+	CSRF dashboard.CSRFConfig
+	// TLS enables HTTPS (and optionally mTLS); the zero value serves HTTP.
+	TLS TLSConfig
 }
 
 // New creates a new Server instance
 func New(cfg *Config) *Server {
+	cors := dashboard.DefaultCORSConfig()
+	if cfg.CORS != nil {
+		cors = *cfg.CORS
+	}
+	addr := cfg.Addr
+	if addr == "" {
+		addr = ":" + cfg.Port
+	}
 	return &Server{
 		dashboard: cfg.Dashboard,
 		port:      cfg.Port,
+		addr:      addr,
+		mode:      cfg.SocketMode,
+		auth:      cfg.Auth,
+		cors:      cors,
+		csrf:      cfg.CSRF,
+		tls:       cfg.TLS,
 		log:       cfg.Logger,
 	}
 }
 
+// Listen binds the configured address without serving yet, so callers can
+// report the actual address (e.g. an ephemeral port) before calling Start.
+// Start calls it automatically if it has not been called.
+func (s *Server) Listen() error {
+	if s.ln != nil {
+		return nil
+	}
+	ln, err := listen(s.addr, s.mode)
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+	return nil
+}
+
+// URL returns the address the dashboard is reachable at, or "" before Listen.
+func (s *Server) URL() string {
+	if s.ln == nil {
+		return ""
+	}
+	scheme := "http"
+	if s.tls.Enabled() {
+		scheme = "https"
+	}
+	return displayURL(scheme, s.ln.Addr())
+}
+
 // Start starts the HTTP server and blocks until ctx is cancelled (e.g. on a
 // shutdown signal) or the server fails, then shuts down gracefully.
 func (s *Server) Start(ctx context.Context) error {
@@ -49,23 +113,55 @@ func (s *Server) Start(ctx context.Context) error {
 
 	s.dashboard.RegisterRoutes(mux)
 
-	handler := dashboard.HTTPLoggingMiddleware(s.log)(mux)
-	handler = dashboard.CORSMiddleware(handler)
+	handler := auth.Middleware(s.auth, s.log)(mux)
+	handler = dashboard.CSRFMiddleware(s.csrf, s.log)(handler)
+	handler = dashboard.HTTPLoggingMiddleware(s.log)(handler)
+	handler = dashboard.NewCORSMiddleware(s.cors)(handler)
+
+	// HTTP/2 is negotiated via ALPN over TLS; plaintext listeners also
+	// accept prior-knowledge HTTP/2 (h2c) for clients that opt in.
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
 
 	s.srv = &http.Server{
-		Addr:         ":" + s.port,
+		Addr:         s.addr,
 		Handler:      handler,
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 		IdleTimeout:  idleTimeout,
+		Protocols:    &protocols,
+	}
+
+	if s.tls.Enabled() {
+		tlsCfg, err := buildTLSConfig(s.tls)
+		if err != nil {
+			if s.ln != nil {
+				_ = s.ln.Close()
+			}
+			return err
+		}
+		s.srv.TLSConfig = tlsCfg
+	}
+
+	if err := s.Listen(); err != nil {
+		return err
 	}
 
 	serverErrors := make(chan error, 1)
 
 	go func() {
-		s.log.Info("Starting HTTP server on port %s", s.port)
-		s.log.Info("Dashboard available at http://localhost:%s", s.port)
-		serverErrors <- s.srv.ListenAndServe()
+		s.log.Info("Starting HTTP server on %s", s.ln.Addr())
+		s.log.Info("Dashboard available at %s", s.URL())
+		if s.srv.TLSConfig != nil {
+			if s.tls.ClientCAFile != "" {
+				s.log.Info("Dashboard requires client certificates (mTLS)")
+			}
+			serverErrors <- s.srv.ServeTLS(s.ln, "", "")
+			return
+		}
+		serverErrors <- s.srv.Serve(s.ln)
 	}()
 
 	select {
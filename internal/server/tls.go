@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// selfSignedValidity is how long a generated development certificate is valid.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// TLSConfig configures HTTPS for the dashboard listener
+type TLSConfig struct {
+	// CertFile and KeyFile are a PEM certificate chain and private key.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, when set, enables mTLS: clients must present a
+	// certificate signed by one of the CAs in this PEM bundle.
+	ClientCAFile string
+	// SelfSigned generates an in-memory certificate for localhost when no
+	// CertFile/KeyFile is given. Intended for local use only.
+	SelfSigned bool
+}
+
+// Enabled reports whether the dashboard should serve HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.SelfSigned
+}
+
+// buildTLSConfig loads (or generates) the server certificate and, for mTLS,
+// the client CA pool.
+func buildTLSConfig(c TLSConfig) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if c.CertFile != "" {
+		cert, err = tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+	} else {
+		cert, err = generateSelfSigned()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate self-signed certificate: %w", err)
+		}
+	}
+
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile != "" {
+		pemBytes, err := os.ReadFile(c.ClientCAFile) // #nosec G304 -- path comes from operator configuration
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemBytes) {
+			return nil, fmt.Errorf("client CA bundle %s contains no certificates", c.ClientCAFile)
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsCfg, nil
+}
+
+// generateSelfSigned creates an ECDSA P-256 certificate valid for localhost,
+// the loopback addresses and this machine's hostname.
+func generateSelfSigned() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	dnsNames := []string{"localhost"}
+	if host, err := os.Hostname(); err == nil && host != "" && host != "localhost" {
+		dnsNames = append(dnsNames, host)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"pubsub-emulator"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
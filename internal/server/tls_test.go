@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/dashboard"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+// writePEM writes a DER certificate and its key as PEM files in dir.
+func writePEM(t *testing.T, dir, name string, der []byte, key *ecdsa.PrivateKey) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// newTestCA returns a CA certificate and a client certificate it signed.
+func newTestCA(t *testing.T) (caDER []byte, client tls.Certificate) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	clientKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clientTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTmpl, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create client cert: %v", err)
+	}
+	return caDER, tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}
+}
+
+func TestTLSConfig_Enabled(t *testing.T) {
+	if (TLSConfig{}).Enabled() {
+		t.Error("Expected zero TLSConfig to be disabled")
+	}
+	if !(TLSConfig{SelfSigned: true}).Enabled() {
+		t.Error("Expected self-signed TLSConfig to be enabled")
+	}
+	if !(TLSConfig{CertFile: "c", KeyFile: "k"}).Enabled() {
+		t.Error("Expected cert TLSConfig to be enabled")
+	}
+}
+
+func TestBuildTLSConfig_SelfSigned(t *testing.T) {
+	cfg, err := buildTLSConfig(TLSConfig{SelfSigned: true})
+	if err != nil {
+		t.Fatalf("Expected self-signed config, got %v", err)
+	}
+	leaf := cfg.Certificates[0].Leaf
+	if leaf == nil || leaf.VerifyHostname("localhost") != nil || leaf.VerifyHostname("127.0.0.1") != nil {
+		t.Error("Expected certificate to be valid for localhost and 127.0.0.1")
+	}
+	if cfg.ClientAuth != tls.NoClientCert {
+		t.Error("Expected no client auth without a CA bundle")
+	}
+}
+
+func TestBuildTLSConfig_FilesAndClientCA(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, err := generateSelfSigned()
+	if err != nil {
+		t.Fatalf("Failed to generate cert: %v", err)
+	}
+	certFile, keyFile := writePEM(t, dir, "server", serverCert.Certificate[0], serverCert.PrivateKey.(*ecdsa.PrivateKey))
+
+	caDER, _ := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write CA: %v", err)
+	}
+
+	cfg, err := buildTLSConfig(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	if err != nil {
+		t.Fatalf("Expected config, got %v", err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert || cfg.ClientCAs == nil {
+		t.Error("Expected mTLS to be required")
+	}
+
+	emptyCA := filepath.Join(dir, "empty.pem")
+	_ = os.WriteFile(emptyCA, []byte("not a cert"), 0o600)
+	if _, err := buildTLSConfig(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: emptyCA}); err == nil {
+		t.Error("Expected error for CA bundle without certificates")
+	}
+	if _, err := buildTLSConfig(TLSConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile}); err == nil {
+		t.Error("Expected error for missing certificate file")
+	}
+}
+
+func TestStart_TLSWithHTTP2AndMTLS(t *testing.T) {
+	dir := t.TempDir()
+	caDER, clientCert := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write CA: %v", err)
+	}
+
+	log := logger.New()
+	srv := New(&Config{
+		Port:      "18443",
+		Dashboard: dashboard.New(nil, "test-project", log),
+		Logger:    log,
+		TLS:       TLSConfig{SelfSigned: true, ClientCAFile: caFile},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-errCh
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	newClient := func(certs []tls.Certificate) *http.Client {
+		return &http.Client{
+			Timeout: 2 * time.Second,
+			Transport: &http.Transport{
+				ForceAttemptHTTP2: true,
+				// #nosec G402 -- test client for a self-signed server certificate
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs},
+			},
+		}
+	}
+
+	resp, err := newClient([]tls.Certificate{clientCert}).Get("https://localhost:18443/api/health")
+	if err != nil {
+		t.Fatalf("Expected request with client certificate to succeed, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2, got %s", resp.Proto)
+	}
+
+	if resp, err := newClient(nil).Get("https://localhost:18443/api/health"); err == nil {
+		_ = resp.Body.Close()
+		t.Error("Expected request without client certificate to be rejected")
+	}
+}
@@ -0,0 +1,68 @@
+package supervisor
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+// maxLineBytes caps a buffered output line; longer lines are logged in
+// pieces.
+const maxLineBytes = 64 << 10
+
+// lineWriter logs each line the emulator writes, at a level guessed from the
+// Java logging prefix (SEVERE, WARNING, ...).
+type lineWriter struct {
+	log *logger.Logger
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func newLineWriter(log *logger.Logger) *lineWriter {
+	return &lineWriter{log: log}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) >= maxLineBytes {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// flush logs a trailing line that was not newline-terminated.
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.emit(w.buf)
+	w.buf = nil
+}
+
+func (w *lineWriter) emit(b []byte) {
+	line := strings.TrimRight(string(b), "\r ")
+	if line == "" {
+		return
+	}
+	switch {
+	case strings.Contains(line, "SEVERE") || strings.Contains(line, "ERROR"):
+		w.log.Error("%s", line)
+	case strings.Contains(line, "WARNING") || strings.Contains(line, "WARN"):
+		w.log.Warn("%s", line)
+	default:
+		w.log.Info("%s", line)
+	}
+}
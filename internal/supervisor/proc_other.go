@@ -0,0 +1,23 @@
+//go:build !unix
+
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(*exec.Cmd) {}
+
+// interrupt asks the command to exit. Platforms without process groups
+// cannot deliver SIGTERM, so this kills the process directly.
+func interrupt(cmd *exec.Cmd) error {
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+func kill(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,319 @@
+// Package supervisor runs the backing Pub/Sub emulator as a child process:
+// it launches the emulator, waits for its gRPC port, restarts it with
+// backoff when it crashes (telling the caller once it is back so the lost
+// state can be recreated), forwards its output to the logger and stops it
+// when the context is cancelled.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+// Defaults applied to zero Options fields.
+const (
+	DefaultStartupTimeout = time.Minute
+	DefaultMinBackoff     = time.Second
+	DefaultMaxBackoff     = 30 * time.Second
+	DefaultStableAfter    = time.Minute
+	DefaultStopTimeout    = 10 * time.Second
+)
+
+// pollInterval is how often Start dials the emulator port while waiting.
+const pollInterval = 100 * time.Millisecond
+
+// Options configures a Supervisor.
+type Options struct {
+	// Command is the emulator command line, e.g. gcloud beta emulators
+	// pubsub start --host-port=0.0.0.0:8085.
+	Command []string
+	// Addr is the host:port the emulator listens on once it is up.
+	Addr string
+	// StartupTimeout bounds how long Start waits for Addr to accept
+	// connections.
+	StartupTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the delay before a restart; the delay
+	// doubles on each consecutive crash.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// StableAfter is how long the emulator must run before a crash is no
+	// longer counted as consecutive, resetting the backoff.
+	StableAfter time.Duration
+	// StopTimeout is how long the emulator gets to exit after SIGTERM before
+	// it is killed.
+	StopTimeout time.Duration
+	// OnRestart, if set, runs once a restarted emulator accepts
+	// connections. The emulator keeps everything in memory, so it comes
+	// back empty; OnRestart recreates what the caller set up.
+	OnRestart func(ctx context.Context)
+	Logger    *logger.Logger
+}
+
+// Supervisor owns the emulator process.
+type Supervisor struct {
+	opts Options
+	log  *logger.Logger
+	done chan struct{}
+
+	mu       sync.Mutex
+	started  bool
+	running  bool
+	restarts int
+	lastErr  error
+}
+
+// New creates a Supervisor, filling unset durations with the defaults.
+func New(opts Options) *Supervisor {
+	if opts.StartupTimeout <= 0 {
+		opts.StartupTimeout = DefaultStartupTimeout
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = DefaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(DefaultMaxBackoff, opts.MinBackoff)
+	}
+	if opts.StableAfter <= 0 {
+		opts.StableAfter = DefaultStableAfter
+	}
+	if opts.StopTimeout <= 0 {
+		opts.StopTimeout = DefaultStopTimeout
+	}
+	return &Supervisor{opts: opts, log: opts.Logger, done: make(chan struct{})}
+}
+
+// Start launches the emulator and waits until it accepts connections on
+// Addr. It fails if the command cannot be started, exits during startup or
+// does not listen within StartupTimeout. Once Start succeeds the emulator is
+// restarted whenever it exits, until ctx is cancelled; Wait blocks until it
+// has then been stopped.
+func (s *Supervisor) Start(ctx context.Context) error {
+	if len(s.opts.Command) == 0 {
+		return errors.New("no emulator command configured")
+	}
+	s.log.Info("Starting emulator: %s", strings.Join(s.opts.Command, " "))
+	p, err := s.launch()
+	if err != nil {
+		return err
+	}
+	if err := s.waitReady(ctx, p); err != nil {
+		s.terminate(p)
+		return err
+	}
+	s.log.Info("Emulator is listening on %s (pid %d)", s.opts.Addr, p.cmd.Process.Pid)
+	s.mu.Lock()
+	s.started = true
+	s.mu.Unlock()
+	go s.supervise(ctx, p)
+	return nil
+}
+
+// Wait blocks until the supervised emulator has been stopped after the
+// context passed to Start was cancelled. It returns immediately if Start
+// failed or was never called.
+func (s *Supervisor) Wait() {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		<-s.done
+	}
+}
+
+// Restarts reports how many times the emulator has been restarted.
+func (s *Supervisor) Restarts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts
+}
+
+// Check reports an error while the emulator process is down or not
+// accepting connections. It is registered as a readiness check.
+func (s *Supervisor) Check(ctx context.Context) error {
+	s.mu.Lock()
+	running, lastErr := s.running, s.lastErr
+	s.mu.Unlock()
+	if !running {
+		if lastErr != nil {
+			return fmt.Errorf("emulator process is not running (last exit: %v)", lastErr)
+		}
+		return errors.New("emulator process is not running")
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", s.opts.Addr)
+	if err != nil {
+		return fmt.Errorf("emulator is not accepting connections: %w", err)
+	}
+	return conn.Close()
+}
+
+// process is one run of the emulator command.
+type process struct {
+	cmd     *exec.Cmd
+	started time.Time
+	stdout  *lineWriter
+	stderr  *lineWriter
+	// exited is closed once the process has exited and err is set.
+	exited chan struct{}
+	err    error
+}
+
+// launch starts the command in its own process group and reaps it in the
+// background.
+func (s *Supervisor) launch() (*process, error) {
+	cmd := exec.Command(s.opts.Command[0], s.opts.Command[1:]...)
+	setProcessGroup(cmd)
+	p := &process{
+		cmd:    cmd,
+		stdout: newLineWriter(s.log.With("component", "emulator", "stream", "stdout")),
+		stderr: newLineWriter(s.log.With("component", "emulator", "stream", "stderr")),
+		exited: make(chan struct{}),
+	}
+	cmd.Stdout, cmd.Stderr = p.stdout, p.stderr
+	// Children that outlive the emulator (gcloud runs a JVM) may hold the
+	// output pipes open; don't let them block Wait forever.
+	cmd.WaitDelay = s.opts.StopTimeout
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start emulator: %w", err)
+	}
+	p.started = time.Now()
+
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		p.stdout.flush()
+		p.stderr.flush()
+		if err == nil {
+			err = errors.New("exit status 0")
+		}
+		p.err = err
+
+		s.mu.Lock()
+		s.running = false
+		s.lastErr = err
+		s.mu.Unlock()
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+// waitReady polls Addr until it accepts a connection.
+func (s *Supervisor) waitReady(ctx context.Context, p *process) error {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.StartupTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		dialCtx, dialCancel := context.WithTimeout(ctx, pollInterval)
+		conn, err := (&net.Dialer{}).DialContext(dialCtx, "tcp", s.opts.Addr)
+		dialCancel()
+		if err == nil {
+			return conn.Close()
+		}
+		select {
+		case <-p.exited:
+			return fmt.Errorf("emulator exited during startup: %w", p.err)
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("emulator did not listen on %s within %s", s.opts.Addr, s.opts.StartupTimeout)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// supervise restarts the emulator whenever it exits and stops it when ctx is
+// cancelled.
+func (s *Supervisor) supervise(ctx context.Context, p *process) {
+	defer close(s.done)
+	backoff := s.opts.MinBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			s.terminate(p)
+			return
+		case <-p.exited:
+		}
+
+		uptime := time.Since(p.started)
+		if uptime >= s.opts.StableAfter {
+			backoff = s.opts.MinBackoff
+		}
+		s.log.Error("Emulator exited after %s (%v); restarting in %s", uptime.Round(time.Millisecond), p.err, backoff)
+
+		var ok bool
+		if p, backoff, ok = s.restart(ctx, backoff); !ok {
+			return
+		}
+		if s.opts.OnRestart == nil {
+			continue
+		}
+		if err := s.waitReady(ctx, p); err != nil {
+			s.log.Error("Restarted emulator is not ready: %v", err)
+			continue
+		}
+		s.opts.OnRestart(ctx)
+	}
+}
+
+// restart waits out the backoff and launches the emulator again, retrying
+// with a growing delay while the command fails to start. It returns false if
+// ctx is cancelled first.
+func (s *Supervisor) restart(ctx context.Context, backoff time.Duration) (*process, time.Duration, bool) {
+	for {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, backoff, false
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, s.opts.MaxBackoff)
+
+		p, err := s.launch()
+		if err != nil {
+			s.log.Error("%v; retrying in %s", err, backoff)
+			continue
+		}
+		s.mu.Lock()
+		s.restarts++
+		restarts := s.restarts
+		s.mu.Unlock()
+		s.log.Warn("Emulator restarted (pid %d, restart %d)", p.cmd.Process.Pid, restarts)
+		return p, backoff, true
+	}
+}
+
+// terminate asks the emulator's process group to exit and kills it if it
+// has not done so within StopTimeout.
+func (s *Supervisor) terminate(p *process) {
+	select {
+	case <-p.exited:
+		return
+	default:
+	}
+	s.log.Info("Stopping emulator (pid %d)", p.cmd.Process.Pid)
+	if err := interrupt(p.cmd); err != nil {
+		s.log.Warn("Failed to signal emulator: %v", err)
+	}
+	select {
+	case <-p.exited:
+		s.log.Info("Emulator stopped")
+	case <-time.After(s.opts.StopTimeout):
+		s.log.Warn("Emulator did not stop within %s; killing it", s.opts.StopTimeout)
+		_ = kill(p.cmd)
+		<-p.exited
+	}
+}
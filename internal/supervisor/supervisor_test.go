@@ -0,0 +1,242 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+)
+
+// TestHelperProcess is not a real test: it is the fake emulator the tests
+// launch by re-running the test binary with SUPERVISOR_HELPER set. It listens
+// on SUPERVISOR_HELPER_ADDR and, if SUPERVISOR_HELPER_EXIT_AFTER is set,
+// exits with status 1 after that long. With SUPERVISOR_HELPER_ONCE naming a
+// file it only exits on the run that creates the file.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("SUPERVISOR_HELPER") != "1" {
+		return
+	}
+	ln, err := net.Listen("tcp", os.Getenv("SUPERVISOR_HELPER_ADDR"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "SEVERE: listen failed: %v\n", err)
+		os.Exit(2)
+	}
+	fmt.Println("[pubsub] INFO: Server started, listening on", ln.Addr())
+	if once := os.Getenv("SUPERVISOR_HELPER_ONCE"); once != "" {
+		f, err := os.OpenFile(once, os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			select {}
+		}
+		_ = f.Close()
+	}
+	if after, err := time.ParseDuration(os.Getenv("SUPERVISOR_HELPER_EXIT_AFTER")); err == nil {
+		time.Sleep(after)
+		os.Exit(1)
+	}
+	select {}
+}
+
+// helperCommand returns a command line running TestHelperProcess.
+func helperCommand(t *testing.T, addr, exitAfter string) []string {
+	t.Helper()
+	t.Setenv("SUPERVISOR_HELPER", "1")
+	t.Setenv("SUPERVISOR_HELPER_ADDR", addr)
+	t.Setenv("SUPERVISOR_HELPER_EXIT_AFTER", exitAfter)
+	return []string{os.Args[0], "-test.run=^TestHelperProcess$"}
+}
+
+// freeAddr returns a loopback address with a currently unused port.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+func newTestLogger(buf *bytes.Buffer) *logger.Logger {
+	return &logger.Logger{Logger: slog.New(slog.NewJSONHandler(buf, nil))}
+}
+
+func TestStart_WaitsForPortAndStopsOnCancel(t *testing.T) {
+	addr := freeAddr(t)
+	sup := New(Options{
+		Command:     helperCommand(t, addr, ""),
+		Addr:        addr,
+		StopTimeout: 2 * time.Second,
+		Logger:      logger.New(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sup.Check(ctx); err != nil {
+		t.Errorf("Expected emulator to be ready, got %v", err)
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		sup.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Wait to return after cancel")
+	}
+
+	if err := sup.Check(context.Background()); err == nil {
+		t.Error("Expected check to fail after shutdown")
+	}
+	if _, err := net.DialTimeout("tcp", addr, 200*time.Millisecond); err == nil {
+		t.Error("Expected emulator port to be closed after shutdown")
+	}
+}
+
+func TestStart_Failures(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		wantErr string
+	}{
+		{"missing binary", []string{"/nonexistent/gcloud"}, "failed to start emulator"},
+		{"exits during startup", []string{"sh", "-c", "exit 3"}, "exited during startup: exit status 3"},
+		{"never listens", []string{"sleep", "30"}, "did not listen"},
+		{"no command", nil, "no emulator command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sup := New(Options{
+				Command:        tt.command,
+				Addr:           freeAddr(t),
+				StartupTimeout: 300 * time.Millisecond,
+				StopTimeout:    time.Second,
+				Logger:         logger.New(),
+			})
+			err := sup.Start(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			// Wait must not block when Start failed.
+			sup.Wait()
+		})
+	}
+}
+
+func TestSupervise_RestartsOnCrash(t *testing.T) {
+	addr := freeAddr(t)
+	sup := New(Options{
+		Command:     helperCommand(t, addr, "200ms"),
+		Addr:        addr,
+		MinBackoff:  10 * time.Millisecond,
+		MaxBackoff:  50 * time.Millisecond,
+		StopTimeout: 2 * time.Second,
+		Logger:      logger.New(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sup.Restarts() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected at least 2 restarts, got %d", sup.Restarts())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSupervise_OnRestart(t *testing.T) {
+	addr := freeAddr(t)
+	command := helperCommand(t, addr, "200ms")
+	t.Setenv("SUPERVISOR_HELPER_ONCE", filepath.Join(t.TempDir(), "crashed"))
+
+	restarted := make(chan error, 2)
+	sup := New(Options{
+		Command:     command,
+		Addr:        addr,
+		MinBackoff:  10 * time.Millisecond,
+		StopTimeout: 2 * time.Second,
+		Logger:      logger.New(),
+		OnRestart: func(ctx context.Context) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+			if err == nil {
+				_ = conn.Close()
+			}
+			restarted <- err
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case err := <-restarted:
+		if err != nil {
+			t.Errorf("Expected OnRestart to run once the emulator listens, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected OnRestart after the crash")
+	}
+	// The restarted emulator keeps running, so there is no second call.
+	select {
+	case <-restarted:
+		t.Error("Expected OnRestart to run once")
+	case <-time.After(500 * time.Millisecond):
+	}
+	if n := sup.Restarts(); n != 1 {
+		t.Errorf("Expected 1 restart, got %d", n)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newLineWriter(newTestLogger(&buf))
+
+	_, _ = w.Write([]byte("[pubsub] INFO: Server started\r\n[pubsub] WARNING: slow"))
+	_, _ = w.Write([]byte(" client\n\nSEVERE: crashed"))
+	w.flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []struct{ level, msg string }{
+		{"INFO", "[pubsub] INFO: Server started"},
+		{"WARN", "[pubsub] WARNING: slow client"},
+		{"ERROR", "SEVERE: crashed"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d log lines, got %d: %s", len(want), len(lines), buf.String())
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], `"level":"`+w.level+`"`) || !strings.Contains(lines[i], strconv.Quote(w.msg)) {
+			t.Errorf("Expected line %d to be %s %q, got %s", i, w.level, w.msg, lines[i])
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package templates stores named publish templates: a topic, a payload and
+// attributes with placeholders such as {{uuid}}, {{now}} or {{seq}} that are
+// filled in afresh each time the template is published. Placeholders are the
+// ones load jobs use (see loadgen.Template).
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dipjyotimetia/pubsub-emulator/internal/loadgen"
+)
+
+// maxNameLength bounds template names.
+const maxNameLength = 100
+
+// namePattern keeps names safe to use in URLs and file names.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Errors returned by Store.
+var (
+	ErrNotFound = errors.New("template not found")
+	ErrExists   = errors.New("template already exists")
+)
+
+// Template is a saved message shape.
+type Template struct {
+	Name string `json:"name"`
+	// Topic is the topic ID it publishes to, in the publishing project.
+	Topic      string            `json:"topic"`
+	Data       string            `json:"data"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Message is a template rendered for one publish.
+type Message struct {
+	Topic      string
+	Data       string
+	Attributes map[string]string
+}
+
+// entry is a stored template with its placeholders parsed.
+type entry struct {
+	Template
+	data  *loadgen.Template
+	attrs map[string]*loadgen.Template
+	// seq is the number of times the template has been rendered; {{seq}}
+	// counts from 1 for each template.
+	seq int64
+}
+
+// compile validates t and parses its placeholders.
+func compile(t Template) (*entry, error) {
+	if len(t.Name) > maxNameLength || !namePattern.MatchString(t.Name) {
+		return nil, fmt.Errorf("name must be at most %d letters, digits, . _ or - and start with a letter or digit, got %q", maxNameLength, t.Name)
+	}
+	if t.Topic == "" {
+		return nil, errors.New("topic is required")
+	}
+	if t.Data == "" {
+		return nil, errors.New("data is required")
+	}
+	e := &entry{Template: t, attrs: make(map[string]*loadgen.Template, len(t.Attributes))}
+	var err error
+	if e.data, err = loadgen.ParseTemplate(t.Data); err != nil {
+		return nil, fmt.Errorf("data: %w", err)
+	}
+	for k, v := range t.Attributes {
+		if e.attrs[k], err = loadgen.ParseTemplate(v); err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", k, err)
+		}
+	}
+	return e, nil
+}
+
+// Store holds templates by name. It is safe for concurrent use.
+type Store struct {
+	mu        sync.Mutex
+	templates map[string]*entry
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{templates: make(map[string]*entry)}
+}
+
+// List returns the templates sorted by name.
+func (s *Store) List() []Template {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Template, 0, len(s.templates))
+	for _, e := range s.templates {
+		list = append(list, e.Template)
+	}
+	slices.SortFunc(list, func(a, b Template) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// Get returns the template called name.
+func (s *Store) Get(name string) (Template, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.templates[name]
+	if !ok {
+		return Template{}, false
+	}
+	return e.Template, true
+}
+
+// Create adds t, failing with ErrExists if its name is taken.
+func (s *Store) Create(t Template) error {
+	e, err := compile(t)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.templates[t.Name]; ok {
+		return ErrExists
+	}
+	s.templates[t.Name] = e
+	return nil
+}
+
+// Put adds or replaces t and reports whether it was added. Replacing a
+// template restarts its {{seq}}.
+func (s *Store) Put(t Template) (bool, error) {
+	e, err := compile(t)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.templates[t.Name]
+	s.templates[t.Name] = e
+	return !exists, nil
+}
+
+// Delete removes the template called name and reports whether it existed.
+func (s *Store) Delete(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.templates[name]
+	delete(s.templates, name)
+	return ok
+}
+
+// Render fills in the template called name for a publish at now.
+func (s *Store) Render(name string, now time.Time) (Message, error) {
+	s.mu.Lock()
+	e, ok := s.templates[name]
+	if !ok {
+		s.mu.Unlock()
+		return Message{}, ErrNotFound
+	}
+	e.seq++
+	seq := e.seq
+	s.mu.Unlock()
+
+	r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	msg := Message{Topic: e.Topic, Data: e.data.Render(seq, now, r)}
+	if len(e.attrs) > 0 {
+		msg.Attributes = make(map[string]string, len(e.attrs))
+		for k, t := range e.attrs {
+			msg.Attributes[k] = t.Render(seq, now, r)
+		}
+	}
+	return msg, nil
+}
+
+// LoadDir adds every *.json file in dir, each holding one template, and
+// returns how many were loaded. A file's name (without .json) is the
+// template's name unless the file sets one.
+func (s *Store) LoadDir(dir string) (int, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return 0, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path) // #nosec G304 -- path comes from operator configuration
+		if err != nil {
+			return 0, fmt.Errorf("failed to read template: %w", err)
+		}
+		var t Template
+		if err := json.Unmarshal(data, &t); err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+		if t.Name == "" {
+			t.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+		if _, err := s.Put(t); err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return len(paths), nil
+}
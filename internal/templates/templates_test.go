@@ -0,0 +1,129 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestStore_CRUD(t *testing.T) {
+	s := NewStore()
+	order := Template{Name: "order", Topic: "orders", Data: `{"id":"{{uuid}}"}`}
+	if err := s.Create(order); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	if err := s.Create(order); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected ErrExists for a duplicate name, got %v", err)
+	}
+	if added, err := s.Put(Template{Name: "audit", Topic: "audit", Data: "x"}); err != nil || !added {
+		t.Errorf("Expected Put to add a new template, got added=%v err=%v", added, err)
+	}
+	order.Data = "changed"
+	if added, err := s.Put(order); err != nil || added {
+		t.Errorf("Expected Put to replace an existing template, got added=%v err=%v", added, err)
+	}
+	if got, ok := s.Get("order"); !ok || got.Data != "changed" {
+		t.Errorf("Expected the replaced template, got %+v (found %v)", got, ok)
+	}
+
+	list := s.List()
+	if len(list) != 2 || list[0].Name != "audit" || list[1].Name != "order" {
+		t.Errorf("Expected templates sorted by name, got %+v", list)
+	}
+	if !s.Delete("audit") || s.Delete("audit") {
+		t.Error("Expected Delete to report whether the template existed")
+	}
+}
+
+func TestStore_Validation(t *testing.T) {
+	tests := []Template{
+		{Name: "", Topic: "t", Data: "x"},
+		{Name: "has space", Topic: "t", Data: "x"},
+		{Name: "../escape", Topic: "t", Data: "x"},
+		{Name: "ok", Data: "x"},
+		{Name: "ok", Topic: "t"},
+		{Name: "ok", Topic: "t", Data: "{{nope}}"},
+		{Name: "ok", Topic: "t", Data: "x", Attributes: map[string]string{"k": "{{int:9:1}}"}},
+	}
+	for _, tt := range tests {
+		if err := NewStore().Create(tt); err == nil {
+			t.Errorf("Expected %+v to be rejected", tt)
+		}
+	}
+}
+
+func TestStore_Render(t *testing.T) {
+	s := NewStore()
+	if err := s.Create(Template{
+		Name:       "event",
+		Topic:      "events",
+		Data:       `{"seq":{{seq}},"id":"{{uuid}}","at":"{{now}}"}`,
+		Attributes: map[string]string{"seq": "{{seq}}"},
+	}); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	first, err := s.Render("event", now)
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	second, _ := s.Render("event", now)
+
+	pattern := regexp.MustCompile(`^\{"seq":1,"id":"[0-9a-f-]{36}","at":"2026-03-04T05:06:07Z"\}$`)
+	if first.Topic != "events" || !pattern.MatchString(first.Data) {
+		t.Errorf("Unexpected first render: %+v", first)
+	}
+	if first.Attributes["seq"] != "1" || second.Attributes["seq"] != "2" {
+		t.Errorf("Expected {{seq}} to count renders, got %q then %q", first.Attributes["seq"], second.Attributes["seq"])
+	}
+	if first.Data[len(`{"seq":1,"id":"`):][:36] == second.Data[len(`{"seq":2,"id":"`):][:36] {
+		t.Error("Expected each render to draw a new UUID")
+	}
+
+	if _, err := s.Render("missing", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"order.json":  `{"topic":"orders","data":"{\"id\":\"{{uuid}}\"}"}`,
+		"named.json":  `{"name":"custom","topic":"audit","data":"x","attributes":{"k":"v"}}`,
+		"ignored.txt": `not a template`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewStore()
+	n, err := s.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 templates loaded, got %d", n)
+	}
+	if _, ok := s.Get("order"); !ok {
+		t.Error("Expected the file name to name a template without one")
+	}
+	if got, ok := s.Get("custom"); !ok || got.Attributes["k"] != "v" {
+		t.Errorf("Expected the named template, got %+v (found %v)", got, ok)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"topic":"t","data":"{{nope}}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStore().LoadDir(dir); err == nil {
+		t.Error("Expected an invalid template file to fail the load")
+	}
+	if _, err := NewStore().LoadDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected a missing directory to fail the load")
+	}
+}
@@ -9,7 +9,8 @@ const state = {
     subscriptions: [],
     isLoading: false,
     lastUpdate: null,
-    theme: localStorage.getItem('theme') || 'light'
+    theme: localStorage.getItem('theme') || 'light',
+    project: localStorage.getItem('project') || ''
 };
 
 // Theme Management
@@ -59,6 +60,10 @@ document.addEventListener('DOMContentLoaded', function() {
     // Initial load
     loadStats();
     loadMessages();
+    loadChaosRules();
+    loadLoadJobs();
+    loadReplays();
+    loadTemplates();
     setupSearchHandlers();
     setupMessageActions();
     setupModalKeyboard();
@@ -68,9 +73,13 @@ document.addEventListener('DOMContentLoaded', function() {
 
     // Refresh stats every 5 seconds
     setInterval(() => rafScheduler(loadStats), 5000);
+    setInterval(() => rafScheduler(loadChaosRules), 5000);
+    setInterval(() => rafScheduler(loadLoadJobs), 2000);
+    setInterval(() => rafScheduler(loadReplays), 5000);
 
-    // Refresh messages every 10 seconds
+    // Refresh messages and templates every 10 seconds
     setInterval(() => rafScheduler(loadMessages), 10000);
+    setInterval(() => rafScheduler(loadTemplates), 10000);
 
     // Performance monitoring
     if ('performance' in window && performance.getEntriesByType) {
@@ -98,19 +107,77 @@ function updateConnectionStatus(connected) {
     }
 }
 
+// apiFetch wraps fetch for dashboard API calls. When the server is configured
+// for token or JWT auth it answers 401 with a Bearer challenge; we then ask for
+// a token once, keep it for the browser session and retry. (Basic auth is
+// handled natively by the browser.)
+let authPromptDismissed = false;
+
+// readCookie returns the named cookie's value (the double-submit CSRF token
+// is issued as a readable cookie when DASHBOARD_CSRF=token).
+function readCookie(name) {
+    const prefix = name + '=';
+    const match = document.cookie.split('; ').find(c => c.startsWith(prefix));
+    return match ? decodeURIComponent(match.slice(prefix.length)) : '';
+}
+
+// scopedURL adds the selected project to dashboard API URLs; without it the
+// server uses its default project.
+function scopedURL(url) {
+    if (!state.project || !url.startsWith('/api/')) return url;
+    const sep = url.includes('?') ? '&' : '?';
+    return `${url}${sep}project=${encodeURIComponent(state.project)}`;
+}
+
+async function apiFetch(url, options = {}) {
+    url = scopedURL(url);
+    const send = () => {
+        const headers = new Headers(options.headers || {});
+        const token = sessionStorage.getItem('apiToken');
+        if (token) headers.set('Authorization', `Bearer ${token}`);
+        const csrf = readCookie('csrf_token');
+        if (csrf && options.method && options.method !== 'GET') headers.set('X-CSRF-Token', csrf);
+        return fetch(url, { ...options, headers });
+    };
+
+    let response = await send();
+    const challenge = response.headers.get('WWW-Authenticate') || '';
+    if (response.status === 401 && challenge.startsWith('Bearer') && !authPromptDismissed) {
+        const token = prompt('This dashboard requires an API token:');
+        if (token && token.trim()) {
+            sessionStorage.setItem('apiToken', token.trim());
+            response = await send();
+        } else {
+            authPromptDismissed = true;
+        }
+    }
+    if (response.status === 403) {
+        showToast('Your role does not allow this action', 'error');
+    }
+    return response;
+}
+
 // Load Statistics with error handling
 async function loadStats() {
     try {
-        const response = await fetch('/api/stats', {
+        const response = await apiFetch('/api/stats', {
             method: 'GET',
             headers: { 'Accept': 'application/json' }
         });
 
+        // A remembered project the server no longer serves: fall back to
+        // the default project.
+        if (response.status === 404 && state.project) {
+            changeProject('');
+            return;
+        }
+
         if (!response.ok) {
             throw new Error(`HTTP error! status: ${response.status}`);
         }
 
         const stats = await response.json();
+        updateProjectSelect(stats.projects || [], stats.project);
         updateStats(stats);
         updateConnectionStatus(true);
     } catch (error) {
@@ -140,6 +207,10 @@ function updateStats(stats) {
             document.getElementById('lastMessage').textContent = formatTimeAgo(time);
         }
 
+        if (stats.history) {
+            updateHistoryStats(stats.history);
+        }
+
         // Update topics list
         if (stats.topic_list) {
             state.topics = stats.topic_list;
@@ -155,6 +226,77 @@ function updateStats(stats) {
     });
 }
 
+// updateHistoryStats shows the history's memory use against its budget and
+// how many messages the retention limits evicted, with a per-limit and
+// per-topic breakdown in the tooltip.
+function updateHistoryStats(history) {
+    const memory = document.getElementById('historyMemory');
+    const evicted = document.getElementById('historyEvicted');
+    if (!memory || !evicted) return;
+
+    memory.textContent = history.memory_budget
+        ? `${formatBytes(history.bytes)} / ${formatBytes(history.memory_budget)}`
+        : formatBytes(history.bytes);
+
+    const reasons = Object.entries(history.evicted || {});
+    const total = reasons.reduce((sum, [, n]) => sum + n, 0);
+    evicted.textContent = total ? `${total} evicted` : 'No evictions';
+
+    const lines = [`${history.messages} of ${history.max_messages} messages`];
+    reasons.forEach(([reason, n]) => lines.push(`${reason}: ${n} evicted`));
+    (history.topics || []).forEach(t => {
+        lines.push(`${t.topic}: ${t.messages} messages, ${formatBytes(t.bytes)}` +
+            (t.truncated ? `, ${t.truncated} truncated` : '') +
+            (t.evicted ? `, ${t.evicted} evicted` : ''));
+    });
+    document.getElementById('historyCard').title = lines.join('\n');
+}
+
+// formatBytes renders a byte count with a binary unit.
+function formatBytes(bytes) {
+    const units = ['B', 'KB', 'MB', 'GB'];
+    let value = bytes || 0;
+    let unit = 0;
+    while (value >= 1024 && unit < units.length - 1) {
+        value /= 1024;
+        unit++;
+    }
+    return `${unit ? value.toFixed(1) : value} ${units[unit]}`;
+}
+
+// updateProjectSelect lists the served projects in the header; the selector
+// stays hidden when there is only one.
+function updateProjectSelect(projects, current) {
+    const select = document.getElementById('projectSelect');
+    if (!select) return;
+
+    select.hidden = projects.length < 2;
+    if (select.dataset.projects !== projects.join(',')) {
+        select.dataset.projects = projects.join(',');
+        select.innerHTML = '';
+        projects.forEach(project => {
+            const option = document.createElement('option');
+            option.value = project;
+            option.textContent = project;
+            select.appendChild(option);
+        });
+    }
+    select.value = current;
+}
+
+// changeProject switches every view to another project.
+function changeProject(project) {
+    state.project = project;
+    localStorage.setItem('project', project);
+    state.messages = [];
+    messagesLoaded = false;
+    loadStats();
+    loadMessages();
+    loadChaosRules();
+    loadLoadJobs();
+    loadReplays();
+}
+
 // Animate number changes for better UX
 function animateNumber(element, start, end, duration = 500) {
     if (start === end) return;
@@ -192,7 +334,7 @@ async function loadMessages() {
     if (!messagesLoaded) showMessagesLoading();
 
     try {
-        const response = await fetch('/api/messages', {
+        const response = await apiFetch('/api/messages', {
             method: 'GET',
             headers: { 'Accept': 'application/json' }
         });
@@ -255,13 +397,17 @@ function createMessageCard(msg) {
     const publishTime = new Date(msg.publish_time);
     const escapedId = escapeHtml(msg.id);
 
+    const tags = (msg.tags || []).map(tag => `<span class="message-tag">#${escapeHtml(tag)}</span>`).join('');
+    const note = msg.note ? `<span class="message-note">${escapeHtml(msg.note)}</span>` : '';
+
     return `
-        <div class="message-card" data-message-id="${escapedId}">
+        <div class="message-card${msg.pinned ? ' pinned' : ''}" data-message-id="${escapedId}">
             <div class="message-header">
-                <span class="message-id">ID: ${escapedId}</span>
+                <span class="message-id">${msg.pinned ? '📌 ' : ''}ID: ${escapedId}</span>
                 <span class="message-topic">${escapeHtml(msg.topic)}</span>
             </div>
-            <div class="message-data">${escapeHtml(formatPayload(msg.data))}</div>
+            <div class="message-data">${escapeHtml(formatPayload(msg.data))}${msg.truncated ? `<span class="message-truncated">✂️ Truncated: ${formatBytes(msg.size)} in full</span>` : ''}</div>
+            ${tags || note ? `<div class="message-annotations">${tags}${note}</div>` : ''}
             <div class="message-footer">
                 <div class="message-time">
                     <span>📤 Published: ${formatTime(publishTime)}</span>
@@ -269,7 +415,9 @@ function createMessageCard(msg) {
                 </div>
                 <div class="message-actions">
                     <button class="btn btn-info" data-action="view">View</button>
+                    <button class="btn btn-secondary" data-action="pin" aria-pressed="${msg.pinned ? 'true' : 'false'}">${msg.pinned ? 'Unpin' : '📌 Pin'}</button>
                     <button class="btn btn-secondary" data-action="copy">📋 Copy</button>
+                    ${msg.replay_of ? '<button class="btn btn-secondary" data-action="diff">🔀 Diff</button>' : ''}
                     <button class="btn btn-primary" data-action="replay">🔄 Replay</button>
                 </div>
             </div>
@@ -302,18 +450,83 @@ function setupMessageActions() {
             case 'copy':
                 copyMessageData(id);
                 break;
+            case 'pin': {
+                const msg = state.messages.find(m => m.id === id);
+                if (msg) annotateMessage(id, { pinned: !msg.pinned });
+                break;
+            }
+            case 'diff':
+                showDiffModal('', id);
+                diffMessages();
+                break;
         }
     });
 }
 
-// copyMessageData copies the raw (unformatted) payload to the clipboard.
-function copyMessageData(messageId) {
+// copyMessageData copies the raw (unformatted) payload to the clipboard,
+// fetching the whole payload of truncated messages first.
+async function copyMessageData(messageId) {
     const msg = state.messages.find(m => m.id === messageId);
     if (!msg) return;
 
-    navigator.clipboard.writeText(msg.data)
-        .then(() => showToast('Message data copied to clipboard', 'success'))
-        .catch(() => showToast('Failed to copy message data', 'error'));
+    try {
+        const data = msg.truncated ? await fetchMessageData(messageId) : msg.data;
+        await navigator.clipboard.writeText(data);
+        showToast('Message data copied to clipboard', 'success');
+    } catch (error) {
+        showToast('Failed to copy message data', 'error');
+    }
+}
+
+// fetchMessageData returns a message's whole payload, including the part
+// left out of a truncated message.
+async function fetchMessageData(messageId) {
+    const response = await apiFetch(`/api/messages/${encodeURIComponent(messageId)}/data`);
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.text();
+}
+
+// loadFullPayload replaces a truncated payload in the detail modal with the
+// whole one.
+async function loadFullPayload() {
+    const messageId = state.currentMessageId;
+    try {
+        const data = await fetchMessageData(messageId);
+        const el = document.getElementById('messageDataValue');
+        if (el && state.currentMessageId === messageId) {
+            el.textContent = formatPayload(data);
+            document.getElementById('loadFullPayloadButton')?.remove();
+        }
+    } catch (error) {
+        showToast(`Failed to load payload: ${error.message}`, 'error');
+    }
+}
+
+// annotateMessage changes a message's pin, tags or note and updates the
+// list in place.
+async function annotateMessage(messageId, changes) {
+    try {
+        const response = await apiFetch(`/api/messages/${encodeURIComponent(messageId)}`, {
+            method: 'PATCH',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(changes)
+        });
+        if (!response.ok) {
+            showToast(`Failed to update message: ${(await response.text()).trim()}`, 'error');
+            return false;
+        }
+        const updated = await response.json();
+        const msg = state.messages.find(m => m.id === messageId);
+        if (msg) Object.assign(msg, { pinned: updated.pinned, tags: updated.tags, note: updated.note });
+        performSearch();
+        return true;
+    } catch (error) {
+        console.error('Error updating message:', error);
+        showToast('Error updating message', 'error');
+        return false;
+    }
 }
 
 // Search and Filter
@@ -323,6 +536,17 @@ function setupSearchHandlers() {
     
     searchInput.addEventListener('input', debounce(performSearch, 300));
     topicFilter.addEventListener('change', performSearch);
+    document.getElementById('pinnedFilter').addEventListener('change', performSearch);
+}
+
+// setSearchParams adds the current search, topic filter and pinned filter
+// to params, as /api/messages/export and /api/replays read them.
+function setSearchParams(params) {
+    const term = document.getElementById('searchInput').value.trim();
+    const topic = document.getElementById('topicFilter').value;
+    if (term) params.set('q', term);
+    if (topic) params.set('topic', topic);
+    if (document.getElementById('pinnedFilter').checked) params.set('pinned', 'true');
 }
 
 function performSearch() {
@@ -336,11 +560,17 @@ function performSearch() {
         filtered = filtered.filter(msg => msg.topic === topicFilter);
     }
 
+    if (document.getElementById('pinnedFilter').checked) {
+        filtered = filtered.filter(msg => msg.pinned);
+    }
+
     // Apply search term
     if (searchTerm) {
         filtered = filtered.filter(msg =>
             msg.data.toLowerCase().includes(searchTerm) ||
-            msg.id.toLowerCase().includes(searchTerm)
+            msg.id.toLowerCase().includes(searchTerm) ||
+            (msg.note || '').toLowerCase().includes(searchTerm) ||
+            (msg.tags || []).some(tag => tag.toLowerCase().includes(searchTerm))
         );
     }
 
@@ -348,6 +578,87 @@ function performSearch() {
     updateMessageBadge(filtered.length);
 }
 
+// exportMessages downloads the messages matching the current search and
+// filters in the chosen format.
+async function exportMessages() {
+    const params = new URLSearchParams({ format: document.getElementById('exportFormat').value });
+    setSearchParams(params);
+    try {
+        const response = await apiFetch(`/api/messages/export?${params}`);
+        if (!response.ok) {
+            showToast('Failed to export messages', 'error');
+            return;
+        }
+        const disposition = response.headers.get('Content-Disposition') || '';
+        const match = disposition.match(/filename="([^"]+)"/);
+        const link = document.createElement('a');
+        link.href = URL.createObjectURL(await response.blob());
+        link.download = match ? match[1] : `messages.${params.get('format')}`;
+        link.click();
+        setTimeout(() => URL.revokeObjectURL(link.href), 0);
+    } catch (error) {
+        console.error('Error exporting messages:', error);
+        showToast('Error exporting messages', 'error');
+    }
+}
+
+function showImportModal() {
+    document.getElementById('importFile').value = '';
+    document.getElementById('importResult').innerHTML = '';
+    openModal('importModal');
+}
+
+// importMessages uploads a fixture to /api/messages/import, which publishes
+// its messages and reports the lines that failed.
+async function importMessages() {
+    const file = document.getElementById('importFile').files[0];
+    if (!file) {
+        showToast('Choose a file to import', 'error');
+        return;
+    }
+    const params = new URLSearchParams({
+        timing: document.getElementById('importTiming').value,
+        speed: document.getElementById('importSpeed').value || '1',
+    });
+    const topic = document.getElementById('importTopic').value.trim();
+    if (topic) params.set('topic', topic);
+    const form = new FormData();
+    form.append('file', file);
+
+    const button = document.getElementById('importButton');
+    button.disabled = true;
+    button.setAttribute('aria-busy', 'true');
+    try {
+        const response = await apiFetch(`/api/messages/import?${params}`, { method: 'POST', body: form });
+        if (!response.ok) {
+            showToast(await response.text(), 'error');
+            return;
+        }
+        const report = await response.json();
+        document.getElementById('importResult').innerHTML = renderImportReport(report);
+        showToast(`Published ${report.published} of ${report.total} messages`, report.failed ? 'error' : 'success');
+        loadMessages();
+    } catch (error) {
+        console.error('Error importing messages:', error);
+        showToast('Error importing messages', 'error');
+    } finally {
+        button.disabled = false;
+        button.removeAttribute('aria-busy');
+    }
+}
+
+function renderImportReport(report) {
+    const topics = Object.entries(report.topics || {})
+        .map(([topic, count]) => `<li><code>${escapeHtml(topic)}</code> ${count}</li>`).join('');
+    const errors = (report.errors || [])
+        .map(e => `<li>Line ${e.line}: ${escapeHtml(e.error)}</li>`).join('');
+    return `
+        <p>Published ${report.published} of ${report.total} messages in ${report.elapsed_seconds.toFixed(1)}s${report.failed ? `; ${report.failed} failed` : ''}.</p>
+        ${topics ? `<ul class="import-topics">${topics}</ul>` : ''}
+        ${errors ? `<ul class="import-errors">${errors}</ul>` : ''}
+    `;
+}
+
 function updateTopicFilter() {
     const select = document.getElementById('topicFilter');
     const currentValue = select.value;
@@ -497,7 +808,7 @@ async function publishMessage() {
     }
     
     try {
-        const response = await fetch('/api/publish', {
+        const response = await apiFetch('/api/publish', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ topic_id: topic, data: data, attributes: attributes })
@@ -532,7 +843,7 @@ async function createTopic() {
     }
 
     try {
-        const response = await fetch('/api/topics', {
+        const response = await apiFetch('/api/topics', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ topic_id: topicId })
@@ -564,7 +875,7 @@ async function createSubscription() {
     }
 
     try {
-        const response = await fetch('/api/subscriptions', {
+        const response = await apiFetch('/api/subscriptions', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({
@@ -588,6 +899,559 @@ async function createSubscription() {
     }
 }
 
+// Fault Injection
+
+// loadChaosRules shows the project's fault injection rules; the section
+// stays hidden when the server has no chaos proxy.
+async function loadChaosRules() {
+    const section = document.getElementById('chaosSection');
+    if (!section) return;
+    try {
+        const response = await apiFetch('/api/chaos', { headers: { 'Accept': 'application/json' } });
+        if (!response.ok) {
+            section.hidden = true;
+            return;
+        }
+        const rules = await response.json();
+        section.hidden = false;
+        renderChaosRules(rules);
+    } catch (error) {
+        console.error('Error loading chaos rules:', error);
+    }
+}
+
+function renderChaosRules(rules) {
+    document.getElementById('chaosBadge').textContent = rules.length;
+    const body = document.getElementById('chaosRules');
+    if (rules.length === 0) {
+        body.innerHTML = '<tr><td colspan="7" class="chaos-empty">No active rules</td></tr>';
+        return;
+    }
+    body.innerHTML = rules.map(rule => {
+        const target = (rule.topic || rule.subscription || '').split('/').pop();
+        const kind = rule.topic ? 'topic' : 'subscription';
+        let details = '';
+        if (rule.code) details = rule.code;
+        if (rule.latency_ms) details = `${rule.latency_ms} ms`;
+        const expires = rule.expires_at ? formatTime(new Date(rule.expires_at)) : 'never';
+        return `
+            <tr>
+                <td><code>${escapeHtml(rule.action)}</code></td>
+                <td>${escapeHtml(target)} <small>(${kind})</small></td>
+                <td>${escapeHtml(details)}</td>
+                <td>${Math.round(rule.probability * 100)}%</td>
+                <td>${escapeHtml(expires)}</td>
+                <td>${rule.hits}</td>
+                <td><button class="outline contrast chaos-remove" onclick="deleteChaosRule('${escapeHtml(rule.id)}')" aria-label="Remove rule ${escapeHtml(rule.id)}">✕</button></td>
+            </tr>`;
+    }).join('');
+}
+
+function showChaosModal() {
+    updateChaosForm();
+    openModal('chaosModal');
+}
+
+// updateChaosForm offers the targets and fields that suit the selected action.
+function updateChaosForm() {
+    const action = document.getElementById('chaosAction').value;
+    const topics = action === 'publish_error' || action === 'latency';
+    const subscriptions = action !== 'publish_error';
+
+    const select = document.getElementById('chaosTarget');
+    select.innerHTML = '';
+    const add = (kind, id) => {
+        const opt = document.createElement('option');
+        opt.value = `${kind}:${id}`;
+        opt.textContent = `${id} (${kind})`;
+        select.appendChild(opt);
+    };
+    if (topics) state.topics.forEach(id => add('topic', id));
+    if (subscriptions) state.subscriptions.forEach(id => add('subscription', id));
+
+    document.getElementById('chaosCodeGroup').hidden = action !== 'publish_error';
+    document.getElementById('chaosLatencyGroup').hidden = action !== 'latency';
+}
+
+async function addChaosRule() {
+    const action = document.getElementById('chaosAction').value;
+    const target = document.getElementById('chaosTarget').value;
+    if (!target) {
+        showToast('No topic or subscription to target', 'error');
+        return;
+    }
+    const [kind, id] = target.split(/:(.*)/);
+    const rule = {
+        action,
+        probability: parseFloat(document.getElementById('chaosProbability').value) || 1,
+        ttl_seconds: parseInt(document.getElementById('chaosTTL').value) || 0
+    };
+    rule[kind === 'topic' ? 'topic_id' : 'subscription_id'] = id;
+    if (action === 'publish_error') rule.code = document.getElementById('chaosCode').value;
+    if (action === 'latency') rule.latency_ms = parseInt(document.getElementById('chaosLatency').value) || 0;
+
+    try {
+        const response = await apiFetch('/api/chaos', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(rule)
+        });
+        if (response.ok) {
+            showToast('Fault injection rule added', 'success');
+            closeModal('chaosModal');
+            loadChaosRules();
+        } else {
+            showToast(`Failed to add rule: ${(await response.text()).trim()}`, 'error');
+        }
+    } catch (error) {
+        console.error('Error adding chaos rule:', error);
+        showToast('Error adding rule', 'error');
+    }
+}
+
+async function deleteChaosRule(id) {
+    try {
+        const response = await apiFetch(`/api/chaos/${encodeURIComponent(id)}`, { method: 'DELETE' });
+        if (response.ok) {
+            loadChaosRules();
+        } else {
+            showToast('Failed to remove rule', 'error');
+        }
+    } catch (error) {
+        console.error('Error removing chaos rule:', error);
+        showToast('Error removing rule', 'error');
+    }
+}
+
+// Load Generator
+
+// Set from here rather than the page, which is a Go template and would treat
+// the placeholders' braces as actions.
+const LOADGEN_DEFAULT_TEMPLATE = '{"seq":{{seq}},"id":"{{uuid}}","value":{{int:1:100}}}';
+const LOADGEN_PLACEHOLDERS = '{{seq}}, {{uuid}}, {{int:min:max}}, {{float:min:max}}, {{bool}}, {{hex:n}}, {{pick:a|b}}, {{word}}, {{name}}, {{email}}, {{now}}, {{unix_ms}}';
+
+// loadLoadJobs shows the project's load jobs with their live statistics; the
+// section stays hidden when the server does not offer load generation.
+async function loadLoadJobs() {
+    const section = document.getElementById('loadgenSection');
+    if (!section) return;
+    try {
+        const response = await apiFetch('/api/loadgen', { headers: { 'Accept': 'application/json' } });
+        if (!response.ok) {
+            section.hidden = true;
+            return;
+        }
+        const jobs = await response.json();
+        section.hidden = false;
+        renderLoadJobs(jobs);
+    } catch (error) {
+        console.error('Error loading load jobs:', error);
+    }
+}
+
+function renderLoadJobs(jobs) {
+    document.getElementById('loadgenBadge').textContent = jobs.filter(job => job.state === 'running').length;
+    const body = document.getElementById('loadgenJobs');
+    if (jobs.length === 0) {
+        body.innerHTML = '<tr><td colspan="8" class="loadgen-empty">No load jobs</td></tr>';
+        return;
+    }
+    body.innerHTML = jobs.slice().reverse().map(job => {
+        const topic = job.topic.split('/').pop();
+        const errors = job.error ? `${job.errors} <small>(${escapeHtml(job.error)})</small>` : job.errors;
+        const stop = job.state === 'running'
+            ? `<button class="outline contrast loadgen-stop" onclick="stopLoadJob('${escapeHtml(job.id)}')" aria-label="Stop load job ${escapeHtml(job.id)}">■</button>`
+            : '';
+        return `
+            <tr>
+                <td>${escapeHtml(topic)}</td>
+                <td><code>${escapeHtml(job.state)}</code></td>
+                <td>${job.published}</td>
+                <td>${errors}</td>
+                <td>${job.throughput.toFixed(1)}/s${job.rate ? ` <small>of ${job.rate}/s</small>` : ''}</td>
+                <td>${job.latency_ms.p50.toFixed(2)} / ${job.latency_ms.p99.toFixed(2)} ms</td>
+                <td>${job.elapsed_seconds.toFixed(1)}s</td>
+                <td>${stop}</td>
+            </tr>`;
+    }).join('');
+}
+
+function showLoadGenModal() {
+    const select = document.getElementById('loadgenTopic');
+    select.innerHTML = '';
+    state.topics.forEach(id => {
+        const opt = document.createElement('option');
+        opt.value = id;
+        opt.textContent = id;
+        select.appendChild(opt);
+    });
+    const template = document.getElementById('loadgenTemplate');
+    if (!template.value) template.value = LOADGEN_DEFAULT_TEMPLATE;
+    document.getElementById('loadgenPlaceholders').textContent = `Placeholders: ${LOADGEN_PLACEHOLDERS}`;
+    document.getElementById('loadgenAttributes').placeholder = '{"region": "{{pick:eu|us}}"}';
+    openModal('loadgenModal');
+}
+
+async function startLoadJob() {
+    const topic = document.getElementById('loadgenTopic').value;
+    if (!topic) {
+        showToast('No topic to publish to', 'error');
+        return;
+    }
+    const job = {
+        topic_id: topic,
+        rate: parseFloat(document.getElementById('loadgenRate').value) || 0,
+        concurrency: parseInt(document.getElementById('loadgenConcurrency').value) || 0,
+        duration: document.getElementById('loadgenDuration').value.trim(),
+        template: document.getElementById('loadgenTemplate').value
+    };
+    const attributes = document.getElementById('loadgenAttributes').value.trim();
+    if (attributes) {
+        try {
+            job.attributes = JSON.parse(attributes);
+        } catch {
+            showToast('Attributes must be a JSON object', 'error');
+            return;
+        }
+    }
+
+    try {
+        const response = await apiFetch('/api/loadgen', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(job)
+        });
+        if (response.ok) {
+            showToast('Load job started', 'success');
+            closeModal('loadgenModal');
+            loadLoadJobs();
+        } else {
+            showToast(`Failed to start load job: ${(await response.text()).trim()}`, 'error');
+        }
+    } catch (error) {
+        console.error('Error starting load job:', error);
+        showToast('Error starting load job', 'error');
+    }
+}
+
+async function stopLoadJob(id) {
+    try {
+        const response = await apiFetch(`/api/loadgen/${encodeURIComponent(id)}`, { method: 'DELETE' });
+        if (response.ok) {
+            loadLoadJobs();
+        } else {
+            showToast('Failed to stop load job', 'error');
+        }
+    } catch (error) {
+        console.error('Error stopping load job:', error);
+        showToast('Error stopping load job', 'error');
+    }
+}
+
+// Replay Sessions
+
+// replaySessions holds the sessions last listed, by ID, so progress events
+// can update one row; replayStreams the sessions being watched.
+const replaySessions = new Map();
+const replayStreams = new Map();
+
+// loadReplays shows the project's replay sessions and watches the running
+// ones; the section stays hidden when the server does not offer replays.
+async function loadReplays() {
+    const section = document.getElementById('replaysSection');
+    if (!section) return;
+    try {
+        const response = await apiFetch('/api/replays', { headers: { 'Accept': 'application/json' } });
+        if (!response.ok) {
+            section.hidden = true;
+            return;
+        }
+        const sessions = await response.json();
+        section.hidden = false;
+        replaySessions.clear();
+        sessions.forEach(session => {
+            replaySessions.set(session.id, session);
+            if (session.state === 'running' || session.state === 'paused') watchReplay(session.id);
+        });
+        // Streams of another project's sessions end on a project switch.
+        replayStreams.forEach((controller, id) => {
+            if (!replaySessions.has(id)) controller.abort();
+        });
+        renderReplays();
+    } catch (error) {
+        console.error('Error loading replay sessions:', error);
+    }
+}
+
+// watchReplay follows a session's progress events until it ends. It reads
+// the stream through apiFetch rather than EventSource so the API token is
+// sent.
+async function watchReplay(id) {
+    if (replayStreams.has(id)) return;
+    const controller = new AbortController();
+    replayStreams.set(id, controller);
+    try {
+        const response = await apiFetch(`/api/replays/${encodeURIComponent(id)}/events`, { signal: controller.signal });
+        if (!response.ok) return;
+        const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+        let buffer = '';
+        for (;;) {
+            const { value, done } = await reader.read();
+            if (done) break;
+            buffer += value;
+            const events = buffer.split('\n\n');
+            buffer = events.pop();
+            events.forEach(event => {
+                const data = event.split('\n').find(line => line.startsWith('data: '));
+                if (!data) return;
+                const session = JSON.parse(data.slice(6));
+                replaySessions.set(session.id, session);
+                renderReplays();
+                if (session.state === 'completed' || session.state === 'cancelled') loadMessages();
+            });
+        }
+    } catch (error) {
+        if (error.name !== 'AbortError') console.error('Error watching replay session:', error);
+    } finally {
+        replayStreams.delete(id);
+    }
+}
+
+function renderReplays() {
+    const sessions = [...replaySessions.values()];
+    document.getElementById('replaysBadge').textContent = sessions.filter(s => s.state === 'running' || s.state === 'paused').length;
+    const body = document.getElementById('replaySessions');
+    if (sessions.length === 0) {
+        body.innerHTML = '<tr><td colspan="7" class="replays-empty">No replay sessions</td></tr>';
+        return;
+    }
+    body.innerHTML = sessions.reverse().map(s => {
+        const id = escapeHtml(s.id);
+        const done = s.published + s.failed;
+        let controls = '';
+        if (s.state === 'running') {
+            controls = `<button class="secondary replay-control" onclick="replayAction('${id}', 'pause')" aria-label="Pause replay ${id}">❚❚</button>`;
+        } else if (s.state === 'paused') {
+            controls = `<button class="secondary replay-control" onclick="replayAction('${id}', 'resume')" aria-label="Resume replay ${id}">▶</button>`;
+        }
+        if (s.state === 'running' || s.state === 'paused') {
+            controls += ` <button class="outline contrast replay-control" onclick="cancelReplay('${id}')" aria-label="Cancel replay ${id}">■</button>`;
+        }
+        return `
+            <tr>
+                <td>${escapeHtml(s.source)}${s.topic ? ` → <code>${escapeHtml(s.topic)}</code>` : ''}</td>
+                <td><code>${escapeHtml(s.state)}</code></td>
+                <td><progress value="${done}" max="${s.total}"></progress> ${done} / ${s.total}</td>
+                <td>${s.failed}</td>
+                <td>${s.speed}×</td>
+                <td>${s.elapsed_seconds.toFixed(1)}s / ${s.expected_seconds.toFixed(1)}s</td>
+                <td>${controls}</td>
+            </tr>`;
+    }).join('');
+}
+
+function showReplayModal() {
+    const term = document.getElementById('searchInput').value.trim();
+    const topic = document.getElementById('topicFilter').value;
+    const pinned = document.getElementById('pinnedFilter').checked;
+    const filter = [topic && `topic ${topic}`, term && `"${term}"`, pinned && 'pinned'].filter(Boolean).join(', ');
+    document.getElementById('replayFilter').textContent = `Current search: ${filter || 'all messages'}`;
+    document.getElementById('replayFile').value = '';
+    updateReplaySource();
+    openModal('replayModal');
+}
+
+function updateReplaySource() {
+    document.getElementById('replayFileGroup').hidden = document.getElementById('replaySource').value !== 'file';
+}
+
+// startReplay starts a session replaying the messages matching the current
+// search, or an uploaded fixture, keeping their original gaps.
+async function startReplay() {
+    const params = new URLSearchParams({ speed: document.getElementById('replaySpeed').value || '1' });
+    const target = document.getElementById('replayTopic').value.trim();
+    if (target) params.set('target_topic', target);
+    const options = { method: 'POST' };
+    if (document.getElementById('replaySource').value === 'file') {
+        const file = document.getElementById('replayFile').files[0];
+        if (!file) {
+            showToast('Choose a file to replay', 'error');
+            return;
+        }
+        options.body = new FormData();
+        options.body.append('file', file);
+    } else {
+        setSearchParams(params);
+    }
+
+    const button = document.getElementById('replayButton');
+    button.disabled = true;
+    try {
+        const response = await apiFetch(`/api/replays?${params}`, options);
+        if (!response.ok) {
+            showToast(`Failed to start replay: ${(await response.text()).trim()}`, 'error');
+            return;
+        }
+        const session = await response.json();
+        showToast(`Replaying ${session.total} messages`, 'success');
+        closeModal('replayModal');
+        replaySessions.set(session.id, session);
+        renderReplays();
+        watchReplay(session.id);
+    } catch (error) {
+        console.error('Error starting replay:', error);
+        showToast('Error starting replay', 'error');
+    } finally {
+        button.disabled = false;
+    }
+}
+
+async function replayAction(id, action) {
+    try {
+        const response = await apiFetch(`/api/replays/${encodeURIComponent(id)}/${action}`, { method: 'POST' });
+        if (!response.ok) {
+            showToast(`Failed to ${action} replay: ${(await response.text()).trim()}`, 'error');
+        }
+        loadReplays();
+    } catch (error) {
+        console.error('Error updating replay session:', error);
+        showToast('Error updating replay session', 'error');
+    }
+}
+
+async function cancelReplay(id) {
+    try {
+        const response = await apiFetch(`/api/replays/${encodeURIComponent(id)}`, { method: 'DELETE' });
+        if (!response.ok) {
+            showToast('Failed to cancel replay', 'error');
+        }
+        loadReplays();
+    } catch (error) {
+        console.error('Error cancelling replay session:', error);
+        showToast('Error cancelling replay session', 'error');
+    }
+}
+
+// Templates
+
+// loadTemplates lists the saved publish templates; the section stays hidden
+// when the server does not offer templates.
+async function loadTemplates() {
+    const section = document.getElementById('templatesSection');
+    if (!section) return;
+    try {
+        const response = await apiFetch('/api/templates', { headers: { 'Accept': 'application/json' } });
+        if (!response.ok) {
+            section.hidden = true;
+            document.getElementById('saveTemplateButton').hidden = true;
+            return;
+        }
+        const templates = await response.json();
+        section.hidden = false;
+        document.getElementById('saveTemplateButton').hidden = false;
+        renderTemplates(templates);
+    } catch (error) {
+        console.error('Error loading templates:', error);
+    }
+}
+
+function renderTemplates(templates) {
+    document.getElementById('templatesBadge').textContent = templates.length;
+    const body = document.getElementById('templatesList');
+    if (templates.length === 0) {
+        body.innerHTML = '<tr><td colspan="4" class="templates-empty">No templates. Save one from the publish dialog.</td></tr>';
+        return;
+    }
+    body.innerHTML = templates.map(t => {
+        const name = escapeHtml(t.name);
+        return `
+            <tr>
+                <td>${name}</td>
+                <td>${escapeHtml(t.topic)}</td>
+                <td><code title="${escapeHtml(t.data)}">${escapeHtml(t.data)}</code></td>
+                <td class="templates-actions">
+                    <button onclick="publishTemplate('${name}')" aria-label="Publish template ${name}">▶ Publish</button>
+                    <button class="outline contrast" onclick="deleteTemplate('${name}')" aria-label="Delete template ${name}">✕</button>
+                </td>
+            </tr>`;
+    }).join('');
+}
+
+// publishTemplate renders a template on the server and publishes it to the
+// template's topic in the current project.
+async function publishTemplate(name) {
+    try {
+        const response = await apiFetch('/api/publish', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ template: name })
+        });
+        if (response.ok) {
+            const result = await response.json();
+            showToast(`Published ${name} (${result.messageId})`, 'success');
+            loadMessages();
+        } else {
+            showToast(`Failed to publish ${name}: ${(await response.text()).trim()}`, 'error');
+        }
+    } catch (error) {
+        console.error('Error publishing template:', error);
+        showToast('Error publishing template', 'error');
+    }
+}
+
+// saveTemplate saves the publish dialog's topic, data and attributes as a
+// template, replacing any template with the same name.
+async function saveTemplate() {
+    const data = document.getElementById('publishData').value;
+    if (!data) {
+        showToast('Message data is required', 'error');
+        return;
+    }
+    const template = { topic: document.getElementById('publishTopic').value, data: data };
+    const attributesText = document.getElementById('publishAttributes').value.trim();
+    if (attributesText) {
+        try {
+            template.attributes = JSON.parse(attributesText);
+        } catch {
+            showToast('Invalid JSON in attributes', 'error');
+            return;
+        }
+    }
+    const name = prompt('Template name:');
+    if (!name) return;
+
+    try {
+        const response = await apiFetch(`/api/templates/${encodeURIComponent(name)}`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(template)
+        });
+        if (response.ok) {
+            showToast(`Template ${name} saved`, 'success');
+            loadTemplates();
+        } else {
+            showToast(`Failed to save template: ${(await response.text()).trim()}`, 'error');
+        }
+    } catch (error) {
+        console.error('Error saving template:', error);
+        showToast('Error saving template', 'error');
+    }
+}
+
+async function deleteTemplate(name) {
+    try {
+        const response = await apiFetch(`/api/templates/${encodeURIComponent(name)}`, { method: 'DELETE' });
+        if (response.ok) {
+            loadTemplates();
+        } else {
+            showToast('Failed to delete template', 'error');
+        }
+    } catch (error) {
+        console.error('Error deleting template:', error);
+        showToast('Error deleting template', 'error');
+    }
+}
+
 // Message Details
 function showMessageDetails(messageId) {
     const msg = state.messages.find(m => m.id === messageId);
@@ -606,8 +1470,9 @@ function showMessageDetails(messageId) {
             <div class="detail-value">${escapeHtml(msg.topic)}</div>
         </div>
         <div class="detail-row">
-            <div class="detail-label">Data</div>
-            <div class="detail-value">${escapeHtml(formatPayload(msg.data))}</div>
+            <div class="detail-label">Data${msg.truncated ? ` (truncated, ${formatBytes(msg.size)} in full)` : ''}</div>
+            <div class="detail-value" id="messageDataValue">${escapeHtml(formatPayload(msg.data))}</div>
+            ${msg.truncated ? '<button type="button" class="btn btn-secondary" id="loadFullPayloadButton">Load Full Payload</button>' : ''}
         </div>
         <div class="detail-row">
             <div class="detail-label">Publish Time</div>
@@ -628,15 +1493,87 @@ function showMessageDetails(messageId) {
                 `).join('') || '<div class="detail-value">No attributes</div>'}
             </div>
         </div>
+        <div class="detail-row">
+            <label class="detail-label" for="messageTags">Tags (comma-separated)</label>
+            <input type="text" id="messageTags" class="form-control" value="${escapeHtml((msg.tags || []).join(', '))}">
+        </div>
+        <div class="detail-row">
+            <label class="detail-label" for="messageNote">Note</label>
+            <textarea id="messageNote" class="form-control" rows="2">${escapeHtml(msg.note || '')}</textarea>
+        </div>
+        <div class="detail-row">
+            <div class="detail-label">Lifecycle</div>
+            <div id="messageTimeline" class="timeline">
+                ${renderTimeline(msg.lifecycle)}
+            </div>
+        </div>
     `;
     
+    document.getElementById('loadFullPayloadButton')?.addEventListener('click', loadFullPayload);
     openModal('messageModal');
+    loadMessageTimeline(messageId);
+}
+
+// loadMessageTimeline refreshes the lifecycle section from the per-message
+// endpoint, which reflects deliveries/acks recorded since the list was polled.
+async function loadMessageTimeline(messageId) {
+    try {
+        const response = await apiFetch(`/api/messages/${encodeURIComponent(messageId)}`, {
+            headers: { 'Accept': 'application/json' }
+        });
+        if (!response.ok) return;
+
+        const msg = await response.json();
+        const timeline = document.getElementById('messageTimeline');
+        if (timeline && state.currentMessageId === messageId) {
+            timeline.innerHTML = renderTimeline(msg.lifecycle);
+        }
+    } catch (error) {
+        console.error('❌ Error loading message lifecycle:', error);
+    }
+}
+
+const timelineLabels = {
+    published: '📤 Published',
+    delivered: '📥 Delivered',
+    acked: '✅ Acked',
+    nacked: '↩️ Nacked',
+    expired: '⌛ Ack deadline expired',
+    dead_lettered: '☠️ Dead-lettered'
+};
+
+// renderTimeline renders lifecycle events oldest-first with a summary line.
+function renderTimeline(lifecycle) {
+    const events = (lifecycle && lifecycle.events) || [];
+    if (events.length === 0) {
+        return '<div class="detail-value">No lifecycle events recorded</div>';
+    }
+
+    const subs = lifecycle.subscriptions || [];
+    const summary = `
+        <div class="timeline-summary">
+            ${lifecycle.delivery_attempts || 0} delivery attempt(s)
+            ${subs.length ? 'to ' + subs.map(escapeHtml).join(', ') : ''}
+            ${lifecycle.dead_lettered ? '<span class="timeline-flag">dead-lettered</span>' : ''}
+        </div>
+    `;
+
+    const items = events.map(ev => `
+        <li class="timeline-event timeline-${escapeHtml(ev.type)}">
+            <span class="timeline-type">${escapeHtml(timelineLabels[ev.type] || ev.type)}</span>
+            ${ev.subscription ? `<span class="timeline-sub">${escapeHtml(ev.subscription)}</span>` : ''}
+            ${ev.attempt ? `<span class="timeline-attempt">attempt ${escapeHtml(ev.attempt)}</span>` : ''}
+            <time class="timeline-time">${formatTime(new Date(ev.time))}</time>
+        </li>
+    `).join('');
+
+    return summary + `<ol class="timeline-events">${items}</ol>`;
 }
 
 // Replay Message
 async function replayMessage(messageId) {
     try {
-        const response = await fetch(`/api/replay?id=${encodeURIComponent(messageId)}`, {
+        const response = await apiFetch(`/api/replay?id=${encodeURIComponent(messageId)}`, {
             method: 'POST'
         });
 
@@ -652,6 +1589,15 @@ async function replayMessage(messageId) {
     }
 }
 
+async function saveCurrentAnnotations() {
+    if (!state.currentMessageId) return;
+    const tags = document.getElementById('messageTags').value.split(',').map(tag => tag.trim()).filter(Boolean);
+    const note = document.getElementById('messageNote').value;
+    if (await annotateMessage(state.currentMessageId, { tags, note })) {
+        showToast('Message updated', 'success');
+    }
+}
+
 function replayCurrentMessage() {
     if (state.currentMessageId) {
         replayMessage(state.currentMessageId);
@@ -659,6 +1605,119 @@ function replayCurrentMessage() {
     }
 }
 
+// Message Diff
+function showDiffModal(a = '', b = '') {
+    document.getElementById('diffA').value = a;
+    document.getElementById('diffB').value = b;
+    document.getElementById('diffResult').innerHTML = '';
+    openModal('diffModal');
+}
+
+// compareCurrentMessage opens the diff view for the message being viewed,
+// against its original when it is a replay.
+function compareCurrentMessage() {
+    const msg = state.messages.find(m => m.id === state.currentMessageId);
+    if (!msg) return;
+    closeModal('messageModal');
+    showDiffModal(msg.replay_of || '', msg.id);
+    if (msg.replay_of) diffMessages();
+}
+
+async function diffMessages() {
+    const a = document.getElementById('diffA').value.trim();
+    const b = document.getElementById('diffB').value.trim();
+    if (!b) {
+        showToast('Enter message B', 'error');
+        return;
+    }
+    const params = new URLSearchParams({ b });
+    if (a) params.set('a', a);
+    try {
+        const response = await apiFetch(`/api/messages/diff?${params}`);
+        if (!response.ok) {
+            showToast(await response.text(), 'error');
+            return;
+        }
+        document.getElementById('diffResult').innerHTML = renderMessageDiff(await response.json());
+    } catch (error) {
+        console.error('Error comparing messages:', error);
+        showToast('Error comparing messages', 'error');
+    }
+}
+
+async function diffHistories() {
+    const fileA = document.getElementById('diffFileA').files[0];
+    const fileB = document.getElementById('diffFileB').files[0];
+    if (!fileA || !fileB) {
+        showToast('Choose both histories', 'error');
+        return;
+    }
+    const form = new FormData();
+    form.append('a', fileA);
+    form.append('b', fileB);
+    const key = document.getElementById('diffKey').value.trim();
+    const query = key ? `?key=${encodeURIComponent(key)}` : '';
+    try {
+        const response = await apiFetch(`/api/messages/diff${query}`, { method: 'POST', body: form });
+        if (!response.ok) {
+            showToast(await response.text(), 'error');
+            return;
+        }
+        document.getElementById('diffResult').innerHTML = renderHistoryDiff(await response.json());
+    } catch (error) {
+        console.error('Error comparing histories:', error);
+        showToast('Error comparing histories', 'error');
+    }
+}
+
+function formatDiffValue(value) {
+    if (value === undefined) return '';
+    return escapeHtml(typeof value === 'string' ? value : JSON.stringify(value));
+}
+
+// renderChanges lists changes as path, kind and before/after values.
+function renderChanges(changes, emptyText) {
+    if (!changes || changes.length === 0) {
+        return `<div class="diff-empty">${escapeHtml(emptyText)}</div>`;
+    }
+    return `<ul class="diff-changes">${changes.map(c => `
+        <li class="diff-change diff-${escapeHtml(c.kind)}">
+            <code class="diff-path">${escapeHtml(c.path || '(payload)')}</code>
+            <span class="diff-kind">${escapeHtml(c.kind)}</span>
+            ${c.kind !== 'added' ? `<del>${formatDiffValue(c.before)}</del>` : ''}
+            ${c.kind !== 'removed' ? `<ins>${formatDiffValue(c.after)}</ins>` : ''}
+        </li>
+    `).join('')}</ul>`;
+}
+
+function renderMessageDiff(d) {
+    const heading = `<div class="diff-summary">${escapeHtml(d.a)} → ${escapeHtml(d.b)}:
+        ${d.identical ? 'identical' : 'different'}</div>`;
+    const topic = d.topic ? `<h4>Topic</h4>${renderChanges([d.topic])}` : '';
+    return heading + topic +
+        `<h4>Data (${escapeHtml(d.data.format)})</h4>${renderChanges(d.data.changes, 'No changes')}` +
+        `<h4>Attributes</h4>${renderChanges(d.attributes, 'No changes')}`;
+}
+
+function renderHistoryDiff(h) {
+    const heading = `<div class="diff-summary">${h.identical ? 'Histories are identical' : 'Histories differ'}
+        ${h.key ? `(paired by ${escapeHtml(h.key)})` : '(paired by position)'}</div>`;
+    const topics = h.topics.map(t => `
+        <section class="diff-topic">
+            <h4>${escapeHtml(t.topic)} <small>${t.count_a} → ${t.count_b} messages, ${t.unchanged} unchanged</small></h4>
+            ${t.changed.map(d => `
+                <details>
+                    <summary>${escapeHtml(d.a)} → ${escapeHtml(d.b)}</summary>
+                    ${renderMessageDiff(d)}
+                </details>
+            `).join('')}
+            ${t.only_in_a.length ? `<div class="diff-only">Only before: ${t.only_in_a.map(escapeHtml).join(', ')}</div>` : ''}
+            ${t.only_in_b.length ? `<div class="diff-only">Only after: ${t.only_in_b.map(escapeHtml).join(', ')}</div>` : ''}
+        </section>
+    `).join('');
+    return heading + topics;
+}
+
 // Clear Messages
 function clearMessages() {
     if (confirm('Are you sure you want to clear all messages from the display? This will not delete messages from Pub/Sub.')) {
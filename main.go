@@ -3,18 +3,33 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
-	"sync"
+	"path"
 	"syscall"
 	"time"
 
 	gcppubsub "cloud.google.com/go/pubsub/v2"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/auth"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/chaos"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/cli"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/clock"
 	"github.com/dipjyotimetia/pubsub-emulator/internal/config"
 	"github.com/dipjyotimetia/pubsub-emulator/internal/dashboard"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/expect"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/health"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/loadgen"
 	"github.com/dipjyotimetia/pubsub-emulator/internal/pubsub"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/reload"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/replay"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/retention"
 	"github.com/dipjyotimetia/pubsub-emulator/internal/server"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/supervisor"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/templates"
 	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 const (
@@ -28,6 +43,20 @@ const (
 )
 
 func main() {
+	// Subcommands script a running instance; no command (or "serve") runs one.
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		app := &cli.App{Stdout: os.Stdout, Stderr: os.Stderr}
+		code := app.Run(ctx, os.Args[1:])
+		stop()
+		os.Exit(code)
+	}
+	serve()
+}
+
+// serve sets up topics, subscriptions and receivers from the environment and
+// runs the dashboard until interrupted.
+func serve() {
 	// Initialize logger
 	log := logger.New()
 	log.Info("Starting Pub/Sub Emulator with refactored architecture")
@@ -41,6 +70,38 @@ func main() {
 	log.Info("Configuration loaded: Project=%s, Topics=%v, Subscriptions=%v",
 		cfg.ProjectID, cfg.TopicIDs, cfg.SubscriptionIDs)
 
+	// With PUBSUB_SUPERVISE the emulator is a child process listening on
+	// PUBSUB_PORT; point the client at it unless a host was set explicitly.
+	// A restarted emulator comes back empty, so the topology is recreated
+	// through the reloader once startup has built it.
+	var sup *supervisor.Supervisor
+	var reloader *reload.Reloader
+	reloaderReady := make(chan struct{})
+	if cfg.SuperviseEmulator {
+		emulatorAddr := net.JoinHostPort("localhost", cfg.PubSubPort)
+		if os.Getenv("PUBSUB_EMULATOR_HOST") == "" {
+			_ = os.Setenv("PUBSUB_EMULATOR_HOST", emulatorAddr)
+		}
+		sup = supervisor.New(supervisor.Options{
+			Command:        cfg.EmulatorCommand,
+			Addr:           emulatorAddr,
+			StartupTimeout: cfg.EmulatorStartupTimeout,
+			OnRestart: func(ctx context.Context) {
+				select {
+				case <-reloaderReady:
+				default:
+					return
+				}
+				if _, err := reloader.Reload(ctx); err != nil {
+					log.Error("Failed to recreate topics and subscriptions after emulator restart: %v", err)
+					return
+				}
+				log.Info("Recreated topics and subscriptions after emulator restart")
+			},
+			Logger: log,
+		})
+	}
+
 	// Context cancelled on SIGINT/SIGTERM; drives both the subscribers and the
 	// HTTP server so shutdown propagates everywhere.
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -56,16 +117,105 @@ func main() {
 	// Get underlying GCP client for dashboard
 	pubsubClient := psClient.GetClient()
 
-	// Create topics and subscriptions
-	if err := setupTopicsAndSubscriptions(ctx, psClient, cfg, log); err != nil {
-		log.Fatal("Failed to setup topics and subscriptions: %v", err)
-	}
-
 	// Initialize dashboard
 	dash := dashboard.New(pubsubClient, cfg.ProjectID, log)
+	dash.SetSchemaClient(psClient.SchemaClient())
+	dash.SetProjects(cfg.ProjectIDs)
+
+	// The message history is bounded by PUBSUB_HISTORY_*; payloads over
+	// the size limit wait on disk until shown, replayed or exported.
+	spill, err := retention.NewSpill(cfg.HistorySpillDir)
+	if err != nil {
+		log.Fatal("Failed to set up message history: %v", err)
+	}
+	defer func() { _ = spill.Close() }()
+	dash.SetRetention(cfg.History, spill)
+	go dash.ExpireHistory(ctx)
+
+	// Readiness starts failing until setup below completes; resource and
+	// receiver checks are added once the reloader tracks the topology.
+	startup := &health.Gate{}
+	readiness := health.New(health.DefaultTimeout)
+	readiness.Add("startup", startup.CheckFunc("startup in progress"))
+	readiness.Add("emulator", psClient.Ping)
+	dash.SetReadiness(readiness)
+
+	// In test mode recorded times follow a manual clock and message IDs are
+	// sequential, both controlled through /api/clock.
+	var test *testMode
+	if cfg.TestMode {
+		manual := clock.NewManual(cfg.TestStartTime)
+		test = &testMode{clock: manual, ids: clock.NewIDs(cfg.TestSeed, manual)}
+		dash.SetTestMode(test.clock, test.ids)
+		log.Info("Test mode: clock starts at %s, message IDs seeded with %d",
+			cfg.TestStartTime.Format(time.RFC3339), cfg.TestSeed)
+	}
 
-	// Initialize publisher
+	// Clients pointed at PUBSUB_CHAOS_PORT go through a proxy that injects
+	// the faults managed on /api/chaos.
+	if cfg.ChaosPort != "" {
+		engine := chaos.NewEngine()
+		if err := startChaosProxy(ctx, cfg, engine, dash, test, log); err != nil {
+			log.Fatal("Failed to start chaos proxy: %v", err)
+		}
+		dash.SetChaos(engine)
+	}
+
+	// Initialize publisher; it also backs the load jobs on /api/loadgen,
+	// which stop when ctx is cancelled.
 	pub := pubsub.NewPublisher(psClient, log)
+	dash.SetLoadGen(loadgen.NewManager(ctx, func(topic string) loadgen.Publisher {
+		return pub.Topic(topic)
+	}))
+
+	// Replay sessions on /api/replays republish recorded or uploaded
+	// messages with their original timing until ctx is cancelled.
+	dash.SetReplays(replay.NewManager(ctx))
+
+	// Publish templates are managed on /api/templates, preloaded from
+	// PUBSUB_TEMPLATES_DIR.
+	store := templates.NewStore()
+	if cfg.TemplatesDir != "" {
+		n, err := store.LoadDir(cfg.TemplatesDir)
+		if err != nil {
+			log.Fatal("Failed to load templates: %v", err)
+		}
+		log.Info("Loaded %d templates from %s", n, cfg.TemplatesDir)
+	}
+	dash.SetTemplates(store)
+
+	// Integration tests register expectations on /api/expectations and wait
+	// for the messages the dashboard records to satisfy them.
+	dash.SetExpectations(expect.NewRegistry())
+
+	// Serve the dashboard while setting up so /livez answers and /readyz
+	// reports progress during startup.
+	srv := newDashboardServer(cfg, dash, log)
+
+	// Bind before serving so the log shows the real address (resolved
+	// ephemeral port or socket path), not just the configured one.
+	if err := srv.Listen(); err != nil {
+		log.Fatal("Failed to bind dashboard: %v", err)
+	}
+	log.Info("Dashboard will be available at %s", srv.URL())
+
+	// Returns when ctx is cancelled (signal) or the server fails.
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- srv.Start(ctx) }()
+
+	// Launch the supervised emulator and wait for its port before setup;
+	// it is restarted on crash and stopped when ctx is cancelled.
+	if sup != nil {
+		readiness.Add("emulator-process", sup.Check)
+		if err := sup.Start(ctx); err != nil {
+			log.Fatal("Failed to start emulator: %v", err)
+		}
+	}
+
+	// Create topics and subscriptions
+	if err := setupTopicsAndSubscriptions(ctx, psClient, cfg, log); err != nil {
+		log.Fatal("Failed to setup topics and subscriptions: %v", err)
+	}
 
 	// Publish initial messages to topics
 	if err := publishInitialMessages(ctx, pub, cfg, dash, log); err != nil {
@@ -74,38 +224,164 @@ func main() {
 
 	// Initialize subscriber and start receiving messages from subscriptions
 	sub := pubsub.NewSubscriber(psClient, log)
-	wg := startSubscriptions(ctx, sub, cfg, dash, log)
-
-	// Initialize and start HTTP server with graceful shutdown
-	srv := server.New(&server.Config{
-		Port:      cfg.DashboardPort,
-		Dashboard: dash,
-		Logger:    log,
+	receivers := startSubscriptions(ctx, sub, cfg, dash, log)
+
+	// Reload topology on SIGHUP or when PUBSUB_CONFIG_FILE changes
+	reloader = reload.New(reload.Options{
+		Client:             psClient,
+		Receivers:          receivers,
+		Load:               config.LoadFromEnv,
+		Initial:            cfg,
+		AckDeadlineSeconds: startupAckDeadlineSeconds,
+		Logger:             log,
 	})
+	close(reloaderReady)
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+	go reloader.Watch(ctx, sighup)
 
-	log.Info("Dashboard will be available at http://localhost:%s", cfg.DashboardPort)
+	readiness.Add("resources", reloader.CheckResources)
+	readiness.Add("receivers", reloader.CheckReceivers)
+	startup.Open()
+	log.Info("Startup complete")
 
-	// Blocks until ctx is cancelled (signal) or the server fails.
-	serverErr := srv.Start(ctx)
+	err = <-serverErr
 
 	// Cancel the context explicitly so subscribers also stop on the
 	// server-error path (where no signal cancelled it), then drain receivers.
 	stop()
-	waitForSubscribers(wg, log)
+	waitForSubscribers(receivers, log)
+	if sup != nil {
+		sup.Wait()
+	}
 
-	if serverErr != nil {
-		log.Error("Server error: %v", serverErr)
+	if err != nil {
+		log.Error("Server error: %v", err)
 	}
 
 	log.Info("Application shutdown complete")
 }
 
+// newDashboardServer builds the dashboard HTTP server with the configured
+// auth, CORS, CSRF and TLS settings.
+func newDashboardServer(cfg *config.Config, dash *dashboard.Dashboard, log *logger.Logger) *server.Server {
+	// Build the optional dashboard authenticator (nil when auth is disabled)
+	authenticator, err := auth.New(auth.Options{
+		Mode:      cfg.AuthMode,
+		Tokens:    cfg.AuthTokens,
+		Users:     cfg.AuthUsers,
+		JWKSFile:  cfg.AuthJWKSFile,
+		Issuer:    cfg.AuthJWTIssuer,
+		Audience:  cfg.AuthJWTAudience,
+		RoleClaim: cfg.AuthRoleClaim,
+	})
+	if err != nil {
+		log.Fatal("Failed to configure dashboard auth: %v", err)
+	}
+	if authenticator != nil {
+		log.Info("Dashboard auth enabled: mode=%s", cfg.AuthMode)
+	}
+
+	// CORS policy: unset methods/headers keep the dashboard defaults
+	cors := dashboard.DefaultCORSConfig()
+	cors.AllowedOrigins = cfg.CORSAllowedOrigins
+	cors.AllowCredentials = cfg.CORSAllowCredentials
+	cors.MaxAge = cfg.CORSMaxAge
+	if len(cfg.CORSAllowedMethods) > 0 {
+		cors.AllowedMethods = cfg.CORSAllowedMethods
+	}
+	if len(cfg.CORSAllowedHeaders) > 0 {
+		cors.AllowedHeaders = cfg.CORSAllowedHeaders
+	}
+
+	return server.New(&server.Config{
+		Port:       cfg.DashboardPort,
+		Addr:       cfg.DashboardAddr,
+		SocketMode: cfg.DashboardSocketMode,
+		Dashboard:  dash,
+		Logger:     log,
+		Auth:       authenticator,
+		CORS:       &cors,
+		CSRF: dashboard.CSRFConfig{
+			Mode:           cfg.CSRFMode,
+			TrustedOrigins: cfg.CORSAllowedOrigins,
+		},
+		TLS: server.TLSConfig{
+			CertFile:     cfg.TLSCertFile,
+			KeyFile:      cfg.TLSKeyFile,
+			ClientCAFile: cfg.TLSClientCAFile,
+			SelfSigned:   cfg.TLSSelfSigned,
+		},
+	})
+}
+
+// testMode is the clock and message ID source shared in test mode.
+type testMode struct {
+	clock *clock.Manual
+	ids   *clock.IDs
+}
+
+// lifecycleObserver records the nacks and expired ack deadlines of clients
+// connected through the chaos proxy in the dashboard's message lifecycle.
+type lifecycleObserver struct {
+	dash *dashboard.Dashboard
+}
+
+func (o lifecycleObserver) Nacked(messageID, subscription string) {
+	o.dash.RecordNack(messageID, path.Base(subscription))
+}
+
+func (o lifecycleObserver) Expired(messageID, subscription string) {
+	o.dash.RecordExpiry(messageID, path.Base(subscription))
+}
+
+// startChaosProxy serves the fault injection proxy on PUBSUB_CHAOS_PORT,
+// relaying to the emulator, until ctx is cancelled. Nacks and expired ack
+// deadlines of its clients are recorded in the dashboard. In test mode the
+// proxy also hands clients deterministic message IDs and runs ack deadlines
+// on the manual clock.
+func startChaosProxy(ctx context.Context, cfg *config.Config, engine *chaos.Engine, dash *dashboard.Dashboard, test *testMode, log *logger.Logger) error {
+	target := os.Getenv("PUBSUB_EMULATOR_HOST")
+	if target == "" {
+		target = net.JoinHostPort("localhost", cfg.PubSubPort)
+	}
+	backend, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", ":"+cfg.ChaosPort)
+	if err != nil {
+		_ = backend.Close()
+		return err
+	}
+
+	proxy := chaos.NewProxy(engine, backend, log)
+	if test != nil {
+		engine.SetClock(test.clock)
+		proxy.SetTestMode(test.clock, test.ids)
+	}
+	proxy.SetObserver(lifecycleObserver{dash})
+	go func() {
+		if err := proxy.Serve(ln); err != nil {
+			log.Error("Chaos proxy stopped: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		proxy.Stop()
+		_ = backend.Close()
+	}()
+	log.Info("Chaos proxy listening on %s, relaying to %s", ln.Addr(), target)
+	return nil
+}
+
 // waitForSubscribers waits for all subscription receivers to stop, bounded by
 // subscriberDrainTimeout so shutdown can never hang indefinitely.
-func waitForSubscribers(wg *sync.WaitGroup, log *logger.Logger) {
+func waitForSubscribers(receivers *pubsub.Receivers, log *logger.Logger) {
 	done := make(chan struct{})
 	go func() {
-		wg.Wait()
+		receivers.Wait()
 		close(done)
 	}()
 
@@ -183,37 +459,34 @@ func publishInitialMessages(ctx context.Context, pub *pubsub.Publisher, cfg *con
 }
 
 // startSubscriptions starts message receivers for all subscriptions and returns
-// a WaitGroup that completes once the receivers stop.
-func startSubscriptions(ctx context.Context, sub *pubsub.Subscriber, cfg *config.Config, dash *dashboard.Dashboard, log *logger.Logger) *sync.WaitGroup {
+// them so config reloads can add or stop receivers and shutdown can drain them.
+func startSubscriptions(ctx context.Context, sub *pubsub.Subscriber, cfg *config.Config, dash *dashboard.Dashboard, log *logger.Logger) *pubsub.Receivers {
 	log.Info("Starting message receivers for %d subscriptions", len(cfg.SubscriptionIDs))
 
-	// Create subscription to topic mapping
-	subToTopicMap := make(map[string]string)
-	for i := range cfg.SubscriptionIDs {
-		if i < len(cfg.TopicIDs) {
-			subToTopicMap[cfg.SubscriptionIDs[i]] = cfg.TopicIDs[i]
-		}
-	}
-
-	// Create handler function that adds messages to dashboard
+	// Record each delivery against the subscription that received it, and the
+	// subsequent ack, so the dashboard can show a per-message timeline.
 	handler := func(ctx context.Context, msg *gcppubsub.Message) {
-		// Extract subscription ID from message (if available)
-		// Note: This is a simplified approach. In production, you'd need to
-		// track which subscription received the message
-		topicID := ""
-		if len(cfg.TopicIDs) > 0 {
-			topicID = cfg.TopicIDs[0] // Fallback to first topic
-		}
+		delivery, _ := pubsub.DeliveryFromContext(ctx)
+		topicID := delivery.TopicID
 
 		// Try to get topic from message attributes if available
 		if topic, ok := msg.Attributes["_topic"]; ok {
 			topicID = topic
 		}
 
-		log.Debug("Received message: %s from topic: %s", msg.ID, topicID)
-		dash.AddMessage(msg, topicID)
+		log.Debug("Received message: %s from topic: %s via %s", msg.ID, topicID, delivery.SubscriptionID)
+		dash.RecordDelivery(msg, topicID, delivery.SubscriptionID)
 	}
 
+	sub.OnAck(func(ctx context.Context, msg *gcppubsub.Message) {
+		delivery, _ := pubsub.DeliveryFromContext(ctx)
+		dash.RecordAck(msg.ID, delivery.SubscriptionID)
+	})
+
 	// Subscribe to all subscriptions
-	return sub.SubscribeToAll(ctx, cfg.SubscriptionIDs, cfg.TopicIDs, handler)
+	receivers := sub.NewReceivers(ctx, handler)
+	for i, subID := range cfg.SubscriptionIDs {
+		receivers.Start(subID, cfg.TopicIDs[i])
+	}
+	return receivers
 }
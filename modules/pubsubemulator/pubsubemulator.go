@@ -0,0 +1,217 @@
+// Package pubsubemulator runs the pubsub-emulator image with testcontainers-go.
+//
+// Run starts the container, creates the configured topics and subscriptions
+// and waits until the dashboard's /readyz reports the emulator ready, so
+// tests don't need to sleep:
+//
+//	ctr, err := pubsubemulator.Run(ctx, pubsubemulator.DefaultImage,
+//		pubsubemulator.WithProject("shop"),
+//		pubsubemulator.WithTopics("orders"),
+//		pubsubemulator.WithSubscriptions("orders-sub"),
+//	)
+//	defer testcontainers.TerminateContainer(ctr)
+//	host, _ := ctr.EmulatorHost(ctx)
+//	t.Setenv("PUBSUB_EMULATOR_HOST", host)
+//
+// The package lives in its own module so the emulator itself does not depend
+// on testcontainers-go.
+package pubsubemulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// DefaultImage is the published emulator image.
+	DefaultImage = "dipjyotimetia/pubsub-emulator:latest"
+	// DefaultProject is the project used without WithProject.
+	DefaultProject = "test-project"
+	// EmulatorPort is the container's gRPC port.
+	EmulatorPort = "8085/tcp"
+	// DashboardPort is the container's dashboard port, which serves /readyz.
+	DashboardPort = "8080/tcp"
+	// DefaultStartupTimeout bounds how long Run waits for /readyz.
+	DefaultStartupTimeout = 2 * time.Minute
+
+	// configFilePath is where WithConfigFile copies the file in the container.
+	configFilePath = "/etc/pubsub-emulator/pubsub.env"
+	// readyPollInterval is how often the wait strategy polls /readyz.
+	readyPollInterval = 250 * time.Millisecond
+)
+
+// Container is a running emulator started by Run.
+type Container struct {
+	testcontainers.Container
+	projectID string
+}
+
+// Run creates and starts an emulator container from img. Without options it
+// serves DefaultProject with the image's demo topic and subscription.
+func Run(ctx context.Context, img string, opts ...testcontainers.ContainerCustomizer) (*Container, error) {
+	req, err := newRequest(img, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr, err := testcontainers.GenericContainer(ctx, req)
+	var c *Container
+	if ctr != nil {
+		c = &Container{Container: ctr, projectID: projectOf(req.Env)}
+	}
+	if err != nil {
+		return c, fmt.Errorf("pubsubemulator: start container: %w", err)
+	}
+	return c, nil
+}
+
+// newRequest builds the container request for img with opts applied, and
+// checks the topology before Docker is involved.
+func newRequest(img string, opts ...testcontainers.ContainerCustomizer) (testcontainers.GenericContainerRequest, error) {
+	req := testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        img,
+			ExposedPorts: []string{EmulatorPort, DashboardPort},
+			Env: map[string]string{
+				"PUBSUB_PROJECT": DefaultProject,
+				"PUBSUB_PORT":    portNumber(EmulatorPort),
+				"DASHBOARD_PORT": portNumber(DashboardPort),
+			},
+			WaitingFor: WaitForReady(),
+		},
+		Started: true,
+	}
+	for _, opt := range opts {
+		if err := opt.Customize(&req); err != nil {
+			return req, fmt.Errorf("pubsubemulator: customize: %w", err)
+		}
+	}
+
+	topics, subs := req.Env["PUBSUB_TOPIC"], req.Env["PUBSUB_SUBSCRIPTION"]
+	if (topics == "") != (subs == "") || strings.Count(topics, ",") != strings.Count(subs, ",") {
+		return req, errors.New("pubsubemulator: topics and subscriptions are paired by position and must have the same count")
+	}
+	return req, nil
+}
+
+// WaitForReady waits until the dashboard's /readyz returns 200, which
+// happens once the emulator answers and the configured topology exists.
+func WaitForReady() *wait.HTTPStrategy {
+	return wait.ForHTTP("/readyz").
+		WithPort(DashboardPort).
+		WithStartupTimeout(DefaultStartupTimeout).
+		WithPollInterval(readyPollInterval)
+}
+
+// WithProject sets PUBSUB_PROJECT. Several projects may be given; the first
+// is the default one.
+func WithProject(ids ...string) testcontainers.CustomizeRequestOption {
+	return func(req *testcontainers.GenericContainerRequest) error {
+		if len(ids) == 0 {
+			return errors.New("WithProject needs at least one project")
+		}
+		return testcontainers.WithEnv(map[string]string{"PUBSUB_PROJECT": strings.Join(ids, ",")})(req)
+	}
+}
+
+// WithTopics adds topics to PUBSUB_TOPIC. They pair by position with the
+// subscriptions from WithSubscriptions.
+func WithTopics(ids ...string) testcontainers.CustomizeRequestOption {
+	return appendEnv("PUBSUB_TOPIC", ids)
+}
+
+// WithSubscriptions adds subscriptions to PUBSUB_SUBSCRIPTION. They pair by
+// position with the topics from WithTopics.
+func WithSubscriptions(ids ...string) testcontainers.CustomizeRequestOption {
+	return appendEnv("PUBSUB_SUBSCRIPTION", ids)
+}
+
+// WithConfigFile copies a KEY=VALUE settings file from the host into the
+// container and points PUBSUB_CONFIG_FILE at it. Its settings override the
+// environment, including those set by the other options.
+func WithConfigFile(hostPath string) testcontainers.CustomizeRequestOption {
+	return func(req *testcontainers.GenericContainerRequest) error {
+		if _, err := os.Stat(hostPath); err != nil {
+			return fmt.Errorf("config file: %w", err)
+		}
+		if err := testcontainers.WithFiles(testcontainers.ContainerFile{
+			HostFilePath:      hostPath,
+			ContainerFilePath: configFilePath,
+			FileMode:          0o644,
+		})(req); err != nil {
+			return err
+		}
+		return testcontainers.WithEnv(map[string]string{"PUBSUB_CONFIG_FILE": configFilePath})(req)
+	}
+}
+
+func appendEnv(key string, values []string) testcontainers.CustomizeRequestOption {
+	return func(req *testcontainers.GenericContainerRequest) error {
+		for _, v := range values {
+			if v == "" || strings.Contains(v, ",") {
+				return fmt.Errorf("invalid %s entry %q", key, v)
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		joined := strings.Join(values, ",")
+		if prev := req.Env[key]; prev != "" {
+			joined = prev + "," + joined
+		}
+		return testcontainers.WithEnv(map[string]string{key: joined})(req)
+	}
+}
+
+// EmulatorHost returns the host:port of the emulator's gRPC endpoint, the
+// value for PUBSUB_EMULATOR_HOST.
+func (c *Container) EmulatorHost(ctx context.Context) (string, error) {
+	return c.endpoint(ctx, EmulatorPort)
+}
+
+// DashboardURL returns the base URL of the dashboard, such as
+// http://localhost:32768.
+func (c *Container) DashboardURL(ctx context.Context) (string, error) {
+	addr, err := c.endpoint(ctx, DashboardPort)
+	if err != nil {
+		return "", err
+	}
+	return "http://" + addr, nil
+}
+
+// ProjectID returns the default project the emulator serves.
+func (c *Container) ProjectID() string {
+	return c.projectID
+}
+
+func (c *Container) endpoint(ctx context.Context, port string) (string, error) {
+	host, err := c.Host(ctx)
+	if err != nil {
+		return "", fmt.Errorf("pubsubemulator: host: %w", err)
+	}
+	mapped, err := c.MappedPort(ctx, port)
+	if err != nil {
+		return "", fmt.Errorf("pubsubemulator: mapped port %s: %w", port, err)
+	}
+	return net.JoinHostPort(host, mapped.Port()), nil
+}
+
+// projectOf returns the default project from the container's environment.
+func projectOf(env map[string]string) string {
+	project, _, _ := strings.Cut(env["PUBSUB_PROJECT"], ",")
+	return project
+}
+
+// portNumber strips the protocol from a port such as "8085/tcp".
+func portNumber(port string) string {
+	n, _, _ := strings.Cut(port, "/")
+	return n
+}
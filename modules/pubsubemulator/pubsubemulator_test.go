@@ -0,0 +1,190 @@
+package pubsubemulator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/network"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// fakeContainer stands in for a running emulator container: its ports map to
+// in-process servers, so the module is tested without Docker.
+type fakeContainer struct {
+	testcontainers.Container
+	host  string
+	ports map[string]string
+}
+
+// newFakeContainer maps each container port to the host:port of a server.
+func newFakeContainer(t *testing.T, addrs map[string]string) *fakeContainer {
+	t.Helper()
+	f := &fakeContainer{ports: map[string]string{}}
+	for port, addr := range addrs {
+		host, hostPort, err := net.SplitHostPort(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.host, f.ports[port] = host, hostPort
+	}
+	return f
+}
+
+func (f *fakeContainer) Host(context.Context) (string, error) {
+	return f.host, nil
+}
+
+func (f *fakeContainer) MappedPort(_ context.Context, port string) (network.Port, error) {
+	hostPort, ok := f.ports[port]
+	if !ok {
+		return network.Port{}, fmt.Errorf("port %s is not mapped", port)
+	}
+	n, _ := strconv.ParseUint(hostPort, 10, 16)
+	p, _ := network.PortFrom(uint16(n), network.TCP)
+	return p, nil
+}
+
+func (f *fakeContainer) State(context.Context) (*container.State, error) {
+	return &container.State{Running: true, Status: container.StateRunning}, nil
+}
+
+func TestWaitForReady(t *testing.T) {
+	// /readyz fails until the topology is in place, as it does in the image.
+	var calls atomic.Int32
+	dashboard := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			http.NotFound(w, r)
+			return
+		}
+		if calls.Add(1) < 3 {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer dashboard.Close()
+
+	target := newFakeContainer(t, map[string]string{DashboardPort: dashboard.Listener.Addr().String()})
+	if err := WaitForReady().WaitUntilReady(context.Background(), target); err != nil {
+		t.Fatalf("Expected the wait to succeed once /readyz does, got %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Expected /readyz polled until ready (3 calls), got %d", n)
+	}
+}
+
+func TestWaitForReady_Timeout(t *testing.T) {
+	dashboard := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+	}))
+	defer dashboard.Close()
+
+	target := newFakeContainer(t, map[string]string{DashboardPort: dashboard.Listener.Addr().String()})
+	err := WaitForReady().WithStartupTimeout(600*time.Millisecond).WaitUntilReady(context.Background(), target)
+	if err == nil {
+		t.Fatal("Expected the wait to fail while /readyz reports not ready")
+	}
+}
+
+func TestContainer_Accessors(t *testing.T) {
+	srv := pstest.NewServer()
+	defer func() { _ = srv.Close() }()
+	dashboard := httptest.NewServer(http.NotFoundHandler())
+	defer dashboard.Close()
+
+	ctr := &Container{
+		Container: newFakeContainer(t, map[string]string{EmulatorPort: srv.Addr, DashboardPort: dashboard.Listener.Addr().String()}),
+		projectID: "shop",
+	}
+	ctx := context.Background()
+	host, err := ctr.EmulatorHost(ctx)
+	if err != nil || host != srv.Addr {
+		t.Fatalf("Expected emulator host %s, got %q (%v)", srv.Addr, host, err)
+	}
+	if url, err := ctr.DashboardURL(ctx); err != nil || url != dashboard.URL {
+		t.Errorf("Expected dashboard URL %s, got %q (%v)", dashboard.URL, url, err)
+	}
+
+	// A client configured the usual way reaches the emulator.
+	t.Setenv("PUBSUB_EMULATOR_HOST", host)
+	client, err := pubsub.NewClient(ctx, ctr.ProjectID())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+	topic, err := client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: "projects/shop/topics/orders"})
+	if err != nil || topic.GetName() != "projects/shop/topics/orders" {
+		t.Errorf("Expected to create a topic through the emulator host, got %v (%v)", topic, err)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "pubsub.env")
+	if err := os.WriteFile(cfg, []byte("PUBSUB_TOPIC=audit\nPUBSUB_SUBSCRIPTION=audit-sub\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := newRequest(DefaultImage,
+		WithProject("shop", "billing"),
+		WithTopics("orders", "payments"),
+		WithSubscriptions("orders-sub"),
+		WithSubscriptions("payments-sub"),
+		WithConfigFile(cfg),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"PUBSUB_PROJECT":      "shop,billing",
+		"PUBSUB_TOPIC":        "orders,payments",
+		"PUBSUB_SUBSCRIPTION": "orders-sub,payments-sub",
+		"PUBSUB_PORT":         "8085",
+		"DASHBOARD_PORT":      "8080",
+		"PUBSUB_CONFIG_FILE":  configFilePath,
+	}
+	for k, v := range want {
+		if req.Env[k] != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, req.Env[k])
+		}
+	}
+	if len(req.Files) != 1 || req.Files[0].HostFilePath != cfg || req.Files[0].ContainerFilePath != configFilePath {
+		t.Errorf("Expected the config file copied into the container, got %+v", req.Files)
+	}
+	if len(req.ExposedPorts) != 2 || req.WaitingFor == nil || !req.Started {
+		t.Errorf("Expected both ports exposed and a wait strategy, got %+v", req.ContainerRequest)
+	}
+	if got := projectOf(req.Env); got != "shop" {
+		t.Errorf("Expected default project shop, got %q", got)
+	}
+}
+
+func TestNewRequest_Invalid(t *testing.T) {
+	tests := map[string][]testcontainers.ContainerCustomizer{
+		"unpaired topics": {WithTopics("orders", "payments"), WithSubscriptions("orders-sub")},
+		"topics only":     {WithTopics("orders")},
+		"comma in an ID":  {WithTopics("orders,payments"), WithSubscriptions("a", "b")},
+		"no project":      {WithProject()},
+		"missing config":  {WithConfigFile(filepath.Join(t.TempDir(), "missing.env"))},
+	}
+	for name, opts := range tests {
+		if _, err := newRequest(DefaultImage, opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		} else if !strings.HasPrefix(err.Error(), "pubsubemulator: ") {
+			t.Errorf("%s: expected a prefixed error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+// Package emulatortest runs the Pub/Sub emulator in-process for go test.
+//
+// Start launches an in-memory Pub/Sub backend, creates the topics and
+// subscriptions passed as options and returns an Emulator with a connected
+// client. Every message published to one of its topics is recorded, the same
+// way the dashboard records them, so tests can assert on what was published:
+//
+//	func TestOrders(t *testing.T) {
+//		emu := emulatortest.Start(t, emulatortest.WithTopic("orders", "orders-sub"))
+//		svc := orders.New(emu.Client)
+//		svc.Place(ctx, order)
+//		emu.WaitForMessage(t, "orders", emulatortest.HasAttribute("type", "OrderCreated"))
+//	}
+//
+// Everything is stopped through t.Cleanup.
+package emulatortest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	gcppubsub "cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/dashboard"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/expect"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/pubsub"
+	"github.com/dipjyotimetia/pubsub-emulator/internal/templates"
+	"github.com/dipjyotimetia/pubsub-emulator/pkg/logger"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// DefaultProject is the project used without WithProject.
+	DefaultProject = "test-project"
+	// DefaultWaitTimeout bounds WaitForMessage without WithWaitTimeout.
+	DefaultWaitTimeout = 5 * time.Second
+	// RecorderSuffix names the subscription each topic gets for recording
+	// its messages: topic "orders" is recorded through "orders-recorder".
+	RecorderSuffix = "-recorder"
+
+	// ackDeadlineSeconds is the ack deadline of created subscriptions.
+	ackDeadlineSeconds = 10
+	// pollInterval is how often WaitForMessage checks for new messages.
+	pollInterval = 10 * time.Millisecond
+)
+
+// Message is a recorded message.
+type Message = dashboard.MessageInfo
+
+// Option configures Start.
+type Option func(*options)
+
+type options struct {
+	project     string
+	topics      []topology
+	waitTimeout time.Duration
+	logLevel    slog.Level
+}
+
+type topology struct {
+	topic         string
+	subscriptions []string
+}
+
+// WithProject sets the project the emulator serves (DefaultProject
+// otherwise).
+func WithProject(id string) Option {
+	return func(o *options) { o.project = id }
+}
+
+// WithTopic creates a topic and subscriptions attached to it. The
+// subscriptions are left for the code under test to consume; recording uses
+// a separate subscription (see RecorderSuffix).
+func WithTopic(topicID string, subscriptionIDs ...string) Option {
+	return func(o *options) {
+		o.topics = append(o.topics, topology{topic: topicID, subscriptions: subscriptionIDs})
+	}
+}
+
+// WithWaitTimeout sets how long WaitForMessage waits before failing the
+// test (DefaultWaitTimeout otherwise).
+func WithWaitTimeout(d time.Duration) Option {
+	return func(o *options) { o.waitTimeout = d }
+}
+
+// WithLogLevel sets the level of the emulator's logs, which are written to
+// the test's output (slog.LevelWarn otherwise).
+func WithLogLevel(level slog.Level) Option {
+	return func(o *options) { o.logLevel = level }
+}
+
+// Emulator is an in-process emulator started by Start.
+type Emulator struct {
+	// Client is connected to the emulator in ProjectID.
+	Client *gcppubsub.Client
+	// Addr is the emulator's gRPC address, for clients created by the code
+	// under test (for example through PUBSUB_EMULATOR_HOST).
+	Addr      string
+	ProjectID string
+
+	dash        *dashboard.Dashboard
+	client      *pubsub.Client
+	receivers   *pubsub.Receivers
+	waitTimeout time.Duration
+	log         *logger.Logger
+}
+
+// Start runs an emulator for the duration of the test, failing it if the
+// emulator cannot be set up.
+func Start(t testing.TB, opts ...Option) *Emulator {
+	t.Helper()
+	o := options{project: DefaultProject, waitTimeout: DefaultWaitTimeout, logLevel: slog.LevelWarn}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(t.Output(), &slog.HandlerOptions{Level: o.logLevel}))}
+
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+	conn, err := grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("emulatortest: failed to dial the emulator: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := pubsub.NewClient(ctx, o.project, log, option.WithGRPCConn(conn))
+	if err != nil {
+		cancel()
+		t.Fatalf("emulatortest: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	e := &Emulator{
+		Client:      client.GetClient(),
+		Addr:        srv.Addr,
+		ProjectID:   o.project,
+		dash:        dashboard.New(client.GetClient(), o.project, log),
+		client:      client,
+		waitTimeout: o.waitTimeout,
+		log:         log,
+	}
+	e.dash.SetTemplates(templates.NewStore())
+	e.dash.SetExpectations(expect.NewRegistry())
+
+	sub := pubsub.NewSubscriber(client, log)
+	sub.OnAck(func(ctx context.Context, msg *gcppubsub.Message) {
+		delivery, _ := pubsub.DeliveryFromContext(ctx)
+		e.dash.RecordAck(msg.ID, delivery.SubscriptionID)
+	})
+	e.receivers = sub.NewReceivers(ctx, func(ctx context.Context, msg *gcppubsub.Message) {
+		delivery, _ := pubsub.DeliveryFromContext(ctx)
+		e.dash.RecordDelivery(msg, delivery.TopicID, delivery.SubscriptionID)
+	})
+	// Cleanups run last-in first-out, so receivers drain before the client
+	// and server close.
+	t.Cleanup(func() {
+		cancel()
+		e.receivers.Wait()
+	})
+
+	for _, tp := range o.topics {
+		e.CreateTopic(t, tp.topic, tp.subscriptions...)
+	}
+	return e
+}
+
+// CreateTopic creates a topic, starts recording its messages and creates
+// subscriptions attached to it, like WithTopic. It fails the test if any of
+// them cannot be created.
+func (e *Emulator) CreateTopic(t testing.TB, topicID string, subscriptionIDs ...string) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := e.client.CreateTopic(ctx, topicID); err != nil {
+		t.Fatalf("emulatortest: %v", err)
+	}
+	recorder := topicID + RecorderSuffix
+	for _, subID := range append([]string{recorder}, subscriptionIDs...) {
+		if _, err := e.client.CreateSubscription(ctx, subID, topicID, ackDeadlineSeconds); err != nil {
+			t.Fatalf("emulatortest: %v", err)
+		}
+	}
+	e.receivers.Start(recorder, topicID)
+}
+
+// Publish publishes a message to topicID and returns its ID, failing the
+// test if the publish fails.
+func (e *Emulator) Publish(t testing.TB, topicID, data string, attributes map[string]string) string {
+	t.Helper()
+	id, err := pubsub.NewPublisher(e.client, e.log).PublishMessage(context.Background(), topicID, data, attributes)
+	if err != nil {
+		t.Fatalf("emulatortest: %v", err)
+	}
+	return id
+}
+
+// Messages returns the recorded messages, oldest first. Like the dashboard
+// it keeps the most recent 1,000.
+func (e *Emulator) Messages() []Message {
+	return e.dash.GetProjectMessages(e.ProjectID)
+}
+
+// Handler serves the dashboard's HTTP API (such as /api/messages and
+// /api/expectations) for the emulator, for use with httptest.
+func (e *Emulator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	e.dash.RegisterRoutes(mux)
+	return mux
+}
+
+// Matcher reports whether a message is the one a test is waiting for.
+type Matcher func(Message) bool
+
+// Any matches every message.
+func Any() Matcher {
+	return func(Message) bool { return true }
+}
+
+// HasAttribute matches messages whose attribute key equals value.
+func HasAttribute(key, value string) Matcher {
+	return func(m Message) bool {
+		v, ok := m.Attributes[key]
+		return ok && v == value
+	}
+}
+
+// DataEquals matches messages whose payload is data.
+func DataEquals(data string) Matcher {
+	return func(m Message) bool { return m.Data == data }
+}
+
+// DataContains matches messages whose payload contains s.
+func DataContains(s string) Matcher {
+	return func(m Message) bool { return strings.Contains(m.Data, s) }
+}
+
+// All matches messages that every matcher matches.
+func All(matchers ...Matcher) Matcher {
+	return func(m Message) bool {
+		for _, match := range matchers {
+			if !match(m) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// WaitForMessage waits for a message on topicID that match accepts, failing
+// the test if none is recorded within the wait timeout. Messages recorded
+// before the call count.
+func (e *Emulator) WaitForMessage(t testing.TB, topicID string, match Matcher) Message {
+	t.Helper()
+	return e.WaitForMessages(t, topicID, 1, match)[0]
+}
+
+// WaitForMessages waits for n messages on topicID that match accepts and
+// returns the first n, failing the test if they are not recorded within the
+// wait timeout.
+func (e *Emulator) WaitForMessages(t testing.TB, topicID string, n int, match Matcher) []Message {
+	t.Helper()
+	deadline := time.Now().Add(e.waitTimeout)
+	for {
+		matched, seen := e.find(topicID, match)
+		if len(matched) >= n {
+			return matched[:n]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("emulatortest: timed out after %s waiting for %d matching messages on %s; got %d of %d recorded there:%s",
+				e.waitTimeout, n, topicID, len(matched), len(seen), describe(seen))
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// AssertNoMessage fails the test if a message on topicID that match accepts
+// is recorded before within passes.
+func (e *Emulator) AssertNoMessage(t testing.TB, topicID string, match Matcher, within time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(within)
+	for {
+		if matched, _ := e.find(topicID, match); len(matched) > 0 {
+			t.Fatalf("emulatortest: expected no matching message on %s, got:%s", topicID, describe(matched))
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// find returns the recorded messages on topicID that match accepts, and all
+// the messages recorded there.
+func (e *Emulator) find(topicID string, match Matcher) (matched, seen []Message) {
+	for _, m := range e.Messages() {
+		if m.Topic != topicID {
+			continue
+		}
+		seen = append(seen, m)
+		if match(m) {
+			matched = append(matched, m)
+		}
+	}
+	return matched, seen
+}
+
+// describe lists messages for a failure message, most recent last.
+func describe(msgs []Message) string {
+	const limit = 10
+	var b strings.Builder
+	if len(msgs) > limit {
+		fmt.Fprintf(&b, "\n\t(%d earlier messages omitted)", len(msgs)-limit)
+		msgs = msgs[len(msgs)-limit:]
+	}
+	for _, m := range msgs {
+		keys := make([]string, 0, len(m.Attributes))
+		for k := range m.Attributes {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		attrs := make([]string, len(keys))
+		for i, k := range keys {
+			attrs[i] = k + "=" + m.Attributes[k]
+		}
+		fmt.Fprintf(&b, "\n\t%s data=%q attributes={%s}", m.ID, m.Data, strings.Join(attrs, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package emulatortest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+)
+
+// fatalRecorder captures Fatalf so failing helpers can be tested.
+type fatalRecorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *fatalRecorder) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// expectFatal runs fn and returns the message it failed with, if any.
+func expectFatal(t *testing.T, fn func(tb testing.TB)) string {
+	r := &fatalRecorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r.failure
+}
+
+func TestStart_RecordsMessages(t *testing.T) {
+	emu := Start(t, WithProject("shop"), WithTopic("orders", "orders-sub"))
+
+	// The client publishes and consumes like any other in the project.
+	res := emu.Client.Publisher("orders").Publish(context.Background(), &pubsub.Message{
+		Data:       []byte(`{"id":1}`),
+		Attributes: map[string]string{"type": "OrderCreated"},
+	})
+	id, err := res.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	emu.Publish(t, "orders", `{"id":2}`, map[string]string{"type": "OrderCancelled"})
+
+	got := emu.WaitForMessage(t, "orders", All(HasAttribute("type", "OrderCreated"), DataContains(`"id":1`)))
+	if got.ID != id || got.Project != "shop" {
+		t.Errorf("Expected message %s in project shop, got %+v", id, got)
+	}
+	if msgs := emu.WaitForMessages(t, "orders", 2, Any()); len(msgs) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(msgs))
+	}
+
+	// The topology's own subscription still gets every message.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	received := 0
+	err = emu.Client.Subscriber("orders-sub").Receive(ctx, func(_ context.Context, m *pubsub.Message) {
+		m.Ack()
+		if received++; received == 2 {
+			cancel()
+		}
+	})
+	if err != nil || received != 2 {
+		t.Errorf("Expected orders-sub to receive 2 messages, got %d (%v)", received, err)
+	}
+}
+
+func TestWaitForMessage_Timeout(t *testing.T) {
+	emu := Start(t, WithTopic("orders"), WithWaitTimeout(100*time.Millisecond))
+	emu.Publish(t, "orders", "hello", map[string]string{"type": "Greeting"})
+	emu.WaitForMessage(t, "orders", DataEquals("hello"))
+
+	failure := expectFatal(t, func(tb testing.TB) {
+		emu.WaitForMessage(tb, "orders", HasAttribute("type", "OrderCreated"))
+	})
+	for _, want := range []string{"timed out", "got 0 of 1", `data="hello"`, "type=Greeting"} {
+		if !strings.Contains(failure, want) {
+			t.Errorf("Expected the failure to mention %q, got %q", want, failure)
+		}
+	}
+}
+
+func TestAssertNoMessage(t *testing.T) {
+	emu := Start(t, WithTopic("orders"), WithTopic("audit"))
+	emu.Publish(t, "audit", "x", nil)
+	emu.WaitForMessage(t, "audit", Any())
+
+	emu.AssertNoMessage(t, "orders", Any(), 50*time.Millisecond)
+	failure := expectFatal(t, func(tb testing.TB) {
+		emu.AssertNoMessage(tb, "audit", DataEquals("x"), 50*time.Millisecond)
+	})
+	if !strings.Contains(failure, "expected no matching message on audit") {
+		t.Errorf("Unexpected failure: %q", failure)
+	}
+}
+
+func TestEmulator_Handler(t *testing.T) {
+	emu := Start(t, WithTopic("orders"))
+	emu.Publish(t, "orders", "hello", nil)
+	emu.WaitForMessage(t, "orders", Any())
+
+	w := httptest.NewRecorder()
+	emu.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/messages", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"hello"`) {
+		t.Errorf("Expected the message from /api/messages, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -16,6 +16,15 @@ fi
 # Export the emulator host for the client application
 export PUBSUB_EMULATOR_HOST="localhost:${PUBSUB_PORT}"
 
+# With PUBSUB_SUPERVISE=true the Go binary launches the emulator itself,
+# restarts it if it crashes and stops it on shutdown, so the container lives
+# and dies with the application.
+if [ "${PUBSUB_SUPERVISE}" = "true" ]; then
+  export PUBSUB_PORT
+  echo "INFO: Starting supervised PubSub emulator on port ${PUBSUB_PORT} for project ${PUBSUB_PROJECT}"
+  exec /usr/bin/pubsub-emulator serve
+fi
+
 # Start the pubsub-emulator client in background with proper error handling
 (
   # Wait for emulator to be ready before starting client
@@ -30,5 +39,6 @@ export PUBSUB_EMULATOR_HOST="localhost:${PUBSUB_PORT}"
 ) &
 
 # Start the actual emulator in foreground
-echo "INFO: Starting PubSub emulator on port ${PUBSUB_PORT} for project ${PUBSUB_PROJECT}"
-exec gcloud beta emulators pubsub start --host-port=0.0.0.0:${PUBSUB_PORT} --project=${PUBSUB_PROJECT} "$@"
\ No newline at end of file
+# PUBSUB_PROJECT may list several projects; the emulator takes the first.
+echo "INFO: Starting PubSub emulator on port ${PUBSUB_PORT} for project ${PUBSUB_PROJECT%%,*}"
+exec gcloud beta emulators pubsub start --host-port=0.0.0.0:${PUBSUB_PORT} --project=${PUBSUB_PROJECT%%,*} "$@"
\ No newline at end of file